@@ -9,10 +9,14 @@ import (
 
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/serial"
 	"github.com/luckyluke-a/xray-core/common/signal/done"
 	core "github.com/luckyluke-a/xray-core/core"
 	"github.com/luckyluke-a/xray-core/features/outbound"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 // Commander is a Xray feature that provides gRPC methods to external clients.
@@ -23,13 +27,30 @@ type Commander struct {
 	ohm      outbound.Manager
 	tag      string
 	listen   string
+
+	instance    *core.Instance
+	journalPath string
+	journal     *Journal
+	journaled   map[string]bool
+	// journalAccess serializes the journaled calls, so that the journal and
+	// the config compacted into it stay in the same order.
+	journalAccess sync.Mutex
+	// base is the config the journal is replayed on.
+	base        *core.Config
+	compactSize int64
 }
 
+// journalCompactSize is the least size of the journal to compact it at.
+const journalCompactSize = 1 << 20
+
 // NewCommander creates a new Commander based on the given config.
 func NewCommander(ctx context.Context, config *Config) (*Commander, error) {
 	c := &Commander{
-		tag:    config.Tag,
-		listen: config.Listen,
+		tag:         config.Tag,
+		listen:      config.Listen,
+		instance:    core.FromContext(ctx),
+		journalPath: config.Journal,
+		journaled:   make(map[string]bool),
 	}
 
 	common.Must(core.RequireFeatures(ctx, func(om outbound.Manager) {
@@ -50,6 +71,11 @@ func NewCommander(ctx context.Context, config *Config) (*Commander, error) {
 			return nil, errors.New("not a Service.")
 		}
 		c.services = append(c.services, service)
+		if js, ok := service.(JournaledService); ok {
+			for _, method := range js.JournaledMethods() {
+				c.journaled[method] = true
+			}
+		}
 	}
 
 	return c, nil
@@ -63,12 +89,18 @@ func (c *Commander) Type() interface{} {
 // Start implements common.Runnable.
 func (c *Commander) Start() error {
 	c.Lock()
-	c.server = grpc.NewServer()
+	c.server = grpc.NewServer(grpc.ChainUnaryInterceptor(c.recordCall))
 	for _, service := range c.services {
 		service.Register(c.server)
 	}
 	c.Unlock()
 
+	if len(c.journalPath) > 0 {
+		if err := c.replayJournal(); err != nil {
+			return err
+		}
+	}
+
 	var listen = func(listener net.Listener) {
 		if err := c.server.Serve(listener); err != nil {
 			errors.LogErrorInner(context.Background(), err, "failed to start grpc server")
@@ -112,10 +144,122 @@ func (c *Commander) Close() error {
 		c.server.Stop()
 		c.server = nil
 	}
+	if c.journal != nil {
+		c.journal.Close()
+		c.journal = nil
+	}
 
 	return nil
 }
 
+// replayJournal calls the methods in the journal again through an in-memory
+// connection, before the API is served. Calls that fail, for example when
+// the change has been added to the config since, are skipped with a warning.
+// The journal is then compacted, which drops the failed calls.
+func (c *Commander) replayJournal() error {
+	ctx := context.Background()
+	journal, entries, err := OpenJournal(c.journalPath)
+	if err != nil {
+		return errors.New("failed to open journal ", c.journalPath).Base(err)
+	}
+	if c.instance != nil {
+		c.base = c.instance.Config()
+	}
+
+	if len(entries) > 0 {
+		listener := &OutboundListener{
+			buffer: make(chan net.Conn, 4),
+			done:   done.New(),
+		}
+		go c.server.Serve(listener)
+		defer listener.Close()
+
+		conn, err := grpc.NewClient("passthrough:///journal",
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				client, server := net.Pipe()
+				listener.add(server)
+				return client, nil
+			}))
+		if err != nil {
+			journal.Close()
+			return err
+		}
+		defer conn.Close()
+
+		for i, entry := range entries {
+			request, err := entry.Request.GetInstance()
+			if err == nil {
+				err = conn.Invoke(ctx, entry.Method, request, new(emptypb.Empty))
+			}
+			if err != nil {
+				errors.LogWarningInner(ctx, err, "skipped journal entry ", i, ": ", entry.Method)
+			}
+		}
+		errors.LogInfo(ctx, "replayed ", len(entries), " journal entries")
+	}
+	c.compactJournal(ctx, journal)
+
+	c.Lock()
+	c.journal = journal
+	c.Unlock()
+	return nil
+}
+
+// compactJournal rewrites the journal with the changes between the config it
+// is replayed on and the running config. It is compacted again once it has
+// doubled in size, and at least journalCompactSize.
+func (c *Commander) compactJournal(ctx context.Context, journal *Journal) {
+	if c.base != nil {
+		entries, err := CompactJournal(c.base, c.instance.Config())
+		if err == nil {
+			err = journal.Rewrite(entries)
+		}
+		if err != nil {
+			errors.LogWarningInner(ctx, err, "failed to compact journal ", c.journalPath)
+		} else {
+			errors.LogInfo(ctx, "compacted journal ", c.journalPath, " into ", len(entries), " entries")
+		}
+	}
+	c.compactSize = journal.Size() * 2
+	if c.compactSize < journalCompactSize {
+		c.compactSize = journalCompactSize
+	}
+}
+
+// recordCall is a grpc.UnaryServerInterceptor that records the successful
+// calls of journaled methods in the journal.
+func (c *Commander) recordCall(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !c.journaled[info.FullMethod] {
+		return handler(ctx, request)
+	}
+
+	c.journalAccess.Lock()
+	defer c.journalAccess.Unlock()
+
+	response, err := handler(ctx, request)
+	if err != nil {
+		return response, err
+	}
+
+	c.Lock()
+	journal := c.journal
+	c.Unlock()
+	if journal == nil {
+		return response, nil
+	}
+	if err := journal.Append(&JournalEntry{
+		Method:  info.FullMethod,
+		Request: serial.ToTypedMessage(request.(proto.Message)),
+	}); err != nil {
+		errors.LogErrorInner(ctx, err, "failed to record ", info.FullMethod, " in the journal")
+	}
+	if journal.Size() >= c.compactSize {
+		c.compactJournal(ctx, journal)
+	}
+	return response, nil
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
 		return NewCommander(ctx, cfg.(*Config))
@@ -0,0 +1,236 @@
+package commander
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	core "github.com/luckyluke-a/xray-core/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// ConfigChange is implemented by the requests of journaled methods, to apply
+// them to a config without a running instance.
+type ConfigChange interface {
+	// ApplyConfig makes the change of the request to the config.
+	ApplyConfig(*core.Config) error
+}
+
+// JournalCompactor returns the entries that change the part of base that a
+// JournaledService changes into the one of current. Replaying the entries on
+// current must change nothing, so that a compacted journal can be kept after
+// the config is replaced by the one it was compacted into.
+type JournalCompactor func(base, current *core.Config) ([]*JournalEntry, error)
+
+var journalCompactors []JournalCompactor
+
+// RegisterJournalCompactor registers the compactor of a JournaledService.
+// Every JournaledService must register one, or its changes are lost on
+// compaction.
+func RegisterJournalCompactor(compactor JournalCompactor) {
+	journalCompactors = append(journalCompactors, compactor)
+}
+
+// CompactJournal returns the entries that change base into current.
+func CompactJournal(base, current *core.Config) ([]*JournalEntry, error) {
+	var entries []*JournalEntry
+	for _, compactor := range journalCompactors {
+		e, err := compactor(base, current)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+// Journal is an append-only file of JournalEntry. Each entry is a
+// length-prefixed protobuf message.
+type Journal struct {
+	access sync.Mutex
+	path   string
+	file   *os.File
+	size   int64
+}
+
+// decodeJournal returns the entries of the journal, and the size of the
+// entries that are complete. A crash while appending leaves an incomplete
+// entry at the end, which is dropped.
+func decodeJournal(content []byte) ([]*JournalEntry, int, error) {
+	var entries []*JournalEntry
+	size := 0
+	for size < len(content) {
+		length, n := protowire.ConsumeVarint(content[size:])
+		if n < 0 || uint64(len(content)-size-n) < length {
+			errors.LogWarning(context.Background(), "dropped incomplete entry at the end of the journal")
+			break
+		}
+		entry := new(JournalEntry)
+		if err := proto.Unmarshal(content[size+n:size+n+int(length)], entry); err != nil {
+			return nil, 0, errors.New("invalid journal entry ", len(entries)).Base(err)
+		}
+		entries = append(entries, entry)
+		size += n + int(length)
+	}
+	return entries, size, nil
+}
+
+// ReadJournal returns the entries of the journal at path.
+func ReadJournal(path string) ([]*JournalEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := decodeJournal(content)
+	return entries, err
+}
+
+// OpenJournal opens the journal at path for appending, which is created if
+// it doesn't exist, and returns the entries in it.
+func OpenJournal(path string) (*Journal, []*JournalEntry, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	entries, size, err := decodeJournal(content)
+	if err == nil && size < len(content) {
+		err = file.Truncate(int64(size))
+	}
+	if err == nil {
+		_, err = file.Seek(int64(size), io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return &Journal{path: path, file: file, size: int64(size)}, entries, nil
+}
+
+func encodeJournalEntry(entry *JournalEntry) ([]byte, error) {
+	b, err := proto.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(protowire.AppendVarint(nil, uint64(len(b))), b...), nil
+}
+
+// writeJournal replaces the journal at path with the entries, and returns the
+// new file open for appending. The file is replaced at once, so that a crash
+// leaves either the old or the new journal.
+func writeJournal(path string, entries []*JournalEntry) (*os.File, int64, error) {
+	var content []byte
+	for _, entry := range entries {
+		b, err := encodeJournalEntry(entry)
+		if err != nil {
+			return nil, 0, err
+		}
+		content = append(content, b...)
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err = file.Write(content); err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return nil, 0, err
+	}
+	return file, int64(len(content)), nil
+}
+
+// WriteJournal replaces the journal at path with the entries.
+func WriteJournal(path string, entries []*JournalEntry) error {
+	file, _, err := writeJournal(path, entries)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Append writes the entry to the end of the journal, and syncs it to disk.
+func (j *Journal) Append(entry *JournalEntry) error {
+	b, err := encodeJournalEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	j.access.Lock()
+	defer j.access.Unlock()
+
+	if _, err := j.file.Write(b); err != nil {
+		// Drop the partial entry, so that later entries can still be read.
+		j.file.Truncate(j.size)
+		j.file.Seek(j.size, io.SeekStart)
+		return err
+	}
+	j.size += int64(len(b))
+	return j.file.Sync()
+}
+
+// Size returns the size of the entries in the journal.
+func (j *Journal) Size() int64 {
+	j.access.Lock()
+	defer j.access.Unlock()
+
+	return j.size
+}
+
+// Rewrite replaces the entries in the journal, to compact it.
+func (j *Journal) Rewrite(entries []*JournalEntry) error {
+	j.access.Lock()
+	defer j.access.Unlock()
+
+	file, size, err := writeJournal(j.path, entries)
+	if err != nil {
+		return err
+	}
+	j.file.Close()
+	j.file = file
+	j.size = size
+	return nil
+}
+
+// Close closes the journal.
+func (j *Journal) Close() error {
+	j.access.Lock()
+	defer j.access.Unlock()
+
+	return j.file.Close()
+}
+
+// ApplyJournal makes the changes of the entries to the config, which
+// compacts a journal into a config. Like on replay, entries that fail are
+// skipped with a warning.
+func ApplyJournal(config *core.Config, entries []*JournalEntry) error {
+	ctx := context.Background()
+	for i, entry := range entries {
+		request, err := entry.Request.GetInstance()
+		if err != nil {
+			return errors.New("unknown request of journal entry ", i, ": ", entry.Method).Base(err)
+		}
+		change, ok := request.(ConfigChange)
+		if !ok {
+			return errors.New("request of journal entry ", i, " can't be applied to a config: ", entry.Method)
+		}
+		if err := change.ApplyConfig(config); err != nil {
+			errors.LogWarningInner(ctx, err, "skipped journal entry ", i, ": ", entry.Method)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package commander_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/luckyluke-a/xray-core/app/commander"
+	"github.com/luckyluke-a/xray-core/app/proxyman/command"
+	"github.com/luckyluke-a/xray-core/app/router"
+	routercommand "github.com/luckyluke-a/xray-core/app/router/command"
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/serial"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/proxy/freedom"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	requests := []*command.AddOutboundRequest{
+		{Outbound: &core.OutboundHandlerConfig{Tag: "a"}},
+		{Outbound: &core.OutboundHandlerConfig{Tag: "b"}},
+		{Outbound: &core.OutboundHandlerConfig{Tag: "a"}},
+	}
+
+	journal, entries, err := OpenJournal(path)
+	common.Must(err)
+	if len(entries) != 0 {
+		t.Fatalf("unexpected entries in a new journal: %v", entries)
+	}
+	for _, request := range requests {
+		common.Must(journal.Append(&JournalEntry{
+			Method:  command.HandlerService_AddOutbound_FullMethodName,
+			Request: serial.ToTypedMessage(request),
+		}))
+	}
+	common.Must(journal.Close())
+
+	// An entry cut off by a crash is dropped, and later entries are kept.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	common.Must(err)
+	_, err = file.Write([]byte{100, 1, 2})
+	common.Must(err)
+	common.Must(file.Close())
+
+	journal, entries, err = OpenJournal(path)
+	common.Must(err)
+	if len(entries) != len(requests) {
+		t.Fatalf("expected %d entries, got %d", len(requests), len(entries))
+	}
+	common.Must(journal.Append(&JournalEntry{
+		Method:  command.HandlerService_RemoveOutbound_FullMethodName,
+		Request: serial.ToTypedMessage(&command.RemoveOutboundRequest{Tag: "a"}),
+	}))
+	common.Must(journal.Close())
+
+	entries, err = ReadJournal(path)
+	common.Must(err)
+	config := &core.Config{
+		Outbound: []*core.OutboundHandlerConfig{{Tag: "direct"}},
+	}
+	common.Must(ApplyJournal(config, entries))
+	if len(config.Outbound) != 2 || config.Outbound[0].Tag != "direct" || config.Outbound[1].Tag != "b" {
+		t.Errorf("unexpected outbounds after applying the journal: %v", config.Outbound)
+	}
+}
+
+func TestCompactJournal(t *testing.T) {
+	base := &core.Config{
+		Inbound:  []*core.InboundHandlerConfig{{Tag: "in"}},
+		Outbound: []*core.OutboundHandlerConfig{{Tag: "direct"}, {Tag: "a"}},
+	}
+	path := filepath.Join(t.TempDir(), "journal")
+	journal, _, err := OpenJournal(path)
+	common.Must(err)
+	defer journal.Close()
+	for _, request := range []interface {
+		proto.Message
+		ConfigChange
+	}{
+		&command.AddOutboundRequest{Outbound: &core.OutboundHandlerConfig{Tag: "b"}},
+		&command.RemoveOutboundRequest{Tag: "a"},
+		&command.AddOutboundRequest{Outbound: &core.OutboundHandlerConfig{Tag: "c"}},
+		&command.RemoveOutboundRequest{Tag: "c"},
+		&command.RemoveInboundRequest{Tag: "in"},
+		&command.AddInboundRequest{Inbound: &core.InboundHandlerConfig{Tag: "in", ProxySettings: serial.ToTypedMessage(&freedom.Config{})}},
+		&routercommand.AddRuleRequest{
+			Config:       serial.ToTypedMessage(&router.Config{Rule: []*router.RoutingRule{{RuleTag: "r"}}}),
+			ShouldAppend: true,
+		},
+		&routercommand.RemoveRuleRequest{RuleTag: "missing"},
+	} {
+		common.Must(journal.Append(&JournalEntry{Request: serial.ToTypedMessage(request)}))
+	}
+
+	current := proto.Clone(base).(*core.Config)
+	entries, err := ReadJournal(path)
+	common.Must(err)
+	common.Must(ApplyJournal(current, entries))
+	compacted, err := CompactJournal(base, current)
+	common.Must(err)
+	if len(compacted) >= len(entries) {
+		t.Fatalf("journal of %d entries not compacted: %v", len(entries), compacted)
+	}
+	common.Must(journal.Rewrite(compacted))
+	common.Must(journal.Append(&JournalEntry{
+		Request: serial.ToTypedMessage(&command.AddOutboundRequest{Outbound: &core.OutboundHandlerConfig{Tag: "d"}}),
+	}))
+	entries, err = ReadJournal(path)
+	common.Must(err)
+	if len(entries) != len(compacted)+1 {
+		t.Fatalf("expected %d entries after rewrite, got %d", len(compacted)+1, len(entries))
+	}
+	compacted = entries
+	expected := proto.Clone(current).(*core.Config)
+	expected.Outbound = append(expected.Outbound, &core.OutboundHandlerConfig{Tag: "d"})
+
+	// The compacted journal makes the same changes to the base config, and
+	// none to the config it was compacted into.
+	for _, config := range []*core.Config{base, proto.Clone(expected).(*core.Config)} {
+		common.Must(ApplyJournal(config, compacted))
+		if !proto.Equal(config, expected) {
+			t.Errorf("unexpected config after applying the compacted journal: %v", config)
+		}
+	}
+}
+
+func TestApplyRemoveRuleWithoutRouter(t *testing.T) {
+	config := &core.Config{
+		Outbound: []*core.OutboundHandlerConfig{{Tag: "direct"}},
+	}
+	common.Must(ApplyJournal(config, []*JournalEntry{{
+		Request: serial.ToTypedMessage(&routercommand.RemoveRuleRequest{RuleTag: "r"}),
+	}}))
+	if len(config.App) != 0 {
+		t.Errorf("unexpected apps after removing a rule: %v", config.App)
+	}
+}
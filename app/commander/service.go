@@ -14,6 +14,15 @@ type Service interface {
 	Register(*grpc.Server)
 }
 
+// JournaledService is a Service with methods that change the instance. Calls
+// of those methods are recorded in the journal, if there is one, and
+// replayed on start.
+type JournaledService interface {
+	Service
+	// JournaledMethods returns the full names of the methods to record.
+	JournaledMethods() []string
+}
+
 type reflectionService struct{}
 
 func (r reflectionService) Register(s *grpc.Server) {
@@ -0,0 +1,167 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/buf"
+	c "github.com/luckyluke-a/xray-core/common/ctx"
+	"github.com/luckyluke-a/xray-core/common/log"
+	"github.com/luckyluke-a/xray-core/common/session"
+)
+
+// accessTracker counts the traffic of a connection, and records its access
+// message again when the connection is closed. The message is written by the
+// dispatch while the connection may be closed, so the tracker only reads a
+// copy of it, given once the connection is routed.
+type accessTracker struct {
+	start    time.Time
+	uplink   atomic.Int64
+	downlink atomic.Int64
+	pending  atomic.Int32
+
+	access  sync.Mutex
+	message *log.AccessMessage
+	closed  bool
+}
+
+type accessTrackerKey struct{}
+
+func newAccessTracker(directions int32) *accessTracker {
+	t := &accessTracker{
+		start: time.Now(),
+	}
+	t.pending.Store(directions)
+	return t
+}
+
+func accessTrackerFromContext(ctx context.Context) *accessTracker {
+	t, _ := ctx.Value(accessTrackerKey{}).(*accessTracker)
+	return t
+}
+
+// routed is called with the message once the connection is routed, which is
+// not written after.
+func (t *accessTracker) routed(message *log.AccessMessage) {
+	msg := *message
+	t.access.Lock()
+	defer t.access.Unlock()
+	t.message = &msg
+	if t.closed {
+		t.record()
+	}
+}
+
+// done is called when a direction is closed. The message is recorded after
+// all, once the connection is routed.
+func (t *accessTracker) done() {
+	if t.pending.Add(-1) != 0 {
+		return
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+	t.closed = true
+	if t.message != nil {
+		t.record()
+	}
+}
+
+func (t *accessTracker) record() {
+	msg := *t.message
+	msg.Status = log.AccessClosed
+	msg.Uplink = t.uplink.Load()
+	msg.Downlink = t.downlink.Load()
+	msg.Duration = time.Since(t.start)
+	log.Record(&msg)
+}
+
+// accessWriter counts a direction of a connection by its writer.
+type accessWriter struct {
+	buf.Writer
+	counter *atomic.Int64
+	tracker *accessTracker
+	once    sync.Once
+}
+
+func (w *accessWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.counter.Add(int64(mb.Len()))
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+func (w *accessWriter) Close() error {
+	w.once.Do(w.tracker.done)
+	return common.Close(w.Writer)
+}
+
+func (w *accessWriter) Interrupt() {
+	w.once.Do(w.tracker.done)
+	common.Interrupt(w.Writer)
+}
+
+// accessReader counts the uplink by the reader, where the writer is not owned
+// by the dispatcher.
+type accessReader struct {
+	buf.Reader
+	tracker *accessTracker
+}
+
+func (r *accessReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	r.tracker.uplink.Add(int64(mb.Len()))
+	return mb, err
+}
+
+func (r *accessReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	var mb buf.MultiBuffer
+	var err error
+	if reader, ok := r.Reader.(buf.TimeoutReader); ok {
+		mb, err = reader.ReadMultiBufferTimeout(timeout)
+	} else {
+		mb, err = r.Reader.ReadMultiBuffer()
+	}
+	r.tracker.uplink.Add(int64(mb.Len()))
+	return mb, err
+}
+
+func (r *accessReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
+
+func recordSniffedDomain(ctx context.Context, result SniffResult) {
+	if message := log.AccessMessageFromContext(ctx); message != nil {
+		message.Domain = result.Domain()
+	}
+}
+
+// trackAccess wraps the writers of the links created by Dispatch. The
+// returned context carries the tracker to routedDispatch.
+func trackAccess(ctx context.Context, inbound, outbound *buf.Writer) context.Context {
+	message := log.AccessMessageFromContext(ctx)
+	if message == nil {
+		return ctx
+	}
+	message.SessionID = uint32(c.IDFromContext(ctx))
+	message.InboundTag = session.InboundTagFromContext(ctx)
+	t := newAccessTracker(2)
+	*inbound = &accessWriter{Writer: *inbound, counter: &t.uplink, tracker: t}
+	*outbound = &accessWriter{Writer: *outbound, counter: &t.downlink, tracker: t}
+	return context.WithValue(ctx, accessTrackerKey{}, t)
+}
+
+// trackAccessLink wraps a link given to DispatchLink, which is closed with
+// its writer.
+func trackAccessLink(ctx context.Context, reader *buf.Reader, writer *buf.Writer) context.Context {
+	message := log.AccessMessageFromContext(ctx)
+	if message == nil {
+		return ctx
+	}
+	message.SessionID = uint32(c.IDFromContext(ctx))
+	message.InboundTag = session.InboundTagFromContext(ctx)
+	t := newAccessTracker(1)
+	*reader = &accessReader{Reader: *reader, tracker: t}
+	*writer = &accessWriter{Writer: *writer, counter: &t.downlink, tracker: t}
+	return context.WithValue(ctx, accessTrackerKey{}, t)
+}
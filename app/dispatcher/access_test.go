@@ -0,0 +1,104 @@
+package dispatcher_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/luckyluke-a/xray-core/app/dispatcher"
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/log"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/features/outbound"
+	"github.com/luckyluke-a/xray-core/features/policy"
+	"github.com/luckyluke-a/xray-core/features/routing"
+	"github.com/luckyluke-a/xray-core/features/stats"
+	"github.com/luckyluke-a/xray-core/transport"
+	"github.com/luckyluke-a/xray-core/transport/pipe"
+)
+
+type accessRecorder struct {
+	access   sync.Mutex
+	messages []*log.AccessMessage
+}
+
+func (r *accessRecorder) Handle(msg log.Message) {
+	if m, ok := msg.(*log.AccessMessage); ok && m.Status == log.AccessClosed {
+		r.access.Lock()
+		r.messages = append(r.messages, m)
+		r.access.Unlock()
+	}
+}
+
+func (r *accessRecorder) closed() []*log.AccessMessage {
+	r.access.Lock()
+	defer r.access.Unlock()
+	return r.messages
+}
+
+// closingRouter closes the link while it's still routed.
+type closingRouter struct {
+	routing.DefaultRouter
+	link *transport.Link
+}
+
+func (r *closingRouter) PickRoute(ctx routing.Context) (routing.Route, error) {
+	writer := r.link.Writer
+	go common.Close(writer)
+	time.Sleep(10 * time.Millisecond)
+	return nil, common.ErrNoClue
+}
+
+type testHandler struct{}
+
+func (testHandler) Start() error                                       { return nil }
+func (testHandler) Close() error                                       { return nil }
+func (testHandler) Tag() string                                        { return "direct" }
+func (testHandler) Dispatch(ctx context.Context, link *transport.Link) {}
+
+type testOutboundManager struct {
+	handler outbound.Handler
+}
+
+func (testOutboundManager) Type() interface{} { return outbound.ManagerType() }
+func (testOutboundManager) Start() error      { return nil }
+func (testOutboundManager) Close() error      { return nil }
+
+func (m testOutboundManager) GetHandler(tag string) outbound.Handler { return m.handler }
+func (m testOutboundManager) GetDefaultHandler() outbound.Handler    { return m.handler }
+
+func (testOutboundManager) AddHandler(ctx context.Context, handler outbound.Handler) error {
+	return nil
+}
+
+func (testOutboundManager) RemoveHandler(ctx context.Context, tag string) error { return nil }
+
+func TestAccessClosedWhileRouted(t *testing.T) {
+	recorder := &accessRecorder{}
+	log.RegisterHandler(recorder)
+
+	reader, writer := pipe.New()
+	link := &transport.Link{Reader: reader, Writer: writer}
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, testOutboundManager{handler: testHandler{}}, &closingRouter{link: link}, policy.DefaultManager{}, stats.NoopManager{}, nil))
+
+	ctx := log.ContextWithAccessMessage(context.Background(), &log.AccessMessage{
+		From:   "127.0.0.1:10000",
+		To:     "example.com:443",
+		Status: log.AccessAccepted,
+	})
+	common.Must(d.DispatchLink(ctx, net.TCPDestination(net.DomainAddress("example.com"), 443), link))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.closed()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	messages := recorder.closed()
+	if len(messages) != 1 {
+		t.Fatal("expected 1 closed access message, but got ", len(messages))
+	}
+	if messages[0].OutboundTag != "direct" {
+		t.Error("expected the closed message to have the outbound of the route, but got ", messages[0].OutboundTag)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/luckyluke-a/xray-core/app/router"
 	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/dice"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/common/session"
@@ -32,6 +34,7 @@ type DNS struct {
 	ctx                    context.Context
 	domainMatcher          strmatcher.IndexMatcher
 	matcherInfos           []*DomainMatcherInfo
+	echRecords             map[string]*ECHRecord
 }
 
 // DomainMatcherInfo contains information attached to index returned by Server.domainMatcher
@@ -222,6 +225,61 @@ func (s *DNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, error) {
 	return nil, errors.New("returning nil for domain ", domain).Base(errors.Combine(errs...))
 }
 
+// LookupECHConfig implements dns.ECHConfigLookup.
+// Only the name servers able to send arbitrary queries, i.e. DOH, are used.
+func (s *DNS) LookupECHConfig(domain string) ([]byte, time.Duration, error) {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return nil, 0, errors.New("empty domain name")
+	}
+
+	if !s.disableCache {
+		s.Lock()
+		rec := s.echRecords[domain]
+		s.Unlock()
+		if rec != nil && rec.Expire.After(time.Now()) {
+			errors.LogDebug(s.ctx, "ECH config cache HIT ", domain)
+			return rec.Config, time.Until(rec.Expire), nil
+		}
+	}
+
+	msg, err := buildHTTPSReqMsg(domain, dice.RollUint16())
+	if err != nil {
+		return nil, 0, errors.New("failed to build HTTPS query for ", domain).Base(err)
+	}
+
+	errs := []error{}
+	ctx := session.ContextWithInbound(s.ctx, &session.Inbound{Tag: s.tag})
+	for _, client := range s.sortClients(domain) {
+		querier, ok := client.server.(rawQuerier)
+		if !ok {
+			continue
+		}
+		queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		resp, err := querier.QueryRaw(queryCtx, msg)
+		cancel()
+		if err == nil {
+			var rec *ECHRecord
+			if rec, err = parseECHResponse(resp); err == nil {
+				errors.LogInfo(s.ctx, client.Name(), " got ECH config for ", domain)
+				s.Lock()
+				if s.echRecords == nil {
+					s.echRecords = make(map[string]*ECHRecord)
+				}
+				s.echRecords[domain] = rec
+				s.Unlock()
+				return rec.Config, time.Until(rec.Expire), nil
+			}
+		}
+		errors.LogInfoInner(s.ctx, err, "failed to lookup ECH config for domain ", domain, " at server ", client.Name())
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, 0, errors.New("no DOH server to query HTTPS record of ", domain)
+	}
+	return nil, 0, errors.New("returning nil ECH config for domain ", domain).Base(errors.Combine(errs...))
+}
+
 // LookupHosts implements dns.HostsLookup.
 func (s *DNS) LookupHosts(domain string) *net.Address {
 	domain = strings.TrimSuffix(domain, ".")
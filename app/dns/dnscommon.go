@@ -230,6 +230,109 @@ L:
 	return ipRecord, nil
 }
 
+// typeHTTPS is the HTTPS resource record type (RFC 9460), not covered by dnsmessage yet
+const typeHTTPS = dnsmessage.Type(65)
+
+// ECHRecord is a cacheable ECHConfigList of a domain
+type ECHRecord struct {
+	Config []byte
+	Expire time.Time
+}
+
+func buildHTTPSReqMsg(domain string, reqID uint16) ([]byte, error) {
+	name, err := dnsmessage.NewName(Fqdn(domain))
+	if err != nil {
+		return nil, err
+	}
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{ID: reqID, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  typeHTTPS,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	return msg.Pack()
+}
+
+// parseECHResponse finds the "ech" parameter of the HTTPS records in a DNS response.
+func parseECHResponse(payload []byte) (*ECHRecord, error) {
+	var parser dnsmessage.Parser
+	h, err := parser.Start(payload)
+	if err != nil {
+		return nil, errors.New("failed to parse DNS response").Base(err).AtWarning()
+	}
+	if h.RCode != dnsmessage.RCodeSuccess {
+		return nil, dns_feature.RCodeError(h.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, errors.New("failed to skip questions in DNS response").Base(err).AtWarning()
+	}
+	for {
+		ah, err := parser.AnswerHeader()
+		if err != nil {
+			if err == dnsmessage.ErrSectionDone {
+				return nil, dns_feature.ErrEmptyResponse
+			}
+			return nil, errors.New("failed to parse answer section").Base(err).AtWarning()
+		}
+		if ah.Type != typeHTTPS {
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, errors.New("failed to skip answer").Base(err).AtWarning()
+			}
+			continue
+		}
+		ans, err := parser.UnknownResource()
+		if err != nil {
+			return nil, errors.New("failed to parse HTTPS record").Base(err).AtWarning()
+		}
+		if config := parseSVCBParam(ans.Data, 5); config != nil { // 5 for "ech"
+			ttl := ah.TTL
+			if ttl == 0 {
+				ttl = 600
+			}
+			return &ECHRecord{
+				Config: config,
+				Expire: time.Now().Add(time.Duration(ttl) * time.Second),
+			}, nil
+		}
+	}
+}
+
+// parseSVCBParam returns the value of the given key in the SVCB/HTTPS record data, or nil.
+func parseSVCBParam(data []byte, key uint16) []byte {
+	if len(data) < 2 {
+		return nil
+	}
+	data = data[2:] // SvcPriority
+	// TargetName, uncompressed
+	for {
+		if len(data) == 0 {
+			return nil
+		}
+		l := int(data[0])
+		if len(data) < l+1 {
+			return nil
+		}
+		data = data[l+1:]
+		if l == 0 {
+			break
+		}
+	}
+	for len(data) >= 4 {
+		k := binary.BigEndian.Uint16(data)
+		l := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < l+4 {
+			return nil
+		}
+		if k == key {
+			return data[4 : l+4]
+		}
+		data = data[l+4:]
+	}
+	return nil
+}
+
 // toDnsContext create a new background context with parent inbound, session and dns log
 func toDnsContext(ctx context.Context, addr string) context.Context {
 	dnsCtx := core.ToBackgroundDetachedContext(ctx)
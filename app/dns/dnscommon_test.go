@@ -181,3 +181,35 @@ func TestFqdn(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseECHResponse(t *testing.T) {
+	echConfig := []byte{0, 4, 0xfe, 0x0d, 0, 0}
+
+	ans := new(dns.Msg)
+	ans.Answer = append(ans.Answer,
+		common.Must2(dns.NewRR("example.com. 300 IN CNAME cdn.example.com.")).(dns.RR),
+		&dns.HTTPS{SVCB: dns.SVCB{
+			Hdr:      dns.RR_Header{Name: "cdn.example.com.", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET, Ttl: 300},
+			Priority: 1,
+			Target:   "svc.example.com.",
+			Value: []dns.SVCBKeyValue{
+				&dns.SVCBAlpn{Alpn: []string{"h2"}},
+				&dns.SVCBECHConfig{ECH: echConfig},
+			},
+		}},
+	)
+	rec, err := parseECHResponse(common.Must2(ans.Pack()).([]byte))
+	common.Must(err)
+	if r := cmp.Diff(rec.Config, echConfig); r != "" {
+		t.Error(r)
+	}
+	if rec.Expire.Before(time.Now().Add(299 * time.Second)) {
+		t.Error("unexpected expire time ", rec.Expire)
+	}
+
+	ans = new(dns.Msg)
+	ans.Answer = append(ans.Answer, common.Must2(dns.NewRR("example.com. IN HTTPS 1 . alpn=h2")).(dns.RR))
+	if _, err := parseECHResponse(common.Must2(ans.Pack()).([]byte)); err != dns_feature.ErrEmptyResponse {
+		t.Error("expecting empty response, got ", err)
+	}
+}
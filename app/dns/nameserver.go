@@ -23,6 +23,12 @@ type Server interface {
 	QueryIP(ctx context.Context, domain string, clientIP net.IP, option dns.IPOption, disableCache bool) ([]net.IP, error)
 }
 
+// rawQuerier is implemented by the name servers which can send arbitrary queries.
+type rawQuerier interface {
+	// QueryRaw sends a packed DNS message and returns the packed response.
+	QueryRaw(ctx context.Context, msg []byte) ([]byte, error)
+}
+
 // Client is the interface for DNS client.
 type Client struct {
 	server       Server
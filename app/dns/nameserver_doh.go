@@ -285,6 +285,15 @@ func (s *DoHNameServer) sendQuery(ctx context.Context, domain string, clientIP n
 	}
 }
 
+// QueryRaw implements rawQuerier.
+func (s *DoHNameServer) QueryRaw(ctx context.Context, msg []byte) ([]byte, error) {
+	dnsCtx := session.ContextWithContent(ctx, &session.Content{
+		Protocol:       "https",
+		SkipDNSResolve: true,
+	})
+	return s.dohHTTPSContext(dnsCtx, msg)
+}
+
 func (s *DoHNameServer) dohHTTPSContext(ctx context.Context, b []byte) ([]byte, error) {
 	body := bytes.NewBuffer(b)
 	req, err := http.NewRequest("POST", s.dohURL, body)
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/log"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/common/serial"
+	"github.com/luckyluke-a/xray-core/common/strmatcher"
+)
+
+// accessFilter decides whether an access log is written. A nil filter allows
+// all.
+type accessFilter struct {
+	inboundTags  map[string]bool
+	emails       map[string]bool
+	statuses     map[log.AccessStatus]bool
+	outboundTags map[string]bool
+	domains      []strmatcher.Matcher
+	sampleRate   float64
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// parseDomainPattern parses a domain pattern like the ones of routing rules,
+// where a pattern without prefix is a keyword.
+func parseDomainPattern(pattern string) (strmatcher.Matcher, error) {
+	switch {
+	case strings.HasPrefix(pattern, "domain:"):
+		return strmatcher.Domain.New(strings.ToLower(pattern[7:]))
+	case strings.HasPrefix(pattern, "full:"):
+		return strmatcher.Full.New(strings.ToLower(pattern[5:]))
+	case strings.HasPrefix(pattern, "regexp:"):
+		return strmatcher.Regex.New(pattern[7:])
+	case strings.HasPrefix(pattern, "keyword:"):
+		return strmatcher.Substr.New(strings.ToLower(pattern[8:]))
+	default:
+		return strmatcher.Substr.New(strings.ToLower(pattern))
+	}
+}
+
+func newAccessFilter(config *AccessLogFilter) (*accessFilter, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, errors.New("invalid sample rate ", config.SampleRate)
+	}
+	f := &accessFilter{
+		inboundTags:  toSet(config.InboundTags),
+		emails:       toSet(config.Emails),
+		outboundTags: toSet(config.OutboundTags),
+		sampleRate:   config.SampleRate,
+	}
+	for _, status := range config.Statuses {
+		switch s := log.AccessStatus(strings.ToLower(status)); s {
+		case log.AccessAccepted, log.AccessRejected, log.AccessClosed:
+			if f.statuses == nil {
+				f.statuses = make(map[log.AccessStatus]bool)
+			}
+			f.statuses[s] = true
+		default:
+			return nil, errors.New("unknown access status ", status)
+		}
+	}
+	for _, pattern := range config.Domains {
+		m, err := parseDomainPattern(pattern)
+		if err != nil {
+			return nil, errors.New("invalid domain pattern ", pattern).Base(err)
+		}
+		f.domains = append(f.domains, m)
+	}
+	return f, nil
+}
+
+// accessDomain returns the sniffed domain, or the domain of the destination.
+func accessDomain(msg *log.AccessMessage) string {
+	if msg.Domain != "" {
+		return strings.ToLower(msg.Domain)
+	}
+	dest, err := net.ParseDestination(serial.ToString(msg.To))
+	if err != nil || !dest.Address.Family().IsDomain() {
+		return ""
+	}
+	return strings.ToLower(dest.Address.Domain())
+}
+
+// sampled decides by the session, so that the accepted and closed logs of a
+// connection are both written or not.
+func (f *accessFilter) sampled(msg *log.AccessMessage) bool {
+	if f.sampleRate == 0 || f.sampleRate == 1 {
+		return true
+	}
+	h := fnv.New32a()
+	if msg.SessionID != 0 {
+		binary.Write(h, binary.BigEndian, msg.SessionID)
+	} else {
+		h.Write([]byte(serial.ToString(msg.From) + serial.ToString(msg.To)))
+	}
+	return float64(h.Sum32()) < f.sampleRate*math.MaxUint32
+}
+
+// Allow returns whether the access log is written.
+func (f *accessFilter) Allow(msg *log.AccessMessage) bool {
+	if f == nil {
+		return true
+	}
+	if f.inboundTags != nil && !f.inboundTags[msg.InboundTag] {
+		return false
+	}
+	if f.emails != nil && !f.emails[msg.Email] {
+		return false
+	}
+	if f.statuses != nil && !f.statuses[msg.Status] {
+		return false
+	}
+	if f.outboundTags != nil && !f.outboundTags[msg.OutboundTag] && !f.outboundTags[msg.Detour] {
+		return false
+	}
+	if len(f.domains) > 0 {
+		domain := accessDomain(msg)
+		matched := false
+		for _, m := range f.domains {
+			if domain != "" && m.Match(domain) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return f.sampled(msg)
+}
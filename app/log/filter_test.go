@@ -0,0 +1,56 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/log"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+func TestAccessFilter(t *testing.T) {
+	filter, err := newAccessFilter(&AccessLogFilter{
+		InboundTags: []string{"in"},
+		Statuses:    []string{"accepted", "rejected"},
+		Domains:     []string{"domain:example.com", "regexp:^api\\."},
+	})
+	common.Must(err)
+
+	cases := []struct {
+		msg   *log.AccessMessage
+		allow bool
+	}{
+		{&log.AccessMessage{InboundTag: "in", Status: log.AccessAccepted, To: net.TCPDestination(net.DomainAddress("www.example.com"), 443)}, true},
+		{&log.AccessMessage{InboundTag: "in", Status: log.AccessAccepted, To: net.TCPDestination(net.DomainAddress("api.test"), 443)}, true},
+		{&log.AccessMessage{InboundTag: "in", Status: log.AccessAccepted, To: net.TCPDestination(net.LocalHostIP, 443), Domain: "example.com"}, true},
+		{&log.AccessMessage{InboundTag: "in", Status: log.AccessAccepted, To: net.TCPDestination(net.DomainAddress("example.org"), 443)}, false},
+		{&log.AccessMessage{InboundTag: "in", Status: log.AccessClosed, To: net.TCPDestination(net.DomainAddress("example.com"), 443)}, false},
+		{&log.AccessMessage{InboundTag: "other", Status: log.AccessAccepted, To: net.TCPDestination(net.DomainAddress("example.com"), 443)}, false},
+	}
+	for _, c := range cases {
+		if allow := filter.Allow(c.msg); allow != c.allow {
+			t.Error("expect ", c.allow, " for ", c.msg.To, " ", c.msg.InboundTag, " ", c.msg.Status, ", but actually ", allow)
+		}
+	}
+}
+
+func TestAccessFilterSampling(t *testing.T) {
+	filter, err := newAccessFilter(&AccessLogFilter{SampleRate: 0.25})
+	common.Must(err)
+
+	allowed := 0
+	for id := uint32(1); id <= 10000; id++ {
+		msg := &log.AccessMessage{SessionID: id, Status: log.AccessAccepted}
+		allow := filter.Allow(msg)
+		msg.Status = log.AccessClosed
+		if filter.Allow(msg) != allow {
+			t.Fatal("sampling differs in a session")
+		}
+		if allow {
+			allowed++
+		}
+	}
+	if allowed < 2000 || allowed > 3000 {
+		t.Error("expect about 2500 sampled, but actually ", allowed)
+	}
+}
@@ -14,11 +14,19 @@ import (
 // Instance is a log.Handler that handles logs.
 type Instance struct {
 	sync.RWMutex
-	config       *Config
-	accessLogger log.Handler
-	errorLogger  log.Handler
-	active       bool
-	dns          bool
+	config        *Config
+	accessLogger  log.Handler
+	accessFilter  *accessFilter
+	accessStreams []accessStream
+	errorLogger   log.Handler
+	active        bool
+	dns           bool
+}
+
+// accessStream is an access logger with its own filter.
+type accessStream struct {
+	handler log.Handler
+	filter  *accessFilter
 }
 
 // New creates a new log.Instance based on the given config.
@@ -42,18 +50,43 @@ func New(ctx context.Context, config *Config) (*Instance, error) {
 
 func (g *Instance) initAccessLogger() error {
 	handler, err := createHandler(g.config.AccessLogType, HandlerCreatorOptions{
-		Path: g.config.AccessLogPath,
+		Path:     g.config.AccessLogPath,
+		Format:   g.config.Format,
+		Rotation: g.config.Rotation,
 	})
 	if err != nil {
 		return err
 	}
 	g.accessLogger = handler
+	if g.accessFilter, err = newAccessFilter(g.config.AccessLogFilter); err != nil {
+		return err
+	}
+
+	for _, config := range g.config.AccessLogStreams {
+		filter, err := newAccessFilter(config.Filter)
+		if err != nil {
+			return err
+		}
+		handler, err := createHandler(config.Type, HandlerCreatorOptions{
+			Path:     config.Path,
+			Format:   g.config.Format,
+			Rotation: g.config.Rotation,
+		})
+		if err != nil {
+			return err
+		}
+		if handler != nil {
+			g.accessStreams = append(g.accessStreams, accessStream{handler: handler, filter: filter})
+		}
+	}
 	return nil
 }
 
 func (g *Instance) initErrorLogger() error {
 	handler, err := createHandler(g.config.ErrorLogType, HandlerCreatorOptions{
-		Path: g.config.ErrorLogPath,
+		Path:     g.config.ErrorLogPath,
+		Format:   g.config.Format,
+		Rotation: g.config.Rotation,
 	})
 	if err != nil {
 		return err
@@ -103,9 +136,20 @@ func (g *Instance) Handle(msg log.Message) {
 
 	switch msg := msg.(type) {
 	case *log.AccessMessage:
-		if g.accessLogger != nil {
+		// Text logs have a line when the connection is accepted, while
+		// structured logs have it closed, with the traffic and duration.
+		if g.config.Format == LogFormat_Text && msg.Status == log.AccessClosed ||
+			g.config.Format != LogFormat_Text && msg.Status == log.AccessAccepted {
+			return
+		}
+		if g.accessLogger != nil && g.accessFilter.Allow(msg) {
 			g.accessLogger.Handle(msg)
 		}
+		for _, stream := range g.accessStreams {
+			if stream.filter.Allow(msg) {
+				stream.handler.Handle(msg)
+			}
+		}
 	case *log.DNSLog:
 		if g.dns && g.accessLogger != nil {
 			g.accessLogger.Handle(msg)
@@ -135,6 +179,11 @@ func (g *Instance) Close() error {
 	common.Close(g.accessLogger)
 	g.accessLogger = nil
 
+	for _, stream := range g.accessStreams {
+		common.Close(stream.handler)
+	}
+	g.accessStreams = nil
+
 	common.Close(g.errorLogger)
 	g.errorLogger = nil
 
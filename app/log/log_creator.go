@@ -2,6 +2,7 @@ package log
 
 import (
 	"sync"
+	"time"
 
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/errors"
@@ -9,7 +10,9 @@ import (
 )
 
 type HandlerCreatorOptions struct {
-	Path string
+	Path     string
+	Format   LogFormat
+	Rotation *LogRotation
 }
 
 type HandlerCreator func(LogType, HandlerCreatorOptions) (log.Handler, error)
@@ -30,6 +33,43 @@ func RegisterHandlerCreator(logType LogType, f HandlerCreator) error {
 	return nil
 }
 
+// formatter returns the formatter of structured logs, or nil for text.
+func (f LogFormat) formatter() log.Formatter {
+	switch f {
+	case LogFormat_JSON:
+		return log.FormatJSON
+	case LogFormat_Logfmt:
+		return log.FormatLogfmt
+	default:
+		return nil
+	}
+}
+
+func (r *LogRotation) options() *log.RotationOptions {
+	if r == nil {
+		return nil
+	}
+	return &log.RotationOptions{
+		MaxSize:    r.MaxSize,
+		Interval:   time.Duration(r.Interval),
+		Compress:   r.Compress,
+		MaxBackups: int(r.MaxBackups),
+		MaxAge:     time.Duration(r.MaxAge),
+	}
+}
+
+// newFormattedLogger returns a logger to a sink with its own timestamps, so
+// that the text format has none.
+func newFormattedLogger(creator log.WriterCreator, format LogFormat) log.Handler {
+	formatter := format.formatter()
+	if formatter == nil {
+		formatter = func(msg log.Message) string {
+			return msg.String()
+		}
+	}
+	return log.NewFormattedLogger(creator, formatter)
+}
+
 func createHandler(logType LogType, options HandlerCreatorOptions) (log.Handler, error) {
 	handlerCreatorMapLock.RLock()
 	defer handlerCreatorMapLock.RUnlock()
@@ -43,15 +83,50 @@ func createHandler(logType LogType, options HandlerCreatorOptions) (log.Handler,
 
 func init() {
 	common.Must(RegisterHandlerCreator(LogType_Console, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
-		return log.NewLogger(log.CreateStdoutLogWriter()), nil
+		formatter := options.Format.formatter()
+		if formatter == nil {
+			return log.NewLogger(log.CreateStdoutLogWriter()), nil
+		}
+		return log.NewFormattedLogger(log.CreatePlainStdoutLogWriter(), formatter), nil
 	}))
 
 	common.Must(RegisterHandlerCreator(LogType_File, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
-		creator, err := log.CreateFileLogWriter(options.Path)
+		formatter := options.Format.formatter()
+		creator, err := log.CreateFileLogWriterWithOptions(options.Path, log.FileLogWriterOptions{
+			Plain:    formatter != nil,
+			Rotation: options.Rotation.options(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if formatter == nil {
+			return log.NewLogger(creator), nil
+		}
+		return log.NewFormattedLogger(creator, formatter), nil
+	}))
+
+	common.Must(RegisterHandlerCreator(LogType_Syslog, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		creator, err := log.CreateSyslogLogWriter(options.Path)
 		if err != nil {
 			return nil, err
 		}
-		return log.NewLogger(creator), nil
+		return newFormattedLogger(creator, options.Format), nil
+	}))
+
+	common.Must(RegisterHandlerCreator(LogType_Journald, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		return newFormattedLogger(log.CreateJournaldLogWriter(), options.Format), nil
+	}))
+
+	common.Must(RegisterHandlerCreator(LogType_Remote, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		creator, err := log.CreateRemoteLogWriter(options.Path)
+		if err != nil {
+			return nil, err
+		}
+		formatter := options.Format.formatter()
+		if formatter == nil {
+			formatter = log.FormatText
+		}
+		return log.NewFormattedLogger(creator, formatter), nil
 	}))
 
 	common.Must(RegisterHandlerCreator(LogType_None, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
@@ -2,26 +2,38 @@ package command
 
 import (
 	"context"
+	"strings"
 
+	"github.com/luckyluke-a/xray-core/app/commander"
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/protocol"
+	"github.com/luckyluke-a/xray-core/common/serial"
 	"github.com/luckyluke-a/xray-core/core"
 	"github.com/luckyluke-a/xray-core/features/inbound"
 	"github.com/luckyluke-a/xray-core/features/outbound"
 	"github.com/luckyluke-a/xray-core/proxy"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // InboundOperation is the interface for operations that applies to inbound handlers.
 type InboundOperation interface {
 	// ApplyInbound applies this operation to the given inbound handler.
 	ApplyInbound(context.Context, inbound.Handler) error
+	// ApplyInboundConfig applies this operation to the config of the handler,
+	// so that the running config keeps up with the handler.
+	ApplyInboundConfig(*core.InboundHandlerConfig) error
 }
 
 // OutboundOperation is the interface for operations that applies to outbound handlers.
 type OutboundOperation interface {
 	// ApplyOutbound applies this operation to the given outbound handler.
 	ApplyOutbound(context.Context, outbound.Handler) error
+	// ApplyOutboundConfig applies this operation to the config of the
+	// handler, so that the running config keeps up with the handler.
+	ApplyOutboundConfig(*core.OutboundHandlerConfig) error
 }
 
 func getInbound(handler inbound.Handler) (proxy.Inbound, error) {
@@ -62,6 +74,8 @@ func (op *RemoveUserOperation) ApplyInbound(ctx context.Context, handler inbound
 	return um.RemoveUser(ctx, op.Email)
 }
 
+var userMessageName = (*protocol.User)(nil).ProtoReflect().Descriptor().FullName()
+
 type handlerServer struct {
 	s   *core.Instance
 	ihm inbound.Manager
@@ -77,7 +91,11 @@ func (s *handlerServer) AddInbound(ctx context.Context, request *AddInboundReque
 }
 
 func (s *handlerServer) RemoveInbound(ctx context.Context, request *RemoveInboundRequest) (*RemoveInboundResponse, error) {
-	return &RemoveInboundResponse{}, s.ihm.RemoveHandler(ctx, request.Tag)
+	if err := s.ihm.RemoveHandler(ctx, request.Tag); err != nil {
+		return nil, err
+	}
+	common.Must(s.s.UpdateConfig(request.ApplyConfig))
+	return &RemoveInboundResponse{}, nil
 }
 
 func (s *handlerServer) AlterInbound(ctx context.Context, request *AlterInboundRequest) (*AlterInboundResponse, error) {
@@ -95,7 +113,83 @@ func (s *handlerServer) AlterInbound(ctx context.Context, request *AlterInboundR
 		return nil, errors.New("failed to get handler: ", request.Tag).Base(err)
 	}
 
-	return &AlterInboundResponse{}, operation.ApplyInbound(ctx, handler)
+	if err := operation.ApplyInbound(ctx, handler); err != nil {
+		return nil, err
+	}
+	if err := s.s.UpdateConfig(request.ApplyConfig); err != nil {
+		errors.LogWarning(ctx, "failed to record the change of inbound ", request.Tag, " in the config: ", err)
+	}
+	return &AlterInboundResponse{}, nil
+}
+
+// ApplyInboundConfig implements InboundOperation.
+func (op *AddUserOperation) ApplyInboundConfig(config *core.InboundHandlerConfig) error {
+	return updateUsers(config, func(users protoreflect.List) error {
+		if users.NewElement().Message().Descriptor().FullName() == userMessageName {
+			users.Append(protoreflect.ValueOfMessage(op.User.ProtoReflect()))
+			return nil
+		}
+		// the users are accounts, such as the ones of Shadowsocks 2022
+		account, err := op.User.GetAccount().GetInstance()
+		if err != nil {
+			return err
+		}
+		if account.ProtoReflect().Descriptor() != users.NewElement().Message().Descriptor() {
+			return errors.New("unexpected account ", op.User.GetAccount().GetType())
+		}
+		users.Append(protoreflect.ValueOfMessage(account.ProtoReflect()))
+		return nil
+	})
+}
+
+// ApplyInboundConfig implements InboundOperation.
+func (op *RemoveUserOperation) ApplyInboundConfig(config *core.InboundHandlerConfig) error {
+	return updateUsers(config, func(users protoreflect.List) error {
+		email := users.NewElement().Message().Descriptor().Fields().ByName("email")
+		n := 0
+		for i := 0; i < users.Len(); i++ {
+			if !strings.EqualFold(users.Get(i).Message().Get(email).String(), op.Email) {
+				users.Set(n, users.Get(i))
+				n++
+			}
+		}
+		users.Truncate(n)
+		return nil
+	})
+}
+
+// updateUsers applies update to the users in the proxy settings of the
+// config. The users are the first list of protocol.User, or of messages with
+// an email, in the settings.
+func updateUsers(config *core.InboundHandlerConfig, update func(protoreflect.List) error) error {
+	settings, err := config.ProxySettings.GetInstance()
+	if err != nil {
+		return err
+	}
+	users := findUserList(settings.ProtoReflect())
+	if !users.IsValid() {
+		return errors.New("users of ", config.ProxySettings.Type, " are not supported")
+	}
+	if err := update(users); err != nil {
+		return err
+	}
+	config.ProxySettings = serial.ToTypedMessage(settings)
+	return nil
+}
+
+func findUserList(m protoreflect.Message) protoreflect.List {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !field.IsList() || field.Message() == nil {
+			continue
+		}
+		email := field.Message().Fields().ByName("email")
+		if field.Message().FullName() == userMessageName || email != nil && email.Kind() == protoreflect.StringKind {
+			return m.Mutable(field).List()
+		}
+	}
+	return nil
 }
 
 func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundRequest) (*AddOutboundResponse, error) {
@@ -106,7 +200,11 @@ func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundReq
 }
 
 func (s *handlerServer) RemoveOutbound(ctx context.Context, request *RemoveOutboundRequest) (*RemoveOutboundResponse, error) {
-	return &RemoveOutboundResponse{}, s.ohm.RemoveHandler(ctx, request.Tag)
+	if err := s.ohm.RemoveHandler(ctx, request.Tag); err != nil {
+		return nil, err
+	}
+	common.Must(s.s.UpdateConfig(request.ApplyConfig))
+	return &RemoveOutboundResponse{}, nil
 }
 
 func (s *handlerServer) AlterOutbound(ctx context.Context, request *AlterOutboundRequest) (*AlterOutboundResponse, error) {
@@ -120,15 +218,180 @@ func (s *handlerServer) AlterOutbound(ctx context.Context, request *AlterOutboun
 	}
 
 	handler := s.ohm.GetHandler(request.Tag)
-	return &AlterOutboundResponse{}, operation.ApplyOutbound(ctx, handler)
+	if handler == nil {
+		return nil, errors.New("failed to get handler: ", request.Tag)
+	}
+	if err := operation.ApplyOutbound(ctx, handler); err != nil {
+		return nil, err
+	}
+	if err := s.s.UpdateConfig(request.ApplyConfig); err != nil {
+		errors.LogWarning(ctx, "failed to record the change of outbound ", request.Tag, " in the config: ", err)
+	}
+	return &AlterOutboundResponse{}, nil
+}
+
+func (s *handlerServer) GetConfig(ctx context.Context, request *GetConfigRequest) (*GetConfigResponse, error) {
+	return &GetConfigResponse{Config: s.s.Config()}, nil
 }
 
 func (s *handlerServer) mustEmbedUnimplementedHandlerServiceServer() {}
 
+// ApplyConfig implements commander.ConfigChange.
+func (r *AddInboundRequest) ApplyConfig(c *core.Config) error {
+	for _, inbound := range c.Inbound {
+		if len(r.Inbound.Tag) > 0 && inbound.Tag == r.Inbound.Tag {
+			return errors.New("existing tag found: ", r.Inbound.Tag)
+		}
+	}
+	c.Inbound = append(c.Inbound, r.Inbound)
+	return nil
+}
+
+// ApplyConfig implements commander.ConfigChange.
+func (r *RemoveInboundRequest) ApplyConfig(c *core.Config) error {
+	inbounds := c.Inbound[:0]
+	for _, inbound := range c.Inbound {
+		if inbound.Tag != r.Tag {
+			inbounds = append(inbounds, inbound)
+		}
+	}
+	c.Inbound = inbounds
+	return nil
+}
+
+// ApplyConfig implements commander.ConfigChange.
+func (r *AlterInboundRequest) ApplyConfig(c *core.Config) error {
+	rawOperation, err := r.Operation.GetInstance()
+	if err != nil {
+		return errors.New("unknown operation").Base(err)
+	}
+	operation, ok := rawOperation.(InboundOperation)
+	if !ok {
+		return errors.New("not an inbound operation")
+	}
+	for i, inbound := range c.Inbound {
+		if inbound.Tag == r.Tag {
+			inbound = proto.Clone(inbound).(*core.InboundHandlerConfig)
+			if err := operation.ApplyInboundConfig(inbound); err != nil {
+				return err
+			}
+			c.Inbound[i] = inbound
+			return nil
+		}
+	}
+	return errors.New("inbound ", r.Tag, " not found")
+}
+
+// ApplyConfig implements commander.ConfigChange.
+func (r *AddOutboundRequest) ApplyConfig(c *core.Config) error {
+	for _, outbound := range c.Outbound {
+		if len(r.Outbound.Tag) > 0 && outbound.Tag == r.Outbound.Tag {
+			return errors.New("existing tag found: ", r.Outbound.Tag)
+		}
+	}
+	c.Outbound = append(c.Outbound, r.Outbound)
+	return nil
+}
+
+// ApplyConfig implements commander.ConfigChange.
+func (r *AlterOutboundRequest) ApplyConfig(c *core.Config) error {
+	rawOperation, err := r.Operation.GetInstance()
+	if err != nil {
+		return errors.New("unknown operation").Base(err)
+	}
+	operation, ok := rawOperation.(OutboundOperation)
+	if !ok {
+		return errors.New("not an outbound operation")
+	}
+	for i, outbound := range c.Outbound {
+		if outbound.Tag == r.Tag {
+			outbound = proto.Clone(outbound).(*core.OutboundHandlerConfig)
+			if err := operation.ApplyOutboundConfig(outbound); err != nil {
+				return err
+			}
+			c.Outbound[i] = outbound
+			return nil
+		}
+	}
+	return errors.New("outbound ", r.Tag, " not found")
+}
+
+// ApplyConfig implements commander.ConfigChange.
+func (r *RemoveOutboundRequest) ApplyConfig(c *core.Config) error {
+	outbounds := c.Outbound[:0]
+	for _, outbound := range c.Outbound {
+		if outbound.Tag != r.Tag {
+			outbounds = append(outbounds, outbound)
+		}
+	}
+	c.Outbound = outbounds
+	return nil
+}
+
+// containsConfig returns whether configs has one equal to config.
+func containsConfig[T proto.Message](configs []T, config T) bool {
+	for _, c := range configs {
+		if proto.Equal(c, config) {
+			return true
+		}
+	}
+	return false
+}
+
+// compactHandlers implements commander.JournalCompactor. Changed handlers are
+// removed and added again, so that replaying the entries on current changes
+// nothing.
+func compactHandlers(base, current *core.Config) ([]*commander.JournalEntry, error) {
+	var entries []*commander.JournalEntry
+	add := func(method string, request proto.Message) {
+		entries = append(entries, &commander.JournalEntry{
+			Method:  method,
+			Request: serial.ToTypedMessage(request),
+		})
+	}
+	for _, inbound := range base.Inbound {
+		if len(inbound.Tag) > 0 && !containsConfig(current.Inbound, inbound) {
+			add(HandlerService_RemoveInbound_FullMethodName, &RemoveInboundRequest{Tag: inbound.Tag})
+		}
+	}
+	for _, inbound := range current.Inbound {
+		if !containsConfig(base.Inbound, inbound) {
+			add(HandlerService_AddInbound_FullMethodName, &AddInboundRequest{Inbound: inbound})
+		}
+	}
+	for _, outbound := range base.Outbound {
+		if len(outbound.Tag) > 0 && !containsConfig(current.Outbound, outbound) {
+			add(HandlerService_RemoveOutbound_FullMethodName, &RemoveOutboundRequest{Tag: outbound.Tag})
+		}
+	}
+	for _, outbound := range current.Outbound {
+		if !containsConfig(base.Outbound, outbound) {
+			add(HandlerService_AddOutbound_FullMethodName, &AddOutboundRequest{Outbound: outbound})
+		}
+	}
+	return entries, nil
+}
+
 type service struct {
 	v *core.Instance
 }
 
+// JournaledMethods implements commander.JournaledService.
+func (s *service) JournaledMethods() []string {
+	methods := []string{
+		HandlerService_AddInbound_FullMethodName,
+		HandlerService_RemoveInbound_FullMethodName,
+		HandlerService_AlterInbound_FullMethodName,
+		HandlerService_AddOutbound_FullMethodName,
+		HandlerService_RemoveOutbound_FullMethodName,
+		HandlerService_AlterOutbound_FullMethodName,
+	}
+	for _, method := range methods {
+		methods = append(methods, strings.Replace(method, "/xray.", "/v2ray.core.", 1))
+	}
+	return methods
+}
+
 func (s *service) Register(server *grpc.Server) {
 	hs := &handlerServer{
 		s: s.v,
@@ -150,4 +413,5 @@ func init() {
 		s := core.MustFromContext(ctx)
 		return &service{v: s}, nil
 	}))
+	commander.RegisterJournalCompactor(compactHandlers)
 }
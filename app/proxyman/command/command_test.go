@@ -0,0 +1,256 @@
+package command_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/app/commander"
+	"github.com/luckyluke-a/xray-core/app/dispatcher"
+	"github.com/luckyluke-a/xray-core/app/proxyman"
+	. "github.com/luckyluke-a/xray-core/app/proxyman/command"
+	_ "github.com/luckyluke-a/xray-core/app/proxyman/inbound"
+	_ "github.com/luckyluke-a/xray-core/app/proxyman/outbound"
+	"github.com/luckyluke-a/xray-core/app/router"
+	routercommand "github.com/luckyluke-a/xray-core/app/router/command"
+	"github.com/luckyluke-a/xray-core/common"
+	xnet "github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/common/protocol"
+	"github.com/luckyluke-a/xray-core/common/serial"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/features/inbound"
+	"github.com/luckyluke-a/xray-core/features/outbound"
+	"github.com/luckyluke-a/xray-core/features/routing"
+	"github.com/luckyluke-a/xray-core/proxy"
+	"github.com/luckyluke-a/xray-core/proxy/freedom"
+	"github.com/luckyluke-a/xray-core/proxy/vless"
+	vlessinbound "github.com/luckyluke-a/xray-core/proxy/vless/inbound"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func vlessUser(email string, id string) *protocol.User {
+	return &protocol.User{
+		Email:   email,
+		Account: serial.ToTypedMessage(&vless.Account{Id: id}),
+	}
+}
+
+func vlessInbound(tag string, port uint32, users ...*protocol.User) *core.InboundHandlerConfig {
+	return &core.InboundHandlerConfig{
+		Tag: tag,
+		ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+			PortList: &xnet.PortList{Range: []*xnet.PortRange{xnet.SinglePortRange(xnet.Port(port))}},
+			Listen:   xnet.NewIPOrDomain(xnet.LocalHostIP),
+		}),
+		ProxySettings: serial.ToTypedMessage(&vlessinbound.Config{
+			Clients:    users,
+			Decryption: "none",
+		}),
+	}
+}
+
+// instanceState is the state of an instance that the API changes.
+type instanceState struct {
+	inbounds  []string
+	users     []string
+	outbounds []string
+	rules     []string
+	balancers []string
+}
+
+// checkState checks the state of the instance. Users are checked by removing
+// them, so the instance can't be used after.
+func checkState(t *testing.T, name string, server *core.Instance, expected instanceState) {
+	t.Helper()
+	common.Must(server.RequireFeatures(func(ihm inbound.Manager, ohm outbound.Manager, r routing.Router) {
+		for _, tag := range []string{"in", "in2"} {
+			_, err := ihm.GetHandler(context.Background(), tag)
+			if (err == nil) != contains(expected.inbounds, tag) {
+				t.Error(name, ": unexpected inbound ", tag, ": ", err)
+			}
+		}
+		for _, tag := range []string{"direct", "b"} {
+			if (ohm.GetHandler(tag) != nil) != contains(expected.outbounds, tag) {
+				t.Error(name, ": unexpected outbound ", tag)
+			}
+		}
+		for _, tag := range []string{"r"} {
+			if r.(*router.Router).RuleExists(tag) != contains(expected.rules, tag) {
+				t.Error(name, ": unexpected rule ", tag)
+			}
+		}
+		for _, tag := range []string{"balancer"} {
+			_, err := r.(routing.BalancerOverrider).GetOverrideTarget(tag)
+			if (err == nil) != contains(expected.balancers, tag) {
+				t.Error(name, ": unexpected balancer ", tag)
+			}
+		}
+		handler, err := ihm.GetHandler(context.Background(), "in")
+		common.Must(err)
+		users := handler.(proxy.GetInbound).GetInbound().(proxy.UserManager)
+		for _, email := range []string{"a", "b"} {
+			if err := users.RemoveUser(context.Background(), email); (err == nil) != contains(expected.users, email) {
+				t.Error(name, ": unexpected user ", email, ": ", err)
+			}
+		}
+	}))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetConfig(t *testing.T) {
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&router.Config{}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			vlessInbound("in", 10001, vlessUser("a", "b831381d-6324-4d53-ad4f-8cda48b30811")),
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{Tag: "direct", ProxySettings: serial.ToTypedMessage(&freedom.Config{})},
+		},
+	}
+	server, err := core.New(config)
+	common.Must(err)
+	defer server.Close()
+
+	grpcServer := grpc.NewServer()
+	for _, serviceConfig := range []interface{}{&Config{}, &routercommand.Config{}} {
+		service, err := core.CreateObject(server, serviceConfig)
+		common.Must(err)
+		service.(commander.Service).Register(grpcServer)
+	}
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	common.Must(err)
+	defer conn.Close()
+	handlers := NewHandlerServiceClient(conn)
+	rules := routercommand.NewRoutingServiceClient(conn)
+	ctx := context.Background()
+
+	steps := []struct {
+		name     string
+		call     func() error
+		expected instanceState
+	}{
+		{
+			name: "AddInbound",
+			call: func() error {
+				_, err := handlers.AddInbound(ctx, &AddInboundRequest{Inbound: vlessInbound("in2", 10002)})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in", "in2"}, users: []string{"a"}, outbounds: []string{"direct"}},
+		},
+		{
+			name: "AlterInbound add user",
+			call: func() error {
+				_, err := handlers.AlterInbound(ctx, &AlterInboundRequest{
+					Tag:       "in",
+					Operation: serial.ToTypedMessage(&AddUserOperation{User: vlessUser("b", "27f9e4a0-8c2b-4c8c-9d35-5b8cfa6c4a59")}),
+				})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in", "in2"}, users: []string{"a", "b"}, outbounds: []string{"direct"}},
+		},
+		{
+			name: "AlterInbound remove user",
+			call: func() error {
+				_, err := handlers.AlterInbound(ctx, &AlterInboundRequest{
+					Tag:       "in",
+					Operation: serial.ToTypedMessage(&RemoveUserOperation{Email: "a"}),
+				})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in", "in2"}, users: []string{"b"}, outbounds: []string{"direct"}},
+		},
+		{
+			name: "RemoveInbound",
+			call: func() error {
+				_, err := handlers.RemoveInbound(ctx, &RemoveInboundRequest{Tag: "in2"})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in"}, users: []string{"b"}, outbounds: []string{"direct"}},
+		},
+		{
+			name: "AddOutbound",
+			call: func() error {
+				_, err := handlers.AddOutbound(ctx, &AddOutboundRequest{Outbound: &core.OutboundHandlerConfig{
+					Tag:           "b",
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+				}})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in"}, users: []string{"b"}, outbounds: []string{"direct", "b"}},
+		},
+		{
+			name: "RemoveOutbound",
+			call: func() error {
+				_, err := handlers.RemoveOutbound(ctx, &RemoveOutboundRequest{Tag: "direct"})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in"}, users: []string{"b"}, outbounds: []string{"b"}},
+		},
+		{
+			name: "AddRule",
+			call: func() error {
+				_, err := rules.AddRule(ctx, &routercommand.AddRuleRequest{
+					Config: serial.ToTypedMessage(&router.Config{
+						Rule: []*router.RoutingRule{{
+							RuleTag:    "r",
+							TargetTag:  &router.RoutingRule_BalancingTag{BalancingTag: "balancer"},
+							InboundTag: []string{"in"},
+						}},
+						BalancingRule: []*router.BalancingRule{{Tag: "balancer", OutboundSelector: []string{"b"}}},
+					}),
+					ShouldAppend: true,
+				})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in"}, users: []string{"b"}, outbounds: []string{"b"}, rules: []string{"r"}, balancers: []string{"balancer"}},
+		},
+		{
+			name: "RemoveRule",
+			call: func() error {
+				_, err := rules.RemoveRule(ctx, &routercommand.RemoveRuleRequest{RuleTag: "r"})
+				return err
+			},
+			expected: instanceState{inbounds: []string{"in"}, users: []string{"b"}, outbounds: []string{"b"}, balancers: []string{"balancer"}},
+		},
+	}
+	for _, step := range steps {
+		if err := step.call(); err != nil {
+			t.Fatal(step.name, ": ", err)
+		}
+		response, err := handlers.GetConfig(ctx, &GetConfigRequest{})
+		if err != nil {
+			t.Fatal(step.name, ": ", err)
+		}
+
+		// an instance of the config returned is the same as the running one
+		restored, err := core.New(response.Config)
+		if err != nil {
+			t.Fatal(step.name, ": failed to create an instance of the config: ", err)
+		}
+		checkState(t, step.name+" (restored)", restored, step.expected)
+		restored.Close()
+	}
+	checkState(t, "running", server, steps[len(steps)-1].expected)
+}
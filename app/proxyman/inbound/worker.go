@@ -26,6 +26,15 @@ import (
 	"github.com/luckyluke-a/xray-core/transport/pipe"
 )
 
+// activeConnections counts the stream connections being handled by inbounds.
+var activeConnections atomic.Int64
+
+// ActiveConnections returns the number of stream connections being handled by
+// inbounds, which is used to drain connections before exiting.
+func ActiveConnections() int64 {
+	return activeConnections.Load()
+}
+
 type worker interface {
 	Start() error
 	Close() error
@@ -58,6 +67,9 @@ func getTProxyType(s *internet.MemoryStreamConfig) internet.SocketConfig_TProxyM
 }
 
 func (w *tcpWorker) callback(conn stat.Connection) {
+	activeConnections.Add(1)
+	defer activeConnections.Add(-1)
+
 	ctx, cancel := context.WithCancel(w.ctx)
 	sid := session.NewID()
 	ctx = c.ContextWithID(ctx, sid)
@@ -104,6 +116,9 @@ func (w *tcpWorker) callback(conn stat.Connection) {
 		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
 		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
 	}
+	for name, value := range stat.AttributesFromConnection(conn) {
+		content.SetAttribute(name, value)
+	}
 	ctx = session.ContextWithContent(ctx, content)
 
 	if err := w.proxy.Process(ctx, net.Network_TCP, conn, w.dispatcher); err != nil {
@@ -452,6 +467,9 @@ type dsWorker struct {
 }
 
 func (w *dsWorker) callback(conn stat.Connection) {
+	activeConnections.Add(1)
+	defer activeConnections.Add(-1)
+
 	ctx, cancel := context.WithCancel(w.ctx)
 	sid := session.NewID()
 	ctx = c.ContextWithID(ctx, sid)
@@ -478,6 +496,9 @@ func (w *dsWorker) callback(conn stat.Connection) {
 		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
 		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
 	}
+	for name, value := range stat.AttributesFromConnection(conn) {
+		content.SetAttribute(name, value)
+	}
 	ctx = session.ContextWithContent(ctx, content)
 
 	if err := w.proxy.Process(ctx, net.Network_UNIX, conn, w.dispatcher); err != nil {
@@ -8,6 +8,7 @@ import (
 	"math/big"
 	gonet "net"
 	"os"
+	"time"
 
 	"github.com/luckyluke-a/xray-core/app/proxyman"
 	"github.com/luckyluke-a/xray-core/common"
@@ -120,18 +121,24 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 			if config.Concurrency == 0 {
 				config.Concurrency = 8 // same as before
 			}
+			strategy := mux.ClientStrategy{
+				MaxConnection:   128,
+				MaxLifetime:     time.Duration(config.MaxLifetime) * time.Second,
+				MaxBytes:        uint64(config.MaxBytes),
+				KeepAlivePeriod: time.Duration(config.KeepAlivePeriod) * time.Second,
+				KeepIdle:        config.WarmConnections > 0,
+			}
 			if config.Concurrency > 0 {
+				strategy.MaxConcurrency = uint32(config.Concurrency)
 				h.mux = &mux.ClientManager{
 					Enabled: true,
 					Picker: &mux.IncrementalWorkerPicker{
 						Factory: &mux.DialingWorkerFactory{
-							Proxy:  proxyHandler,
-							Dialer: h,
-							Strategy: mux.ClientStrategy{
-								MaxConcurrency: uint32(config.Concurrency),
-								MaxConnection:  128,
-							},
+							Proxy:    proxyHandler,
+							Dialer:   h,
+							Strategy: strategy,
 						},
+						WarmConnections: uint32(config.WarmConnections),
 					},
 				}
 			}
@@ -142,17 +149,16 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 				h.xudp = nil // same as before
 			}
 			if config.XudpConcurrency > 0 {
+				strategy.MaxConcurrency = uint32(config.XudpConcurrency)
 				h.xudp = &mux.ClientManager{
 					Enabled: true,
 					Picker: &mux.IncrementalWorkerPicker{
 						Factory: &mux.DialingWorkerFactory{
-							Proxy:  proxyHandler,
-							Dialer: h,
-							Strategy: mux.ClientStrategy{
-								MaxConcurrency: uint32(config.XudpConcurrency),
-								MaxConnection:  128,
-							},
+							Proxy:    proxyHandler,
+							Dialer:   h,
+							Strategy: strategy,
 						},
+						WarmConnections: uint32(config.WarmConnections),
 					},
 				}
 			}
@@ -310,12 +316,27 @@ func (h *Handler) GetOutbound() proxy.Outbound {
 
 // Start implements common.Runnable.
 func (h *Handler) Start() error {
+	if h.mux != nil && h.mux.Enabled {
+		if err := h.mux.Start(); err != nil {
+			return err
+		}
+	}
+	if h.xudp != nil && h.xudp.Enabled {
+		if err := h.xudp.Start(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (h *Handler) Close() error {
-	common.Close(h.mux)
+	if h.mux != nil {
+		common.Close(h.mux)
+	}
+	if h.xudp != nil {
+		common.Close(h.xudp)
+	}
 	return nil
 }
 
@@ -2,8 +2,11 @@ package reverse
 
 import (
 	"context"
+	"sync"
 	"time"
 
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/buf"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/mux"
 	"github.com/luckyluke-a/xray-core/common/net"
@@ -17,9 +20,14 @@ import (
 
 // Bridge is a component in reverse proxy, that relays connections from Portal to local address.
 type Bridge struct {
+	access      sync.Mutex
 	dispatcher  routing.Dispatcher
 	tag         string
 	domain      string
+	name        string
+	minWorkers  uint32
+	maxWorkers  uint32
+	services    *ServiceList
 	workers     []*BridgeWorker
 	monitorTask *task.Periodic
 }
@@ -33,10 +41,29 @@ func NewBridge(config *BridgeConfig, dispatcher routing.Dispatcher) (*Bridge, er
 		return nil, errors.New("bridge domain is empty")
 	}
 
+	if config.MaxWorkers > 0 && config.MinWorkers > config.MaxWorkers {
+		return nil, errors.New("bridge min workers is greater than max workers")
+	}
+
+	services, err := NewServiceList(config.Services)
+	if err != nil {
+		return nil, errors.New("failed to build bridge services").Base(err)
+	}
+
 	b := &Bridge{
 		dispatcher: dispatcher,
+		services:   services,
 		tag:        config.Tag,
 		domain:     config.Domain,
+		name:       config.Name,
+		minWorkers: config.MinWorkers,
+		maxWorkers: config.MaxWorkers,
+	}
+	if b.name == "" {
+		b.name = b.tag
+	}
+	if b.minWorkers == 0 {
+		b.minWorkers = 1
 	}
 	b.monitorTask = &task.Periodic{
 		Execute:  b.monitor,
@@ -60,6 +87,9 @@ func (b *Bridge) cleanup() {
 }
 
 func (b *Bridge) monitor() error {
+	b.access.Lock()
+	defer b.access.Unlock()
+
 	b.cleanup()
 
 	var numConnections uint32
@@ -72,18 +102,45 @@ func (b *Bridge) monitor() error {
 		}
 	}
 
-	if numWorker == 0 || numConnections/numWorker > 16 {
-		worker, err := NewBridgeWorker(b.domain, b.tag, b.dispatcher)
-		if err != nil {
-			errors.LogWarningInner(context.Background(), err, "failed to create bridge worker")
+	for ; numWorker < b.minWorkers; numWorker++ {
+		if err := b.addWorker(); err != nil {
 			return nil
 		}
-		b.workers = append(b.workers, worker)
 	}
 
+	if numConnections/numWorker > 16 && (b.maxWorkers == 0 || numWorker < b.maxWorkers) {
+		b.addWorker()
+	}
+
+	return nil
+}
+
+func (b *Bridge) addWorker() error {
+	worker, err := NewBridgeWorker(b.domain, b.tag, b.name, b.services, b.dispatcher)
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to create bridge worker")
+		return err
+	}
+	b.workers = append(b.workers, worker)
 	return nil
 }
 
+// Status returns the state of the workers of this bridge.
+func (b *Bridge) Status() *BridgeStatus {
+	b.access.Lock()
+	defer b.access.Unlock()
+
+	status := &BridgeStatus{
+		Name:   b.name,
+		Tag:    b.tag,
+		Domain: b.domain,
+	}
+	for _, w := range b.workers {
+		status.Workers = append(status.Workers, w.Status())
+	}
+	return status
+}
+
 func (b *Bridge) Start() error {
 	return b.monitorTask.Start()
 }
@@ -93,13 +150,17 @@ func (b *Bridge) Close() error {
 }
 
 type BridgeWorker struct {
+	access     sync.Mutex
 	tag        string
+	name       string
+	services   *ServiceList
 	worker     *mux.ServerWorker
 	dispatcher routing.Dispatcher
 	state      Control_State
+	lastSeen   time.Time
 }
 
-func NewBridgeWorker(domain string, tag string, d routing.Dispatcher) (*BridgeWorker, error) {
+func NewBridgeWorker(domain string, tag string, name string, services *ServiceList, d routing.Dispatcher) (*BridgeWorker, error) {
 	ctx := context.Background()
 	ctx = session.ContextWithInbound(ctx, &session.Inbound{
 		Tag: tag,
@@ -116,6 +177,8 @@ func NewBridgeWorker(domain string, tag string, d routing.Dispatcher) (*BridgeWo
 	w := &BridgeWorker{
 		dispatcher: d,
 		tag:        tag,
+		name:       name,
+		services:   services,
 	}
 
 	worker, err := mux.NewServerWorker(context.Background(), w, link)
@@ -140,6 +203,9 @@ func (w *BridgeWorker) Close() error {
 }
 
 func (w *BridgeWorker) IsActive() bool {
+	w.access.Lock()
+	defer w.access.Unlock()
+
 	return w.state == Control_ACTIVE && !w.worker.Closed()
 }
 
@@ -147,6 +213,21 @@ func (w *BridgeWorker) Connections() uint32 {
 	return w.worker.ActiveConnections()
 }
 
+func (w *BridgeWorker) Status() *WorkerStatus {
+	w.access.Lock()
+	defer w.access.Unlock()
+
+	status := &WorkerStatus{
+		ActiveConnections: w.worker.ActiveConnections(),
+		Draining:          w.state == Control_DRAIN,
+		Healthy:           w.state == Control_ACTIVE && !w.worker.Closed(),
+	}
+	if !w.lastSeen.IsZero() {
+		status.LastSeen = w.lastSeen.Unix()
+	}
+	return status
+}
+
 func (w *BridgeWorker) handleInternalConn(link *transport.Link) {
 	go func() {
 		reader := link.Reader
@@ -161,16 +242,37 @@ func (w *BridgeWorker) handleInternalConn(link *transport.Link) {
 					errors.LogInfoInner(context.Background(), err, "failed to parse proto message")
 					break
 				}
-				if ctl.State != w.state {
-					w.state = ctl.State
+				w.access.Lock()
+				w.state = ctl.State
+				w.lastSeen = time.Now()
+				w.access.Unlock()
+
+				// Portals that measure RTT set the timestamp and read the echo.
+				if ctl.Timestamp != 0 {
+					reply := &Control{
+						State:     ctl.State,
+						Bridge:    w.name,
+						Timestamp: ctl.Timestamp,
+					}
+					reply.FillInRandom()
+					b, err := proto.Marshal(reply)
+					common.Must(err)
+					if err := link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, b)); err != nil {
+						errors.LogInfoInner(context.Background(), err, "failed to reply heartbeat")
+					}
 				}
 			}
+			buf.ReleaseMulti(mb)
 		}
 	}()
 }
 
 func (w *BridgeWorker) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
 	if !isInternalDomain(dest) {
+		dest, err := w.services.Resolve(dest)
+		if err != nil {
+			return nil, errors.New("rejected reverse connection").Base(err).AtInfo()
+		}
 		ctx = session.ContextWithInbound(ctx, &session.Inbound{
 			Tag: w.tag,
 		})
@@ -194,6 +296,10 @@ func (w *BridgeWorker) Dispatch(ctx context.Context, dest net.Destination) (*tra
 
 func (w *BridgeWorker) DispatchLink(ctx context.Context, dest net.Destination, link *transport.Link) error {
 	if !isInternalDomain(dest) {
+		dest, err := w.services.Resolve(dest)
+		if err != nil {
+			return errors.New("rejected reverse connection").Base(err).AtInfo()
+		}
 		ctx = session.ContextWithInbound(ctx, &session.Inbound{
 			Tag: w.tag,
 		})
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+
+	"github.com/luckyluke-a/xray-core/app/reverse"
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	core "github.com/luckyluke-a/xray-core/core"
+	"google.golang.org/grpc"
+)
+
+type service struct {
+	UnimplementedReverseServiceServer
+	v *core.Instance
+}
+
+func (s *service) GetStatus(ctx context.Context, request *GetStatusRequest) (*GetStatusResponse, error) {
+	r, ok := s.v.GetFeature((*reverse.Reverse)(nil)).(*reverse.Reverse)
+	if !ok {
+		return nil, errors.New("reverse is not enabled")
+	}
+
+	resp := &GetStatusResponse{}
+	for _, p := range r.PortalStatus() {
+		if request.Tag == "" || request.Tag == p.Tag {
+			resp.Portals = append(resp.Portals, p)
+		}
+	}
+	for _, b := range r.BridgeStatus() {
+		if request.Tag == "" || request.Tag == b.Tag {
+			resp.Bridges = append(resp.Bridges, b)
+		}
+	}
+	return resp, nil
+}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterReverseServiceServer(server, s)
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		s := core.MustFromContext(ctx)
+		return &service{v: s}, nil
+	}))
+}
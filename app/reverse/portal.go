@@ -19,11 +19,12 @@ import (
 )
 
 type Portal struct {
-	ohm    outbound.Manager
-	tag    string
-	domain string
-	picker *StaticMuxPicker
-	client *mux.ClientManager
+	ohm      outbound.Manager
+	tag      string
+	domain   string
+	services map[string]string
+	picker   *StaticMuxPicker
+	client   *mux.ClientManager
 }
 
 func NewPortal(config *PortalConfig, ohm outbound.Manager) (*Portal, error) {
@@ -39,12 +40,14 @@ func NewPortal(config *PortalConfig, ohm outbound.Manager) (*Portal, error) {
 	if err != nil {
 		return nil, err
 	}
+	picker.Strategy = config.Strategy
 
 	return &Portal{
-		ohm:    ohm,
-		tag:    config.Tag,
-		domain: config.Domain,
-		picker: picker,
+		ohm:      ohm,
+		tag:      config.Tag,
+		domain:   config.Domain,
+		services: config.Services,
+		picker:   picker,
 		client: &mux.ClientManager{
 			Picker: picker,
 		},
@@ -62,6 +65,15 @@ func (p *Portal) Close() error {
 	return p.ohm.RemoveHandler(context.Background(), p.tag)
 }
 
+// Status returns the state of the bridges connected to this portal.
+func (p *Portal) Status() *PortalStatus {
+	return &PortalStatus{
+		Tag:     p.tag,
+		Domain:  p.domain,
+		Bridges: p.picker.Status(),
+	}
+}
+
 func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) error {
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
@@ -84,6 +96,12 @@ func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) err
 		return nil
 	}
 
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		if name, found := p.services[inbound.Tag]; found {
+			ob.Target = serviceDestination(name, ob.Target.Network)
+		}
+	}
+
 	return p.client.Dispatch(ctx, link)
 }
 
@@ -112,6 +130,8 @@ func (o *Outbound) Close() error {
 }
 
 type StaticMuxPicker struct {
+	Strategy PortalConfig_Strategy
+
 	access  sync.Mutex
 	workers []*PortalWorker
 	cTask   *task.Periodic
@@ -145,48 +165,102 @@ func (p *StaticMuxPicker) cleanup() error {
 	return nil
 }
 
-func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
-	p.access.Lock()
-	defer p.access.Unlock()
+// bridgeLoad is the load of all the available workers of one bridge.
+type bridgeLoad struct {
+	connections uint32
+	rtt         time.Duration
+	worker      *PortalWorker
+}
 
-	if len(p.workers) == 0 {
-		return nil, errors.New("empty worker list")
+func (p *StaticMuxPicker) better(a, b *bridgeLoad) bool {
+	if p.Strategy == PortalConfig_LEAST_LATENCY && a.rtt != b.rtt {
+		return a.rtt < b.rtt
 	}
+	if a.connections != b.connections {
+		return a.connections < b.connections
+	}
+	return a.rtt < b.rtt
+}
 
-	var minIdx int = -1
-	var minConn uint32 = 9999
-	for i, w := range p.workers {
-		if w.draining {
+func (p *StaticMuxPicker) pick(healthyOnly bool) *PortalWorker {
+	bridges := make(map[string]*bridgeLoad)
+	for _, w := range p.workers {
+		if w.IsFull() {
+			continue
+		}
+		if healthyOnly && (w.Draining() || !w.Healthy()) {
 			continue
 		}
-		if w.client.Closed() {
+		conns := w.client.ActiveConnections()
+		load, found := bridges[w.Bridge()]
+		if !found {
+			bridges[w.Bridge()] = &bridgeLoad{
+				connections: conns,
+				rtt:         w.RTT(),
+				worker:      w,
+			}
 			continue
 		}
-		if w.client.ActiveConnections() < minConn {
-			minConn = w.client.ActiveConnections()
-			minIdx = i
+		load.connections += conns
+		if rtt := w.RTT(); rtt < load.rtt {
+			load.rtt = rtt
+		}
+		if conns < load.worker.client.ActiveConnections() {
+			load.worker = w
 		}
 	}
 
-	if minIdx == -1 {
-		for i, w := range p.workers {
-			if w.IsFull() {
-				continue
-			}
-			if w.client.ActiveConnections() < minConn {
-				minConn = w.client.ActiveConnections()
-				minIdx = i
-			}
+	var best *bridgeLoad
+	for _, load := range bridges {
+		if best == nil || p.better(load, best) {
+			best = load
 		}
 	}
+	if best == nil {
+		return nil
+	}
+	return best.worker
+}
+
+func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	if len(p.workers) == 0 {
+		return nil, errors.New("empty worker list")
+	}
 
-	if minIdx != -1 {
-		return p.workers[minIdx].client, nil
+	w := p.pick(true)
+	if w == nil {
+		w = p.pick(false)
+	}
+	if w != nil {
+		return w.client, nil
 	}
 
 	return nil, errors.New("no mux client worker available")
 }
 
+// Status returns the state of the workers, grouped by bridge.
+func (p *StaticMuxPicker) Status() []*BridgeStatus {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	var bridges []*BridgeStatus
+	index := make(map[string]*BridgeStatus)
+	for _, w := range p.workers {
+		name := w.Bridge()
+		status, found := index[name]
+		if !found {
+			status = &BridgeStatus{Name: name}
+			index[name] = status
+			bridges = append(bridges, status)
+		}
+		status.Workers = append(status.Workers, w.Status())
+	}
+	return bridges
+}
+
 func (p *StaticMuxPicker) AddWorker(worker *PortalWorker) {
 	p.access.Lock()
 	defer p.access.Unlock()
@@ -200,6 +274,11 @@ type PortalWorker struct {
 	writer   buf.Writer
 	reader   buf.Reader
 	draining bool
+
+	access   sync.Mutex
+	bridge   string
+	rtt      time.Duration
+	lastSeen time.Time
 }
 
 func NewPortalWorker(client *mux.ClientWorker) (*PortalWorker, error) {
@@ -229,20 +308,51 @@ func NewPortalWorker(client *mux.ClientWorker) (*PortalWorker, error) {
 		Interval: time.Second * 2,
 	}
 	w.control.Start()
+	go w.readReplies(downlinkReader)
 	return w, nil
 }
 
+// readReplies consumes the heartbeat echoes of the bridge, which identify the
+// bridge and carry the timestamp for RTT measurement.
+func (w *PortalWorker) readReplies(reader buf.Reader) {
+	for {
+		mb, err := reader.ReadMultiBuffer()
+		if err != nil {
+			return
+		}
+		for _, b := range mb {
+			var ctl Control
+			if err := proto.Unmarshal(b.Bytes(), &ctl); err != nil {
+				errors.LogInfoInner(context.Background(), err, "failed to parse proto message")
+				continue
+			}
+			w.access.Lock()
+			w.bridge = ctl.Bridge
+			w.lastSeen = time.Now()
+			if ctl.Timestamp != 0 {
+				w.rtt = time.Since(time.Unix(0, ctl.Timestamp))
+			}
+			w.access.Unlock()
+		}
+		buf.ReleaseMulti(mb)
+	}
+}
+
 func (w *PortalWorker) heartbeat() error {
 	if w.client.Closed() {
 		return errors.New("client worker stopped")
 	}
 
+	w.access.Lock()
+	defer w.access.Unlock()
+
 	if w.draining || w.writer == nil {
 		return errors.New("already disposed")
 	}
 
 	msg := &Control{}
 	msg.FillInRandom()
+	msg.Timestamp = time.Now().UnixNano()
 
 	if w.client.TotalConnections() > 256 {
 		w.draining = true
@@ -268,3 +378,50 @@ func (w *PortalWorker) IsFull() bool {
 func (w *PortalWorker) Closed() bool {
 	return w.client.Closed()
 }
+
+func (w *PortalWorker) Draining() bool {
+	w.access.Lock()
+	defer w.access.Unlock()
+
+	return w.draining
+}
+
+// Bridge returns the name reported by the bridge, empty if the bridge has not replied.
+func (w *PortalWorker) Bridge() string {
+	w.access.Lock()
+	defer w.access.Unlock()
+
+	return w.bridge
+}
+
+func (w *PortalWorker) RTT() time.Duration {
+	w.access.Lock()
+	defer w.access.Unlock()
+
+	return w.rtt
+}
+
+// Healthy returns false if a bridge that used to reply heartbeats stopped doing so.
+// Bridges that never reply are considered healthy.
+func (w *PortalWorker) Healthy() bool {
+	w.access.Lock()
+	defer w.access.Unlock()
+
+	return w.lastSeen.IsZero() || time.Since(w.lastSeen) < time.Second*10
+}
+
+func (w *PortalWorker) Status() *WorkerStatus {
+	status := &WorkerStatus{
+		ActiveConnections: w.client.ActiveConnections(),
+		TotalConnections:  w.client.TotalConnections(),
+		Draining:          w.Draining(),
+		Healthy:           w.Healthy() && !w.Closed(),
+	}
+	w.access.Lock()
+	status.Rtt = w.rtt.Milliseconds()
+	if !w.lastSeen.IsZero() {
+		status.LastSeen = w.lastSeen.Unix()
+	}
+	w.access.Unlock()
+	return status
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/luckyluke-a/xray-core/app/reverse"
 	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/mux"
+	"github.com/luckyluke-a/xray-core/transport"
+	"github.com/luckyluke-a/xray-core/transport/pipe"
 )
 
 func TestStaticPickerEmpty(t *testing.T) {
@@ -18,3 +21,26 @@ func TestStaticPickerEmpty(t *testing.T) {
 		t.Error("expected nil worker, but not nil")
 	}
 }
+
+func TestStaticPickerStatus(t *testing.T) {
+	picker, err := reverse.NewStaticMuxPicker()
+	common.Must(err)
+
+	for i := 0; i < 2; i++ {
+		r, w := pipe.New(pipe.WithoutSizeLimit())
+		client, err := mux.NewClientWorker(transport.Link{Reader: r, Writer: w}, mux.ClientStrategy{})
+		common.Must(err)
+		worker, err := reverse.NewPortalWorker(client)
+		common.Must(err)
+		picker.AddWorker(worker)
+	}
+
+	if _, err := picker.PickAvailable(); err != nil {
+		t.Error("expected a worker, but got ", err)
+	}
+
+	status := picker.Status()
+	if len(status) != 1 || len(status[0].Workers) != 2 {
+		t.Error("expected 2 workers of one unnamed bridge, but got ", status)
+	}
+}
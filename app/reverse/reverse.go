@@ -62,6 +62,24 @@ func (r *Reverse) Init(config *Config, d routing.Dispatcher, ohm outbound.Manage
 	return nil
 }
 
+// PortalStatus returns the state of the portals, with the bridges connected to them.
+func (r *Reverse) PortalStatus() []*PortalStatus {
+	status := make([]*PortalStatus, 0, len(r.portals))
+	for _, p := range r.portals {
+		status = append(status, p.Status())
+	}
+	return status
+}
+
+// BridgeStatus returns the state of the local bridges.
+func (r *Reverse) BridgeStatus() []*BridgeStatus {
+	status := make([]*BridgeStatus, 0, len(r.bridges))
+	for _, b := range r.bridges {
+		status = append(status, b.Status())
+	}
+	return status
+}
+
 func (r *Reverse) Type() interface{} {
 	return (*Reverse)(nil)
 }
@@ -0,0 +1,120 @@
+package reverse
+
+import (
+	gonet "net"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+// serviceSuffix marks the destinations that portals request by service name.
+const serviceSuffix = ".service." + internalDomain
+
+func serviceDestination(name string, network net.Network) net.Destination {
+	return net.Destination{
+		Network: network,
+		Address: net.DomainAddress(name + serviceSuffix),
+	}
+}
+
+type serviceMatcher struct {
+	name   string
+	domain string
+	ip     *gonet.IPNet
+	target net.Address
+	ports  net.MemoryPortList
+}
+
+func newServiceMatcher(s *Service) (*serviceMatcher, error) {
+	m := &serviceMatcher{
+		name: s.Name,
+	}
+	if s.PortList != nil {
+		m.ports = net.PortListFromProto(s.PortList)
+	}
+	switch {
+	case s.Address == "":
+	case strings.Contains(s.Address, "/"):
+		_, ipNet, err := gonet.ParseCIDR(s.Address)
+		if err != nil {
+			return nil, errors.New("invalid service address: ", s.Address).Base(err)
+		}
+		m.ip = ipNet
+	default:
+		m.target = net.ParseAddress(s.Address)
+		if m.target.Family().IsDomain() {
+			m.domain = strings.ToLower(m.target.Domain())
+		} else {
+			ip := m.target.IP()
+			m.ip = &gonet.IPNet{IP: ip, Mask: gonet.CIDRMask(len(ip)*8, len(ip)*8)}
+		}
+	}
+	if m.name != "" && (m.target == nil || len(m.ports) == 0) {
+		return nil, errors.New("named service ", m.name, " requires a domain or IP and a port")
+	}
+	return m, nil
+}
+
+func (m *serviceMatcher) Match(dest net.Destination) bool {
+	if len(m.ports) > 0 && !m.ports.Contains(dest.Port) {
+		return false
+	}
+	switch {
+	case m.ip != nil:
+		return dest.Address.Family().IsIP() && m.ip.Contains(dest.Address.IP())
+	case m.domain != "":
+		return dest.Address.Family().IsDomain() && strings.ToLower(dest.Address.Domain()) == m.domain
+	}
+	return true
+}
+
+// ServiceList is the allow-list of destinations that a bridge relays.
+type ServiceList struct {
+	services []*serviceMatcher
+	named    map[string]*serviceMatcher
+}
+
+func NewServiceList(services []*Service) (*ServiceList, error) {
+	l := &ServiceList{
+		named: make(map[string]*serviceMatcher),
+	}
+	for _, s := range services {
+		m, err := newServiceMatcher(s)
+		if err != nil {
+			return nil, err
+		}
+		if m.name != "" {
+			if _, found := l.named[m.name]; found {
+				return nil, errors.New("duplicated service name: ", m.name)
+			}
+			l.named[m.name] = m
+		}
+		l.services = append(l.services, m)
+	}
+	return l, nil
+}
+
+// Resolve checks the destination requested by a portal, and translates service names into addresses.
+func (l *ServiceList) Resolve(dest net.Destination) (net.Destination, error) {
+	if dest.Address.Family().IsDomain() && strings.HasSuffix(dest.Address.Domain(), serviceSuffix) {
+		name := strings.TrimSuffix(dest.Address.Domain(), serviceSuffix)
+		m, found := l.named[name]
+		if !found {
+			return dest, errors.New("unknown service: ", name)
+		}
+		dest.Address = m.target
+		dest.Port = m.ports[0].From
+		return dest, nil
+	}
+
+	if len(l.services) == 0 {
+		return dest, nil
+	}
+	for _, m := range l.services {
+		if m.Match(dest) {
+			return dest, nil
+		}
+	}
+	return dest, errors.New("destination not allowed: ", dest)
+}
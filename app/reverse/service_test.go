@@ -0,0 +1,50 @@
+package reverse
+
+import (
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+func TestServiceList(t *testing.T) {
+	list, err := NewServiceList([]*Service{
+		{
+			Name:     "web",
+			Address:  "192.168.1.10",
+			PortList: &net.PortList{Range: []*net.PortRange{{From: 80, To: 80}}},
+		},
+		{
+			Address:  "10.0.0.0/8",
+			PortList: &net.PortList{Range: []*net.PortRange{{From: 1000, To: 2000}}},
+		},
+		{
+			Address: "nas.lan",
+		},
+	})
+	common.Must(err)
+
+	cases := []struct {
+		dest    net.Destination
+		allowed bool
+	}{
+		{net.TCPDestination(net.ParseAddress("192.168.1.10"), 80), true},
+		{net.TCPDestination(net.ParseAddress("192.168.1.10"), 22), false},
+		{net.TCPDestination(net.ParseAddress("10.1.2.3"), 1500), true},
+		{net.TCPDestination(net.ParseAddress("10.1.2.3"), 80), false},
+		{net.TCPDestination(net.DomainAddress("NAS.lan"), 445), true},
+		{net.TCPDestination(net.DomainAddress("router.lan"), 80), false},
+		{serviceDestination("ssh", net.Network_TCP), false},
+	}
+	for _, c := range cases {
+		if _, err := list.Resolve(c.dest); (err == nil) != c.allowed {
+			t.Error("unexpected result for ", c.dest, ": ", err)
+		}
+	}
+
+	dest, err := list.Resolve(serviceDestination("web", net.Network_TCP))
+	common.Must(err)
+	if dest != net.TCPDestination(net.ParseAddress("192.168.1.10"), 80) {
+		t.Error("unexpected service destination: ", dest)
+	}
+}
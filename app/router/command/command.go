@@ -4,20 +4,26 @@ package command
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/luckyluke-a/xray-core/app/commander"
+	"github.com/luckyluke-a/xray-core/app/router"
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/serial"
 	"github.com/luckyluke-a/xray-core/core"
 	"github.com/luckyluke-a/xray-core/features/routing"
 	"github.com/luckyluke-a/xray-core/features/stats"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 // routingServer is an implementation of RoutingService.
 type routingServer struct {
 	router       routing.Router
 	routingStats stats.Channel
+	instance     *core.Instance
 }
 
 func (s *routingServer) GetBalancerInfo(ctx context.Context, request *GetBalancerInfoRequest) (*GetBalancerInfoResponse, error) {
@@ -57,18 +63,148 @@ func (s *routingServer) OverrideBalancerTarget(ctx context.Context, request *Ove
 
 func (s *routingServer) AddRule(ctx context.Context, request *AddRuleRequest) (*AddRuleResponse, error) {
 	if bo, ok := s.router.(routing.Router); ok {
-		return &AddRuleResponse{}, bo.AddRule(request.Config, request.ShouldAppend)
+		if err := bo.AddRule(request.Config, request.ShouldAppend); err != nil {
+			return nil, err
+		}
+		s.recordRules(ctx, request)
+		return &AddRuleResponse{}, nil
 	}
 	return nil, errors.New("unsupported router implementation")
 
 }
 func (s *routingServer) RemoveRule(ctx context.Context, request *RemoveRuleRequest) (*RemoveRuleResponse, error) {
 	if bo, ok := s.router.(routing.Router); ok {
-		return &RemoveRuleResponse{}, bo.RemoveRule(request.RuleTag)
+		if err := bo.RemoveRule(request.RuleTag); err != nil {
+			return nil, err
+		}
+		s.recordRules(ctx, request)
+		return &RemoveRuleResponse{}, nil
 	}
 	return nil, errors.New("unsupported router implementation")
 }
 
+// recordRules applies a change of the routing rules to the config of the
+// instance, so that the change shows up in the running config.
+func (s *routingServer) recordRules(ctx context.Context, change interface{ ApplyConfig(*core.Config) error }) {
+	if s.instance == nil {
+		return
+	}
+	if err := s.instance.UpdateConfig(change.ApplyConfig); err != nil {
+		errors.LogWarning(ctx, "failed to record routing rules in the config: ", err)
+	}
+}
+
+// updateRouterConfig applies update to the router config in c, which is
+// added if there is none.
+func updateRouterConfig(c *core.Config, update func(*router.Config) error) error {
+	for i, app := range c.App {
+		inst, err := app.GetInstance()
+		if err != nil {
+			return err
+		}
+		if config, ok := inst.(*router.Config); ok {
+			if err := update(config); err != nil {
+				return err
+			}
+			c.App[i] = serial.ToTypedMessage(config)
+			return nil
+		}
+	}
+	config := new(router.Config)
+	if err := update(config); err != nil {
+		return err
+	}
+	c.App = append(c.App, serial.ToTypedMessage(config))
+	return nil
+}
+
+// ApplyConfig implements commander.ConfigChange.
+func (r *AddRuleRequest) ApplyConfig(c *core.Config) error {
+	inst, err := r.Config.GetInstance()
+	if err != nil {
+		return err
+	}
+	rules, ok := inst.(*router.Config)
+	if !ok {
+		return errors.New("not a router config")
+	}
+	return updateRouterConfig(c, func(config *router.Config) error {
+		if !r.ShouldAppend {
+			config.Rule = nil
+			config.BalancingRule = nil
+		}
+		config.Rule = append(config.Rule, rules.Rule...)
+		config.BalancingRule = append(config.BalancingRule, rules.BalancingRule...)
+		return nil
+	})
+}
+
+// ApplyConfig implements commander.ConfigChange.
+func (r *RemoveRuleRequest) ApplyConfig(c *core.Config) error {
+	for i, app := range c.App {
+		inst, err := app.GetInstance()
+		if err != nil {
+			return err
+		}
+		config, ok := inst.(*router.Config)
+		if !ok {
+			continue
+		}
+		rules := config.Rule[:0]
+		for _, rule := range config.Rule {
+			if rule.RuleTag != r.RuleTag {
+				rules = append(rules, rule)
+			}
+		}
+		config.Rule = rules
+		c.App[i] = serial.ToTypedMessage(config)
+		return nil
+	}
+	// there are no rules to remove without a router config, which is not
+	// added as the running instance has none
+	return nil
+}
+
+// routerConfig returns the router config in c, or an empty one if there is
+// none.
+func routerConfig(c *core.Config) (*router.Config, error) {
+	for _, app := range c.App {
+		inst, err := app.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		if config, ok := inst.(*router.Config); ok {
+			return config, nil
+		}
+	}
+	return new(router.Config), nil
+}
+
+// compactRules implements commander.JournalCompactor. Changed rules are
+// replaced as a whole, so that replaying the entry on current changes
+// nothing.
+func compactRules(base, current *core.Config) ([]*commander.JournalEntry, error) {
+	baseConfig, err := routerConfig(base)
+	if err != nil {
+		return nil, err
+	}
+	currentConfig, err := routerConfig(current)
+	if err != nil {
+		return nil, err
+	}
+	rules := &router.Config{
+		Rule:          currentConfig.Rule,
+		BalancingRule: currentConfig.BalancingRule,
+	}
+	if proto.Equal(rules, &router.Config{Rule: baseConfig.Rule, BalancingRule: baseConfig.BalancingRule}) {
+		return nil, nil
+	}
+	return []*commander.JournalEntry{{
+		Method:  RoutingService_AddRule_FullMethodName,
+		Request: serial.ToTypedMessage(&AddRuleRequest{Config: serial.ToTypedMessage(rules)}),
+	}}, nil
+}
+
 // NewRoutingServer creates a statistics service with statistics manager.
 func NewRoutingServer(router routing.Router, routingStats stats.Channel) RoutingServiceServer {
 	return &routingServer{
@@ -128,9 +264,24 @@ type service struct {
 	v *core.Instance
 }
 
+// JournaledMethods implements commander.JournaledService.
+func (s *service) JournaledMethods() []string {
+	methods := []string{
+		RoutingService_AddRule_FullMethodName,
+		RoutingService_RemoveRule_FullMethodName,
+	}
+	for _, method := range methods {
+		methods = append(methods, strings.Replace(method, "/xray.", "/v2ray.core.", 1))
+	}
+	return methods
+}
+
 func (s *service) Register(server *grpc.Server) {
 	common.Must(s.v.RequireFeatures(func(router routing.Router, stats stats.Manager) {
-		rs := NewRoutingServer(router, nil)
+		rs := &routingServer{
+			router:   router,
+			instance: s.v,
+		}
 		RegisterRoutingServiceServer(server, rs)
 
 		// For compatibility purposes
@@ -145,4 +296,5 @@ func init() {
 		s := core.MustFromContext(ctx)
 		return &service{v: s}, nil
 	}))
+	commander.RegisterJournalCompactor(compactRules)
 }
@@ -56,6 +56,26 @@ func (err *Error) Error() string {
 	return builder.String()
 }
 
+// Fields returns the error as structured log fields, with the session ID and
+// the component apart from the message.
+func (err *Error) Fields() []log.Field {
+	var fields []log.Field
+	for _, prefix := range err.prefix {
+		if id, ok := prefix.(uint32); ok {
+			fields = append(fields, log.Field{Key: "session_id", Value: id})
+		}
+	}
+	if len(err.caller) > 0 {
+		fields = append(fields, log.Field{Key: "component", Value: err.caller})
+	}
+
+	msg := serial.Concat(err.message...)
+	if err.inner != nil {
+		msg += " > " + err.inner.Error()
+	}
+	return append(fields, log.Field{Key: "msg", Value: msg})
+}
+
 // Unwrap implements hasInnerError.Unwrap()
 func (err *Error) Unwrap() error {
 	if err.inner == nil {
@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/luckyluke-a/xray-core/common/serial"
 )
@@ -18,6 +19,9 @@ type AccessStatus string
 const (
 	AccessAccepted = AccessStatus("accepted")
 	AccessRejected = AccessStatus("rejected")
+	// AccessClosed is logged when an accepted connection is closed, with the
+	// traffic and duration.
+	AccessClosed = AccessStatus("closed")
 )
 
 type AccessMessage struct {
@@ -27,6 +31,15 @@ type AccessMessage struct {
 	Reason interface{}
 	Email  string
 	Detour string
+
+	SessionID   uint32
+	InboundTag  string
+	OutboundTag string
+	RuleTag     string
+	Domain      string
+	Uplink      int64
+	Downlink    int64
+	Duration    time.Duration
 }
 
 func (m *AccessMessage) String() string {
@@ -58,6 +71,39 @@ func (m *AccessMessage) String() string {
 	return builder.String()
 }
 
+// Fields implements StructuredMessage.
+func (m *AccessMessage) Fields() []Field {
+	fields := []Field{
+		{Key: "type", Value: "access"},
+		{Key: "status", Value: string(m.Status)},
+		{Key: "from", Value: serial.ToString(m.From)},
+		{Key: "to", Value: serial.ToString(m.To)},
+	}
+	appendString := func(key, value string) {
+		if value != "" {
+			fields = append(fields, Field{Key: key, Value: value})
+		}
+	}
+	appendString("reason", serial.ToString(m.Reason))
+	appendString("email", m.Email)
+	appendString("detour", m.Detour)
+	appendString("inbound_tag", m.InboundTag)
+	appendString("outbound_tag", m.OutboundTag)
+	appendString("rule_tag", m.RuleTag)
+	appendString("sniffed_domain", m.Domain)
+	if m.SessionID != 0 {
+		fields = append(fields, Field{Key: "session_id", Value: m.SessionID})
+	}
+	if m.Status == AccessClosed {
+		fields = append(fields,
+			Field{Key: "uplink_bytes", Value: m.Uplink},
+			Field{Key: "downlink_bytes", Value: m.Downlink},
+			Field{Key: "duration_ms", Value: m.Duration.Milliseconds()},
+		)
+	}
+	return fields
+}
+
 func ContextWithAccessMessage(ctx context.Context, accessMessage *AccessMessage) context.Context {
 	return context.WithValue(ctx, accessMessageKey, accessMessage)
 }
@@ -40,6 +40,26 @@ func (l *DNSLog) String() string {
 	return builder.String()
 }
 
+// Fields implements StructuredMessage.
+func (l *DNSLog) Fields() []Field {
+	result := make([]string, 0, len(l.Result))
+	for _, ip := range l.Result {
+		result = append(result, ip.String())
+	}
+	fields := []Field{
+		{Key: "type", Value: "dns"},
+		{Key: "server", Value: l.Server},
+		{Key: "status", Value: strings.TrimSuffix(string(l.Status), ":")},
+		{Key: "domain", Value: l.Domain},
+		{Key: "result", Value: strings.Join(result, ",")},
+		{Key: "elapsed_ms", Value: l.Elapsed.Milliseconds()},
+	}
+	if l.Error != nil {
+		fields = append(fields, Field{Key: "error", Value: l.Error.Error()})
+	}
+	return fields
+}
+
 type dnsStatus string
 
 var (
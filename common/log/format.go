@@ -0,0 +1,103 @@
+package log
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/luckyluke-a/xray-core/common/serial"
+)
+
+// Field is a key-value pair in a structured log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// StructuredMessage is a message which can be logged as fields.
+type StructuredMessage interface {
+	Message
+	Fields() []Field
+}
+
+// Formatter renders a message into a log line.
+type Formatter func(Message) string
+
+// FormatText renders a message like the text logs to the console and files,
+// with the time before it.
+func FormatText(msg Message) string {
+	return time.Now().Format("2006/01/02 15:04:05 ") + msg.String()
+}
+
+// messageFields returns the fields of a message with the time first. Messages
+// without fields are logged in "msg".
+func messageFields(msg Message) []Field {
+	fields := []Field{{Key: "time", Value: time.Now().Format(time.RFC3339Nano)}}
+	if m, ok := msg.(StructuredMessage); ok {
+		return append(fields, m.Fields()...)
+	}
+	return append(fields, Field{Key: "msg", Value: msg.String()})
+}
+
+// FormatJSON renders a message as a JSON object.
+func FormatJSON(msg Message) string {
+	builder := strings.Builder{}
+	builder.WriteByte('{')
+	for i, field := range messageFields(msg) {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Key)
+		builder.Write(key)
+		builder.WriteByte(':')
+		value, err := json.Marshal(jsonValue(field.Value))
+		if err != nil {
+			value, _ = json.Marshal(serial.ToString(field.Value))
+		}
+		builder.Write(value)
+	}
+	builder.WriteByte('}')
+	return builder.String()
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, bool, int, int32, int64, uint32, uint64, float64:
+		return v
+	default:
+		return serial.ToString(v)
+	}
+}
+
+// FormatLogfmt renders a message as logfmt, which is key=value pairs
+// separated by spaces.
+func FormatLogfmt(msg Message) string {
+	builder := strings.Builder{}
+	for i, field := range messageFields(msg) {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(field.Key)
+		builder.WriteByte('=')
+		value := serial.ToString(field.Value)
+		if needsQuote(value) {
+			value = strconv.Quote(value)
+		}
+		builder.WriteString(value)
+	}
+	return builder.String()
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
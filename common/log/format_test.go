@@ -0,0 +1,68 @@
+package log_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/luckyluke-a/xray-core/common/log"
+)
+
+func TestFormatJSON(t *testing.T) {
+	line := log.FormatJSON(&log.AccessMessage{
+		From:        "127.0.0.1:1234",
+		To:          "tcp:example.com:443",
+		Status:      log.AccessClosed,
+		InboundTag:  "in",
+		OutboundTag: "out",
+		RuleTag:     "rule",
+		Domain:      "example.com",
+		SessionID:   42,
+		Uplink:      100,
+		Downlink:    200,
+		Duration:    1500 * time.Millisecond,
+	})
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatal(line, err)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry["time"].(string)); err != nil {
+		t.Error(err)
+	}
+	delete(entry, "time")
+	expected := map[string]interface{}{
+		"type":           "access",
+		"status":         "closed",
+		"from":           "127.0.0.1:1234",
+		"to":             "tcp:example.com:443",
+		"inbound_tag":    "in",
+		"outbound_tag":   "out",
+		"rule_tag":       "rule",
+		"sniffed_domain": "example.com",
+		"session_id":     float64(42),
+		"uplink_bytes":   float64(100),
+		"downlink_bytes": float64(200),
+		"duration_ms":    float64(1500),
+	}
+	if diff := cmp.Diff(expected, entry); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestFormatLogfmt(t *testing.T) {
+	line := log.FormatLogfmt(&log.GeneralMessage{
+		Severity: log.Severity_Warning,
+		Content:  "failed to dial",
+	})
+
+	_, line, found := strings.Cut(line, " ")
+	if !found {
+		t.Fatal("no time in ", line)
+	}
+	if diff := cmp.Diff(`level=warning msg="failed to dial"`, line); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/serial"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+type journaldLogWriter struct {
+	conn net.Conn
+}
+
+// CreateJournaldLogWriter returns a LogWriterCreator that sends messages to
+// journald in its native protocol. Fields of structured messages are sent as
+// journal fields prefixed by XRAY_, like XRAY_INBOUND_TAG.
+func CreateJournaldLogWriter() WriterCreator {
+	return func() Writer {
+		conn, err := net.Dial("unixgram", journaldSocket)
+		if err != nil {
+			return nil
+		}
+		return &journaldLogWriter{conn: conn}
+	}
+}
+
+// appendJournalField appends a field in the native protocol of journald, where
+// values with newlines are sized.
+func appendJournalField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalFieldName converts a key to a journal field name, which has only
+// uppercase letters, digits and underscores.
+func journalFieldName(key string) string {
+	return "XRAY_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+func (w *journaldLogWriter) send(severity Severity, line string, fields []Field) error {
+	var b bytes.Buffer
+	appendJournalField(&b, "MESSAGE", line)
+	appendJournalField(&b, "PRIORITY", serial.ToString(syslogSeverity(severity)))
+	appendJournalField(&b, "SYSLOG_IDENTIFIER", syslogAppName)
+	for _, field := range fields {
+		if field.Key == "msg" {
+			// it's in MESSAGE
+			continue
+		}
+		appendJournalField(&b, journalFieldName(field.Key), serial.ToString(field.Value))
+	}
+	_, err := w.conn.Write(b.Bytes())
+	return err
+}
+
+// Write implements Writer.
+func (w *journaldLogWriter) Write(s string) error {
+	return w.send(Severity_Info, s, nil)
+}
+
+// WriteMessage implements MessageWriter.
+func (w *journaldLogWriter) WriteMessage(msg Message, line string) error {
+	var fields []Field
+	if m, ok := msg.(StructuredMessage); ok {
+		fields = m.Fields()
+	}
+	return w.send(severityOf(msg), line, fields)
+}
+
+// Close implements Writer.
+func (w *journaldLogWriter) Close() error {
+	return w.conn.Close()
+}
@@ -1,6 +1,7 @@
 package log // import "github.com/luckyluke-a/xray-core/common/log"
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/luckyluke-a/xray-core/common/serial"
@@ -27,6 +28,16 @@ func (m *GeneralMessage) String() string {
 	return serial.Concat("[", m.Severity, "] ", m.Content)
 }
 
+// Fields implements StructuredMessage. The content may carry its own fields,
+// like the session ID and component of an error.
+func (m *GeneralMessage) Fields() []Field {
+	fields := []Field{{Key: "level", Value: strings.ToLower(m.Severity.String())}}
+	if c, ok := m.Content.(interface{ Fields() []Field }); ok {
+		return append(fields, c.Fields()...)
+	}
+	return append(fields, Field{Key: "msg", Value: serial.ToString(m.Content)})
+}
+
 // Record writes a message into log stream.
 func Record(msg Message) {
 	logHandler.Handle(msg)
@@ -17,14 +17,30 @@ type Writer interface {
 	io.Closer
 }
 
+// MessageWriter is a Writer which keeps the severity and fields of messages,
+// like syslog and journald.
+type MessageWriter interface {
+	Writer
+	// WriteMessage writes a message, which is also formatted as a line.
+	WriteMessage(msg Message, line string) error
+}
+
+// persistentWriter is a Writer which is kept open when the logger is idle,
+// as closing it would drop the lines it buffers.
+type persistentWriter interface {
+	Writer
+	persistent()
+}
+
 // WriterCreator is a function to create LogWriters.
 type WriterCreator func() Writer
 
 type generalLogger struct {
-	creator WriterCreator
-	buffer  chan Message
-	access  *semaphore.Instance
-	done    *done.Instance
+	creator   WriterCreator
+	formatter Formatter
+	buffer    chan Message
+	access    *semaphore.Instance
+	done      *done.Instance
 }
 
 type serverityLogger struct {
@@ -42,6 +58,18 @@ func NewLogger(logWriterCreator WriterCreator) Handler {
 	}
 }
 
+// NewFormattedLogger returns a generic log handler which renders messages with
+// the formatter, instead of their String().
+func NewFormattedLogger(logWriterCreator WriterCreator, formatter Formatter) Handler {
+	return &generalLogger{
+		creator:   logWriterCreator,
+		formatter: formatter,
+		buffer:    make(chan Message, 16),
+		access:    semaphore.New(1),
+		done:      done.New(),
+	}
+}
+
 func ReplaceWithSeverityLogger(serverity Severity) {
 	w := CreateStdoutLogWriter()
 	g := &generalLogger{
@@ -80,16 +108,21 @@ func (l *generalLogger) run() {
 		return
 	}
 	defer logger.Close()
+	_, persistent := logger.(persistentWriter)
 
 	for {
 		select {
 		case <-l.done.Wait():
 			return
 		case msg := <-l.buffer:
-			logger.Write(msg.String() + platform.LineSeparator())
+			if w, ok := logger.(MessageWriter); ok {
+				w.WriteMessage(msg, l.format(msg))
+			} else {
+				logger.Write(l.format(msg) + platform.LineSeparator())
+			}
 			dataWritten = true
 		case <-ticker.C:
-			if !dataWritten {
+			if !dataWritten && !persistent {
 				return
 			}
 			dataWritten = false
@@ -97,6 +130,13 @@ func (l *generalLogger) run() {
 	}
 }
 
+func (l *generalLogger) format(msg Message) string {
+	if l.formatter != nil {
+		return l.formatter(msg)
+	}
+	return msg.String()
+}
+
 func (l *generalLogger) Handle(msg Message) {
 
 	select {
@@ -129,7 +169,7 @@ func (w *consoleLogWriter) Close() error {
 }
 
 type fileLogWriter struct {
-	file   *os.File
+	file   io.Closer
 	logger *log.Logger
 }
 
@@ -144,29 +184,64 @@ func (w *fileLogWriter) Close() error {
 
 // CreateStdoutLogWriter returns a LogWriterCreator that creates LogWriter for stdout.
 func CreateStdoutLogWriter() WriterCreator {
-	return func() Writer {
-		return &consoleLogWriter{
-			logger: log.New(os.Stdout, "", log.Ldate|log.Ltime),
-		}
-	}
+	return createConsoleLogWriter(os.Stdout, log.Ldate|log.Ltime)
 }
 
 // CreateStderrLogWriter returns a LogWriterCreator that creates LogWriter for stderr.
 func CreateStderrLogWriter() WriterCreator {
+	return createConsoleLogWriter(os.Stderr, log.Ldate|log.Ltime)
+}
+
+// CreatePlainStdoutLogWriter is like CreateStdoutLogWriter, but without the
+// timestamp before each line. It's for formatters which have their own.
+func CreatePlainStdoutLogWriter() WriterCreator {
+	return createConsoleLogWriter(os.Stdout, 0)
+}
+
+func createConsoleLogWriter(out io.Writer, flag int) WriterCreator {
 	return func() Writer {
 		return &consoleLogWriter{
-			logger: log.New(os.Stderr, "", log.Ldate|log.Ltime),
+			logger: log.New(out, "", flag),
 		}
 	}
 }
 
 // CreateFileLogWriter returns a LogWriterCreator that creates LogWriter for the given file.
 func CreateFileLogWriter(path string) (WriterCreator, error) {
+	return CreateFileLogWriterWithOptions(path, FileLogWriterOptions{})
+}
+
+// FileLogWriterOptions are the options of a log file.
+type FileLogWriterOptions struct {
+	// Plain omits the timestamp before each line. It's for formatters which
+	// have their own.
+	Plain bool
+	// Rotation rotates the file if it's set.
+	Rotation *RotationOptions
+}
+
+// CreateFileLogWriterWithOptions is like CreateFileLogWriter, with options.
+func CreateFileLogWriterWithOptions(path string, options FileLogWriterOptions) (WriterCreator, error) {
 	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
 	if err != nil {
 		return nil, err
 	}
 	file.Close()
+
+	flag := log.Ldate | log.Ltime
+	if options.Plain {
+		flag = 0
+	}
+	if options.Rotation != nil {
+		// the state of rotation outlives the writers, which are closed when idle
+		file := getRotatingFile(path, *options.Rotation)
+		return func() Writer {
+			return &fileLogWriter{
+				file:   file,
+				logger: log.New(file, "", flag),
+			}
+		}, nil
+	}
 	return func() Writer {
 		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
 		if err != nil {
@@ -174,7 +249,7 @@ func CreateFileLogWriter(path string) (WriterCreator, error) {
 		}
 		return &fileLogWriter{
 			file:   file,
-			logger: log.New(file, "", log.Ldate|log.Ltime),
+			logger: log.New(file, "", flag),
 		}
 	}, nil
 }
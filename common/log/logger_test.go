@@ -2,6 +2,7 @@ package log_test
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -36,3 +37,82 @@ func TestFileLogger(t *testing.T) {
 		t.Fatal("Expect log text contains 'Test Log', but actually: ", string(b))
 	}
 }
+
+func TestRotatingFileLogger(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	creator, err := CreateFileLogWriterWithOptions(path, FileLogWriterOptions{
+		Plain: true,
+		Rotation: &RotationOptions{
+			MaxSize:    16,
+			Compress:   true,
+			MaxBackups: 2,
+		},
+	})
+	common.Must(err)
+
+	writer := creator()
+	for i := 0; i < 4; i++ {
+		common.Must(writer.Write("0123456789\n"))
+		// backups are named by milliseconds
+		time.Sleep(10 * time.Millisecond)
+	}
+	common.Must(writer.Close())
+
+	var compressed []string
+	for i := 0; i < 50; i++ {
+		compressed, _ = filepath.Glob(filepath.Join(dir, "access-*.log.gz"))
+		if len(compressed) == 2 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(compressed) != 2 {
+		entries, _ := os.ReadDir(dir)
+		t.Fatal("expect 2 compressed backups, but actually: ", entries)
+	}
+
+	b, err := os.ReadFile(path)
+	common.Must(err)
+	if string(b) != "0123456789\n" {
+		t.Fatal("unexpected content: ", string(b))
+	}
+}
+
+func TestRotatingFileLoggerSharedPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "xray.log")
+
+	options := FileLogWriterOptions{
+		Plain: true,
+		Rotation: &RotationOptions{
+			MaxSize: 16,
+		},
+	}
+	access, err := CreateFileLogWriterWithOptions(path, options)
+	common.Must(err)
+	errorLog, err := CreateFileLogWriterWithOptions(path, options)
+	common.Must(err)
+
+	accessWriter := access()
+	errorWriter := errorLog()
+	common.Must(accessWriter.Write("0123456789\n"))
+	common.Must(errorWriter.Write("0123456789\n"))
+	// the file rotated for the error log is the one of the access log
+	common.Must(accessWriter.Write("abc\n"))
+	common.Must(accessWriter.Close())
+	common.Must(errorWriter.Close())
+
+	backups, err := filepath.Glob(filepath.Join(dir, "xray-*.log"))
+	common.Must(err)
+	if len(backups) != 1 {
+		entries, _ := os.ReadDir(dir)
+		t.Fatal("expect 1 backup, but actually: ", entries)
+	}
+	b, err := os.ReadFile(path)
+	common.Must(err)
+	if string(b) != "0123456789\nabc\n" {
+		t.Fatal("unexpected content: ", string(b))
+	}
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/signal/done"
+)
+
+const (
+	// remoteBufferSize is the number of lines buffered for a remote sink.
+	// Writes block when it's full, so that the logger drops new messages
+	// instead of growing.
+	remoteBufferSize = 1024
+	// remoteWriteTimeout limits each write, so that a stalled endpoint is
+	// redialed instead of blocking the writer.
+	remoteWriteTimeout = 10 * time.Second
+	// remoteFlushTimeout is the time to send the buffered lines on close.
+	remoteFlushTimeout = 5 * time.Second
+)
+
+type remoteLogWriter struct {
+	address string
+	lines   chan string
+	done    *done.Instance
+	stopped chan struct{}
+}
+
+// CreateRemoteLogWriter returns a LogWriterCreator that sends lines to a TCP
+// endpoint like tcp://host:port. The lines are buffered, and the connection
+// is redialed when it's dropped. The writer is kept open when the logger is
+// idle, and sends the buffered lines on close.
+func CreateRemoteLogWriter(address string) (WriterCreator, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "tcp" || u.Host == "" {
+		return nil, errors.New("invalid remote log address: " + address)
+	}
+	return func() Writer {
+		w := &remoteLogWriter{
+			address: u.Host,
+			lines:   make(chan string, remoteBufferSize),
+			done:    done.New(),
+			stopped: make(chan struct{}),
+		}
+		go w.run()
+		return w
+	}, nil
+}
+
+func (w *remoteLogWriter) run() {
+	defer close(w.stopped)
+
+	var conn net.Conn
+	var writer *bufio.Writer
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	backoff := time.Second
+	for {
+		var line string
+		select {
+		case line = <-w.lines:
+		case <-w.done.Wait():
+			w.flush(conn, writer, "")
+			return
+		}
+
+		// the line is kept until it's sent
+		for {
+			if conn == nil {
+				c, err := net.DialTimeout("tcp", w.address, 5*time.Second)
+				if err != nil {
+					select {
+					case <-time.After(backoff):
+					case <-w.done.Wait():
+						w.flush(nil, nil, line)
+						return
+					}
+					if backoff < 30*time.Second {
+						backoff *= 2
+					}
+					continue
+				}
+				backoff = time.Second
+				conn, writer = c, bufio.NewWriter(c)
+			}
+			conn.SetWriteDeadline(time.Now().Add(remoteWriteTimeout))
+			_, err := writer.WriteString(line)
+			if err == nil && len(w.lines) == 0 {
+				err = writer.Flush()
+			}
+			if err == nil {
+				break
+			}
+			conn.Close()
+			conn, writer = nil, nil
+		}
+	}
+}
+
+// flush sends the pending line and the buffered lines on close, in
+// remoteFlushTimeout. It dials again if not connected.
+func (w *remoteLogWriter) flush(conn net.Conn, writer *bufio.Writer, pending string) {
+	deadline := time.Now().Add(remoteFlushTimeout)
+	if conn == nil {
+		if pending == "" && len(w.lines) == 0 {
+			return
+		}
+		c, err := net.DialTimeout("tcp", w.address, time.Until(deadline))
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		conn, writer = c, bufio.NewWriter(c)
+	}
+	conn.SetWriteDeadline(deadline)
+	if _, err := writer.WriteString(pending); err != nil {
+		return
+	}
+	for {
+		select {
+		case line := <-w.lines:
+			if _, err := writer.WriteString(line); err != nil {
+				return
+			}
+		default:
+			writer.Flush()
+			return
+		}
+	}
+}
+
+// Write implements Writer. It blocks when the buffer is full.
+func (w *remoteLogWriter) Write(s string) error {
+	select {
+	case w.lines <- s:
+		return nil
+	case <-w.done.Wait():
+		return io.ErrClosedPipe
+	}
+}
+
+func (w *remoteLogWriter) persistent() {}
+
+// Close implements Writer.
+func (w *remoteLogWriter) Close() error {
+	w.done.Close()
+	<-w.stopped
+	return nil
+}
@@ -0,0 +1,72 @@
+package log_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common"
+	. "github.com/luckyluke-a/xray-core/common/log"
+)
+
+func TestRemoteLogWriter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	creator, err := CreateRemoteLogWriter("tcp://" + listener.Addr().String())
+	common.Must(err)
+	writer := creator()
+	common.Must(writer.Write("line 1\n"))
+	common.Must(writer.Write("line 2\n"))
+
+	conn, err := listener.Accept()
+	common.Must(err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{"line 1\n", "line 2\n"} {
+		line, err := reader.ReadString('\n')
+		common.Must(err)
+		if line != expected {
+			t.Error("expect ", expected, ", but actually ", line)
+		}
+	}
+	common.Must(writer.Close())
+}
+
+func TestRemoteLogWriterFlushOnClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	address := listener.Addr().String()
+	// the endpoint is down, so the lines are queued
+	listener.Close()
+
+	creator, err := CreateRemoteLogWriter("tcp://" + address)
+	common.Must(err)
+	writer := creator()
+	common.Must(writer.Write("line 1\n"))
+	common.Must(writer.Write("line 2\n"))
+	time.Sleep(100 * time.Millisecond)
+
+	listener, err = net.Listen("tcp", address)
+	common.Must(err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		content, _ := io.ReadAll(conn)
+		received <- string(content)
+	}()
+
+	common.Must(writer.Close())
+	if content := <-received; content != "line 1\nline 2\n" {
+		t.Error("expect the queued lines sent on close, but actually ", content)
+	}
+}
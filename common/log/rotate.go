@@ -0,0 +1,246 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationOptions controls when a log file is rotated, and how long the
+// rotated files are kept.
+type RotationOptions struct {
+	// MaxSize is the size in bytes of a log file to be rotated at.
+	MaxSize int64
+	// Interval rotates the log file periodically. The periods are aligned to
+	// the Unix epoch in UTC, so "24h" rotates at midnight UTC.
+	Interval time.Duration
+	// Compress gzips the rotated files.
+	Compress bool
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int
+	// MaxAge is the duration to keep the rotated files for.
+	MaxAge time.Duration
+}
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotatingFile is a log file which is rotated on write. It's shared by the
+// writers created for the same path, and the file is reopened after Close.
+type rotatingFile struct {
+	sync.Mutex
+	path      string
+	options   RotationOptions
+	file      *os.File
+	size      int64
+	lastWrite time.Time
+
+	cleanupAccess sync.Mutex
+}
+
+var rotatingFiles = struct {
+	sync.Mutex
+	files map[string]*rotatingFile
+}{
+	files: make(map[string]*rotatingFile),
+}
+
+// getRotatingFile returns the rotating file of the path, so that the logs
+// written to the same file, e.g. the access and error logs, rotate it once.
+// The options given last take effect.
+func getRotatingFile(path string, options RotationOptions) *rotatingFile {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+
+	rotatingFiles.Lock()
+	defer rotatingFiles.Unlock()
+
+	if f, found := rotatingFiles.files[key]; found {
+		f.Lock()
+		f.options = options
+		f.Unlock()
+		return f
+	}
+	f := &rotatingFile{
+		path:    path,
+		options: options,
+	}
+	rotatingFiles.files[key] = f
+	return f
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.lastWrite = info.ModTime()
+	return nil
+}
+
+func (f *rotatingFile) shouldRotate(now time.Time, n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.options.MaxSize > 0 && f.size+int64(n) > f.options.MaxSize {
+		return true
+	}
+	if f.options.Interval > 0 && !now.Truncate(f.options.Interval).Equal(f.lastWrite.Truncate(f.options.Interval)) {
+		return true
+	}
+	return false
+}
+
+func (f *rotatingFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := os.Rename(f.path, f.backupName(now)); err != nil {
+		return err
+	}
+	go f.cleanup(f.options)
+	return f.open()
+}
+
+// Write implements io.Writer.
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	now := time.Now()
+	if f.shouldRotate(now, len(b)) {
+		if err := f.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	f.lastWrite = now
+	return n, err
+}
+
+// Close implements io.Closer.
+func (f *rotatingFile) Close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-" + t.Format(backupTimeFormat) + ext
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rotated files, from the newest to the oldest.
+func (f *rotatingFile) backups() ([]backupFile, error) {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		timestamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)[len(prefix):]
+		t, err := time.ParseInLocation(backupTimeFormat, timestamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+// cleanup compresses the rotated files, and removes those beyond the
+// retention.
+func (f *rotatingFile) cleanup(options RotationOptions) {
+	f.cleanupAccess.Lock()
+	defer f.cleanupAccess.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for i, backup := range backups {
+		if (options.MaxBackups > 0 && i >= options.MaxBackups) ||
+			(options.MaxAge > 0 && now.Sub(backup.time) > options.MaxAge) {
+			os.Remove(backup.path)
+			continue
+		}
+		if options.Compress && !strings.HasSuffix(backup.path, ".gz") {
+			compressFile(backup.path)
+		}
+	}
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz.tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(dst)
+	if _, err := io.Copy(writer, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := os.Rename(dst.Name(), path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,171 @@
+package log
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// syslogFacilities are the facilities in RFC 5424, by name.
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// localSyslogPaths are the sockets of local syslog daemons.
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+const syslogAppName = "xray"
+
+// severityOf returns the severity of a message, where access and DNS logs are
+// at info.
+func severityOf(msg Message) Severity {
+	if m, ok := msg.(*GeneralMessage); ok {
+		return m.Severity
+	}
+	return Severity_Info
+}
+
+// syslogSeverity converts a severity to its syslog value.
+func syslogSeverity(severity Severity) int {
+	switch severity {
+	case Severity_Error:
+		return 3
+	case Severity_Warning:
+		return 4
+	case Severity_Debug:
+		return 7
+	default:
+		return 6
+	}
+}
+
+type syslogLogWriter struct {
+	network  string
+	address  string
+	facility int
+	hostname string
+	conn     net.Conn
+}
+
+// CreateSyslogLogWriter returns a LogWriterCreator that sends RFC 5424 syslog
+// messages. The address is one of:
+//
+//	"" for the local syslog daemon
+//	syslog://host:514 or syslog+udp://host:514 for UDP
+//	syslog+tcp://host:514 for TCP, framed by octet counting in RFC 6587
+//	syslog+unix:///dev/log for a unix socket
+//
+// The facility is "daemon" by default, which is set by the query like
+// syslog://host:514?facility=local0.
+func CreateSyslogLogWriter(address string) (WriterCreator, error) {
+	w := &syslogLogWriter{
+		network:  "unixgram",
+		facility: syslogFacilities["daemon"],
+	}
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		switch u.Scheme {
+		case "syslog", "syslog+udp":
+			w.network, w.address = "udp", u.Host
+		case "syslog+tcp":
+			w.network, w.address = "tcp", u.Host
+		case "syslog+unix":
+			w.network, w.address = "unixgram", u.Path
+		default:
+			return nil, errors.New("unknown syslog scheme: " + u.Scheme)
+		}
+		if name := u.Query().Get("facility"); name != "" {
+			facility, found := syslogFacilities[name]
+			if !found {
+				return nil, errors.New("unknown syslog facility: " + name)
+			}
+			w.facility = facility
+		}
+	}
+	w.hostname, _ = os.Hostname()
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+
+	return func() Writer {
+		c := *w
+		if err := c.connect(); err != nil {
+			return nil
+		}
+		return &c
+	}, nil
+}
+
+func (w *syslogLogWriter) connect() error {
+	if w.address != "" {
+		conn, err := net.DialTimeout(w.network, w.address, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		return nil
+	}
+	var err error
+	for _, path := range localSyslogPaths {
+		var conn net.Conn
+		if conn, err = net.Dial("unixgram", path); err == nil {
+			w.conn = conn
+			return nil
+		}
+	}
+	return err
+}
+
+func (w *syslogLogWriter) send(severity Severity, msgID string, line string) error {
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	message := "<" + strconv.Itoa(w.facility*8+syslogSeverity(severity)) + ">1 " +
+		time.Now().Format("2006-01-02T15:04:05.000000Z07:00") + " " +
+		w.hostname + " " + syslogAppName + " " + strconv.Itoa(os.Getpid()) + " " +
+		msgID + " - " + line
+
+	if w.network == "tcp" {
+		message = strconv.Itoa(len(message)) + " " + message
+	}
+	// each message is a datagram, so it's written at once
+	_, err := w.conn.Write([]byte(message))
+	if err != nil {
+		// the connection may be dropped by the server, so it's retried once
+		w.conn.Close()
+		if err := w.connect(); err != nil {
+			return err
+		}
+		_, err = w.conn.Write([]byte(message))
+	}
+	return err
+}
+
+// Write implements Writer.
+func (w *syslogLogWriter) Write(s string) error {
+	return w.send(Severity_Info, "-", s)
+}
+
+// WriteMessage implements MessageWriter.
+func (w *syslogLogWriter) WriteMessage(msg Message, line string) error {
+	msgID := "-"
+	switch msg.(type) {
+	case *AccessMessage:
+		msgID = "access"
+	case *DNSLog:
+		msgID = "dns"
+	}
+	return w.send(severityOf(msg), msgID, line)
+}
+
+// Close implements Writer.
+func (w *syslogLogWriter) Close() error {
+	return w.conn.Close()
+}
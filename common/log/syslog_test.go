@@ -0,0 +1,33 @@
+package log_test
+
+import (
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	. "github.com/luckyluke-a/xray-core/common/log"
+)
+
+func TestSyslogLogWriter(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	common.Must(err)
+	defer conn.Close()
+
+	creator, err := CreateSyslogLogWriter("syslog://" + conn.LocalAddr().String() + "?facility=local0")
+	common.Must(err)
+	writer := creator().(MessageWriter)
+	defer writer.Close()
+
+	msg := &GeneralMessage{Severity: Severity_Warning, Content: "test"}
+	common.Must(writer.WriteMessage(msg, msg.String()))
+
+	b := make([]byte, 1024)
+	n, err := conn.Read(b)
+	common.Must(err)
+	// local0 is 16, and warning is 4
+	pattern := regexp.MustCompile(`^<132>1 \S+ \S+ xray \d+ - - \[Warning\] test$`)
+	if !pattern.Match(b[:n]) {
+		t.Error("unexpected syslog message: ", string(b[:n]))
+	}
+}
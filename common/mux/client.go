@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/luckyluke-a/xray-core/common"
@@ -40,12 +41,27 @@ func (m *ClientManager) Dispatch(ctx context.Context, link *transport.Link) erro
 	return errors.New("unable to find an available mux client").AtWarning()
 }
 
+// Start implements common.Runnable.
+func (m *ClientManager) Start() error {
+	if r, ok := m.Picker.(common.Runnable); ok {
+		return r.Start()
+	}
+	return nil
+}
+
+// Close implements common.Closable.
+func (m *ClientManager) Close() error {
+	return common.Close(m.Picker)
+}
+
 type WorkerPicker interface {
 	PickAvailable() (*ClientWorker, error)
 }
 
 type IncrementalWorkerPicker struct {
 	Factory ClientWorkerFactory
+	// WarmConnections is the number of idle workers kept established ahead of demand.
+	WarmConnections uint32
 
 	access      sync.Mutex
 	workers     []*ClientWorker
@@ -56,6 +72,12 @@ func (p *IncrementalWorkerPicker) cleanupFunc() error {
 	p.access.Lock()
 	defer p.access.Unlock()
 
+	if p.WarmConnections > 0 {
+		p.cleanup()
+		p.warmUp()
+		return nil
+	}
+
 	if len(p.workers) == 0 {
 		return errors.New("no worker")
 	}
@@ -64,6 +86,30 @@ func (p *IncrementalWorkerPicker) cleanupFunc() error {
 	return nil
 }
 
+// warmUp keeps exactly WarmConnections idle workers around, dialing new ones
+// for rotated or closed workers and releasing the surplus.
+func (p *IncrementalWorkerPicker) warmUp() {
+	var idle uint32
+	for _, w := range p.workers {
+		if w.IsFull() || w.ActiveConnections() > 0 {
+			continue
+		}
+		if idle >= p.WarmConnections && w.CloseIfIdle() {
+			continue
+		}
+		idle++
+	}
+
+	for ; idle < p.WarmConnections; idle++ {
+		worker, err := p.Factory.Create()
+		if err != nil {
+			errors.LogInfoInner(context.Background(), err, "failed to warm up mux worker")
+			return
+		}
+		p.workers = append(p.workers, worker)
+	}
+}
+
 func (p *IncrementalWorkerPicker) cleanup() {
 	var activeWorkers []*ClientWorker
 	for _, w := range p.workers {
@@ -106,15 +152,53 @@ func (p *IncrementalWorkerPicker) pickInternal() (*ClientWorker, bool, error) {
 	p.workers = append(p.workers, worker)
 
 	if p.cleanupTask == nil {
-		p.cleanupTask = &task.Periodic{
-			Interval: time.Second * 30,
-			Execute:  p.cleanupFunc,
-		}
+		p.cleanupTask = p.newCleanupTask()
 	}
 
 	return worker, true, nil
 }
 
+func (p *IncrementalWorkerPicker) newCleanupTask() *task.Periodic {
+	interval := time.Second * 30
+	if p.WarmConnections > 0 {
+		interval = time.Second * 5
+	}
+	return &task.Periodic{
+		Interval: interval,
+		Execute:  p.cleanupFunc,
+	}
+}
+
+// Start implements common.Runnable. It establishes the warm workers, if any.
+func (p *IncrementalWorkerPicker) Start() error {
+	if p.WarmConnections == 0 {
+		return nil
+	}
+
+	p.access.Lock()
+	if p.cleanupTask == nil {
+		p.cleanupTask = p.newCleanupTask()
+	}
+	p.access.Unlock()
+
+	return p.cleanupTask.Start()
+}
+
+// Close implements common.Closable.
+func (p *IncrementalWorkerPicker) Close() error {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	if p.cleanupTask != nil {
+		common.Close(p.cleanupTask)
+	}
+	for _, w := range p.workers {
+		common.Close(w.done)
+	}
+	p.workers = nil
+	return nil
+}
+
 func (p *IncrementalWorkerPicker) PickAvailable() (*ClientWorker, error) {
 	worker, start, err := p.pickInternal()
 	if start {
@@ -167,6 +251,14 @@ func (f *DialingWorkerFactory) Create() (*ClientWorker, error) {
 type ClientStrategy struct {
 	MaxConcurrency uint32
 	MaxConnection  uint32
+	// MaxLifetime and MaxBytes rotate the worker: once reached, no new session is
+	// dispatched to it and it is closed as soon as the last session ends.
+	MaxLifetime time.Duration
+	MaxBytes    uint64
+	// KeepAlivePeriod is the interval of silence after which a keepalive frame is sent.
+	KeepAlivePeriod time.Duration
+	// KeepIdle leaves idle workers open, so that the picker can reuse them.
+	KeepIdle bool
 }
 
 type ClientWorker struct {
@@ -174,6 +266,8 @@ type ClientWorker struct {
 	link           transport.Link
 	done           *done.Instance
 	strategy       ClientStrategy
+	created        time.Time
+	traffic        atomic.Uint64
 }
 
 var (
@@ -185,9 +279,13 @@ var (
 func NewClientWorker(stream transport.Link, s ClientStrategy) (*ClientWorker, error) {
 	c := &ClientWorker{
 		sessionManager: NewSessionManager(),
-		link:           stream,
 		done:           done.New(),
 		strategy:       s,
+		created:        time.Now(),
+	}
+	c.link = transport.Link{
+		Reader: &trafficReader{Reader: stream.Reader, counter: &c.traffic},
+		Writer: &trafficWriter{Writer: stream.Writer, counter: &c.traffic},
 	}
 
 	go c.fetchOutput()
@@ -204,15 +302,37 @@ func (m *ClientWorker) ActiveConnections() uint32 {
 	return uint32(m.sessionManager.Size())
 }
 
+// TotalBytes returns the number of bytes sent and received by this worker.
+func (m *ClientWorker) TotalBytes() uint64 {
+	return m.traffic.Load()
+}
+
 // Closed returns true if this Client is closed.
 func (m *ClientWorker) Closed() bool {
 	return m.done.Done()
 }
 
+// CloseIfIdle closes the worker if it has no session, and returns whether it did.
+func (m *ClientWorker) CloseIfIdle() bool {
+	if m.sessionManager.Size() == 0 && m.sessionManager.CloseIfNoSession() {
+		common.Must(m.done.Close())
+		return true
+	}
+	return false
+}
+
 func (m *ClientWorker) monitor() {
 	timer := time.NewTicker(time.Second * 16)
 	defer timer.Stop()
 
+	var keepAlive <-chan time.Time
+	if m.strategy.KeepAlivePeriod > 0 {
+		keepAliveTimer := time.NewTicker(m.strategy.KeepAlivePeriod)
+		defer keepAliveTimer.Stop()
+		keepAlive = keepAliveTimer.C
+	}
+	lastTraffic := m.TotalBytes()
+
 	for {
 		select {
 		case <-m.done.Wait():
@@ -221,14 +341,32 @@ func (m *ClientWorker) monitor() {
 			common.Interrupt(m.link.Reader)
 			return
 		case <-timer.C:
-			size := m.sessionManager.Size()
-			if size == 0 && m.sessionManager.CloseIfNoSession() {
-				common.Must(m.done.Close())
+			if m.strategy.KeepIdle && !m.IsClosing() {
+				continue
 			}
+			m.CloseIfIdle()
+		case <-keepAlive:
+			traffic := m.TotalBytes()
+			if traffic == lastTraffic {
+				if err := m.writeKeepAlive(); err != nil {
+					errors.LogInfoInner(context.Background(), err, "failed to write keepalive frame")
+				}
+				traffic = m.TotalBytes()
+			}
+			lastTraffic = traffic
 		}
 	}
 }
 
+func (m *ClientWorker) writeKeepAlive() error {
+	meta := FrameMetadata{
+		SessionStatus: SessionStatusKeepAlive,
+	}
+	frame := buf.New()
+	common.Must(meta.WriteTo(frame))
+	return m.link.Writer.WriteMultiBuffer(buf.MultiBuffer{frame})
+}
+
 func writeFirstPayload(reader buf.Reader, writer *Writer) error {
 	err := buf.CopyOnceTimeout(reader, writer, time.Millisecond*100)
 	if err == buf.ErrNotTimeoutReader || err == buf.ErrReadTimeout {
@@ -273,6 +411,12 @@ func (m *ClientWorker) IsClosing() bool {
 	if m.strategy.MaxConnection > 0 && sm.Count() >= int(m.strategy.MaxConnection) {
 		return true
 	}
+	if m.strategy.MaxLifetime > 0 && time.Since(m.created) >= m.strategy.MaxLifetime {
+		return true
+	}
+	if m.strategy.MaxBytes > 0 && m.TotalBytes() >= m.strategy.MaxBytes {
+		return true
+	}
 	return false
 }
 
@@ -391,3 +535,32 @@ func (m *ClientWorker) fetchOutput() {
 		}
 	}
 }
+
+type trafficReader struct {
+	buf.Reader
+	counter *atomic.Uint64
+}
+
+func (r *trafficReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	r.counter.Add(uint64(mb.Len()))
+	return mb, err
+}
+
+func (r *trafficReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
+
+type trafficWriter struct {
+	buf.Writer
+	counter *atomic.Uint64
+}
+
+func (w *trafficWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.counter.Add(uint64(mb.Len()))
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+func (w *trafficWriter) Close() error {
+	return common.Close(w.Writer)
+}
@@ -33,6 +33,59 @@ func TestIncrementalPickerFailure(t *testing.T) {
 	}
 }
 
+func TestIncrementalPickerWarmUp(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	newWorker := func() *mux.ClientWorker {
+		r, w := pipe.New(pipe.WithoutSizeLimit())
+		worker, err := mux.NewClientWorker(transport.Link{Reader: r, Writer: w}, mux.ClientStrategy{
+			MaxConcurrency: 4,
+			KeepIdle:       true,
+		})
+		common.Must(err)
+		return worker
+	}
+	worker1 := newWorker()
+	worker2 := newWorker()
+
+	factory := mocks.NewMuxClientWorkerFactory(mockCtl)
+	gomock.InOrder(
+		factory.EXPECT().Create().Return(worker1, nil),
+		factory.EXPECT().Create().Return(worker2, nil),
+	)
+
+	picker := &mux.IncrementalWorkerPicker{
+		Factory:         factory,
+		WarmConnections: 2,
+	}
+	common.Must(picker.Start())
+	defer picker.Close()
+
+	worker, err := picker.PickAvailable()
+	common.Must(err)
+	if worker != worker1 && worker != worker2 {
+		t.Error("expected a warm worker")
+	}
+}
+
+func TestClientWorkerRotation(t *testing.T) {
+	r, w := pipe.New(pipe.WithoutSizeLimit())
+	worker, err := mux.NewClientWorker(transport.Link{Reader: r, Writer: w}, mux.ClientStrategy{
+		MaxLifetime: time.Millisecond * 100,
+	})
+	common.Must(err)
+	defer w.Close()
+
+	if worker.IsFull() {
+		t.Error("expected a fresh worker to accept sessions")
+	}
+	time.Sleep(time.Millisecond * 200)
+	if !worker.IsFull() {
+		t.Error("expected an expired worker to reject new sessions")
+	}
+}
+
 func TestClientWorkerEOF(t *testing.T) {
 	reader, writer := pipe.New(pipe.WithoutSizeLimit())
 	common.Must(writer.Close())
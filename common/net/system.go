@@ -21,13 +21,19 @@ var (
 
 var LookupIP = net.LookupIP
 
-var FileConn = net.FileConn
+var (
+	FileConn       = net.FileConn
+	FileListener   = net.FileListener
+	FilePacketConn = net.FilePacketConn
+)
 
 // ParseIP is an alias of net.ParseIP
 var ParseIP = net.ParseIP
 
 var SplitHostPort = net.SplitHostPort
 
+var JoinHostPort = net.JoinHostPort
+
 var CIDRMask = net.CIDRMask
 
 type (
@@ -76,6 +82,7 @@ type (
 )
 
 var (
+	ResolveTCPAddr  = net.ResolveTCPAddr
 	ResolveUnixAddr = net.ResolveUnixAddr
 	ResolveUDPAddr  = net.ResolveUDPAddr
 )
@@ -23,6 +23,15 @@ const (
 	BrowserDialerAddress = "xray.browser.dialer"
 	XUDPLog              = "xray.xudp.show"
 	XUDPBaseKey          = "xray.xudp.basekey"
+
+	RemoteConfigToken     = "xray.remote.token"
+	RemoteConfigUsername  = "xray.remote.username"
+	RemoteConfigPassword  = "xray.remote.password"
+	RemoteConfigCert      = "xray.remote.cert"
+	RemoteConfigKey       = "xray.remote.key"
+	RemoteConfigCA        = "xray.remote.ca"
+	RemoteConfigPublicKey = "xray.remote.pubkey"
+	RemoteConfigCache     = "xray.remote.cache"
 )
 
 type EnvFlag struct {
@@ -36,6 +36,15 @@ func InboundFromContext(ctx context.Context) *Inbound {
 	return nil
 }
 
+// InboundTagFromContext returns the tag of the inbound in this context, or an
+// empty string if not contained.
+func InboundTagFromContext(ctx context.Context) string {
+	if inbound := InboundFromContext(ctx); inbound != nil {
+		return inbound.Tag
+	}
+	return ""
+}
+
 func ContextWithOutbounds(ctx context.Context, outbounds []*Outbound) context.Context {
 	return context.WithValue(ctx, outboundSessionKey, outbounds)
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/luckyluke-a/xray-core/features/routing"
 	"github.com/luckyluke-a/xray-core/features/stats"
 	"github.com/luckyluke-a/xray-core/transport/internet"
+	"google.golang.org/protobuf/proto"
 )
 
 // Server is an instance of Xray. At any time, there must be at most one Server instance running.
@@ -94,6 +95,9 @@ type Instance struct {
 	featureResolutions []resolution
 	running            bool
 
+	configAccess sync.Mutex
+	config       *Config
+
 	ctx context.Context
 }
 
@@ -110,7 +114,10 @@ func AddInboundHandler(server *Instance, config *InboundHandlerConfig) error {
 	if err := inboundManager.AddHandler(server.ctx, handler); err != nil {
 		return err
 	}
-	return nil
+	return server.UpdateConfig(func(c *Config) error {
+		c.Inbound = append(c.Inbound, proto.Clone(config).(*InboundHandlerConfig))
+		return nil
+	})
 }
 
 func addInboundHandlers(server *Instance, configs []*InboundHandlerConfig) error {
@@ -136,7 +143,10 @@ func AddOutboundHandler(server *Instance, config *OutboundHandlerConfig) error {
 	if err := outboundManager.AddHandler(server.ctx, handler); err != nil {
 		return err
 	}
-	return nil
+	return server.UpdateConfig(func(c *Config) error {
+		c.Outbound = append(c.Outbound, proto.Clone(config).(*OutboundHandlerConfig))
+		return nil
+	})
 }
 
 func addOutboundHandlers(server *Instance, configs []*OutboundHandlerConfig) error {
@@ -182,6 +192,11 @@ func NewWithContext(ctx context.Context, config *Config) (*Instance, error) {
 }
 
 func initInstanceWithConfig(config *Config, server *Instance) (bool, error) {
+	// the handlers are recorded as they are added, and the config is copied
+	// so that changes to the running one don't reach the caller
+	server.config = proto.Clone(config).(*Config)
+	server.config.Inbound = nil
+	server.config.Outbound = nil
 	server.ctx = context.WithValue(server.ctx, "cone",
 		platform.NewEnvFlag(platform.UseCone).GetValue(func() string { return "" }) != "true")
 
@@ -253,6 +268,26 @@ func (s *Instance) Type() interface{} {
 	return ServerType()
 }
 
+// Config returns a copy of the config the instance is running with. It
+// includes the handlers added after start and the changes recorded by
+// UpdateConfig.
+func (s *Instance) Config() *Config {
+	s.configAccess.Lock()
+	defer s.configAccess.Unlock()
+
+	return proto.Clone(s.config).(*Config)
+}
+
+// UpdateConfig records a change made to the running instance, for example
+// through the API, in the config returned by Config. update must not keep
+// the config after it returns.
+func (s *Instance) UpdateConfig(update func(*Config) error) error {
+	s.configAccess.Lock()
+	defer s.configAccess.Unlock()
+
+	return update(s.config)
+}
+
 // Close shutdown the Xray instance.
 func (s *Instance) Close() error {
 	s.access.Lock()
@@ -14,7 +14,9 @@ import (
 	"github.com/luckyluke-a/xray-core/features/dns"
 	"github.com/luckyluke-a/xray-core/features/dns/localdns"
 	_ "github.com/luckyluke-a/xray-core/main/distro/all"
+	"github.com/luckyluke-a/xray-core/proxy/blackhole"
 	"github.com/luckyluke-a/xray-core/proxy/dokodemo"
+	"github.com/luckyluke-a/xray-core/proxy/freedom"
 	"github.com/luckyluke-a/xray-core/proxy/vmess"
 	"github.com/luckyluke-a/xray-core/proxy/vmess/outbound"
 	"github.com/luckyluke-a/xray-core/testing/servers/tcp"
@@ -90,3 +92,57 @@ func TestXrayClose(t *testing.T) {
 	common.Must(err)
 	server.Close()
 }
+
+func TestXrayConfig(t *testing.T) {
+	config := &Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+		Outbound: []*OutboundHandlerConfig{
+			{
+				Tag:           "direct",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	server, err := New(config)
+	common.Must(err)
+	defer server.Close()
+
+	if !proto.Equal(server.Config(), config) {
+		t.Error("expected the config the instance was created with, but got ", server.Config())
+	}
+
+	blocked := &OutboundHandlerConfig{
+		Tag:           "blocked",
+		ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+	}
+	common.Must(AddOutboundHandler(server, blocked))
+
+	actual := server.Config()
+	if len(actual.Outbound) != 2 || !proto.Equal(actual.Outbound[1], blocked) {
+		t.Error("expected the added outbound in the config, but got ", actual.Outbound)
+	}
+
+	common.Must(server.UpdateConfig(func(c *Config) error {
+		c.Outbound = c.Outbound[:1]
+		return nil
+	}))
+	if len(server.Config().Outbound) != 1 || len(actual.Outbound) != 2 {
+		t.Error("expected the config returned before to be left untouched")
+	}
+
+	// changes to the running config don't reach the one it was created with
+	expected := proto.Clone(config).(*Config)
+	common.Must(server.UpdateConfig(func(c *Config) error {
+		c.App[0] = serial.ToTypedMessage(&proxyman.OutboundConfig{})
+		c.Outbound[0].Tag = "changed"
+		return nil
+	}))
+	if !proto.Equal(config, expected) {
+		t.Error("expected the config the instance was created with to be left untouched, but got ", config)
+	}
+}
@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"time"
+
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/common/serial"
@@ -28,6 +30,13 @@ type HostsLookup interface {
 	LookupHosts(domain string) *net.Address
 }
 
+// ECHConfigLookup is implemented by DNS clients which can query the HTTPS record of a domain.
+type ECHConfigLookup interface {
+	// LookupECHConfig returns the ECHConfigList in the HTTPS record of the given domain, and the
+	// TTL of the record.
+	LookupECHConfig(domain string) ([]byte, time.Duration, error)
+}
+
 // ClientType returns the type of Client interface. Can be used for implementing common.HasType.
 //
 // xray:api:beta
@@ -7,6 +7,7 @@ import (
 	loggerservice "github.com/luckyluke-a/xray-core/app/log/command"
 	observatoryservice "github.com/luckyluke-a/xray-core/app/observatory/command"
 	handlerservice "github.com/luckyluke-a/xray-core/app/proxyman/command"
+	reverseservice "github.com/luckyluke-a/xray-core/app/reverse/command"
 	routerservice "github.com/luckyluke-a/xray-core/app/router/command"
 	statsservice "github.com/luckyluke-a/xray-core/app/stats/command"
 	"github.com/luckyluke-a/xray-core/common/errors"
@@ -17,6 +18,7 @@ type APIConfig struct {
 	Tag      string   `json:"tag"`
 	Listen   string   `json:"listen"`
 	Services []string `json:"services"`
+	Journal  string   `json:"journal"`
 }
 
 func (c *APIConfig) Build() (*commander.Config, error) {
@@ -39,6 +41,8 @@ func (c *APIConfig) Build() (*commander.Config, error) {
 			services = append(services, serial.ToTypedMessage(&observatoryservice.Config{}))
 		case "routingservice":
 			services = append(services, serial.ToTypedMessage(&routerservice.Config{}))
+		case "reverseservice":
+			services = append(services, serial.ToTypedMessage(&reverseservice.Config{}))
 		}
 	}
 
@@ -46,5 +50,6 @@ func (c *APIConfig) Build() (*commander.Config, error) {
 		Tag:     c.Tag,
 		Listen:  c.Listen,
 		Service: services,
+		Journal: c.Journal,
 	}, nil
 }
@@ -2,4 +2,5 @@ package conf
 
 func init() {
 	RegisterConfigureFilePostProcessingStage("FakeDNS", &FakeDNSPostProcessingStage{})
+	RegisterConfigureFilePostProcessingStage("Routing", &RoutingPostProcessingStage{})
 }
@@ -1,6 +1,14 @@
 package conf
 
-import "github.com/luckyluke-a/xray-core/common/errors"
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/serial"
+)
 
 type ConfigureFilePostProcessingStage interface {
 	Process(conf *Config) error
@@ -23,3 +31,210 @@ func PostProcessConfigureFile(conf *Config) error {
 	}
 	return nil
 }
+
+// LintIssue is a problem found in a config.
+type LintIssue struct {
+	// Path is the JSON path of the problem, like outbounds[1].settings.
+	Path    string
+	Message string
+	// Warning is a problem which doesn't stop the config from working.
+	Warning bool
+}
+
+// ConfigureFileLinter is a post-processing stage which also reports all the
+// problems it finds to `xray lint`.
+type ConfigureFileLinter interface {
+	ConfigureFilePostProcessingStage
+	Lint(conf *Config) []LintIssue
+}
+
+// LintConfigureFile runs all the registered linters on the config.
+func LintConfigureFile(conf *Config) []LintIssue {
+	names := make([]string, 0, len(configureFilePostProcessingStages))
+	for name := range configureFilePostProcessingStages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []LintIssue
+	for _, name := range names {
+		if linter, ok := configureFilePostProcessingStages[name].(ConfigureFileLinter); ok {
+			issues = append(issues, linter.Lint(conf)...)
+		}
+	}
+	return issues
+}
+
+// JSONPath returns the path of the key in the parent, which is a field name
+// or an index of an array, as in the paths of the LintIssues.
+func JSONPath(parent string, key interface{}) string {
+	switch key := key.(type) {
+	case int:
+		return parent + "[" + strconv.Itoa(key) + "]"
+	default:
+		if parent == "" {
+			return serial.ToString(key)
+		}
+		return parent + "." + serial.ToString(key)
+	}
+}
+
+// Validate validates a JSON value decoded with UseNumber against the schema.
+// Unlike JSON Schema, the fields are matched case-insensitively as
+// encoding/json does.
+func (s *Schema) Validate(value interface{}) []LintIssue {
+	v := &schemaValidator{root: s}
+	v.validate(s, value, "")
+	return v.issues
+}
+
+type schemaValidator struct {
+	root   *Schema
+	issues []LintIssue
+}
+
+func (v *schemaValidator) report(path string, warning bool, msg ...interface{}) {
+	v.issues = append(v.issues, LintIssue{Path: path, Message: serial.Concat(msg...), Warning: warning})
+}
+
+func (v *schemaValidator) resolve(s *Schema) *Schema {
+	for s.Ref != "" {
+		def := v.root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if def == nil {
+			return &Schema{}
+		}
+		s = def
+	}
+	return s
+}
+
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		if _, err := strconv.ParseUint(value.String(), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// constEqual compares strings case-insensitively, as protocols and other
+// names are.
+func constEqual(c interface{}, value interface{}) bool {
+	if c, ok := c.(string); ok {
+		value, ok := value.(string)
+		return ok && strings.EqualFold(c, value)
+	}
+	return c == value
+}
+
+func lookupField(obj map[string]interface{}, name string) interface{} {
+	if value, found := obj[name]; found {
+		return value
+	}
+	for key, value := range obj {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) matches(s *Schema, value interface{}) bool {
+	nested := &schemaValidator{root: v.root}
+	nested.validate(s, value, "")
+	return len(nested.issues) == 0
+}
+
+func (v *schemaValidator) validate(s *Schema, value interface{}, path string) {
+	s = v.resolve(s)
+	if value == nil {
+		// null is the zero value of any type
+		return
+	}
+	if s.Type != "" {
+		t := jsonType(value)
+		if t != s.Type && !(s.Type == "number" && t == "integer") {
+			v.report(path, false, "expect ", s.Type, ", but got ", t)
+			return
+		}
+	}
+	if s.Const != nil && !constEqual(s.Const, value) {
+		v.report(path, false, "expect ", s.Const)
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if lookupField(value, name) == nil {
+				v.report(JSONPath(path, name), false, "missing field \"", name, "\"")
+			}
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			v.validateProperty(s, key, value[key], JSONPath(path, key))
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				v.validate(s.Items, item, JSONPath(path, i))
+			}
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		if sub.If != nil {
+			if v.matches(sub.If, value) {
+				v.validate(sub.Then, value, path)
+			}
+			continue
+		}
+		v.validate(sub, value, path)
+	}
+}
+
+func (v *schemaValidator) validateProperty(s *Schema, key string, value interface{}, path string) {
+	property := s.Properties[key]
+	if property == nil {
+		for name, p := range s.Properties {
+			if strings.EqualFold(name, key) {
+				property = p
+				break
+			}
+		}
+	}
+	if property == nil {
+		switch additional := s.AdditionalProperties.(type) {
+		case *Schema:
+			v.validate(additional, value, path)
+		case bool:
+			if !additional {
+				v.report(path, false, "unknown field \"", key, "\"")
+			}
+		}
+		return
+	}
+	if property.Deprecated {
+		v.report(path, true, "deprecated field \"", key, "\"")
+	}
+	v.validate(property, value, path)
+}
@@ -0,0 +1,225 @@
+package conf
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/serial"
+)
+
+// RoutingPostProcessingStage checks the tags referred by routing rules,
+// balancers and outbounds. The problems are only logged when the config is
+// loaded, as the core has always accepted them.
+type RoutingPostProcessingStage struct{}
+
+func (s RoutingPostProcessingStage) Process(config *Config) error {
+	for _, issue := range s.Lint(config) {
+		errors.LogWarning(context.Background(), issue.Path, ": ", issue.Message)
+	}
+	return nil
+}
+
+type routingRule struct {
+	path       string
+	outbound   string
+	balancer   string
+	ruleTag    string
+	inbounds   StringList
+	conditions map[string]json.RawMessage
+}
+
+// ruleActionFields are the fields of a routing rule which aren't conditions.
+var ruleActionFields = map[string]bool{
+	"type":          true,
+	"outboundtag":   true,
+	"balancertag":   true,
+	"ruletag":       true,
+	"domainmatcher": true,
+}
+
+func parseRoutingRule(path string, raw json.RawMessage) (*routingRule, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	rule := &routingRule{
+		path:       path,
+		conditions: make(map[string]json.RawMessage),
+	}
+	for key, value := range fields {
+		var err error
+		switch strings.ToLower(key) {
+		case "outboundtag":
+			err = json.Unmarshal(value, &rule.outbound)
+		case "balancertag":
+			err = json.Unmarshal(value, &rule.balancer)
+		case "ruletag":
+			err = json.Unmarshal(value, &rule.ruleTag)
+		case "inboundtag":
+			err = json.Unmarshal(value, &rule.inbounds)
+		}
+		if err != nil {
+			return nil, errors.New("invalid field ", key).Base(err)
+		}
+		if !ruleActionFields[strings.ToLower(key)] {
+			rule.conditions[strings.ToLower(key)] = value
+		}
+	}
+	return rule, nil
+}
+
+func (s RoutingPostProcessingStage) Lint(config *Config) []LintIssue {
+	var issues []LintIssue
+	report := func(path string, warning bool, msg ...interface{}) {
+		issues = append(issues, LintIssue{Path: path, Message: serial.Concat(msg...), Warning: warning})
+	}
+
+	inbounds := make(map[string]bool)
+	for i, inbound := range config.InboundConfigs {
+		if inbound.Tag == "" {
+			continue
+		}
+		if inbounds[inbound.Tag] {
+			report(JSONPath(JSONPath("inbounds", i), "tag"), false, "duplicate inbound tag \"", inbound.Tag, "\"")
+		}
+		inbounds[inbound.Tag] = true
+	}
+	outbounds := make(map[string]bool)
+	for i, outbound := range config.OutboundConfigs {
+		if outbound.Tag == "" {
+			continue
+		}
+		if outbounds[outbound.Tag] {
+			report(JSONPath(JSONPath("outbounds", i), "tag"), false, "duplicate outbound tag \"", outbound.Tag, "\"")
+		}
+		outbounds[outbound.Tag] = true
+	}
+
+	// the tags of the inbounds and outbounds created by other features
+	if config.API != nil && config.API.Tag != "" {
+		inbounds[config.API.Tag] = true
+		outbounds[config.API.Tag] = true
+	}
+	if config.DNSConfig != nil && config.DNSConfig.Tag != "" {
+		inbounds[config.DNSConfig.Tag] = true
+	}
+	if config.Reverse != nil {
+		for _, bridge := range config.Reverse.Bridges {
+			inbounds[bridge.Tag] = true
+		}
+		for _, portal := range config.Reverse.Portals {
+			outbounds[portal.Tag] = true
+		}
+	}
+
+	for i, outbound := range config.OutboundConfigs {
+		if outbound.ProxySettings != nil && outbound.ProxySettings.Tag != "" && !outbounds[outbound.ProxySettings.Tag] {
+			report(JSONPath(JSONPath(JSONPath("outbounds", i), "proxySettings"), "tag"), false, "outbound \"", outbound.ProxySettings.Tag, "\" not found")
+		}
+		if outbound.StreamSetting != nil && outbound.StreamSetting.SocketSettings != nil {
+			if tag := outbound.StreamSetting.SocketSettings.DialerProxy; tag != "" && !outbounds[tag] {
+				report(JSONPath(JSONPath(JSONPath(JSONPath("outbounds", i), "streamSettings"), "sockopt"), "dialerProxy"), false, "outbound \"", tag, "\" not found")
+			}
+		}
+	}
+
+	routing := config.RouterConfig
+	if routing == nil {
+		return issues
+	}
+
+	balancers := make(map[string]bool)
+	for i, balancer := range routing.Balancers {
+		if balancer == nil || balancer.Tag == "" {
+			continue
+		}
+		path := JSONPath(JSONPath("routing", "balancers"), i)
+		if balancers[balancer.Tag] {
+			report(JSONPath(path, "tag"), false, "duplicate balancer tag \"", balancer.Tag, "\"")
+		}
+		balancers[balancer.Tag] = true
+		for j, selector := range balancer.Selectors {
+			if !matchesOutboundPrefix(outbounds, selector) {
+				report(JSONPath(JSONPath(path, "selector"), j), true, "no outbound matches selector \"", selector, "\"")
+			}
+		}
+		if balancer.FallbackTag != "" && !outbounds[balancer.FallbackTag] {
+			report(JSONPath(path, "fallbackTag"), false, "outbound \"", balancer.FallbackTag, "\" not found")
+		}
+	}
+
+	var rules []*routingRule
+	for i, raw := range routing.RuleList {
+		path := JSONPath(JSONPath("routing", "rules"), i)
+		rule, err := parseRoutingRule(path, raw)
+		if err != nil {
+			report(path, false, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if routing.Settings != nil {
+		for i, raw := range routing.Settings.RuleList {
+			path := JSONPath(JSONPath(JSONPath("routing", "settings"), "rules"), i)
+			rule, err := parseRoutingRule(path, raw)
+			if err != nil {
+				report(path, false, err)
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	ruleTags := make(map[string]bool)
+	seen := make(map[string]string)
+	catchAll := ""
+	for _, rule := range rules {
+		if rule.ruleTag != "" {
+			if ruleTags[rule.ruleTag] {
+				report(JSONPath(rule.path, "ruleTag"), false, "duplicate rule tag \"", rule.ruleTag, "\"")
+			}
+			ruleTags[rule.ruleTag] = true
+		}
+		if rule.outbound != "" && !outbounds[rule.outbound] {
+			report(JSONPath(rule.path, "outboundTag"), false, "outbound \"", rule.outbound, "\" not found")
+		}
+		if rule.balancer != "" && !balancers[rule.balancer] {
+			report(JSONPath(rule.path, "balancerTag"), false, "balancer \"", rule.balancer, "\" not found")
+		}
+		for j, tag := range rule.inbounds {
+			if !inbounds[tag] {
+				report(JSONPath(JSONPath(rule.path, "inboundTag"), j), true, "inbound \"", tag, "\" not found")
+			}
+		}
+
+		// rules are matched in order, so a rule is never used after a
+		// rule matching everything, or a rule with the same conditions
+		if catchAll != "" {
+			report(rule.path, true, "unreachable rule, as ", catchAll, " matches all traffic")
+			continue
+		}
+		if len(rule.conditions) == 0 {
+			catchAll = rule.path
+			continue
+		}
+		key, _ := json.Marshal(rule.conditions)
+		if previous, found := seen[string(key)]; found {
+			report(rule.path, true, "unreachable rule, as ", previous, " has the same conditions")
+			continue
+		}
+		seen[string(key)] = rule.path
+	}
+
+	return issues
+}
+
+func matchesOutboundPrefix(outbounds map[string]bool, prefix string) bool {
+	for tag := range outbounds {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
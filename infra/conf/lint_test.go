@@ -0,0 +1,94 @@
+package conf_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/luckyluke-a/xray-core/infra/conf"
+)
+
+func decodeLintValue(t *testing.T, s string) interface{} {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := GenerateSchema()
+	cases := []struct {
+		input  string
+		issues []LintIssue
+	}{
+		{
+			input: `{
+				"log": {"logLevel": "debug"},
+				"inbounds": [{"port": "1080-1090", "protocol": "SOCKS", "settings": {"udp": true}}],
+				"outbounds": [{"protocol": "freedom", "settings": {"domainStrategy": "UseIP"}}]
+			}`,
+		},
+		{
+			input: `{
+				"log": {"level": "debug"},
+				"inbound": {"protocol": "socks"},
+				"inbounds": [{"port": 1080, "protocol": "socks", "settings": {"udp": "yes"}}],
+				"outbounds": [{"protocol": "freedom", "settings": {"timeout": 1.5}}]
+			}`,
+			issues: []LintIssue{
+				{Path: "inbound", Message: `deprecated field "inbound"`, Warning: true},
+				{Path: "inbounds[0].settings.udp", Message: "expect boolean, but got string"},
+				{Path: "log.level", Message: `unknown field "level"`},
+				{Path: "outbounds[0].settings.timeout", Message: "expect integer, but got number"},
+			},
+		},
+	}
+	for _, c := range cases {
+		issues := schema.Validate(decodeLintValue(t, c.input))
+		if r := cmp.Diff(issues, c.issues); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+func TestRoutingLint(t *testing.T) {
+	config := new(Config)
+	if err := json.Unmarshal([]byte(`{
+		"api": {"tag": "api"},
+		"inbounds": [{"tag": "in"}, {"tag": "in"}],
+		"outbounds": [{"tag": "proxy-a"}, {"tag": "direct"}, {"tag": "chained", "proxySettings": {"tag": "missing"}}],
+		"routing": {
+			"rules": [
+				{"inboundTag": ["api"], "outboundTag": "api"},
+				{"inboundTag": ["in", "other"], "outboundTag": "nowhere", "ruleTag": "r"},
+				{"domain": ["example.com"], "balancerTag": "b", "ruleTag": "r"},
+				{"domain": ["example.com"], "outboundTag": "direct"},
+				{"outboundTag": "direct"},
+				{"ip": ["10.0.0.0/8"], "outboundTag": "direct"}
+			],
+			"balancers": [{"tag": "b", "selector": ["proxy-", "backup-"], "fallbackTag": "none"}]
+		}
+	}`), config); err != nil {
+		t.Fatal(err)
+	}
+
+	issues := (&RoutingPostProcessingStage{}).Lint(config)
+	expected := []LintIssue{
+		{Path: "inbounds[1].tag", Message: `duplicate inbound tag "in"`},
+		{Path: "outbounds[2].proxySettings.tag", Message: `outbound "missing" not found`},
+		{Path: "routing.balancers[0].selector[1]", Message: `no outbound matches selector "backup-"`, Warning: true},
+		{Path: "routing.balancers[0].fallbackTag", Message: `outbound "none" not found`},
+		{Path: "routing.rules[1].outboundTag", Message: `outbound "nowhere" not found`},
+		{Path: "routing.rules[1].inboundTag[1]", Message: `inbound "other" not found`, Warning: true},
+		{Path: "routing.rules[2].ruleTag", Message: `duplicate rule tag "r"`},
+		{Path: "routing.rules[3]", Message: "unreachable rule, as routing.rules[2] has the same conditions", Warning: true},
+		{Path: "routing.rules[5]", Message: "unreachable rule, as routing.rules[4] matches all traffic", Warning: true},
+	}
+	if r := cmp.Diff(issues, expected); r != "" {
+		t.Error(r)
+	}
+}
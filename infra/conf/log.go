@@ -4,7 +4,9 @@ import (
 	"strings"
 
 	"github.com/luckyluke-a/xray-core/app/log"
+	"github.com/luckyluke-a/xray-core/common/errors"
 	clog "github.com/luckyluke-a/xray-core/common/log"
+	"github.com/luckyluke-a/xray-core/infra/conf/cfgcommon/duration"
 )
 
 func DefaultLogConfig() *log.Config {
@@ -20,29 +22,142 @@ type LogConfig struct {
 	ErrorLog  string `json:"error"`
 	LogLevel  string `json:"loglevel"`
 	DNSLog    bool   `json:"dnsLog"`
+	Format    string `json:"format"`
+
+	Rotation *LogRotationConfig `json:"rotation"`
+
+	AccessFilter  *AccessLogFilterConfig   `json:"accessFilter"`
+	AccessStreams []*AccessLogStreamConfig `json:"accessStreams"`
 }
 
-func (v *LogConfig) Build() *log.Config {
-	if v == nil {
+type AccessLogFilterConfig struct {
+	InboundTags  []string `json:"inboundTags"`
+	Emails       []string `json:"emails"`
+	Statuses     []string `json:"statuses"`
+	OutboundTags []string `json:"outboundTags"`
+	Domains      []string `json:"domains"`
+	SampleRate   float64  `json:"sampleRate"`
+}
+
+func (c *AccessLogFilterConfig) Build() (*log.AccessLogFilter, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return nil, errors.New("sampleRate must be between 0 and 1, but got ", c.SampleRate)
+	}
+	return &log.AccessLogFilter{
+		InboundTags:  c.InboundTags,
+		Emails:       c.Emails,
+		Statuses:     c.Statuses,
+		OutboundTags: c.OutboundTags,
+		Domains:      c.Domains,
+		SampleRate:   c.SampleRate,
+	}, nil
+}
+
+// AccessLogStreamConfig writes the filtered access logs to the target, which
+// is like the access log.
+type AccessLogStreamConfig struct {
+	Target string                 `json:"target"`
+	Filter *AccessLogFilterConfig `json:"filter"`
+}
+
+func (c *AccessLogStreamConfig) Build() (*log.AccessLogStream, error) {
+	if c.Target == "" {
+		return nil, errors.New("empty target of access log stream")
+	}
+	filter, err := c.Filter.Build()
+	if err != nil {
+		return nil, err
+	}
+	stream := &log.AccessLogStream{Filter: filter}
+	stream.Type, stream.Path = parseLogTarget(c.Target)
+	return stream, nil
+}
+
+// LogRotationConfig rotates the access and error log files.
+type LogRotationConfig struct {
+	// MaxSize is in megabytes.
+	MaxSize    int64             `json:"maxSize"`
+	Interval   duration.Duration `json:"interval"`
+	Compress   bool              `json:"compress"`
+	MaxBackups uint32            `json:"maxBackups"`
+	MaxAge     duration.Duration `json:"maxAge"`
+}
+
+func (c *LogRotationConfig) Build() *log.LogRotation {
+	if c == nil {
 		return nil
 	}
+	return &log.LogRotation{
+		MaxSize:    c.MaxSize * 1024 * 1024,
+		Interval:   int64(c.Interval),
+		Compress:   c.Compress,
+		MaxBackups: c.MaxBackups,
+		MaxAge:     int64(c.MaxAge),
+	}
+}
+
+// parseLogTarget parses the access or error log, which is "none", "syslog:"
+// for the local syslog, "journald:", an address like syslog+tcp://host:514 or
+// tcp://host:port, or a file path. Sinks always have a scheme, so that a file
+// can be named like "syslog".
+func parseLogTarget(target string) (log.LogType, string) {
+	switch {
+	case target == "none":
+		return log.LogType_None, ""
+	case target == "syslog:":
+		return log.LogType_Syslog, ""
+	case target == "journald:":
+		return log.LogType_Journald, ""
+	case strings.HasPrefix(target, "syslog://"), strings.HasPrefix(target, "syslog+"):
+		return log.LogType_Syslog, target
+	case strings.HasPrefix(target, "tcp://"):
+		return log.LogType_Remote, target
+	default:
+		return log.LogType_File, target
+	}
+}
+
+func (v *LogConfig) Build() (*log.Config, error) {
+	if v == nil {
+		return nil, nil
+	}
 	config := &log.Config{
 		ErrorLogType:  log.LogType_Console,
 		AccessLogType: log.LogType_Console,
 		EnableDnsLog:  v.DNSLog,
+		Rotation:      v.Rotation.Build(),
 	}
 
-	if v.AccessLog == "none" {
-		config.AccessLogType = log.LogType_None
-	} else if len(v.AccessLog) > 0 {
-		config.AccessLogPath = v.AccessLog
-		config.AccessLogType = log.LogType_File
+	if len(v.AccessLog) > 0 {
+		config.AccessLogType, config.AccessLogPath = parseLogTarget(v.AccessLog)
 	}
-	if v.ErrorLog == "none" {
-		config.ErrorLogType = log.LogType_None
-	} else if len(v.ErrorLog) > 0 {
-		config.ErrorLogPath = v.ErrorLog
-		config.ErrorLogType = log.LogType_File
+	if len(v.ErrorLog) > 0 {
+		config.ErrorLogType, config.ErrorLogPath = parseLogTarget(v.ErrorLog)
+	}
+
+	filter, err := v.AccessFilter.Build()
+	if err != nil {
+		return nil, errors.New("invalid accessFilter").Base(err)
+	}
+	config.AccessLogFilter = filter
+	for _, streamConfig := range v.AccessStreams {
+		stream, err := streamConfig.Build()
+		if err != nil {
+			return nil, errors.New("invalid accessStreams").Base(err)
+		}
+		config.AccessLogStreams = append(config.AccessLogStreams, stream)
+	}
+
+	switch strings.ToLower(v.Format) {
+	case "json":
+		config.Format = log.LogFormat_JSON
+	case "logfmt":
+		config.Format = log.LogFormat_Logfmt
+	default:
+		config.Format = log.LogFormat_Text
 	}
 
 	level := strings.ToLower(v.LogLevel)
@@ -56,8 +171,9 @@ func (v *LogConfig) Build() *log.Config {
 	case "none":
 		config.ErrorLogType = log.LogType_None
 		config.AccessLogType = log.LogType_None
+		config.AccessLogStreams = nil
 	default:
 		config.ErrorLogLevel = clog.Severity_Warning
 	}
-	return config
+	return config, nil
 }
@@ -0,0 +1,45 @@
+package conf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/app/log"
+	clog "github.com/luckyluke-a/xray-core/common/log"
+	. "github.com/luckyluke-a/xray-core/infra/conf"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLogTargetJSON(t *testing.T) {
+	parser := func(s string) (proto.Message, error) {
+		config := new(LogConfig)
+		if err := json.Unmarshal([]byte(s), config); err != nil {
+			return nil, err
+		}
+		return config.Build()
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input:  `{"access": "syslog:", "error": "journald:"}`,
+			Parser: parser,
+			Output: &log.Config{
+				AccessLogType: log.LogType_Syslog,
+				ErrorLogType:  log.LogType_Journald,
+				ErrorLogLevel: clog.Severity_Warning,
+			},
+		},
+		{
+			// sinks need the scheme, so these are files
+			Input:  `{"access": "syslog", "error": "journald"}`,
+			Parser: parser,
+			Output: &log.Config{
+				AccessLogType: log.LogType_File,
+				AccessLogPath: "syslog",
+				ErrorLogType:  log.LogType_File,
+				ErrorLogPath:  "journald",
+				ErrorLogLevel: clog.Severity_Warning,
+			},
+		},
+	})
+}
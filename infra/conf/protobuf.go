@@ -0,0 +1,280 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/app/proxyman"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+	core "github.com/luckyluke-a/xray-core/core"
+)
+
+// pbObject is a JSON object rebuilt from a protobuf message. set skips zero
+// values so that the result only carries what differs from the defaults.
+type pbObject map[string]interface{}
+
+func (o pbObject) set(key string, value interface{}) {
+	if value == nil {
+		return
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		if v.Len() == 0 {
+			return
+		}
+	default:
+		if v.IsZero() {
+			return
+		}
+	}
+	o[key] = value
+}
+
+// ConfigFromProtobuf converts a built config back into the JSON layout read
+// by Config. Building the result yields a config equivalent to the input,
+// so it can be used to inspect or edit a config of a running instance.
+//
+// A few things can not be expressed in JSON and are resolved on the way:
+// routing domains from geosite lists are written out one by one, routing
+// and DNS IPs from external geoip files are written out as CIDRs, and the
+// expiry and comment of outbound handlers are dropped.
+func ConfigFromProtobuf(config *core.Config) (map[string]interface{}, error) {
+	if config.Transport != nil {
+		return nil, errors.New("global transport settings are not supported")
+	}
+	if len(config.Extension) > 0 {
+		return nil, errors.New("config extensions are not supported")
+	}
+
+	c := pbObject{}
+	for _, app := range config.App {
+		instance, err := app.GetInstance()
+		if err != nil {
+			return nil, errors.New("failed to decode app config").Base(err)
+		}
+		if err := appFromProtobuf(c, instance); err != nil {
+			return nil, err
+		}
+	}
+
+	inbounds := make([]interface{}, 0, len(config.Inbound))
+	for _, inbound := range config.Inbound {
+		ib, err := inboundFromProtobuf(inbound)
+		if err != nil {
+			return nil, errors.New("failed to convert inbound ", inbound.Tag).Base(err)
+		}
+		inbounds = append(inbounds, ib)
+	}
+	c.set("inbounds", inbounds)
+
+	outbounds := make([]interface{}, 0, len(config.Outbound))
+	for _, outbound := range config.Outbound {
+		ob, err := outboundFromProtobuf(outbound)
+		if err != nil {
+			return nil, errors.New("failed to convert outbound ", outbound.Tag).Base(err)
+		}
+		outbounds = append(outbounds, ob)
+	}
+	c.set("outbounds", outbounds)
+
+	return c, nil
+}
+
+func inboundFromProtobuf(config *core.InboundHandlerConfig) (pbObject, error) {
+	if config.ReceiverSettings == nil || config.ProxySettings == nil {
+		return nil, errors.New("incomplete inbound handler config")
+	}
+	instance, err := config.ReceiverSettings.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	receiver, ok := instance.(*proxyman.ReceiverConfig)
+	if !ok {
+		return nil, errors.New("unknown receiver settings: ", config.ReceiverSettings.Type)
+	}
+
+	inbound := pbObject{}
+	inbound.set("tag", config.Tag)
+	inbound.set("listen", pbAddress(receiver.Listen))
+	inbound.set("port", pbPortList(receiver.PortList))
+
+	if as := receiver.AllocationStrategy; as != nil {
+		allocate := pbObject{
+			"strategy": strings.ToLower(as.Type.String()),
+		}
+		if as.Concurrency != nil {
+			allocate["concurrency"] = as.Concurrency.Value
+		}
+		if as.Refresh != nil {
+			allocate["refresh"] = as.Refresh.Value
+		}
+		inbound["allocate"] = allocate
+	}
+
+	if receiver.StreamSettings != nil {
+		stream, err := streamFromProtobuf(receiver.StreamSettings)
+		if err != nil {
+			return nil, err
+		}
+		inbound["streamSettings"] = stream
+	}
+
+	if len(receiver.DomainOverride) > 0 {
+		protocols := make([]string, 0, len(receiver.DomainOverride))
+		for _, p := range receiver.DomainOverride {
+			protocols = append(protocols, strings.ToLower(p.String()))
+		}
+		inbound["domainOverride"] = protocols
+	}
+
+	if s := receiver.SniffingSettings; s != nil {
+		sniffing := pbObject{
+			"enabled": s.Enabled,
+		}
+		sniffing.set("destOverride", s.DestinationOverride)
+		sniffing.set("domainsExcluded", s.DomainsExcluded)
+		sniffing.set("metadataOnly", s.MetadataOnly)
+		sniffing.set("routeOnly", s.RouteOnly)
+		inbound["sniffing"] = sniffing
+	}
+
+	proxy, err := config.ProxySettings.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	protocol, settings, err := inboundProxyFromProtobuf(proxy)
+	if err != nil {
+		return nil, err
+	}
+	inbound["protocol"] = protocol
+	inbound.set("settings", settings)
+
+	return inbound, nil
+}
+
+func outboundFromProtobuf(config *core.OutboundHandlerConfig) (pbObject, error) {
+	if config.ProxySettings == nil {
+		return nil, errors.New("incomplete outbound handler config")
+	}
+
+	outbound := pbObject{}
+	outbound.set("tag", config.Tag)
+
+	if config.SenderSettings != nil {
+		instance, err := config.SenderSettings.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		sender, ok := instance.(*proxyman.SenderConfig)
+		if !ok {
+			return nil, errors.New("unknown sender settings: ", config.SenderSettings.Type)
+		}
+
+		if via := pbAddress(sender.Via); via != "" {
+			if sender.ViaCidr != "" {
+				via += "/" + sender.ViaCidr
+			}
+			outbound["sendThrough"] = via
+		}
+
+		if sender.StreamSettings != nil {
+			stream, err := streamFromProtobuf(sender.StreamSettings)
+			if err != nil {
+				return nil, err
+			}
+			outbound["streamSettings"] = stream
+		}
+
+		if ps := sender.ProxySettings; ps != nil {
+			proxySettings := pbObject{
+				"tag": ps.Tag,
+			}
+			proxySettings.set("transportLayer", ps.TransportLayerProxy)
+			outbound["proxySettings"] = proxySettings
+		}
+
+		if m := sender.MultiplexSettings; m != nil {
+			mux := pbObject{
+				"enabled": m.Enabled,
+			}
+			mux.set("concurrency", m.Concurrency)
+			mux.set("xudpConcurrency", m.XudpConcurrency)
+			mux.set("xudpProxyUDP443", m.XudpProxyUDP443)
+			mux.set("warmConnections", m.WarmConnections)
+			mux.set("maxLifetime", m.MaxLifetime)
+			mux.set("maxBytes", m.MaxBytes)
+			mux.set("keepAlivePeriod", m.KeepAlivePeriod)
+			outbound["mux"] = mux
+		}
+	}
+
+	proxy, err := config.ProxySettings.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	protocol, settings, err := outboundProxyFromProtobuf(proxy)
+	if err != nil {
+		return nil, err
+	}
+	outbound["protocol"] = protocol
+	outbound.set("settings", settings)
+
+	return outbound, nil
+}
+
+func pbAddress(address *net.IPOrDomain) string {
+	if address.GetAddress() == nil {
+		return ""
+	}
+	addr := address.AsAddress()
+	if addr.Family().IsIP() {
+		return addr.IP().String()
+	}
+	return addr.Domain()
+}
+
+func pbPortList(list *net.PortList) interface{} {
+	if list == nil || len(list.Range) == 0 {
+		return nil
+	}
+	if len(list.Range) == 1 && list.Range[0].From == list.Range[0].To {
+		return list.Range[0].From
+	}
+	ranges := make([]string, 0, len(list.Range))
+	for _, r := range list.Range {
+		ranges = append(ranges, pbRange(r.From, r.To))
+	}
+	return strings.Join(ranges, ",")
+}
+
+func pbRange[T uint32 | int32](from, to T) string {
+	if from == to {
+		return fmt.Sprint(from)
+	}
+	return fmt.Sprint(from, "-", to)
+}
+
+func pbNetworks(networks []net.Network) []string {
+	list := make([]string, 0, len(networks))
+	for _, network := range networks {
+		list = append(list, network.SystemString())
+	}
+	return list
+}
+
+func pbDuration(d int64) interface{} {
+	if d == 0 {
+		return nil
+	}
+	return time.Duration(d).String()
+}
+
+func pbFloat(f float32) interface{} {
+	return json.Number(strconv.FormatFloat(float64(f), 'g', -1, 32))
+}
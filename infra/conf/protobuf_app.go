@@ -0,0 +1,582 @@
+package conf
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/app/commander"
+	"github.com/luckyluke-a/xray-core/app/dispatcher"
+	"github.com/luckyluke-a/xray-core/app/dns"
+	"github.com/luckyluke-a/xray-core/app/dns/fakedns"
+	"github.com/luckyluke-a/xray-core/app/log"
+	loggerservice "github.com/luckyluke-a/xray-core/app/log/command"
+	"github.com/luckyluke-a/xray-core/app/metrics"
+	"github.com/luckyluke-a/xray-core/app/observatory"
+	"github.com/luckyluke-a/xray-core/app/observatory/burst"
+	observatoryservice "github.com/luckyluke-a/xray-core/app/observatory/command"
+	"github.com/luckyluke-a/xray-core/app/policy"
+	"github.com/luckyluke-a/xray-core/app/proxyman"
+	handlerservice "github.com/luckyluke-a/xray-core/app/proxyman/command"
+	"github.com/luckyluke-a/xray-core/app/reverse"
+	reverseservice "github.com/luckyluke-a/xray-core/app/reverse/command"
+	"github.com/luckyluke-a/xray-core/app/router"
+	routerservice "github.com/luckyluke-a/xray-core/app/router/command"
+	"github.com/luckyluke-a/xray-core/app/stats"
+	statsservice "github.com/luckyluke-a/xray-core/app/stats/command"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	clog "github.com/luckyluke-a/xray-core/common/log"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/common/serial"
+	"google.golang.org/protobuf/proto"
+)
+
+var logLevelNames = map[clog.Severity]string{
+	clog.Severity_Debug: "debug",
+	clog.Severity_Info:  "info",
+	clog.Severity_Error: "error",
+}
+
+var routerDomainStrategyNames = map[router.Config_DomainStrategy]string{
+	router.Config_UseIp:        "AlwaysIP",
+	router.Config_IpIfNonMatch: "IPIfNonMatch",
+	router.Config_IpOnDemand:   "IPOnDemand",
+}
+
+var routerDomainPrefixes = map[router.Domain_Type]string{
+	router.Domain_Plain:  "keyword:",
+	router.Domain_Regex:  "regexp:",
+	router.Domain_Domain: "domain:",
+	router.Domain_Full:   "full:",
+}
+
+var hostPrefixes = map[dns.DomainMatchingType]string{
+	dns.DomainMatchingType_Subdomain: "domain:",
+	dns.DomainMatchingType_Keyword:   "keyword:",
+	dns.DomainMatchingType_Regex:     "regexp:",
+}
+
+var queryStrategyNames = map[dns.QueryStrategy]string{
+	dns.QueryStrategy_USE_IP4: "UseIPv4",
+	dns.QueryStrategy_USE_IP6: "UseIPv6",
+}
+
+func apiServiceName(tm *serial.TypedMessage) (string, error) {
+	instance, err := tm.GetInstance()
+	if err != nil {
+		return "", err
+	}
+	switch instance.(type) {
+	case *commander.ReflectionConfig:
+		return "ReflectionService", nil
+	case *handlerservice.Config:
+		return "HandlerService", nil
+	case *loggerservice.Config:
+		return "LoggerService", nil
+	case *statsservice.Config:
+		return "StatsService", nil
+	case *observatoryservice.Config:
+		return "ObservatoryService", nil
+	case *routerservice.Config:
+		return "RoutingService", nil
+	case *reverseservice.Config:
+		return "ReverseService", nil
+	default:
+		return "", errors.New("unknown API service: ", tm.Type)
+	}
+}
+
+func appFromProtobuf(c pbObject, app proto.Message) error {
+	switch config := app.(type) {
+	case *dispatcher.Config, *proxyman.InboundConfig, *proxyman.OutboundConfig:
+		// Always added by the builder.
+	case *log.Config:
+		c["log"] = logFromProtobuf(config)
+	case *commander.Config:
+		api := pbObject{
+			"tag": config.Tag,
+		}
+		api.set("listen", config.Listen)
+		services := make([]string, 0, len(config.Service))
+		for _, service := range config.Service {
+			name, err := apiServiceName(service)
+			if err != nil {
+				return err
+			}
+			services = append(services, name)
+		}
+		api.set("services", services)
+		api.set("journal", config.Journal)
+		c["api"] = api
+	case *metrics.Config:
+		c["metrics"] = pbObject{
+			"tag": config.Tag,
+		}
+	case *stats.Config:
+		c["stats"] = pbObject{}
+	case *router.Config:
+		routing, err := routerFromProtobuf(config)
+		if err != nil {
+			return errors.New("failed to convert routing config").Base(err)
+		}
+		c["routing"] = routing
+	case *dns.Config:
+		dnsConfig, err := dnsFromProtobuf(config)
+		if err != nil {
+			return errors.New("failed to convert DNS config").Base(err)
+		}
+		c["dns"] = dnsConfig
+	case *policy.Config:
+		c["policy"] = policyFromProtobuf(config)
+	case *reverse.Config:
+		c["reverse"] = reverseFromProtobuf(config)
+	case *fakedns.FakeDnsPoolMulti:
+		pools := make([]interface{}, 0, len(config.Pools))
+		for _, pool := range config.Pools {
+			pools = append(pools, pbObject{
+				"ipPool":   pool.IpPool,
+				"poolSize": pool.LruSize,
+			})
+		}
+		c["fakedns"] = pools
+	case *observatory.Config:
+		o := pbObject{}
+		o.set("subjectSelector", config.SubjectSelector)
+		o.set("probeURL", config.ProbeUrl)
+		o.set("probeInterval", pbDuration(config.ProbeInterval))
+		o.set("enableConcurrency", config.EnableConcurrency)
+		c["observatory"] = o
+	case *burst.Config:
+		o := pbObject{}
+		o.set("subjectSelector", config.SubjectSelector)
+		ping := pbObject{}
+		if p := config.PingConfig; p != nil {
+			ping.set("destination", p.Destination)
+			ping.set("connectivity", p.Connectivity)
+			ping.set("interval", pbDuration(p.Interval))
+			ping.set("sampling", p.SamplingCount)
+			ping.set("timeout", pbDuration(p.Timeout))
+		}
+		o["pingConfig"] = ping
+		c["burstObservatory"] = o
+	default:
+		return errors.New("unknown app config: ", string(proto.MessageName(app)))
+	}
+	return nil
+}
+
+func logTarget(typ log.LogType, path string) string {
+	switch typ {
+	case log.LogType_None:
+		return "none"
+	case log.LogType_Console:
+		return ""
+	case log.LogType_Journald:
+		return "journald:"
+	case log.LogType_Syslog:
+		if path == "" {
+			return "syslog:"
+		}
+	}
+	return path
+}
+
+func logFilterFromProtobuf(filter *log.AccessLogFilter) pbObject {
+	if filter == nil {
+		return nil
+	}
+	f := pbObject{}
+	f.set("inboundTags", filter.InboundTags)
+	f.set("emails", filter.Emails)
+	f.set("statuses", filter.Statuses)
+	f.set("outboundTags", filter.OutboundTags)
+	f.set("domains", filter.Domains)
+	f.set("sampleRate", filter.SampleRate)
+	return f
+}
+
+func logFromProtobuf(config *log.Config) pbObject {
+	l := pbObject{}
+	if config.ErrorLogType == log.LogType_None && config.AccessLogType == log.LogType_None &&
+		config.ErrorLogLevel == clog.Severity_Unknown {
+		// "none" turns both logs off but keeps their paths.
+		l["loglevel"] = "none"
+		l.set("access", config.AccessLogPath)
+		l.set("error", config.ErrorLogPath)
+	} else {
+		l.set("access", logTarget(config.AccessLogType, config.AccessLogPath))
+		l.set("error", logTarget(config.ErrorLogType, config.ErrorLogPath))
+		l.set("loglevel", logLevelNames[config.ErrorLogLevel])
+	}
+	l.set("dnsLog", config.EnableDnsLog)
+	switch config.Format {
+	case log.LogFormat_JSON:
+		l["format"] = "json"
+	case log.LogFormat_Logfmt:
+		l["format"] = "logfmt"
+	}
+
+	if r := config.Rotation; r != nil {
+		rotation := pbObject{}
+		rotation.set("maxSize", r.MaxSize/(1024*1024))
+		rotation.set("interval", pbDuration(r.Interval))
+		rotation.set("compress", r.Compress)
+		rotation.set("maxBackups", r.MaxBackups)
+		rotation.set("maxAge", pbDuration(r.MaxAge))
+		l["rotation"] = rotation
+	}
+
+	if f := logFilterFromProtobuf(config.AccessLogFilter); f != nil {
+		l["accessFilter"] = f
+	}
+	if len(config.AccessLogStreams) > 0 {
+		streams := make([]interface{}, 0, len(config.AccessLogStreams))
+		for _, stream := range config.AccessLogStreams {
+			s := pbObject{
+				"target": logTarget(stream.Type, stream.Path),
+			}
+			if f := logFilterFromProtobuf(stream.Filter); f != nil {
+				s["filter"] = f
+			}
+			streams = append(streams, s)
+		}
+		l["accessStreams"] = streams
+	}
+	return l
+}
+
+func routerFromProtobuf(config *router.Config) (pbObject, error) {
+	routing := pbObject{}
+	routing.set("domainStrategy", routerDomainStrategyNames[config.DomainStrategy])
+
+	rules := make([]interface{}, 0, len(config.Rule))
+	for _, rule := range config.Rule {
+		r, err := routingRuleFromProtobuf(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	routing.set("rules", rules)
+
+	balancers := make([]interface{}, 0, len(config.BalancingRule))
+	for _, balancer := range config.BalancingRule {
+		b, err := balancerFromProtobuf(balancer)
+		if err != nil {
+			return nil, err
+		}
+		balancers = append(balancers, b)
+	}
+	routing.set("balancers", balancers)
+
+	return routing, nil
+}
+
+func routingRuleFromProtobuf(rule *router.RoutingRule) (map[string]interface{}, error) {
+	r := pbObject{}
+	r.set("ruleTag", rule.RuleTag)
+	switch tag := rule.TargetTag.(type) {
+	case *router.RoutingRule_Tag:
+		r["outboundTag"] = tag.Tag
+	case *router.RoutingRule_BalancingTag:
+		r["balancerTag"] = tag.BalancingTag
+	default:
+		return nil, errors.New("routing rule has no target")
+	}
+	r.set("domainMatcher", rule.DomainMatcher)
+
+	domains := make([]string, 0, len(rule.Domain))
+	for _, d := range rule.Domain {
+		domains = append(domains, routerDomainPrefixes[d.Type]+d.Value)
+	}
+	r.set("domain", domains)
+
+	ips, err := geoipFromProtobuf(rule.Geoip, rule.Cidr)
+	if err != nil {
+		return nil, err
+	}
+	r.set("ip", ips)
+
+	if rule.PortList != nil {
+		r.set("port", pbPortList(rule.PortList))
+	} else if pr := rule.PortRange; pr != nil {
+		r["port"] = pbRange(pr.From, pr.To)
+	}
+
+	networks := rule.Networks
+	if len(networks) == 0 {
+		networks = rule.NetworkList.GetNetwork()
+	}
+	if len(networks) > 0 {
+		r["network"] = pbNetworks(networks)
+	}
+
+	sources, err := geoipFromProtobuf(rule.SourceGeoip, rule.SourceCidr)
+	if err != nil {
+		return nil, err
+	}
+	r.set("source", sources)
+	r.set("sourcePort", pbPortList(rule.SourcePortList))
+	r.set("user", rule.UserEmail)
+	r.set("inboundTag", rule.InboundTag)
+	r.set("protocol", rule.Protocol)
+	r.set("attrs", rule.Attributes)
+
+	return r, nil
+}
+
+func cidrFromProtobuf(cidr *router.CIDR) string {
+	return net.IP(cidr.Ip).String() + "/" + strconv.FormatUint(uint64(cidr.Prefix), 10)
+}
+
+// geoipFromProtobuf converts IP matchers back into the list read by
+// ToCidrList. Lists from external files are written out as CIDRs, as the
+// original file name is not kept.
+func geoipFromProtobuf(geoips []*router.GeoIP, cidrs []*router.CIDR) ([]string, error) {
+	var list []string
+	for _, geoip := range geoips {
+		code := geoip.CountryCode
+		switch {
+		case code == "":
+			for _, cidr := range geoip.Cidr {
+				list = append(list, cidrFromProtobuf(cidr))
+			}
+		case !strings.Contains(code, "_"):
+			if geoip.ReverseMatch {
+				list = append(list, "geoip:!"+strings.ToLower(code))
+			} else {
+				list = append(list, "geoip:"+strings.ToLower(code))
+			}
+		case geoip.ReverseMatch:
+			return nil, errors.New("reverse match of external IP list ", code, " can not be converted")
+		default:
+			for _, cidr := range geoip.Cidr {
+				list = append(list, cidrFromProtobuf(cidr))
+			}
+		}
+	}
+	for _, cidr := range cidrs {
+		list = append(list, cidrFromProtobuf(cidr))
+	}
+	return list, nil
+}
+
+func balancerFromProtobuf(balancer *router.BalancingRule) (pbObject, error) {
+	b := pbObject{
+		"tag":      balancer.Tag,
+		"selector": balancer.OutboundSelector,
+	}
+	b.set("fallbackTag", balancer.FallbackTag)
+
+	strategy := pbObject{}
+	strategy.set("type", balancer.Strategy)
+	if balancer.StrategySettings != nil {
+		instance, err := balancer.StrategySettings.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		settings, ok := instance.(*router.StrategyLeastLoadConfig)
+		if !ok {
+			return nil, errors.New("unknown balancing strategy settings: ", balancer.StrategySettings.Type)
+		}
+		s := pbObject{}
+		costs := make([]interface{}, 0, len(settings.Costs))
+		for _, cost := range settings.Costs {
+			w := pbObject{
+				"match": cost.Match,
+				"value": pbFloat(cost.Value),
+			}
+			w.set("regexp", cost.Regexp)
+			costs = append(costs, w)
+		}
+		s.set("costs", costs)
+		baselines := make([]interface{}, 0, len(settings.Baselines))
+		for _, baseline := range settings.Baselines {
+			baselines = append(baselines, pbDuration(baseline))
+		}
+		s.set("baselines", baselines)
+		s.set("expected", settings.Expected)
+		s.set("maxRTT", pbDuration(settings.MaxRTT))
+		if settings.Tolerance != 0 {
+			s["tolerance"] = pbFloat(settings.Tolerance)
+		}
+		strategy["settings"] = s
+	}
+	b.set("strategy", strategy)
+
+	return b, nil
+}
+
+func dnsFromProtobuf(config *dns.Config) (pbObject, error) {
+	d := pbObject{}
+
+	servers := make([]interface{}, 0, len(config.NameServer)+len(config.NameServers))
+	for _, ns := range config.NameServer {
+		server, err := nameServerFromProtobuf(ns)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	for _, endpoint := range config.NameServers {
+		servers = append(servers, pbObject{
+			"address": pbAddress(endpoint.Address),
+			"port":    endpoint.Port,
+		})
+	}
+	d.set("servers", servers)
+
+	hosts := pbObject{}
+	for domain, addr := range config.Hosts {
+		hosts[domain] = pbAddress(addr)
+	}
+	for _, mapping := range config.StaticHosts {
+		domain := mapping.Domain
+		if prefix, found := hostPrefixes[mapping.Type]; found {
+			domain = prefix + domain
+		} else if strings.Contains(domain, ":") {
+			domain = "full:" + domain
+		}
+		switch {
+		case mapping.ProxiedDomain != "":
+			hosts[domain] = mapping.ProxiedDomain
+		case len(mapping.Ip) == 1:
+			hosts[domain] = net.IP(mapping.Ip[0]).String()
+		default:
+			ips := make([]string, 0, len(mapping.Ip))
+			for _, ip := range mapping.Ip {
+				ips = append(ips, net.IP(ip).String())
+			}
+			hosts[domain] = ips
+		}
+	}
+	d.set("hosts", hosts)
+
+	if len(config.ClientIp) > 0 {
+		d["clientIp"] = net.IP(config.ClientIp).String()
+	}
+	d.set("tag", config.Tag)
+	d.set("queryStrategy", queryStrategyNames[config.QueryStrategy])
+	d.set("disableCache", config.DisableCache)
+	d.set("disableFallback", config.DisableFallback)
+	d.set("disableFallbackIfMatch", config.DisableFallbackIfMatch)
+
+	return d, nil
+}
+
+func nameServerFromProtobuf(ns *dns.NameServer) (interface{}, error) {
+	server := pbObject{
+		"address": pbAddress(ns.Address.GetAddress()),
+	}
+	server.set("port", ns.Address.GetPort())
+	if len(ns.ClientIp) > 0 {
+		server["clientIp"] = net.IP(ns.ClientIp).String()
+	}
+	server.set("skipFallback", ns.SkipFallback)
+
+	domains := make([]string, 0, len(ns.OriginalRules))
+	for _, rule := range ns.OriginalRules {
+		domains = append(domains, rule.Rule)
+	}
+	server.set("domains", domains)
+
+	expectIPs, err := geoipFromProtobuf(ns.Geoip, nil)
+	if err != nil {
+		return nil, err
+	}
+	server.set("expectIps", expectIPs)
+	server.set("queryStrategy", queryStrategyNames[ns.QueryStrategy])
+
+	if len(server) == 1 {
+		return server["address"], nil
+	}
+	return server, nil
+}
+
+func policyFromProtobuf(config *policy.Config) pbObject {
+	p := pbObject{}
+
+	levels := pbObject{}
+	for level, lp := range config.Level {
+		l := pbObject{}
+		if t := lp.Timeout; t != nil {
+			if t.Handshake != nil {
+				l["handshake"] = t.Handshake.Value
+			}
+			if t.ConnectionIdle != nil {
+				l["connIdle"] = t.ConnectionIdle.Value
+			}
+			if t.UplinkOnly != nil {
+				l["uplinkOnly"] = t.UplinkOnly.Value
+			}
+			if t.DownlinkOnly != nil {
+				l["downlinkOnly"] = t.DownlinkOnly.Value
+			}
+		}
+		if s := lp.Stats; s != nil {
+			l.set("statsUserUplink", s.UserUplink)
+			l.set("statsUserDownlink", s.UserDownlink)
+		}
+		if b := lp.Buffer; b != nil {
+			if b.Connection < 0 {
+				l["bufferSize"] = -1
+			} else {
+				l["bufferSize"] = b.Connection / 1024
+			}
+		}
+		levels[strconv.FormatUint(uint64(level), 10)] = l
+	}
+	p.set("levels", levels)
+
+	if config.System != nil {
+		system := pbObject{}
+		if s := config.System.Stats; s != nil {
+			system.set("statsInboundUplink", s.InboundUplink)
+			system.set("statsInboundDownlink", s.InboundDownlink)
+			system.set("statsOutboundUplink", s.OutboundUplink)
+			system.set("statsOutboundDownlink", s.OutboundDownlink)
+		}
+		p["system"] = system
+	}
+
+	return p
+}
+
+func reverseFromProtobuf(config *reverse.Config) pbObject {
+	r := pbObject{}
+
+	bridges := make([]interface{}, 0, len(config.BridgeConfig))
+	for _, bridge := range config.BridgeConfig {
+		b := pbObject{}
+		b.set("tag", bridge.Tag)
+		b.set("domain", bridge.Domain)
+		b.set("name", bridge.Name)
+		b.set("minWorkers", bridge.MinWorkers)
+		b.set("maxWorkers", bridge.MaxWorkers)
+		services := make([]interface{}, 0, len(bridge.Services))
+		for _, service := range bridge.Services {
+			s := pbObject{}
+			s.set("name", service.Name)
+			s.set("address", service.Address)
+			s.set("port", pbPortList(service.PortList))
+			services = append(services, s)
+		}
+		b.set("services", services)
+		bridges = append(bridges, b)
+	}
+	r.set("bridges", bridges)
+
+	portals := make([]interface{}, 0, len(config.PortalConfig))
+	for _, portal := range config.PortalConfig {
+		p := pbObject{}
+		p.set("tag", portal.Tag)
+		p.set("domain", portal.Domain)
+		if portal.Strategy == reverse.PortalConfig_LEAST_LATENCY {
+			p["strategy"] = "leastLatency"
+		}
+		p.set("services", portal.Services)
+		portals = append(portals, p)
+	}
+	r.set("portals", portals)
+
+	return r
+}
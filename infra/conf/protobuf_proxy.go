@@ -0,0 +1,583 @@
+package conf
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	v2net "github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/common/protocol"
+	"github.com/luckyluke-a/xray-core/proxy/blackhole"
+	dnsproxy "github.com/luckyluke-a/xray-core/proxy/dns"
+	"github.com/luckyluke-a/xray-core/proxy/dokodemo"
+	"github.com/luckyluke-a/xray-core/proxy/freedom"
+	httpproxy "github.com/luckyluke-a/xray-core/proxy/http"
+	"github.com/luckyluke-a/xray-core/proxy/loopback"
+	"github.com/luckyluke-a/xray-core/proxy/shadowsocks"
+	"github.com/luckyluke-a/xray-core/proxy/shadowsocks_2022"
+	"github.com/luckyluke-a/xray-core/proxy/socks"
+	"github.com/luckyluke-a/xray-core/proxy/trojan"
+	"github.com/luckyluke-a/xray-core/proxy/vless"
+	vlessinbound "github.com/luckyluke-a/xray-core/proxy/vless/inbound"
+	vlessoutbound "github.com/luckyluke-a/xray-core/proxy/vless/outbound"
+	"github.com/luckyluke-a/xray-core/proxy/vmess"
+	vmessinbound "github.com/luckyluke-a/xray-core/proxy/vmess/inbound"
+	vmessoutbound "github.com/luckyluke-a/xray-core/proxy/vmess/outbound"
+	"github.com/luckyluke-a/xray-core/proxy/wireguard"
+	"github.com/luckyluke-a/xray-core/transport/internet"
+	"google.golang.org/protobuf/proto"
+)
+
+var securityTypeNames = map[protocol.SecurityType]string{
+	protocol.SecurityType_AES128_GCM:        "aes-128-gcm",
+	protocol.SecurityType_CHACHA20_POLY1305: "chacha20-poly1305",
+	protocol.SecurityType_AUTO:              "auto",
+	protocol.SecurityType_NONE:              "none",
+	protocol.SecurityType_ZERO:              "zero",
+}
+
+var cipherTypeNames = map[shadowsocks.CipherType]string{
+	shadowsocks.CipherType_AES_128_GCM:        "aes-128-gcm",
+	shadowsocks.CipherType_AES_256_GCM:        "aes-256-gcm",
+	shadowsocks.CipherType_CHACHA20_POLY1305:  "chacha20-poly1305",
+	shadowsocks.CipherType_XCHACHA20_POLY1305: "xchacha20-poly1305",
+	shadowsocks.CipherType_NONE:               "none",
+}
+
+var wireguardDomainStrategyNames = map[wireguard.DeviceConfig_DomainStrategy]string{
+	wireguard.DeviceConfig_FORCE_IP4:  "ForceIPv4",
+	wireguard.DeviceConfig_FORCE_IP6:  "ForceIPv6",
+	wireguard.DeviceConfig_FORCE_IP46: "ForceIPv4v6",
+	wireguard.DeviceConfig_FORCE_IP64: "ForceIPv6v4",
+}
+
+func inboundProxyFromProtobuf(config proto.Message) (string, pbObject, error) {
+	c := pbObject{}
+	switch config := config.(type) {
+	case *dokodemo.Config:
+		c.set("address", pbAddress(config.Address))
+		c.set("port", config.Port)
+		networks := config.Networks
+		if len(networks) == 0 {
+			networks = config.NetworkList.GetNetwork()
+		}
+		c["network"] = pbNetworks(networks)
+		c.set("timeout", config.Timeout)
+		c.set("followRedirect", config.FollowRedirect)
+		c.set("userLevel", config.UserLevel)
+		return "dokodemo-door", c, nil
+	case *httpproxy.ServerConfig:
+		c.set("timeout", config.Timeout)
+		c.set("accounts", accountsFromProtobuf(config.Accounts))
+		c.set("allowTransparent", config.AllowTransparent)
+		c.set("userLevel", config.UserLevel)
+		return "http", c, nil
+	case *socks.ServerConfig:
+		if config.AuthType == socks.AuthType_PASSWORD {
+			c["auth"] = AuthMethodUserPass
+		} else {
+			c["auth"] = AuthMethodNoAuth
+		}
+		c.set("accounts", accountsFromProtobuf(config.Accounts))
+		c.set("udp", config.UdpEnabled)
+		c.set("ip", pbAddress(config.Address))
+		c.set("timeout", config.Timeout)
+		c.set("userLevel", config.UserLevel)
+		return "socks", c, nil
+	case *shadowsocks.ServerConfig:
+		c["network"] = pbNetworks(config.Network)
+		if len(config.Users) == 1 {
+			user, account, err := shadowsocksUserFromProtobuf(config.Users[0])
+			if err != nil {
+				return "", nil, err
+			}
+			for k, v := range user {
+				c[k] = v
+			}
+			c.set("ivCheck", account.IvCheck)
+			return "shadowsocks", c, nil
+		}
+		clients := make([]pbObject, 0, len(config.Users))
+		for i, u := range config.Users {
+			user, account, err := shadowsocksUserFromProtobuf(u)
+			if err != nil {
+				return "", nil, err
+			}
+			if i == 0 {
+				c.set("ivCheck", account.IvCheck)
+			}
+			clients = append(clients, user)
+		}
+		c["clients"] = clients
+		return "shadowsocks", c, nil
+	case *shadowsocks_2022.ServerConfig:
+		c.set("method", config.Method)
+		c.set("password", config.Key)
+		c.set("email", config.Email)
+		c["network"] = pbNetworks(config.Network)
+		return "shadowsocks", c, nil
+	case *shadowsocks_2022.MultiUserServerConfig:
+		c.set("method", config.Method)
+		c.set("password", config.Key)
+		c["network"] = pbNetworks(config.Network)
+		clients := make([]pbObject, 0, len(config.Users))
+		for _, u := range config.Users {
+			user := pbObject{}
+			user.set("password", u.Key)
+			user.set("email", u.Email)
+			clients = append(clients, user)
+		}
+		c["clients"] = clients
+		return "shadowsocks", c, nil
+	case *shadowsocks_2022.RelayServerConfig:
+		c.set("method", config.Method)
+		c.set("password", config.Key)
+		c["network"] = pbNetworks(config.Network)
+		clients := make([]pbObject, 0, len(config.Destinations))
+		for _, d := range config.Destinations {
+			user := pbObject{}
+			user.set("password", d.Key)
+			user.set("email", d.Email)
+			user["address"] = pbAddress(d.Address)
+			user.set("port", d.Port)
+			clients = append(clients, user)
+		}
+		c["clients"] = clients
+		return "shadowsocks", c, nil
+	case *vlessinbound.Config:
+		clients := make([]pbObject, 0, len(config.Clients))
+		for _, u := range config.Clients {
+			user, account, err := vlessUserFromProtobuf(u)
+			if err != nil {
+				return "", nil, err
+			}
+			user.set("flow", account.Flow)
+			clients = append(clients, user)
+		}
+		c["clients"] = clients
+		c["decryption"] = config.Decryption
+		c.set("fallbacks", fallbacksFromProtobuf(config.Fallbacks))
+		c.set("segaroSettings", segaroFromProtobuf(config.SegaroSettings))
+		return "vless", c, nil
+	case *vmessinbound.Config:
+		clients := make([]pbObject, 0, len(config.User))
+		for _, u := range config.User {
+			user, err := vmessUserFromProtobuf(u)
+			if err != nil {
+				return "", nil, err
+			}
+			clients = append(clients, user)
+		}
+		c["clients"] = clients
+		if config.Default != nil {
+			c["default"] = pbObject{"level": config.Default.Level}
+		}
+		if config.Detour != nil {
+			c["detour"] = pbObject{"to": config.Detour.To}
+		}
+		return "vmess", c, nil
+	case *trojan.ServerConfig:
+		clients := make([]pbObject, 0, len(config.Users))
+		for _, u := range config.Users {
+			user, err := trojanUserFromProtobuf(u)
+			if err != nil {
+				return "", nil, err
+			}
+			clients = append(clients, user)
+		}
+		c["clients"] = clients
+		c.set("fallbacks", fallbacksFromProtobuf(config.Fallbacks))
+		return "trojan", c, nil
+	case *wireguard.DeviceConfig:
+		wireguardFromProtobuf(c, config)
+		return "wireguard", c, nil
+	default:
+		return "", nil, errors.New("unknown inbound proxy settings: ", proto.MessageName(config))
+	}
+}
+
+func outboundProxyFromProtobuf(config proto.Message) (string, pbObject, error) {
+	c := pbObject{}
+	switch config := config.(type) {
+	case *blackhole.Config:
+		if config.Response != nil {
+			response, err := config.Response.GetInstance()
+			if err != nil {
+				return "", nil, err
+			}
+			switch response.(type) {
+			case *blackhole.NoneResponse:
+				c["response"] = pbObject{"type": "none"}
+			case *blackhole.HTTPResponse:
+				c["response"] = pbObject{"type": "http"}
+			default:
+				return "", nil, errors.New("unknown blackhole response: ", config.Response.Type)
+			}
+		}
+		return "blackhole", c, nil
+	case *loopback.Config:
+		c.set("inboundTag", config.InboundTag)
+		return "loopback", c, nil
+	case *freedom.Config:
+		c.set("domainStrategy", domainStrategyNames[internet.DomainStrategy(config.DomainStrategy)])
+		c.set("timeout", config.Timeout)
+		if server := config.DestinationOverride.GetServer(); server != nil {
+			c["redirect"] = net.JoinHostPort(pbAddress(server.Address), strconv.FormatUint(uint64(server.Port), 10))
+		}
+		c.set("userLevel", config.UserLevel)
+		if f := config.Fragment; f != nil {
+			fragment := pbObject{}
+			switch {
+			case f.PacketsFrom == 0 && f.PacketsTo == 1:
+				fragment["packets"] = "tlshello"
+			case f.PacketsFrom == 0 && f.PacketsTo == 0:
+			default:
+				fragment["packets"] = pbUintRange(f.PacketsFrom, f.PacketsTo)
+			}
+			fragment["length"] = pbUintRange(f.LengthMin, f.LengthMax)
+			fragment["interval"] = pbUintRange(f.IntervalMin, f.IntervalMax)
+			c["fragment"] = fragment
+		}
+		c.set("proxyProtocol", config.ProxyProtocol)
+		return "freedom", c, nil
+	case *httpproxy.ClientConfig:
+		servers, err := serversFromProtobuf(config.Server, func(user *protocol.User, account proto.Message) (pbObject, error) {
+			a, ok := account.(*httpproxy.Account)
+			if !ok {
+				return nil, errors.New("unknown HTTP account: ", proto.MessageName(account))
+			}
+			u := pbUser(user)
+			u.set("user", a.Username)
+			u.set("pass", a.Password)
+			return u, nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		c["servers"] = servers
+		if len(config.Header) > 0 {
+			headers := make(map[string]string, len(config.Header))
+			for _, h := range config.Header {
+				headers[h.Key] = h.Value
+			}
+			c["headers"] = headers
+		}
+		return "http", c, nil
+	case *socks.ClientConfig:
+		switch config.Version {
+		case socks.Version_SOCKS4:
+			c["version"] = "4"
+		case socks.Version_SOCKS4A:
+			c["version"] = "4a"
+		}
+		servers, err := serversFromProtobuf(config.Server, func(user *protocol.User, account proto.Message) (pbObject, error) {
+			a, ok := account.(*socks.Account)
+			if !ok {
+				return nil, errors.New("unknown socks account: ", proto.MessageName(account))
+			}
+			u := pbUser(user)
+			u.set("user", a.Username)
+			u.set("pass", a.Password)
+			return u, nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		c["servers"] = servers
+		return "socks", c, nil
+	case *shadowsocks.ClientConfig:
+		servers := make([]pbObject, 0, len(config.Server))
+		for _, s := range config.Server {
+			for _, u := range s.User {
+				server, account, err := shadowsocksUserFromProtobuf(u)
+				if err != nil {
+					return "", nil, err
+				}
+				server["address"] = pbAddress(s.Address)
+				server["port"] = s.Port
+				server.set("ivCheck", account.IvCheck)
+				servers = append(servers, server)
+			}
+		}
+		c["servers"] = servers
+		return "shadowsocks", c, nil
+	case *shadowsocks_2022.ClientConfig:
+		server := pbObject{
+			"address": pbAddress(config.Address),
+			"port":    config.Port,
+		}
+		server.set("method", config.Method)
+		server.set("password", config.Key)
+		server.set("uot", config.UdpOverTcp)
+		server.set("uotVersion", config.UdpOverTcpVersion)
+		c["servers"] = []pbObject{server}
+		return "shadowsocks", c, nil
+	case *vlessoutbound.Config:
+		vnext, err := serversFromProtobuf(config.Vnext, func(user *protocol.User, _ proto.Message) (pbObject, error) {
+			u, account, err := vlessUserFromProtobuf(user)
+			if err != nil {
+				return nil, err
+			}
+			u.set("flow", account.Flow)
+			u["encryption"] = account.Encryption
+			u.set("segaroSettings", segaroFromProtobuf(account.SegaroSettings))
+			return u, nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		c["vnext"] = vnext
+		return "vless", c, nil
+	case *vmessoutbound.Config:
+		vnext, err := serversFromProtobuf(config.Receiver, func(user *protocol.User, _ proto.Message) (pbObject, error) {
+			return vmessUserFromProtobuf(user)
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		c["vnext"] = vnext
+		return "vmess", c, nil
+	case *trojan.ClientConfig:
+		servers := make([]pbObject, 0, len(config.Server))
+		for _, s := range config.Server {
+			for _, u := range s.User {
+				server, err := trojanUserFromProtobuf(u)
+				if err != nil {
+					return "", nil, err
+				}
+				server["address"] = pbAddress(s.Address)
+				server["port"] = s.Port
+				servers = append(servers, server)
+			}
+		}
+		c["servers"] = servers
+		return "trojan", c, nil
+	case *dnsproxy.Config:
+		if server := config.Server; server != nil {
+			if server.Network != v2net.Network_Unknown {
+				c["network"] = server.Network.SystemString()
+			}
+			c.set("address", pbAddress(server.Address))
+			c.set("port", server.Port)
+		}
+		c.set("userLevel", config.UserLevel)
+		c.set("nonIPQuery", config.Non_IPQuery)
+		return "dns", c, nil
+	case *wireguard.DeviceConfig:
+		wireguardFromProtobuf(c, config)
+		return "wireguard", c, nil
+	default:
+		return "", nil, errors.New("unknown outbound proxy settings: ", proto.MessageName(config))
+	}
+}
+
+func pbUintRange(from, to uint64) string {
+	if from == to {
+		return strconv.FormatUint(from, 10)
+	}
+	return strconv.FormatUint(from, 10) + "-" + strconv.FormatUint(to, 10)
+}
+
+func pbUser(user *protocol.User) pbObject {
+	u := pbObject{}
+	u.set("level", user.Level)
+	u.set("email", user.Email)
+	return u
+}
+
+func userAccount(user *protocol.User) (proto.Message, error) {
+	if user.Account == nil {
+		return nil, errors.New("user ", user.Email, " has no account")
+	}
+	return user.Account.GetInstance()
+}
+
+func serversFromProtobuf(servers []*protocol.ServerEndpoint, convert func(*protocol.User, proto.Message) (pbObject, error)) ([]pbObject, error) {
+	list := make([]pbObject, 0, len(servers))
+	for _, s := range servers {
+		users := make([]pbObject, 0, len(s.User))
+		for _, user := range s.User {
+			account, err := userAccount(user)
+			if err != nil {
+				return nil, err
+			}
+			u, err := convert(user, account)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, u)
+		}
+		server := pbObject{
+			"address": pbAddress(s.Address),
+			"port":    s.Port,
+		}
+		server.set("users", users)
+		list = append(list, server)
+	}
+	return list, nil
+}
+
+func accountsFromProtobuf(accounts map[string]string) []pbObject {
+	names := make([]string, 0, len(accounts))
+	for name := range accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]pbObject, 0, len(names))
+	for _, name := range names {
+		list = append(list, pbObject{"user": name, "pass": accounts[name]})
+	}
+	return list
+}
+
+func shadowsocksUserFromProtobuf(user *protocol.User) (pbObject, *shadowsocks.Account, error) {
+	instance, err := userAccount(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	account, ok := instance.(*shadowsocks.Account)
+	if !ok {
+		return nil, nil, errors.New("unknown shadowsocks account: ", proto.MessageName(instance))
+	}
+	u := pbUser(user)
+	u.set("method", cipherTypeNames[account.CipherType])
+	u.set("password", account.Password)
+	return u, account, nil
+}
+
+func vlessUserFromProtobuf(user *protocol.User) (pbObject, *vless.Account, error) {
+	instance, err := userAccount(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	account, ok := instance.(*vless.Account)
+	if !ok {
+		return nil, nil, errors.New("unknown VLESS account: ", proto.MessageName(instance))
+	}
+	u := pbUser(user)
+	u["id"] = account.Id
+	return u, account, nil
+}
+
+func vmessUserFromProtobuf(user *protocol.User) (pbObject, error) {
+	instance, err := userAccount(user)
+	if err != nil {
+		return nil, err
+	}
+	account, ok := instance.(*vmess.Account)
+	if !ok {
+		return nil, errors.New("unknown VMess account: ", proto.MessageName(instance))
+	}
+	u := pbUser(user)
+	u["id"] = account.Id
+	u.set("security", securityTypeNames[account.SecuritySettings.GetType()])
+	u.set("experiments", account.TestsEnabled)
+	return u, nil
+}
+
+func trojanUserFromProtobuf(user *protocol.User) (pbObject, error) {
+	instance, err := userAccount(user)
+	if err != nil {
+		return nil, err
+	}
+	account, ok := instance.(*trojan.Account)
+	if !ok {
+		return nil, errors.New("unknown trojan account: ", proto.MessageName(instance))
+	}
+	u := pbUser(user)
+	u["password"] = account.Password
+	return u, nil
+}
+
+// fallbackGetter is implemented by the fallbacks of both VLESS and Trojan.
+type fallbackGetter interface {
+	GetName() string
+	GetAlpn() string
+	GetPath() string
+	GetType() string
+	GetDest() string
+	GetXver() uint64
+}
+
+func fallbacksFromProtobuf[T fallbackGetter](fallbacks []T) []pbObject {
+	list := make([]pbObject, 0, len(fallbacks))
+	for _, fb := range fallbacks {
+		f := pbObject{}
+		f.set("name", fb.GetName())
+		f.set("alpn", fb.GetAlpn())
+		f.set("path", fb.GetPath())
+		dest, padded := unixDestFromProtobuf(fb.GetType(), fb.GetDest())
+		if !padded {
+			f.set("type", fb.GetType())
+		}
+		f["dest"] = dest
+		f.set("xver", fb.GetXver())
+		list = append(list, f)
+	}
+	return list
+}
+
+func segaroFromProtobuf(config *vless.SegaroConfig) pbObject {
+	if config == nil {
+		return nil
+	}
+	c := pbObject{}
+	if config.PaddingSize != 0 {
+		c.set("paddingSize", config.PaddingSize)
+		c.set("subchunkSize", config.SubchunkSize)
+		c.set("splitPacket", config.SplitPacket)
+		c.set("serverRandPacket", config.ServerRandPacket)
+		c.set("clientRandPacket", config.ClientRandPacket)
+		c.set("serverRandPacketCount", config.ServerRandPacketCount)
+		c.set("clientRandPacketCount", config.ClientRandPacketCount)
+		c.set("chunkDelay", config.ChunkDelay)
+	}
+	if len(config.Profiles) > 0 {
+		profiles := make([]pbObject, 0, len(config.Profiles))
+		for _, p := range config.Profiles {
+			profile := pbObject{}
+			profile.set("name", p.Name)
+			profile.set("paddingSize", p.PaddingSize)
+			profile.set("subchunkSize", p.SubchunkSize)
+			profile.set("splitPacket", p.SplitPacket)
+			profile.set("serverRandPacket", p.ServerRandPacket)
+			profile.set("clientRandPacket", p.ClientRandPacket)
+			profile.set("serverRandPacketCount", p.ServerRandPacketCount)
+			profile.set("clientRandPacketCount", p.ClientRandPacketCount)
+			profile.set("chunkDelay", p.ChunkDelay)
+			profiles = append(profiles, profile)
+		}
+		c["profiles"] = profiles
+	}
+	return c
+}
+
+func wireguardFromProtobuf(c pbObject, config *wireguard.DeviceConfig) {
+	c.set("secretKey", config.SecretKey)
+	c.set("address", config.Endpoint)
+	if len(config.Peers) > 0 {
+		peers := make([]pbObject, 0, len(config.Peers))
+		for _, p := range config.Peers {
+			peer := pbObject{}
+			peer.set("publicKey", p.PublicKey)
+			peer.set("preSharedKey", p.PreSharedKey)
+			peer.set("endpoint", p.Endpoint)
+			peer.set("keepAlive", p.KeepAlive)
+			peer["allowedIPs"] = append([]string{}, p.AllowedIps...)
+			peers = append(peers, peer)
+		}
+		c["peers"] = peers
+	}
+	c.set("mtu", config.Mtu)
+	c.set("workers", config.NumWorkers)
+	if len(config.Reserved) > 0 {
+		reserved := make([]int, 0, len(config.Reserved))
+		for _, b := range config.Reserved {
+			reserved = append(reserved, int(b))
+		}
+		c["reserved"] = reserved
+	}
+	c.set("domainStrategy", wireguardDomainStrategyNames[config.DomainStrategy])
+	c["kernelMode"] = config.KernelMode
+}
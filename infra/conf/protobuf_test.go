@@ -0,0 +1,251 @@
+package conf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	core "github.com/luckyluke-a/xray-core/core"
+	. "github.com/luckyluke-a/xray-core/infra/conf"
+	"google.golang.org/protobuf/proto"
+)
+
+func buildConfig(t *testing.T, content []byte) *core.Config {
+	t.Helper()
+	config := new(Config)
+	common.Must(json.Unmarshal(content, config))
+	pbConfig, err := config.Build()
+	if err != nil {
+		t.Fatalf("failed to build config %s: %v", content, err)
+	}
+	return pbConfig
+}
+
+func TestConfigFromProtobuf(t *testing.T) {
+	cases := []string{
+		`{
+			"log": {
+				"access": "/var/log/xray/access.log",
+				"error": "syslog:",
+				"loglevel": "debug",
+				"format": "json",
+				"rotation": {"maxSize": 10, "interval": "24h", "maxBackups": 3},
+				"accessFilter": {"statuses": ["rejected"], "sampleRate": 0.5},
+				"accessStreams": [{"target": "journald:", "filter": {"emails": ["a@example.com"]}}]
+			},
+			"api": {"tag": "api", "services": ["HandlerService", "StatsService", "RoutingService"], "journal": "/var/lib/xray/journal"},
+			"stats": {},
+			"policy": {
+				"levels": {"0": {"handshake": 4, "connIdle": 300, "statsUserUplink": true, "bufferSize": 4}},
+				"system": {"statsInboundUplink": true}
+			},
+			"routing": {
+				"domainStrategy": "IPIfNonMatch",
+				"rules": [
+					{"inboundTag": ["api"], "outboundTag": "api"},
+					{"ruleTag": "lan", "ip": ["10.0.0.0/8", "fc00::/7"], "port": "53,443-444", "network": "tcp,udp", "outboundTag": "direct"},
+					{"domain": ["domain:example.com", "full:www.example.org", "regexp:^ads\\.", "keyword:track"], "balancerTag": "b"},
+					{"source": ["192.168.1.1"], "sourcePort": 1000, "user": ["a@example.com"], "protocol": ["bittorrent"], "attrs": {":method": "GET"}, "outboundTag": "block"}
+				],
+				"balancers": [
+					{"tag": "b", "selector": ["proxy"], "fallbackTag": "direct", "strategy": {"type": "leastLoad", "settings": {"expected": 2, "maxRTT": "1s", "tolerance": 0.01, "baselines": ["400ms"], "costs": [{"regexp": true, "match": "^p", "value": 0.5}]}}},
+					{"tag": "r", "selector": ["proxy"], "strategy": {"type": "roundRobin"}}
+				]
+			},
+			"dns": {
+				"servers": [
+					"1.1.1.1",
+					{"address": "8.8.8.8", "port": 5353, "domains": ["domain:example.com"], "expectIps": ["8.8.0.0/16"], "skipFallback": true, "queryStrategy": "UseIPv4"},
+					"https://dns.google/dns-query",
+					"fakedns"
+				],
+				"hosts": {"domain:example.com": "127.0.0.1", "example.org": ["1.2.3.4", "::1"], "keyword:proxied": "example.net"},
+				"clientIp": "1.2.3.4",
+				"tag": "dns",
+				"disableCache": true
+			},
+			"fakedns": {"ipPool": "198.18.0.0/15", "poolSize": 65535},
+			"reverse": {
+				"bridges": [{"tag": "bridge", "domain": "reverse.example.com"}],
+				"portals": [{"tag": "portal", "domain": "reverse.example.com", "strategy": "leastLatency"}]
+			},
+			"observatory": {"subjectSelector": ["proxy"], "probeURL": "https://www.google.com/generate_204", "probeInterval": "10s"},
+			"burstObservatory": {"subjectSelector": ["proxy"], "pingConfig": {"destination": "https://www.google.com/generate_204", "interval": "1m", "sampling": 3, "timeout": "5s"}}
+		}`,
+		`{
+			"inbounds": [
+				{
+					"tag": "vless-in",
+					"listen": "0.0.0.0",
+					"port": 443,
+					"protocol": "vless",
+					"settings": {
+						"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "flow": "xtls-rprx-vision", "email": "a@example.com", "level": 1}],
+						"decryption": "none",
+						"fallbacks": [{"dest": 80}, {"alpn": "h2", "dest": "@h2", "xver": 1}]
+					},
+					"streamSettings": {
+						"network": "tcp",
+						"security": "reality",
+						"realitySettings": {
+							"dest": "www.example.com:443",
+							"serverNames": ["www.example.com"],
+							"privateKey": "PtPUe0-tsOg6HQlnzfMQgbA1HKbYzbj2pKbMf05_Ook",
+							"shortIds": ["", "0123456789abcdef"]
+						},
+						"sockopt": {"tcpFastOpen": true, "mark": 255}
+					},
+					"sniffing": {"enabled": true, "destOverride": ["http", "tls", "quic"], "routeOnly": true}
+				},
+				{
+					"tag": "vmess-in",
+					"port": "10000-10010",
+					"listen": "127.0.0.1",
+					"protocol": "vmess",
+					"settings": {"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "email": "b@example.com"}]},
+					"streamSettings": {"network": "ws", "wsSettings": {"path": "/ws", "headers": {"X-Forwarded-For": "1.1.1.1"}}}
+				},
+				{
+					"tag": "trojan-in",
+					"port": 8443,
+					"protocol": "trojan",
+					"settings": {"clients": [{"password": "secret", "email": "c@example.com"}]},
+					"streamSettings": {"network": "grpc", "grpcSettings": {"serviceName": "grpc", "multiMode": true}}
+				},
+				{
+					"tag": "ss-in",
+					"port": 8388,
+					"protocol": "shadowsocks",
+					"settings": {"method": "2022-blake3-aes-128-gcm", "password": "0a/VUXT9CLgGnljKhtj9JA==", "network": "tcp,udp"}
+				},
+				{
+					"tag": "socks-in",
+					"port": 1080,
+					"listen": "127.0.0.1",
+					"protocol": "socks",
+					"settings": {"auth": "password", "accounts": [{"user": "u", "pass": "p"}], "udp": true, "ip": "127.0.0.1"}
+				},
+				{
+					"tag": "http-in",
+					"port": 8080,
+					"protocol": "http",
+					"settings": {"accounts": [{"user": "u", "pass": "p"}], "allowTransparent": true}
+				},
+				{
+					"tag": "door",
+					"port": 5353,
+					"protocol": "dokodemo-door",
+					"settings": {"address": "1.1.1.1", "port": 53, "network": "udp"}
+				}
+			],
+			"outbounds": [
+				{"tag": "direct", "protocol": "freedom", "settings": {"domainStrategy": "UseIPv4", "fragment": {"packets": "tlshello", "length": "100-200", "interval": "10-20"}}},
+				{"tag": "block", "protocol": "blackhole", "settings": {"response": {"type": "http"}}},
+				{"tag": "dns-out", "protocol": "dns", "settings": {"nonIPQuery": "skip"}},
+				{
+					"tag": "proxy",
+					"protocol": "vless",
+					"sendThrough": "192.168.1.2",
+					"settings": {"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "encryption": "none", "flow": "xtls-rprx-vision"}]}]},
+					"streamSettings": {
+						"network": "tcp",
+						"security": "tls",
+						"tlsSettings": {"serverName": "example.com", "alpn": ["h2", "http/1.1"], "fingerprint": "chrome"}
+					}
+				},
+				{
+					"tag": "vmess-out",
+					"protocol": "vmess",
+					"settings": {"vnext": [{"address": "1.2.3.4", "port": 10086, "users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "security": "aes-128-gcm"}]}]},
+					"streamSettings": {"network": "httpupgrade", "httpupgradeSettings": {"path": "/up", "host": "example.com"}},
+					"mux": {"enabled": true, "concurrency": 8}
+				},
+				{
+					"tag": "trojan-out",
+					"protocol": "trojan",
+					"settings": {"servers": [{"address": "example.com", "port": 443, "password": "secret"}]},
+					"streamSettings": {"network": "kcp", "kcpSettings": {"mtu": 1350, "header": {"type": "wechat-video"}, "seed": "seed"}},
+					"proxySettings": {"tag": "proxy"}
+				},
+				{
+					"tag": "ss-out",
+					"protocol": "shadowsocks",
+					"settings": {"servers": [{"address": "example.com", "port": 8388, "method": "aes-128-gcm", "password": "secret", "uot": true}]}
+				},
+				{
+					"tag": "socks-out",
+					"protocol": "socks",
+					"settings": {"servers": [{"address": "127.0.0.1", "port": 1080, "users": [{"user": "u", "pass": "p"}]}]}
+				},
+				{
+					"tag": "wg",
+					"protocol": "wireguard",
+					"settings": {
+						"secretKey": "FRZo61f/a0SvAcSTDItLHi4Ek1t6hHmyKGngunDwU+Y=",
+						"address": ["10.0.0.2/32"],
+						"peers": [{"publicKey": "sEXkzRKBD4Gkm1gc/VJ4VLJZ+cqw15mEVZKCTmgLlyY=", "endpoint": "1.2.3.4:51820", "keepAlive": 25}],
+						"mtu": 1420,
+						"reserved": [1, 2, 3]
+					}
+				}
+			]
+		}`,
+		`{
+			"inbounds": [
+				{
+					"port": 443,
+					"protocol": "shadowsocks",
+					"settings": {
+						"method": "2022-blake3-aes-128-gcm",
+						"password": "0a/VUXT9CLgGnljKhtj9JA==",
+						"clients": [{"password": "0a/VUXT9CLgGnljKhtj9JA==", "email": "a@example.com"}]
+					},
+					"streamSettings": {
+						"network": "tcp",
+						"tcpSettings": {"header": {"type": "http", "request": {"path": ["/"], "headers": {"Host": ["example.com"]}}, "response": {"headers": {"Content-Type": ["text/html"]}}}}
+					}
+				},
+				{
+					"port": 444,
+					"protocol": "vless",
+					"settings": {"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b"}], "decryption": "none"},
+					"streamSettings": {"network": "splithttp", "splithttpSettings": {"path": "/split", "host": "example.com", "scMaxConcurrentPosts": "10-20"}}
+				},
+				{
+					"port": 445,
+					"protocol": "vmess",
+					"settings": {"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b"}]},
+					"streamSettings": {"network": "h2", "httpSettings": {"path": "/h2", "host": ["example.com"]}}
+				},
+				{
+					"port": 446,
+					"protocol": "trojan",
+					"settings": {"clients": [{"password": "secret"}]},
+					"streamSettings": {"network": "ds", "dsSettings": {"path": "@@xray.sock", "abstract": true, "padding": true}}
+				}
+			],
+			"outbounds": [
+				{
+					"protocol": "vless",
+					"settings": {"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "encryption": "none"}]}]},
+					"streamSettings": {"network": "quic", "quicSettings": {"security": "aes-128-gcm", "key": "key", "header": {"type": "srtp"}}},
+					"mux": {"enabled": true, "concurrency": -1, "xudpConcurrency": 16, "xudpProxyUDP443": "skip"}
+				}
+			]
+		}`,
+	}
+
+	for _, c := range cases {
+		expected := buildConfig(t, []byte(c))
+		converted, err := ConfigFromProtobuf(expected)
+		if err != nil {
+			t.Fatalf("failed to convert %s: %v", c, err)
+		}
+		content, err := json.Marshal(converted)
+		common.Must(err)
+		actual := buildConfig(t, content)
+		if !proto.Equal(expected, actual) {
+			t.Errorf("converted config %s does not build into the original config %s", content, c)
+		}
+	}
+}
@@ -0,0 +1,582 @@
+package conf
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/protocol"
+	"github.com/luckyluke-a/xray-core/common/serial"
+	"github.com/luckyluke-a/xray-core/transport/internet"
+	"github.com/luckyluke-a/xray-core/transport/internet/domainsocket"
+	"github.com/luckyluke-a/xray-core/transport/internet/grpc"
+	dnsheader "github.com/luckyluke-a/xray-core/transport/internet/headers/dns"
+	httpheader "github.com/luckyluke-a/xray-core/transport/internet/headers/http"
+	"github.com/luckyluke-a/xray-core/transport/internet/headers/noop"
+	"github.com/luckyluke-a/xray-core/transport/internet/headers/srtp"
+	tlsheader "github.com/luckyluke-a/xray-core/transport/internet/headers/tls"
+	"github.com/luckyluke-a/xray-core/transport/internet/headers/utp"
+	"github.com/luckyluke-a/xray-core/transport/internet/headers/wechat"
+	"github.com/luckyluke-a/xray-core/transport/internet/headers/wireguard"
+	"github.com/luckyluke-a/xray-core/transport/internet/http"
+	"github.com/luckyluke-a/xray-core/transport/internet/httpfallback"
+	"github.com/luckyluke-a/xray-core/transport/internet/httpupgrade"
+	"github.com/luckyluke-a/xray-core/transport/internet/kcp"
+	"github.com/luckyluke-a/xray-core/transport/internet/quic"
+	"github.com/luckyluke-a/xray-core/transport/internet/reality"
+	"github.com/luckyluke-a/xray-core/transport/internet/shadowtls"
+	"github.com/luckyluke-a/xray-core/transport/internet/splithttp"
+	"github.com/luckyluke-a/xray-core/transport/internet/tcp"
+	"github.com/luckyluke-a/xray-core/transport/internet/tls"
+	"github.com/luckyluke-a/xray-core/transport/internet/websocket"
+)
+
+var transportSettingsKeys = map[string]string{
+	"tcp":          "tcpSettings",
+	"mkcp":         "kcpSettings",
+	"websocket":    "wsSettings",
+	"http":         "httpSettings",
+	"domainsocket": "dsSettings",
+	"quic":         "quicSettings",
+	"grpc":         "grpcSettings",
+	"httpupgrade":  "httpupgradeSettings",
+	"splithttp":    "splithttpSettings",
+}
+
+var domainStrategyNames = map[internet.DomainStrategy]string{
+	internet.DomainStrategy_USE_IP:     "UseIP",
+	internet.DomainStrategy_USE_IP4:    "UseIPv4",
+	internet.DomainStrategy_USE_IP6:    "UseIPv6",
+	internet.DomainStrategy_USE_IP46:   "UseIPv4v6",
+	internet.DomainStrategy_USE_IP64:   "UseIPv6v4",
+	internet.DomainStrategy_FORCE_IP:   "ForceIP",
+	internet.DomainStrategy_FORCE_IP4:  "ForceIPv4",
+	internet.DomainStrategy_FORCE_IP6:  "ForceIPv6",
+	internet.DomainStrategy_FORCE_IP46: "ForceIPv4v6",
+	internet.DomainStrategy_FORCE_IP64: "ForceIPv6v4",
+}
+
+func streamFromProtobuf(config *internet.StreamConfig) (pbObject, error) {
+	stream := pbObject{}
+	stream.set("network", config.ProtocolName)
+
+	for _, tm := range config.SecuritySettings {
+		instance, err := tm.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		switch settings := instance.(type) {
+		case *tls.Config:
+			stream["security"] = "tls"
+			stream["tlsSettings"] = tlsFromProtobuf(settings)
+		case *reality.Config:
+			stream["security"] = "reality"
+			stream["realitySettings"] = realityFromProtobuf(settings)
+		case *shadowtls.Config:
+			stream["security"] = "shadowtls"
+			stream["shadowtlsSettings"] = shadowtlsFromProtobuf(settings)
+		default:
+			return nil, errors.New("unknown security settings: ", tm.Type)
+		}
+	}
+
+	for _, ts := range config.TransportSettings {
+		key, found := transportSettingsKeys[ts.ProtocolName]
+		if !found {
+			return nil, errors.New("unknown transport protocol: ", ts.ProtocolName)
+		}
+		settings, err := transportFromProtobuf(ts.Settings)
+		if err != nil {
+			return nil, errors.New("failed to convert ", ts.ProtocolName, " settings").Base(err)
+		}
+		stream[key] = settings
+	}
+
+	if config.SocketSettings != nil {
+		stream["sockopt"] = sockoptFromProtobuf(config.SocketSettings)
+	}
+
+	return stream, nil
+}
+
+func transportFromProtobuf(tm *serial.TypedMessage) (pbObject, error) {
+	c := pbObject{}
+	if tm == nil {
+		return c, nil
+	}
+	instance, err := tm.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	switch settings := instance.(type) {
+	case *tcp.Config:
+		if settings.HeaderSettings != nil {
+			header, err := tcpHeaderFromProtobuf(settings.HeaderSettings)
+			if err != nil {
+				return nil, err
+			}
+			c["header"] = header
+		}
+		c.set("acceptProxyProtocol", settings.AcceptProxyProtocol)
+	case *kcp.Config:
+		if settings.Mtu != nil {
+			c["mtu"] = settings.Mtu.Value
+		}
+		if settings.Tti != nil {
+			c["tti"] = settings.Tti.Value
+		}
+		if settings.UplinkCapacity != nil {
+			c["uplinkCapacity"] = settings.UplinkCapacity.Value
+		}
+		if settings.DownlinkCapacity != nil {
+			c["downlinkCapacity"] = settings.DownlinkCapacity.Value
+		}
+		c["congestion"] = settings.Congestion
+		if settings.ReadBuffer != nil {
+			c["readBufferSize"] = kcpBufferSize(settings.ReadBuffer.Size)
+		}
+		if settings.WriteBuffer != nil {
+			c["writeBufferSize"] = kcpBufferSize(settings.WriteBuffer.Size)
+		}
+		if settings.HeaderConfig != nil {
+			header, err := packetHeaderFromProtobuf(settings.HeaderConfig)
+			if err != nil {
+				return nil, err
+			}
+			c["header"] = header
+		}
+		if settings.Seed != nil {
+			c["seed"] = settings.Seed.Seed
+		}
+		if fec := settings.Fec; fec != nil {
+			c["fec"] = pbObject{
+				"dataShards":      fec.DataShards,
+				"parityShards":    fec.ParityShards,
+				"adaptive":        fec.Adaptive,
+				"maxParityShards": fec.MaxParityShards,
+			}
+		}
+	case *websocket.Config:
+		c.set("host", settings.Host)
+		c.set("path", pathWithEarlyData(settings.Path, settings.Ed))
+		c.set("headers", settings.Header)
+		c.set("acceptProxyProtocol", settings.AcceptProxyProtocol)
+		c.set("fallback", fallbackFromProtobuf(settings.Fallback))
+	case *httpupgrade.Config:
+		c.set("host", settings.Host)
+		c.set("path", pathWithEarlyData(settings.Path, settings.Ed))
+		c.set("headers", settings.Header)
+		c.set("acceptProxyProtocol", settings.AcceptProxyProtocol)
+		c.set("fallback", fallbackFromProtobuf(settings.Fallback))
+	case *splithttp.Config:
+		c.set("host", settings.Host)
+		c.set("path", settings.Path)
+		c.set("headers", settings.Header)
+		putRandRange(c, "scMaxConcurrentPosts", settings.ScMaxConcurrentPosts)
+		putRandRange(c, "scMaxEachPostBytes", settings.ScMaxEachPostBytes)
+		putRandRange(c, "scMinPostsIntervalMs", settings.ScMinPostsIntervalMs)
+		c.set("noSSEHeader", settings.NoSSEHeader)
+		putRandRange(c, "xPaddingBytes", settings.XPaddingBytes)
+		c.set("mode", settings.Mode)
+		if ds := settings.DownloadSettings; ds != nil {
+			download := pbObject{}
+			if ds.StreamSettings != nil {
+				stream, err := streamFromProtobuf(ds.StreamSettings)
+				if err != nil {
+					return nil, err
+				}
+				download = stream
+			}
+			download.set("address", pbAddress(ds.Address))
+			download.set("port", ds.Port)
+			c["downloadSettings"] = download
+		}
+		if xmux := settings.Xmux; xmux != nil {
+			x := pbObject{}
+			putRandRange(x, "maxConcurrency", xmux.MaxConcurrency)
+			putRandRange(x, "maxConnections", xmux.MaxConnections)
+			putRandRange(x, "cMaxReuseTimes", xmux.CMaxReuseTimes)
+			putRandRange(x, "cMaxLifetimeMs", xmux.CMaxLifetimeMs)
+			c["xmux"] = x
+		}
+		c.set("fallback", fallbackFromProtobuf(settings.Fallback))
+	case *http.Config:
+		c.set("host", settings.Host)
+		c.set("path", settings.Path)
+		c.set("read_idle_timeout", settings.IdleTimeout)
+		c.set("health_check_timeout", settings.HealthCheckTimeout)
+		c.set("method", settings.Method)
+		c.set("headers", httpHeadersFromProtobuf(settings.Header))
+	case *domainsocket.Config:
+		c.set("path", settings.Path)
+		c.set("abstract", settings.Abstract)
+		c.set("padding", settings.Padding)
+		c.set("seqpacket", settings.Seqpacket)
+		c.set("allowedUids", settings.AllowedUids)
+		c.set("allowedGids", settings.AllowedGids)
+		c.set("peerAttributes", settings.PeerAttributes)
+		if settings.Mode != 0 {
+			c["mode"] = strconv.FormatUint(uint64(settings.Mode), 8)
+		}
+		c.set("owner", settings.Owner)
+		c.set("group", settings.Group)
+	case *quic.Config:
+		c.set("key", settings.Key)
+		switch settings.Security.GetType() {
+		case protocol.SecurityType_AES128_GCM:
+			c["security"] = "aes-128-gcm"
+		case protocol.SecurityType_CHACHA20_POLY1305:
+			c["security"] = "chacha20-poly1305"
+		}
+		if settings.Header != nil {
+			header, err := packetHeaderFromProtobuf(settings.Header)
+			if err != nil {
+				return nil, err
+			}
+			c["header"] = header
+		}
+		c.set("initStreamReceiveWindow", settings.InitialStreamReceiveWindow)
+		c.set("maxStreamReceiveWindow", settings.MaxStreamReceiveWindow)
+		c.set("initConnReceiveWindow", settings.InitialConnectionReceiveWindow)
+		c.set("maxConnReceiveWindow", settings.MaxConnectionReceiveWindow)
+		c.set("keepAlivePeriod", settings.KeepAlivePeriod)
+		c.set("maxIdleTimeout", settings.MaxIdleTimeout)
+		c.set("maxIncomingStreams", settings.MaxIncomingStreams)
+		c.set("disablePathMTUDiscovery", settings.DisablePathMtuDiscovery)
+		c.set("0rtt", settings.ZeroRtt)
+		c.set("hopPorts", pbPortList(settings.HopPorts))
+		c.set("hopInterval", settings.HopInterval)
+	case *grpc.Config:
+		c.set("authority", settings.Authority)
+		c.set("serviceName", settings.ServiceName)
+		c.set("multiMode", settings.MultiMode)
+		c.set("idle_timeout", settings.IdleTimeout)
+		c.set("health_check_timeout", settings.HealthCheckTimeout)
+		c.set("permit_without_stream", settings.PermitWithoutStream)
+		c.set("initial_windows_size", settings.InitialWindowsSize)
+		c.set("user_agent", settings.UserAgent)
+	default:
+		return nil, errors.New("unknown transport settings: ", tm.Type)
+	}
+	return c, nil
+}
+
+func kcpBufferSize(size uint32) uint32 {
+	if size == 512*1024 {
+		return 0
+	}
+	return size / (1024 * 1024)
+}
+
+func pathWithEarlyData(path string, ed uint32) string {
+	if ed == 0 {
+		return path
+	}
+	if strings.Contains(path, "?") {
+		return path + "&ed=" + strconv.FormatUint(uint64(ed), 10)
+	}
+	return path + "?ed=" + strconv.FormatUint(uint64(ed), 10)
+}
+
+func putRandRange(o pbObject, key string, r *splithttp.RandRangeConfig) {
+	if r == nil {
+		return
+	}
+	if r.From == r.To {
+		o[key] = r.From
+	} else {
+		o[key] = pbRange(r.From, r.To)
+	}
+}
+
+// unixDestFromProtobuf reverses the padding of abstract unix socket
+// addresses written as "@@name" in the JSON config.
+func unixDestFromProtobuf(typ, dest string) (string, bool) {
+	if typ == "unix" && strings.HasSuffix(dest, "\x00") {
+		return "@" + strings.TrimRight(dest, "\x00"), true
+	}
+	return dest, false
+}
+
+func fallbackFromProtobuf(config *httpfallback.Config) pbObject {
+	if config == nil {
+		return nil
+	}
+	fallback := pbObject{}
+	dest, padded := unixDestFromProtobuf(config.Type, config.Dest)
+	if !padded {
+		fallback["type"] = config.Type
+	}
+	fallback["dest"] = dest
+	return fallback
+}
+
+func httpHeadersFromProtobuf(headers []*httpheader.Header) map[string][]string {
+	m := make(map[string][]string, len(headers))
+	for _, h := range headers {
+		m[h.Name] = append(m[h.Name], h.Value...)
+	}
+	return m
+}
+
+func tcpHeaderFromProtobuf(tm *serial.TypedMessage) (pbObject, error) {
+	instance, err := tm.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	switch header := instance.(type) {
+	case *noop.ConnectionConfig:
+		return pbObject{"type": "none"}, nil
+	case *httpheader.Config:
+		request := pbObject{}
+		if r := header.Request; r != nil {
+			request.set("version", r.Version.GetValue())
+			request.set("method", r.Method.GetValue())
+			request.set("path", r.Uri)
+			request.set("headers", httpHeadersFromProtobuf(r.Header))
+		}
+		response := pbObject{}
+		if r := header.Response; r != nil {
+			response.set("version", r.Version.GetValue())
+			if r.Status != nil {
+				response["status"] = r.Status.Code
+				response["reason"] = r.Status.Reason
+			}
+			response.set("headers", httpHeadersFromProtobuf(r.Header))
+		}
+		return pbObject{
+			"type":     "http",
+			"request":  request,
+			"response": response,
+		}, nil
+	default:
+		return nil, errors.New("unknown TCP header: ", tm.Type)
+	}
+}
+
+func packetHeaderFromProtobuf(tm *serial.TypedMessage) (pbObject, error) {
+	instance, err := tm.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	switch header := instance.(type) {
+	case *noop.Config:
+		return pbObject{"type": "none"}, nil
+	case *srtp.Config:
+		return pbObject{"type": "srtp"}, nil
+	case *utp.Config:
+		return pbObject{"type": "utp"}, nil
+	case *wechat.VideoConfig:
+		return pbObject{"type": "wechat-video"}, nil
+	case *tlsheader.PacketConfig:
+		return pbObject{"type": "dtls"}, nil
+	case *wireguard.WireguardConfig:
+		return pbObject{"type": "wireguard"}, nil
+	case *dnsheader.Config:
+		return pbObject{"type": "dns", "domain": header.Domain}, nil
+	default:
+		return nil, errors.New("unknown packet header: ", tm.Type)
+	}
+}
+
+func sockoptFromProtobuf(config *internet.SocketConfig) pbObject {
+	c := pbObject{}
+	c.set("mark", config.Mark)
+	switch {
+	case config.Tfo < 0:
+		c["tcpFastOpen"] = false
+	case config.Tfo > 0:
+		c["tcpFastOpen"] = config.Tfo
+	}
+	switch config.Tproxy {
+	case internet.SocketConfig_TProxy:
+		c["tproxy"] = "tproxy"
+	case internet.SocketConfig_Redirect:
+		c["tproxy"] = "redirect"
+	}
+	c.set("acceptProxyProtocol", config.AcceptProxyProtocol)
+	c.set("domainStrategy", domainStrategyNames[config.DomainStrategy])
+	c.set("dialerProxy", config.DialerProxy)
+	c.set("tcpKeepAliveInterval", config.TcpKeepAliveInterval)
+	c.set("tcpKeepAliveIdle", config.TcpKeepAliveIdle)
+	c.set("tcpCongestion", config.TcpCongestion)
+	c.set("tcpWindowClamp", config.TcpWindowClamp)
+	c.set("tcpMaxSeg", config.TcpMaxSeg)
+	c.set("tcpNoDelay", config.TcpNoDelay)
+	c.set("tcpUserTimeout", config.TcpUserTimeout)
+	c.set("v6only", config.V6Only)
+	c.set("interface", config.Interface)
+	c.set("tcpMptcp", config.TcpMptcp)
+	if len(config.CustomSockopt) > 0 {
+		sockopts := make([]pbObject, 0, len(config.CustomSockopt))
+		for _, opt := range config.CustomSockopt {
+			sockopt := pbObject{}
+			sockopt.set("level", opt.Level)
+			sockopt.set("opt", opt.Opt)
+			sockopt.set("value", opt.Value)
+			sockopt.set("type", opt.Type)
+			sockopts = append(sockopts, sockopt)
+		}
+		c["customSockopt"] = sockopts
+	}
+	c.set("inheritedFdName", config.InheritedFdName)
+	return c
+}
+
+func base64List(list [][]byte) []string {
+	encoded := make([]string, 0, len(list))
+	for _, b := range list {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(b))
+	}
+	return encoded
+}
+
+func tlsFromProtobuf(config *tls.Config) pbObject {
+	c := pbObject{}
+	c.set("allowInsecure", config.AllowInsecure)
+	c.set("serverName", config.ServerName)
+	c.set("alpn", config.NextProtocol)
+	c.set("enableSessionResumption", config.EnableSessionResumption)
+	c.set("disableSystemRoot", config.DisableSystemRoot)
+	c.set("minVersion", config.MinVersion)
+	c.set("maxVersion", config.MaxVersion)
+	c.set("cipherSuites", config.CipherSuites)
+	c.set("fingerprint", config.Fingerprint)
+	c.set("rejectUnknownSni", config.RejectUnknownSni)
+	if config.PinnedPeerCertificateChainSha256 != nil {
+		c["pinnedPeerCertificateChainSha256"] = base64List(config.PinnedPeerCertificateChainSha256)
+	}
+	if config.PinnedPeerCertificatePublicKeySha256 != nil {
+		c["pinnedPeerCertificatePublicKeySha256"] = base64List(config.PinnedPeerCertificatePublicKeySha256)
+	}
+	c.set("masterKeyLog", config.MasterKeyLog)
+	if len(config.EchServerKeys) > 0 {
+		keys := make([]pbObject, 0, len(config.EchServerKeys))
+		for _, key := range config.EchServerKeys {
+			keys = append(keys, pbObject{"key": []string{base64.StdEncoding.EncodeToString(key)}})
+		}
+		c["echServerKeys"] = keys
+	}
+	if len(config.EchConfigList) > 0 {
+		c["echConfigList"] = base64.StdEncoding.EncodeToString(config.EchConfigList)
+	}
+	c.set("echQueryDomain", config.EchQueryDomain)
+	c.set("echForceQuery", config.EchForceQuery)
+
+	if len(config.Certificate) > 0 {
+		certs := make([]pbObject, 0, len(config.Certificate))
+		for _, cert := range config.Certificate {
+			certs = append(certs, certificateFromProtobuf(cert))
+		}
+		c["certificates"] = certs
+	}
+	return c
+}
+
+func certificateFromProtobuf(cert *tls.Certificate) pbObject {
+	c := pbObject{}
+	if cert.CertificatePath != "" {
+		c["certificateFile"] = cert.CertificatePath
+	} else {
+		c["certificate"] = strings.Split(string(cert.Certificate), "\n")
+	}
+	if cert.KeyPath != "" {
+		c["keyFile"] = cert.KeyPath
+	} else if len(cert.Key) > 0 {
+		c["key"] = strings.Split(string(cert.Key), "\n")
+	}
+	switch cert.Usage {
+	case tls.Certificate_AUTHORITY_VERIFY:
+		c["usage"] = "verify"
+	case tls.Certificate_AUTHORITY_ISSUE:
+		c["usage"] = "issue"
+	}
+	c.set("ocspStapling", cert.OcspStapling)
+	if cert.CertificatePath != "" || cert.KeyPath != "" {
+		c.set("oneTimeLoading", cert.OneTimeLoading)
+	}
+	c.set("buildChain", cert.BuildChain)
+	return c
+}
+
+func realityFromProtobuf(config *reality.Config) pbObject {
+	c := pbObject{}
+	c.set("show", config.Show)
+	c.set("masterKeyLog", config.MasterKeyLog)
+	if config.Dest != "" {
+		dest, padded := unixDestFromProtobuf(config.Type, config.Dest)
+		c["dest"] = dest
+		if !padded {
+			c["type"] = config.Type
+		}
+		c.set("xver", config.Xver)
+		c.set("serverNames", config.ServerNames)
+		c["privateKey"] = base64.RawURLEncoding.EncodeToString(config.PrivateKey)
+		c.set("minClientVer", clientVersion(config.MinClientVer))
+		c.set("maxClientVer", clientVersion(config.MaxClientVer))
+		c.set("maxTimeDiff", config.MaxTimeDiff)
+		shortIds := make([]string, 0, len(config.ShortIds))
+		for _, id := range config.ShortIds {
+			shortIds = append(shortIds, hex.EncodeToString(id))
+		}
+		c["shortIds"] = shortIds
+	} else {
+		c["fingerprint"] = config.Fingerprint
+		c.set("serverName", config.ServerName)
+		c["publicKey"] = base64.RawURLEncoding.EncodeToString(config.PublicKey)
+		c["shortId"] = hex.EncodeToString(config.ShortId)
+		c.set("spiderX", spiderXFromProtobuf(config.SpiderX, config.SpiderY))
+	}
+	c.set("serverRandPacket", config.ServerRandPacket)
+	c.set("clientRandPacket", config.ClientRandPacket)
+	c.set("serverRandPacketCount", config.ServerRandPacketCount)
+	c.set("clientRandPacketCount", config.ClientRandPacketCount)
+	c.set("splitPacket", config.SplitPacket)
+	c.set("paddingSize", config.PaddingSize)
+	c.set("subchunkSize", config.SubchunkSize)
+	return c
+}
+
+func clientVersion(v []byte) string {
+	if len(v) != 3 {
+		return ""
+	}
+	return strconv.Itoa(int(v[0])) + "." + strconv.Itoa(int(v[1])) + "." + strconv.Itoa(int(v[2]))
+}
+
+// spiderXFromProtobuf puts the spider parameters parsed out of "spiderX"
+// back into its query.
+func spiderXFromProtobuf(spiderX string, spiderY []int64) string {
+	if len(spiderY) != 10 {
+		return spiderX
+	}
+	u, err := url.Parse(spiderX)
+	if err != nil {
+		return spiderX
+	}
+	q := u.Query()
+	for i, param := range []string{"p", "c", "t", "i", "r"} {
+		from, to := spiderY[2*i], spiderY[2*i+1]
+		switch {
+		case from == 0 && to == 0:
+		case from == to:
+			q.Set(param, strconv.FormatInt(from, 10))
+		default:
+			q.Set(param, strconv.FormatInt(from, 10)+"-"+strconv.FormatInt(to, 10))
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func shadowtlsFromProtobuf(config *shadowtls.Config) pbObject {
+	c := pbObject{}
+	c.set("password", config.Password)
+	c.set("dest", config.Dest)
+	c.set("serverName", config.ServerName)
+	c.set("fingerprint", config.Fingerprint)
+	return c
+}
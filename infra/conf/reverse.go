@@ -1,32 +1,85 @@
 package conf
 
 import (
+	"strings"
+
 	"github.com/luckyluke-a/xray-core/app/reverse"
+	"github.com/luckyluke-a/xray-core/common/errors"
 	"google.golang.org/protobuf/proto"
 )
 
+type ReverseServiceConfig struct {
+	Name    string    `json:"name"`
+	Address string    `json:"address"`
+	Port    *PortList `json:"port"`
+}
+
+func (c *ReverseServiceConfig) Build() (*reverse.Service, error) {
+	service := &reverse.Service{
+		Name:    c.Name,
+		Address: c.Address,
+	}
+	if c.Port != nil {
+		service.PortList = c.Port.Build()
+	}
+	if c.Name != "" && (c.Address == "" || strings.Contains(c.Address, "/") || service.PortList == nil) {
+		return nil, errors.New("reverse service ", c.Name, " requires a domain or IP and a port")
+	}
+	return service, nil
+}
+
 type BridgeConfig struct {
-	Tag    string `json:"tag"`
-	Domain string `json:"domain"`
+	Tag        string                 `json:"tag"`
+	Domain     string                 `json:"domain"`
+	Name       string                 `json:"name"`
+	MinWorkers uint32                 `json:"minWorkers"`
+	MaxWorkers uint32                 `json:"maxWorkers"`
+	Services   []ReverseServiceConfig `json:"services"`
 }
 
 func (c *BridgeConfig) Build() (*reverse.BridgeConfig, error) {
-	return &reverse.BridgeConfig{
-		Tag:    c.Tag,
-		Domain: c.Domain,
-	}, nil
+	if c.MaxWorkers > 0 && c.MinWorkers > c.MaxWorkers {
+		return nil, errors.New("bridge minWorkers is greater than maxWorkers")
+	}
+	config := &reverse.BridgeConfig{
+		Tag:        c.Tag,
+		Domain:     c.Domain,
+		Name:       c.Name,
+		MinWorkers: c.MinWorkers,
+		MaxWorkers: c.MaxWorkers,
+	}
+	for _, s := range c.Services {
+		service, err := s.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Services = append(config.Services, service)
+	}
+	return config, nil
 }
 
 type PortalConfig struct {
-	Tag    string `json:"tag"`
-	Domain string `json:"domain"`
+	Tag      string            `json:"tag"`
+	Domain   string            `json:"domain"`
+	Strategy string            `json:"strategy"`
+	Services map[string]string `json:"services"`
 }
 
 func (c *PortalConfig) Build() (*reverse.PortalConfig, error) {
-	return &reverse.PortalConfig{
-		Tag:    c.Tag,
-		Domain: c.Domain,
-	}, nil
+	config := &reverse.PortalConfig{
+		Tag:      c.Tag,
+		Domain:   c.Domain,
+		Services: c.Services,
+	}
+	switch strings.ToLower(c.Strategy) {
+	case "", "leastconnections":
+		config.Strategy = reverse.PortalConfig_LEAST_CONNECTIONS
+	case "leastlatency":
+		config.Strategy = reverse.PortalConfig_LEAST_LATENCY
+	default:
+		return nil, errors.New("unknown portal strategy: ", c.Strategy)
+	}
+	return config, nil
 }
 
 type ReverseConfig struct {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/luckyluke-a/xray-core/app/reverse"
+	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/infra/conf"
 )
 
@@ -41,5 +42,74 @@ func TestReverseConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"bridges": [{
+					"tag": "test",
+					"domain": "test.example.com",
+					"name": "office",
+					"minWorkers": 2,
+					"maxWorkers": 8
+				}],
+				"portals": [{
+					"tag": "test",
+					"domain": "test.example.com",
+					"strategy": "leastLatency"
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &reverse.Config{
+				BridgeConfig: []*reverse.BridgeConfig{
+					{Tag: "test", Domain: "test.example.com", Name: "office", MinWorkers: 2, MaxWorkers: 8},
+				},
+				PortalConfig: []*reverse.PortalConfig{
+					{Tag: "test", Domain: "test.example.com", Strategy: reverse.PortalConfig_LEAST_LATENCY},
+				},
+			},
+		},
+		{
+			Input: `{
+				"bridges": [{
+					"tag": "test",
+					"domain": "test.example.com",
+					"services": [
+						{"name": "web", "address": "192.168.1.10", "port": 80},
+						{"address": "10.0.0.0/8", "port": "1000-2000"}
+					]
+				}],
+				"portals": [{
+					"tag": "test",
+					"domain": "test.example.com",
+					"services": {"web-in": "web"}
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &reverse.Config{
+				BridgeConfig: []*reverse.BridgeConfig{
+					{
+						Tag:    "test",
+						Domain: "test.example.com",
+						Services: []*reverse.Service{
+							{
+								Name:    "web",
+								Address: "192.168.1.10",
+								PortList: &net.PortList{
+									Range: []*net.PortRange{{From: 80, To: 80}},
+								},
+							},
+							{
+								Address: "10.0.0.0/8",
+								PortList: &net.PortList{
+									Range: []*net.PortRange{{From: 1000, To: 2000}},
+								},
+							},
+						},
+					},
+				},
+				PortalConfig: []*reverse.PortalConfig{
+					{Tag: "test", Domain: "test.example.com", Services: map[string]string{"web-in": "web"}},
+				},
+			},
+		},
 	})
 }
@@ -0,0 +1,169 @@
+package conf
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is a JSON Schema, which is generated from the types of the config.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	If                   *Schema            `json:"if,omitempty"`
+	Then                 *Schema            `json:"then,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// deprecatedFields are the fields kept for compatibility, by the type.
+var deprecatedFields = map[reflect.Type][]string{
+	reflect.TypeOf(Config{}):       {"inbound", "outbound", "inboundDetour", "outboundDetour"},
+	reflect.TypeOf(RouterConfig{}): {"settings"},
+	reflect.TypeOf(QUICConfig{}):   {"key", "header"},
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+type schemaGenerator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+// GenerateSchema returns the JSON Schema of Config. Fields which have their
+// own JSON formats, like port ranges and protocol settings of unknown
+// protocols, accept any value.
+func GenerateSchema() *Schema {
+	g := &schemaGenerator{
+		defs:  make(map[string]*Schema),
+		names: make(map[reflect.Type]string),
+	}
+	root := g.typeSchema(reflect.TypeOf(Config{}))
+	g.addProtocolSettings(reflect.TypeOf(InboundDetourConfig{}), inboundConfigLoader)
+	g.addProtocolSettings(reflect.TypeOf(OutboundDetourConfig{}), outboundConfigLoader)
+	return &Schema{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Ref:    root.Ref,
+		Defs:   g.defs,
+	}
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// base64
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		return &Schema{Ref: "#/$defs/" + g.structDef(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// structDef adds the definition of a struct, and returns its name.
+func (g *schemaGenerator) structDef(t reflect.Type) string {
+	if name, found := g.names[t]; found {
+		return name
+	}
+	name := t.Name()
+	if name == "" {
+		name = "Anonymous"
+	}
+	for i := 2; g.defs[name] != nil; i++ {
+		name = t.Name() + strconv.Itoa(i)
+	}
+	s := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	g.names[t] = name
+	g.defs[name] = s
+	g.addFields(s, t)
+	for _, field := range deprecatedFields[t] {
+		if p := s.Properties[field]; p != nil {
+			p.Deprecated = true
+		}
+	}
+	return name
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(s, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = g.typeSchema(field.Type)
+	}
+}
+
+// addProtocolSettings validates the settings of inbounds or outbounds by the
+// protocol.
+func (g *schemaGenerator) addProtocolSettings(t reflect.Type, loader *JSONConfigLoader) {
+	s := g.defs[g.structDef(t)]
+	ids := make([]string, 0, len(loader.cache))
+	for id := range loader.cache {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		s.AllOf = append(s.AllOf, &Schema{
+			If: &Schema{
+				Properties: map[string]*Schema{loader.idKey: {Const: id}},
+				Required:   []string{loader.idKey},
+			},
+			Then: &Schema{
+				Properties: map[string]*Schema{loader.configKey: g.typeSchema(reflect.TypeOf(loader.cache[id]()))},
+			},
+		})
+	}
+}
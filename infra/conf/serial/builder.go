@@ -1,6 +1,7 @@
 package serial
 
 import (
+	"bytes"
 	"context"
 	"io"
 
@@ -31,7 +32,15 @@ func mergeConfigs(files []*core.ConfigSource) (*conf.Config, error) {
 		if err != nil {
 			return nil, errors.New("failed to read config: ", file).Base(err)
 		}
-		c, err := ReaderDecoderByFormat[file.Format](r)
+		content, err := toJSON(r, file.Format)
+		if err != nil {
+			return nil, errors.New("failed to decode config: ", file).Base(err)
+		}
+		content, err = expandTemplate(content, file.Name)
+		if err != nil {
+			return nil, errors.New("failed to expand config: ", file).Base(err)
+		}
+		c, err := DecodeJSONConfig(bytes.NewReader(content))
 		if err != nil {
 			return nil, errors.New("failed to decode config: ", file).Base(err)
 		}
@@ -0,0 +1,72 @@
+package serial
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ghodss/yaml"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/infra/conf"
+	"github.com/pelletier/go-toml"
+)
+
+// EncodeConfig converts a built config back into a config file of the given
+// format, which is one of "json", "yaml" and "toml".
+func EncodeConfig(config *core.Config, format string) ([]byte, error) {
+	c, err := conf.ConfigFromProtobuf(config)
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, errors.New("failed to marshal config").Base(err)
+	}
+
+	switch format {
+	case "json":
+		return append(content, '\n'), nil
+	case "yaml":
+		content, err = yaml.JSONToYAML(content)
+		if err != nil {
+			return nil, errors.New("failed to convert json to yaml").Base(err)
+		}
+		return content, nil
+	case "toml":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		configMap := make(map[string]interface{})
+		if err := decoder.Decode(&configMap); err != nil {
+			return nil, errors.New("failed to convert json to map").Base(err)
+		}
+		tree, err := toml.TreeFromMap(tomlValue(configMap).(map[string]interface{}))
+		if err != nil {
+			return nil, errors.New("failed to convert map to toml").Base(err)
+		}
+		return tree.Marshal()
+	default:
+		return nil, errors.New("unsupported config format: ", format)
+	}
+}
+
+// tomlValue replaces JSON numbers with the integer or float types known to
+// go-toml.
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = tomlValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = tomlValue(item)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
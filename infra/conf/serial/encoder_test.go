@@ -0,0 +1,54 @@
+package serial_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/infra/conf/serial"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeConfig(t *testing.T) {
+	config, err := serial.LoadJSONConfig(strings.NewReader(`{
+		"log": {"loglevel": "debug"},
+		"routing": {"rules": [{"ip": ["10.0.0.0/8"], "port": 53, "outboundTag": "direct"}]},
+		"inbounds": [{
+			"tag": "in",
+			"port": 1080,
+			"protocol": "socks",
+			"settings": {"udp": true}
+		}],
+		"outbounds": [{
+			"tag": "direct",
+			"protocol": "freedom",
+			"settings": {"fragment": {"packets": "1-3", "length": "100-200", "interval": "10"}}
+		}]
+	}`))
+	common.Must(err)
+
+	loaders := map[string]func(*bytes.Reader) (*core.Config, error){
+		"json": func(r *bytes.Reader) (*core.Config, error) { return serial.LoadJSONConfig(r) },
+		"yaml": func(r *bytes.Reader) (*core.Config, error) { return serial.LoadYAMLConfig(r) },
+		"toml": func(r *bytes.Reader) (*core.Config, error) { return serial.LoadTOMLConfig(r) },
+	}
+	for format, load := range loaders {
+		content, err := serial.EncodeConfig(config, format)
+		if err != nil {
+			t.Fatalf("failed to encode config as %s: %v", format, err)
+		}
+		decoded, err := load(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("failed to load %s config %s: %v", format, content, err)
+		}
+		if !proto.Equal(config, decoded) {
+			t.Errorf("%s config %s does not load into the original config", format, content)
+		}
+	}
+
+	if _, err := serial.EncodeConfig(config, "xml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
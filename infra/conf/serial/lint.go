@@ -0,0 +1,328 @@
+package serial
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/infra/conf"
+	json_reader "github.com/luckyluke-a/xray-core/infra/conf/json"
+	"github.com/luckyluke-a/xray-core/main/confloader"
+	"github.com/pelletier/go-toml"
+)
+
+// LintProblem is a problem found in a config file.
+type LintProblem struct {
+	conf.LintIssue
+	// File is empty if the problem is not in a single file, like the
+	// failure to build the merged config.
+	File string
+	// Line is 0 if unknown. Only JSON files without templates have line
+	// numbers.
+	Line int
+}
+
+func (p LintProblem) String() string {
+	var b strings.Builder
+	if p.File != "" {
+		b.WriteString(p.File)
+		if p.Line > 0 {
+			b.WriteString(":" + strconv.Itoa(p.Line))
+		}
+		b.WriteString(": ")
+	}
+	if p.Warning {
+		b.WriteString("warning: ")
+	}
+	if p.Path != "" {
+		b.WriteString(p.Path + ": ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+type lintFile struct {
+	name   string
+	lines  map[string]int
+	value  map[string]interface{}
+	config *conf.Config
+}
+
+// line returns the line of the path, or of its closest parent.
+func (f *lintFile) line(path string) int {
+	path = strings.ToLower(path)
+	for {
+		if line, found := f.lines[path]; found {
+			return line
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return 0
+		}
+		path = path[:i]
+	}
+}
+
+func (f *lintFile) problem(issue conf.LintIssue) LintProblem {
+	return LintProblem{LintIssue: issue, File: f.name, Line: f.line(issue.Path)}
+}
+
+// LintConfigFiles checks the config files against the schema of the config
+// one by one, then checks the merged config of the files that decode with
+// the linters registered in infra/conf, and by building it if there are no
+// errors so far. The problems are sorted by the files and the lines.
+func LintConfigFiles(files []*core.ConfigSource) []LintProblem {
+	problems := lintConfigFiles(files)
+	order := make(map[string]int, len(files))
+	for i, file := range files {
+		order[file.Name] = i
+	}
+	order[""] = len(files)
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if order[a.File] != order[b.File] {
+			return order[a.File] < order[b.File]
+		}
+		return a.Line < b.Line
+	})
+	return problems
+}
+
+func lintConfigFiles(files []*core.ConfigSource) []LintProblem {
+	var problems []LintProblem
+	schema := conf.GenerateSchema()
+
+	var lintFiles []*lintFile
+	for _, file := range files {
+		f, fileProblems := lintConfigFile(file, schema)
+		problems = append(problems, fileProblems...)
+		if f != nil {
+			lintFiles = append(lintFiles, f)
+		}
+	}
+	if len(lintFiles) == 0 {
+		return problems
+	}
+
+	merged := &conf.Config{}
+	for i, f := range lintFiles {
+		if i == 0 {
+			*merged = *f.config
+			continue
+		}
+		merged.Override(f.config, f.name)
+	}
+	for _, issue := range conf.LintConfigureFile(merged) {
+		problems = append(problems, locateIssue(lintFiles, merged, issue))
+	}
+
+	if !hasError(problems) {
+		if _, err := merged.Build(); err != nil {
+			problems = append(problems, LintProblem{LintIssue: conf.LintIssue{Message: err.Error()}})
+		}
+	}
+	return problems
+}
+
+func hasError(problems []LintProblem) bool {
+	for _, p := range problems {
+		if !p.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+func lintConfigFile(file *core.ConfigSource, schema *conf.Schema) (*lintFile, []LintProblem) {
+	fail := func(err error) (*lintFile, []LintProblem) {
+		return nil, []LintProblem{{File: file.Name, LintIssue: conf.LintIssue{Message: err.Error()}}}
+	}
+
+	r, err := confloader.LoadConfig(file.Name)
+	if err != nil {
+		return fail(errors.New("failed to read config").Base(err))
+	}
+	content, err := toJSON(r, file.Format)
+	if err != nil {
+		return fail(err)
+	}
+	expanded, err := expandTemplate(content, file.Name)
+	if err != nil {
+		return fail(errors.New("failed to expand config").Base(err))
+	}
+	hasLines := file.Format == "json" && bytes.Equal(expanded, content)
+	content = expanded
+
+	f := &lintFile{name: file.Name}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		problem := LintProblem{File: file.Name, LintIssue: conf.LintIssue{Message: err.Error()}}
+		if err, ok := err.(*json.SyntaxError); ok && file.Format == "json" {
+			if pos := findOffset(content, int(err.Offset)-1); pos != nil {
+				problem.Line = pos.line
+			}
+		}
+		return nil, []LintProblem{problem}
+	}
+	if hasLines {
+		f.lines = indexLines(content)
+	}
+
+	var problems []LintProblem
+	for _, issue := range schema.Validate(value) {
+		problems = append(problems, f.problem(issue))
+	}
+
+	// the config is still checked with the others if it decodes despite the
+	// problems found by the schema
+	f.value, _ = value.(map[string]interface{})
+	f.config, err = DecodeJSONConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, append(problems, LintProblem{File: file.Name, LintIssue: conf.LintIssue{Message: err.Error()}})
+	}
+	return f, problems
+}
+
+// toJSON converts the config to JSON without comments. The lines of JSON
+// files are kept.
+func toJSON(r io.Reader, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		content, err := io.ReadAll(&json_reader.Reader{Reader: r})
+		if err != nil {
+			return nil, errors.New("failed to read config").Base(err)
+		}
+		return content, nil
+	case "yaml":
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, errors.New("failed to read config").Base(err)
+		}
+		content, err = yaml.YAMLToJSON(content)
+		if err != nil {
+			return nil, errors.New("failed to convert yaml to json").Base(err)
+		}
+		return content, nil
+	case "toml":
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, errors.New("failed to read config").Base(err)
+		}
+		configMap := make(map[string]interface{})
+		if err := toml.Unmarshal(content, &configMap); err != nil {
+			return nil, errors.New("failed to convert toml to map").Base(err)
+		}
+		return json.Marshal(configMap)
+	default:
+		return nil, errors.New("unsupported config format: ", format)
+	}
+}
+
+// indexLines returns the lines of the values in the JSON content, by the
+// lower-cased paths.
+func indexLines(content []byte) map[string]int {
+	lines := make(map[string]int)
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	line := func() int {
+		if pos := findOffset(content, int(decoder.InputOffset())-1); pos != nil {
+			return pos.line
+		}
+		return 0
+	}
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if _, found := lines[path]; !found {
+			lines[path] = line()
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				p := conf.JSONPath(path, strings.ToLower(key.(string)))
+				lines[p] = line()
+				if err := walk(p); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if err := walk(conf.JSONPath(path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err
+	}
+	walk("")
+	return lines
+}
+
+// locateIssue finds the file of an issue of the merged config. Inbounds and
+// outbounds are found by themselves, and other fields are in the last file
+// defining them.
+func locateIssue(files []*lintFile, merged *conf.Config, issue conf.LintIssue) LintProblem {
+	root, rest, _ := strings.Cut(issue.Path, ".")
+	name, index, indexed := strings.Cut(root, "[")
+	if indexed {
+		i, _ := strconv.Atoi(strings.TrimSuffix(index, "]"))
+		switch name {
+		case "inbounds":
+			if i < len(merged.InboundConfigs) {
+				in := merged.InboundConfigs[i]
+				for k := len(files) - 1; k >= 0; k-- {
+					for j, c := range files[k].config.InboundConfigs {
+						if c.Tag == in.Tag && c.Protocol == in.Protocol && c.Settings == in.Settings {
+							return files[k].problem(reindex(issue, name, j, rest))
+						}
+					}
+				}
+			}
+		case "outbounds":
+			if i < len(merged.OutboundConfigs) {
+				out := merged.OutboundConfigs[i]
+				for k := len(files) - 1; k >= 0; k-- {
+					for j, c := range files[k].config.OutboundConfigs {
+						if c.Tag == out.Tag && c.Protocol == out.Protocol && c.Settings == out.Settings {
+							return files[k].problem(reindex(issue, name, j, rest))
+						}
+					}
+				}
+			}
+		}
+	}
+	for k := len(files) - 1; k >= 0; k-- {
+		for key := range files[k].value {
+			if strings.EqualFold(key, name) {
+				return files[k].problem(issue)
+			}
+		}
+	}
+	return LintProblem{LintIssue: issue}
+}
+
+// reindex replaces the index of the path with the one in the file.
+func reindex(issue conf.LintIssue, name string, index int, rest string) conf.LintIssue {
+	issue.Path = conf.JSONPath(name, index)
+	if rest != "" {
+		issue.Path += "." + rest
+	}
+	return issue
+}
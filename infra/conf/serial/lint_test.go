@@ -0,0 +1,81 @@
+package serial_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/infra/conf/serial"
+	_ "github.com/luckyluke-a/xray-core/main/confloader/external"
+)
+
+func TestLintConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	if err := os.WriteFile(base, []byte(`{
+	// outbounds
+	"outbounds": [
+		{"tag": "direct", "protocol": "freedom"}
+	],
+	"routing": {
+		"rules": [
+			{"outboundTag": "proxy"}
+		]
+	}
+}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	override := filepath.Join(dir, "override.json")
+	if err := os.WriteFile(override, []byte(`{
+	"outbounds": [
+		{"tag": "direct", "protocol": "freedom", "proxySettings": {"tag": "none"}}
+	],
+	"log": {"level": "debug"}
+}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	problems := serial.LintConfigFiles([]*core.ConfigSource{
+		{Name: base, Format: "json"},
+		{Name: override, Format: "json"},
+	})
+	// the merged config is checked despite the unknown field
+	expected := []string{
+		base + `:8: routing.rules[0].outboundTag: outbound "proxy" not found`,
+		override + `:3: outbounds[0].proxySettings.tag: outbound "none" not found`,
+		override + `:5: log.level: unknown field "level"`,
+	}
+	if len(problems) != len(expected) {
+		t.Fatal("unexpected problems: ", problems)
+	}
+	for i, p := range problems {
+		if p.String() != expected[i] {
+			t.Error("expected ", expected[i], ", but got ", p.String())
+		}
+	}
+
+	if err := os.WriteFile(override, []byte(`{
+	"outbounds": [
+		{"tag": "direct", "protocol": "freedom", "proxySettings": {"tag": "none"}}
+	]
+}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	problems = serial.LintConfigFiles([]*core.ConfigSource{
+		{Name: base, Format: "json"},
+		{Name: override, Format: "json"},
+	})
+	expected = []string{
+		base + `:8: routing.rules[0].outboundTag: outbound "proxy" not found`,
+		override + `:3: outbounds[0].proxySettings.tag: outbound "none" not found`,
+	}
+	if len(problems) != len(expected) {
+		t.Fatal("unexpected problems: ", problems)
+	}
+	for i, p := range problems {
+		if p.String() != expected[i] {
+			t.Error("expected ", expected[i], ", but got ", p.String())
+		}
+	}
+}
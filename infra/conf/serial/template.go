@@ -0,0 +1,335 @@
+package serial
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/main/confloader"
+)
+
+const (
+	// templateKey is the top-level field to enable templates in a file.
+	templateKey = "template"
+	// templateSnippetsKey is the top-level field of the named snippets.
+	templateSnippetsKey = "snippets"
+	templateIncludeKey  = "$include"
+	templateSnippetKey  = "$snippet"
+	maxTemplateDepth    = 16
+)
+
+// templateExpander expands the templates of a config file with the top-level
+// "template": true, and of the files included by it:
+//
+//   - "${NAME}" or "${env:NAME}" in strings is replaced by the environment
+//     variable, and "${file:path}" by the content of the file without the
+//     trailing newline. "${NAME:-default}" is replaced by the default if it's
+//     unset or empty, and "$${" is a literal "${".
+//   - {"$include": "path"} is replaced by the content of the file, which is
+//     JSON, YAML or TOML by the extension. An included array in an array is
+//     inlined.
+//   - {"$snippet": "name"} is replaced by the snippet in the top-level
+//     "snippets" of the file.
+//
+// Other fields next to "$include" or "$snippet" override the fields of the
+// included object. Paths are relative to the file.
+type templateExpander struct {
+	lookupEnv func(string) (string, bool)
+	snippets  map[string]interface{}
+	// stack is the files and snippets being expanded, to find cycles.
+	stack []string
+}
+
+// expandTemplate returns the config with the templates expanded. The content
+// is returned as is if templates are not enabled, to keep the lines for
+// errors.
+func expandTemplate(content []byte, name string) ([]byte, error) {
+	e := &templateExpander{
+		lookupEnv: os.LookupEnv,
+		stack:     []string{name},
+	}
+	value, err := decodeTemplate(content)
+	if err != nil {
+		// let the decoder report the error with the line
+		return content, nil
+	}
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return content, nil
+	}
+	flag, found := root[templateKey]
+	if !found {
+		return content, nil
+	}
+	enabled, ok := flag.(bool)
+	if !ok {
+		return nil, errors.New(templateKey, " must be a boolean")
+	}
+	delete(root, templateKey)
+	if !enabled {
+		return json.Marshal(root)
+	}
+	if snippets, found := root[templateSnippetsKey]; found {
+		e.snippets, ok = snippets.(map[string]interface{})
+		if !ok {
+			return nil, errors.New(templateSnippetsKey, " must be an object")
+		}
+		delete(root, templateSnippetsKey)
+	}
+	value, err = e.expand(root, templateDir(name))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func decodeTemplate(content []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// templateDir returns the dir of relative paths in the file. It's the
+// working dir for stdin and remote files.
+func templateDir(name string) string {
+	if name == "stdin:" || strings.Contains(name, "://") {
+		return ""
+	}
+	return filepath.Dir(name)
+}
+
+func templatePath(dir string, path string) string {
+	if dir == "" || filepath.IsAbs(path) || strings.Contains(path, "://") || path == "stdin:" {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (e *templateExpander) push(name string) error {
+	for _, s := range e.stack {
+		if s == name {
+			return errors.New("circular template: ", strings.Join(append(e.stack, name), " -> "))
+		}
+	}
+	if len(e.stack) >= maxTemplateDepth {
+		return errors.New("too deep template: ", name)
+	}
+	e.stack = append(e.stack, name)
+	return nil
+}
+
+func (e *templateExpander) pop() {
+	e.stack = e.stack[:len(e.stack)-1]
+}
+
+func (e *templateExpander) expand(value interface{}, dir string) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		return e.interpolate(value, dir)
+	case []interface{}:
+		result := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			_, isDirective := directive(item)
+			expanded, err := e.expand(item, dir)
+			if err != nil {
+				return nil, err
+			}
+			if items, ok := expanded.([]interface{}); ok && isDirective {
+				result = append(result, items...)
+				continue
+			}
+			result = append(result, expanded)
+		}
+		return result, nil
+	case map[string]interface{}:
+		if key, ok := directive(value); ok {
+			return e.expandDirective(value, key, dir)
+		}
+		for k, v := range value {
+			expanded, err := e.expand(v, dir)
+			if err != nil {
+				return nil, err
+			}
+			value[k] = expanded
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// directive returns the directive key of an object, if any.
+func directive(value interface{}) (string, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	for _, key := range []string{templateIncludeKey, templateSnippetKey} {
+		if _, found := obj[key]; found {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func (e *templateExpander) expandDirective(obj map[string]interface{}, key string, dir string) (interface{}, error) {
+	arg, err := e.expand(obj[key], dir)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := arg.(string)
+	if !ok || name == "" {
+		return nil, errors.New(key, " must be a non-empty string")
+	}
+
+	var base interface{}
+	if key == templateIncludeKey {
+		base, err = e.include(templatePath(dir, name))
+	} else {
+		base, err = e.snippet(name, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(obj) == 1 {
+		return base, nil
+	}
+
+	baseObj, ok := base.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("failed to override ", key, " ", name, ": not an object")
+	}
+	for k, v := range obj {
+		if k == key {
+			continue
+		}
+		expanded, err := e.expand(v, dir)
+		if err != nil {
+			return nil, err
+		}
+		baseObj[k] = expanded
+	}
+	return baseObj, nil
+}
+
+func (e *templateExpander) include(path string) (interface{}, error) {
+	if err := e.push(path); err != nil {
+		return nil, err
+	}
+	defer e.pop()
+
+	r, err := confloader.LoadConfig(path)
+	if err != nil {
+		return nil, errors.New("failed to read included config: ", path).Base(err)
+	}
+	format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(path), "."))
+	if format == "" {
+		format = "json"
+	}
+	content, err := toJSON(r, format)
+	if err != nil {
+		return nil, errors.New("failed to read included config: ", path).Base(err)
+	}
+	value, err := decodeTemplate(content)
+	if err != nil {
+		return nil, errors.New("failed to decode included config: ", path).Base(err)
+	}
+	return e.expand(value, templateDir(path))
+}
+
+func (e *templateExpander) snippet(name string, dir string) (interface{}, error) {
+	value, found := e.snippets[name]
+	if !found {
+		return nil, errors.New("snippet not found: ", name)
+	}
+	if err := e.push(templateSnippetKey + ":" + name); err != nil {
+		return nil, err
+	}
+	defer e.pop()
+	// expand a copy, as a snippet can be used many times
+	return e.expand(copyTemplate(value), dir)
+}
+
+func copyTemplate(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = copyTemplate(v)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[k] = copyTemplate(v)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+func (e *templateExpander) interpolate(s string, dir string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", errors.New("unclosed variable in: ", s)
+		}
+		value, err := e.variable(s[i+2:i+end], dir)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:i] + value)
+		s = s[i+end+1:]
+	}
+	return b.String(), nil
+}
+
+func (e *templateExpander) variable(expr string, dir string) (string, error) {
+	name, def, hasDefault := strings.Cut(expr, ":-")
+	var value string
+	switch {
+	case strings.HasPrefix(name, "file:"):
+		r, err := confloader.LoadConfig(templatePath(dir, name[5:]))
+		if err == nil {
+			var content []byte
+			content, err = io.ReadAll(r)
+			value = strings.TrimRight(string(content), "\r\n")
+		}
+		if err != nil && !hasDefault {
+			return "", errors.New("failed to read variable ", name).Base(err)
+		}
+	default:
+		value, _ = e.lookupEnv(strings.TrimPrefix(name, "env:"))
+	}
+	if value != "" {
+		return value, nil
+	}
+	if !hasDefault {
+		return "", errors.New("variable ", name, " is not set")
+	}
+	return def, nil
+}
@@ -0,0 +1,102 @@
+package serial
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	_ "github.com/luckyluke-a/xray-core/main/confloader/external"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"outbounds.json": `[{"tag": "direct", "protocol": "freedom"}, {"tag": "block", "protocol": "blackhole"}]`,
+		"routing.yaml":   "domainStrategy: AsIs\nrules:\n  - {outboundTag: block, ip: [\"${BLOCKED:-10.0.0.0/8}\"]}\n",
+		"password":       "secret\n",
+		"a.json":         `{"$include": "b.json"}`,
+		"b.json":         `{"$include": "a.json"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("NODE_NAME", "node1")
+
+	content, err := expandTemplate([]byte(`{
+		"template": true,
+		"snippets": {
+			"socks": {"protocol": "socks", "listen": "127.0.0.1", "settings": {"auth": "noauth"}}
+		},
+		"inbounds": [
+			{"$snippet": "socks", "tag": "${NODE_NAME}-a", "port": 1080},
+			{"$snippet": "socks", "tag": "${NODE_NAME}-b", "port": 1081}
+		],
+		"outbounds": [
+			{"$include": "outbounds.json"},
+			{"tag": "proxy", "protocol": "trojan", "settings": {"servers": [{"password": "${file:password}"}]}}
+		],
+		"routing": {"$include": "routing.yaml"},
+		"log": {"access": "$${literal}"}
+	}`), filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual interface{}
+	if err := json.Unmarshal(content, &actual); err != nil {
+		t.Fatal(err)
+	}
+	var expected interface{}
+	if err := json.Unmarshal([]byte(`{
+		"inbounds": [
+			{"protocol": "socks", "listen": "127.0.0.1", "settings": {"auth": "noauth"}, "tag": "node1-a", "port": 1080},
+			{"protocol": "socks", "listen": "127.0.0.1", "settings": {"auth": "noauth"}, "tag": "node1-b", "port": 1081}
+		],
+		"outbounds": [
+			{"tag": "direct", "protocol": "freedom"},
+			{"tag": "block", "protocol": "blackhole"},
+			{"tag": "proxy", "protocol": "trojan", "settings": {"servers": [{"password": "secret"}]}}
+		],
+		"routing": {"domainStrategy": "AsIs", "rules": [{"outboundTag": "block", "ip": ["10.0.0.0/8"]}]},
+		"log": {"access": "${literal}"}
+	}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if r := cmp.Diff(actual, expected); r != "" {
+		t.Error(r)
+	}
+
+	// templates are only expanded in files enabling them
+	plain := []byte(`{
+	"snippets": {"a": {}},
+	"inbounds": [{"$snippet": "a", "tag": "${NODE_NAME}"}],
+	"outbounds": [{"$include": "outbounds.json"}],
+	"log": {"access": "$${literal}"}
+}`)
+	if content, err := expandTemplate(plain, filepath.Join(dir, "config.json")); err != nil || string(content) != string(plain) {
+		t.Error("expected the config without templates as is, but got ", string(content), err)
+	}
+	content, err = expandTemplate([]byte(`{"template": false, "log": {"access": "${NODE_NAME}"}}`), filepath.Join(dir, "config.json"))
+	if err != nil || string(content) != `{"log":{"access":"${NODE_NAME}"}}` {
+		t.Error("expected the config with templates disabled as is, but got ", string(content), err)
+	}
+
+	errorCases := map[string]string{
+		`{"template": true, "log": {"access": "${XRAY_TEST_UNSET}"}}`: "variable XRAY_TEST_UNSET is not set",
+		`{"template": true, "inbounds": [{"$snippet": "missing"}]}`:   "snippet not found: missing",
+		`{"template": true, "routing": {"$include": "a.json"}}`:       "circular template",
+		`{"template": true, "log": {"access": "${NODE_NAME"}}`:        "unclosed variable",
+		`{"template": "yes"}`: "template must be a boolean",
+	}
+	for input, expectedErr := range errorCases {
+		_, err := expandTemplate([]byte(input), filepath.Join(dir, "config.json"))
+		if err == nil || !strings.Contains(err.Error(), expectedErr) {
+			t.Error("expected error ", expectedErr, " for ", input, ", but got ", err)
+		}
+	}
+}
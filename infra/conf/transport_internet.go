@@ -4,8 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"math"
 	"net/url"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -16,14 +18,17 @@ import (
 	"github.com/luckyluke-a/xray-core/common/platform/filesystem"
 	"github.com/luckyluke-a/xray-core/common/protocol"
 	"github.com/luckyluke-a/xray-core/common/serial"
+	"github.com/luckyluke-a/xray-core/features"
 	"github.com/luckyluke-a/xray-core/transport/internet"
 	"github.com/luckyluke-a/xray-core/transport/internet/domainsocket"
 	httpheader "github.com/luckyluke-a/xray-core/transport/internet/headers/http"
 	"github.com/luckyluke-a/xray-core/transport/internet/http"
+	"github.com/luckyluke-a/xray-core/transport/internet/httpfallback"
 	"github.com/luckyluke-a/xray-core/transport/internet/httpupgrade"
 	"github.com/luckyluke-a/xray-core/transport/internet/kcp"
 	"github.com/luckyluke-a/xray-core/transport/internet/quic"
 	"github.com/luckyluke-a/xray-core/transport/internet/reality"
+	"github.com/luckyluke-a/xray-core/transport/internet/shadowtls"
 	"github.com/luckyluke-a/xray-core/transport/internet/splithttp"
 	"github.com/luckyluke-a/xray-core/transport/internet/tcp"
 	"github.com/luckyluke-a/xray-core/transport/internet/tls"
@@ -58,6 +63,31 @@ type KCPConfig struct {
 	WriteBufferSize *uint32         `json:"writeBufferSize"`
 	HeaderConfig    json.RawMessage `json:"header"`
 	Seed            *string         `json:"seed"`
+	FEC             *KCPFECConfig   `json:"fec"`
+}
+
+type KCPFECConfig struct {
+	DataShards      uint32 `json:"dataShards"`
+	ParityShards    uint32 `json:"parityShards"`
+	Adaptive        bool   `json:"adaptive"`
+	MaxParityShards uint32 `json:"maxParityShards"`
+}
+
+// Build implements Buildable.
+func (c *KCPFECConfig) Build() (*kcp.FEC, error) {
+	config := &kcp.FEC{
+		DataShards:      c.DataShards,
+		ParityShards:    c.ParityShards,
+		Adaptive:        c.Adaptive,
+		MaxParityShards: c.MaxParityShards,
+	}
+	if c.MaxParityShards != 0 && c.MaxParityShards < c.ParityShards {
+		return nil, errors.New("mKCP FEC maxParityShards is less than parityShards").AtError()
+	}
+	if config.GetDataShardsValue()+config.GetParityShardsValue() > 255 || config.GetDataShardsValue()+int(c.MaxParityShards) > 255 {
+		return nil, errors.New("too many mKCP FEC shards").AtError()
+	}
+	return config, nil
 }
 
 // Build implements Buildable.
@@ -119,6 +149,14 @@ func (c *KCPConfig) Build() (proto.Message, error) {
 		config.Seed = &kcp.EncryptionSeed{Seed: *c.Seed}
 	}
 
+	if c.FEC != nil {
+		fec, err := c.FEC.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fec = fec
+	}
+
 	return config, nil
 }
 
@@ -147,11 +185,65 @@ func (c *TCPConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+// HTTPFallbackConfig is where HTTP-based transport listeners send the
+// requests which are not for them.
+type HTTPFallbackConfig struct {
+	Type string          `json:"type"`
+	Dest json.RawMessage `json:"dest"`
+}
+
+// Build implements Buildable.
+func (c *HTTPFallbackConfig) Build() (*httpfallback.Config, error) {
+	var i uint16
+	var dest string
+	if err := json.Unmarshal(c.Dest, &i); err == nil {
+		dest = strconv.Itoa(int(i))
+	} else {
+		_ = json.Unmarshal(c.Dest, &dest)
+	}
+	if dest == "" {
+		return nil, errors.New(`fallback: please fill in a valid value for "dest"`)
+	}
+
+	config := &httpfallback.Config{
+		Type: c.Type,
+		Dest: dest,
+	}
+	switch config.Type {
+	case "":
+		if filepath.IsAbs(dest) || dest[0] == '@' {
+			config.Type = "unix"
+			if strings.HasPrefix(dest, "@@") && (runtime.GOOS == "linux" || runtime.GOOS == "android") {
+				fullAddr := make([]byte, len(syscall.RawSockaddrUnix{}.Path)) // may need padding to work with haproxy
+				copy(fullAddr, dest[1:])
+				config.Dest = string(fullAddr)
+			}
+		} else {
+			if _, err := strconv.Atoi(dest); err == nil {
+				config.Dest = "127.0.0.1:" + dest
+			}
+			if _, _, err := net.SplitHostPort(config.Dest); err != nil {
+				return nil, errors.New(`fallback: please fill in a valid value for "dest"`)
+			}
+			config.Type = "tcp"
+		}
+	case "tcp", "unix":
+	case "dir":
+		if !filepath.IsAbs(dest) {
+			return nil, errors.New(`fallback: "dest" of "dir" must be an absolute path`)
+		}
+	default:
+		return nil, errors.New("fallback: unknown type ", config.Type)
+	}
+	return config, nil
+}
+
 type WebSocketConfig struct {
-	Host                string            `json:"host"`
-	Path                string            `json:"path"`
-	Headers             map[string]string `json:"headers"`
-	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
+	Host                string              `json:"host"`
+	Path                string              `json:"path"`
+	Headers             map[string]string   `json:"headers"`
+	AcceptProxyProtocol bool                `json:"acceptProxyProtocol"`
+	Fallback            *HTTPFallbackConfig `json:"fallback"`
 }
 
 // Build implements Buildable.
@@ -182,14 +274,22 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 		AcceptProxyProtocol: c.AcceptProxyProtocol,
 		Ed:                  ed,
 	}
+	if c.Fallback != nil {
+		fallback, err := c.Fallback.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fallback = fallback
+	}
 	return config, nil
 }
 
 type HttpUpgradeConfig struct {
-	Host                string            `json:"host"`
-	Path                string            `json:"path"`
-	Headers             map[string]string `json:"headers"`
-	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
+	Host                string              `json:"host"`
+	Path                string              `json:"path"`
+	Headers             map[string]string   `json:"headers"`
+	AcceptProxyProtocol bool                `json:"acceptProxyProtocol"`
+	Fallback            *HTTPFallbackConfig `json:"fallback"`
 }
 
 // Build implements Buildable.
@@ -222,18 +322,91 @@ func (c *HttpUpgradeConfig) Build() (proto.Message, error) {
 		AcceptProxyProtocol: c.AcceptProxyProtocol,
 		Ed:                  ed,
 	}
+	if c.Fallback != nil {
+		fallback, err := c.Fallback.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fallback = fallback
+	}
 	return config, nil
 }
 
 type SplitHTTPConfig struct {
-	Host                 string            `json:"host"`
-	Path                 string            `json:"path"`
-	Headers              map[string]string `json:"headers"`
-	ScMaxConcurrentPosts *Int32Range       `json:"scMaxConcurrentPosts"`
-	ScMaxEachPostBytes   *Int32Range       `json:"scMaxEachPostBytes"`
-	ScMinPostsIntervalMs *Int32Range       `json:"scMinPostsIntervalMs"`
-	NoSSEHeader          bool              `json:"noSSEHeader"`
-	XPaddingBytes        *Int32Range       `json:"xPaddingBytes"`
+	Host                 string                   `json:"host"`
+	Path                 string                   `json:"path"`
+	Headers              map[string]string        `json:"headers"`
+	ScMaxConcurrentPosts *Int32Range              `json:"scMaxConcurrentPosts"`
+	ScMaxEachPostBytes   *Int32Range              `json:"scMaxEachPostBytes"`
+	ScMinPostsIntervalMs *Int32Range              `json:"scMinPostsIntervalMs"`
+	NoSSEHeader          bool                     `json:"noSSEHeader"`
+	XPaddingBytes        *Int32Range              `json:"xPaddingBytes"`
+	Mode                 string                   `json:"mode"`
+	DownloadSettings     *SplitHTTPDownloadConfig `json:"downloadSettings"`
+	Xmux                 *SplitHTTPXmuxConfig     `json:"xmux"`
+	Fallback             *HTTPFallbackConfig      `json:"fallback"`
+}
+
+type SplitHTTPXmuxConfig struct {
+	MaxConcurrency *Int32Range `json:"maxConcurrency"`
+	MaxConnections *Int32Range `json:"maxConnections"`
+	CMaxReuseTimes *Int32Range `json:"cMaxReuseTimes"`
+	CMaxLifetimeMs *Int32Range `json:"cMaxLifetimeMs"`
+}
+
+// Build implements Buildable.
+func (c *SplitHTTPXmuxConfig) Build() (*splithttp.Multiplexing, error) {
+	for _, r := range []*Int32Range{c.MaxConcurrency, c.MaxConnections, c.CMaxReuseTimes, c.CMaxLifetimeMs} {
+		if r != nil && (r.From < 0 || r.From > r.To) {
+			return nil, errors.New("invalid splithttp xmux range: ", r.From, "-", r.To)
+		}
+	}
+	if c.MaxConcurrency != nil && c.MaxConcurrency.To > 0 && c.MaxConnections != nil && c.MaxConnections.To > 0 {
+		return nil, errors.New("splithttp xmux maxConcurrency and maxConnections cannot be used together")
+	}
+	return &splithttp.Multiplexing{
+		MaxConcurrency: splithttpNewRandRangeConfig(c.MaxConcurrency),
+		MaxConnections: splithttpNewRandRangeConfig(c.MaxConnections),
+		CMaxReuseTimes: splithttpNewRandRangeConfig(c.CMaxReuseTimes),
+		CMaxLifetimeMs: splithttpNewRandRangeConfig(c.CMaxLifetimeMs),
+	}, nil
+}
+
+// SplitHTTPDownloadConfig is the stream settings of the download requests,
+// along with the address to send them to.
+type SplitHTTPDownloadConfig struct {
+	Address *Address `json:"address"`
+	Port    uint16   `json:"port"`
+	StreamConfig
+}
+
+// Build implements Buildable.
+func (c *SplitHTTPDownloadConfig) Build() (*splithttp.DownloadConfig, error) {
+	if c.Address == nil {
+		return nil, errors.New("splithttp download address is not set")
+	}
+	if c.Port == 0 {
+		return nil, errors.New("splithttp download port is not set")
+	}
+	if c.Network == nil {
+		network := TransportProtocol("splithttp")
+		c.Network = &network
+	}
+	streamSettings, err := c.StreamConfig.Build()
+	if err != nil {
+		return nil, errors.New("failed to build splithttp download settings").Base(err)
+	}
+	if streamSettings.ProtocolName != "splithttp" {
+		return nil, errors.New("splithttp download settings must use splithttp, but got ", streamSettings.ProtocolName)
+	}
+	if c.SplitHTTPSettings != nil && c.SplitHTTPSettings.DownloadSettings != nil {
+		return nil, errors.New("splithttp download settings cannot have download settings")
+	}
+	return &splithttp.DownloadConfig{
+		Address:        c.Address.Build(),
+		Port:           uint32(c.Port),
+		StreamSettings: streamSettings,
+	}, nil
 }
 
 func splithttpNewRandRangeConfig(input *Int32Range) *splithttp.RandRangeConfig {
@@ -267,6 +440,36 @@ func (c *SplitHTTPConfig) Build() (proto.Message, error) {
 		NoSSEHeader:          c.NoSSEHeader,
 		XPaddingBytes:        splithttpNewRandRangeConfig(c.XPaddingBytes),
 	}
+	switch c.Mode {
+	case "", "auto", splithttp.ModePacketUp, splithttp.ModeStreamUp, splithttp.ModeStreamOne:
+		config.Mode = c.Mode
+	default:
+		return nil, errors.New("unsupported splithttp mode: ", c.Mode)
+	}
+	if c.DownloadSettings != nil {
+		if c.Mode == splithttp.ModeStreamOne {
+			return nil, errors.New("splithttp download settings are not supported in stream-one mode")
+		}
+		downloadSettings, err := c.DownloadSettings.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.DownloadSettings = downloadSettings
+	}
+	if c.Xmux != nil {
+		xmux, err := c.Xmux.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Xmux = xmux
+	}
+	if c.Fallback != nil {
+		fallback, err := c.Fallback.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fallback = fallback
+	}
 	return config, nil
 }
 
@@ -319,12 +522,57 @@ type QUICConfig struct {
 	Header   json.RawMessage `json:"header"`
 	Security string          `json:"security"`
 	Key      string          `json:"key"`
+
+	InitialStreamReceiveWindow     uint64    `json:"initStreamReceiveWindow"`
+	MaxStreamReceiveWindow         uint64    `json:"maxStreamReceiveWindow"`
+	InitialConnectionReceiveWindow uint64    `json:"initConnReceiveWindow"`
+	MaxConnectionReceiveWindow     uint64    `json:"maxConnReceiveWindow"`
+	KeepAlivePeriod                uint32    `json:"keepAlivePeriod"`
+	MaxIdleTimeout                 uint32    `json:"maxIdleTimeout"`
+	MaxIncomingStreams             int64     `json:"maxIncomingStreams"`
+	DisablePathMTUDiscovery        bool      `json:"disablePathMTUDiscovery"`
+	Congestion                     string    `json:"congestion"`
+	ZeroRTT                        bool      `json:"0rtt"`
+	HopPorts                       *PortList `json:"hopPorts"`
+	HopInterval                    uint32    `json:"hopInterval"`
 }
 
 // Build implements Buildable.
 func (c *QUICConfig) Build() (proto.Message, error) {
 	config := &quic.Config{
-		Key: c.Key,
+		Key:                            c.Key,
+		InitialStreamReceiveWindow:     c.InitialStreamReceiveWindow,
+		MaxStreamReceiveWindow:         c.MaxStreamReceiveWindow,
+		InitialConnectionReceiveWindow: c.InitialConnectionReceiveWindow,
+		MaxConnectionReceiveWindow:     c.MaxConnectionReceiveWindow,
+		KeepAlivePeriod:                c.KeepAlivePeriod,
+		MaxIdleTimeout:                 c.MaxIdleTimeout,
+		MaxIncomingStreams:             c.MaxIncomingStreams,
+		DisablePathMtuDiscovery:        c.DisablePathMTUDiscovery,
+		ZeroRtt:                        c.ZeroRTT,
+		HopInterval:                    c.HopInterval,
+	}
+
+	// quic-go always uses Cubic
+	switch strings.ToLower(c.Congestion) {
+	case "", "cubic":
+	case "bbr":
+		return nil, errors.New(`QUIC: congestion control "bbr" is not supported by quic-go, only "cubic" is`)
+	default:
+		return nil, errors.New("QUIC: unknown congestion control: ", c.Congestion)
+	}
+	if c.MaxStreamReceiveWindow != 0 && c.InitialStreamReceiveWindow > c.MaxStreamReceiveWindow {
+		return nil, errors.New(`QUIC: "initStreamReceiveWindow" is greater than "maxStreamReceiveWindow"`)
+	}
+	if c.MaxConnectionReceiveWindow != 0 && c.InitialConnectionReceiveWindow > c.MaxConnectionReceiveWindow {
+		return nil, errors.New(`QUIC: "initConnReceiveWindow" is greater than "maxConnReceiveWindow"`)
+	}
+	if c.HopPorts != nil {
+		config.HopPorts = c.HopPorts.Build()
+	}
+
+	if c.Key != "" || len(c.Header) > 0 {
+		features.PrintDeprecatedFeatureWarning(`"key" and "header" of QUIC`)
 	}
 
 	if len(c.Header) > 0 {
@@ -357,18 +605,42 @@ func (c *QUICConfig) Build() (proto.Message, error) {
 }
 
 type DomainSocketConfig struct {
-	Path     string `json:"path"`
-	Abstract bool   `json:"abstract"`
-	Padding  bool   `json:"padding"`
+	Path           string   `json:"path"`
+	Abstract       bool     `json:"abstract"`
+	Padding        bool     `json:"padding"`
+	Seqpacket      bool     `json:"seqpacket"`
+	AllowedUIDs    []uint32 `json:"allowedUids"`
+	AllowedGIDs    []uint32 `json:"allowedGids"`
+	PeerAttributes bool     `json:"peerAttributes"`
+	Mode           string   `json:"mode"`
+	Owner          string   `json:"owner"`
+	Group          string   `json:"group"`
 }
 
 // Build implements Buildable.
 func (c *DomainSocketConfig) Build() (proto.Message, error) {
-	return &domainsocket.Config{
-		Path:     c.Path,
-		Abstract: c.Abstract,
-		Padding:  c.Padding,
-	}, nil
+	config := &domainsocket.Config{
+		Path:           c.Path,
+		Abstract:       c.Abstract,
+		Padding:        c.Padding,
+		Seqpacket:      c.Seqpacket,
+		AllowedUids:    c.AllowedUIDs,
+		AllowedGids:    c.AllowedGIDs,
+		PeerAttributes: c.PeerAttributes,
+		Owner:          c.Owner,
+		Group:          c.Group,
+	}
+	if c.Mode != "" {
+		mode, err := strconv.ParseUint(c.Mode, 8, 32)
+		if err != nil || mode > 0o777 {
+			return nil, errors.New("invalid domain socket mode: ", c.Mode)
+		}
+		config.Mode = uint32(mode)
+	}
+	if c.Abstract && (config.Mode != 0 || c.Owner != "" || c.Group != "") {
+		return nil, errors.New("mode, owner and group are not applicable to abstract domain socket")
+	}
+	return config, nil
 }
 
 func readFileOrString(f string, s []string) ([]byte, error) {
@@ -434,20 +706,50 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 }
 
 type TLSConfig struct {
-	Insecure                             bool             `json:"allowInsecure"`
-	Certs                                []*TLSCertConfig `json:"certificates"`
-	ServerName                           string           `json:"serverName"`
-	ALPN                                 *StringList      `json:"alpn"`
-	EnableSessionResumption              bool             `json:"enableSessionResumption"`
-	DisableSystemRoot                    bool             `json:"disableSystemRoot"`
-	MinVersion                           string           `json:"minVersion"`
-	MaxVersion                           string           `json:"maxVersion"`
-	CipherSuites                         string           `json:"cipherSuites"`
-	Fingerprint                          string           `json:"fingerprint"`
-	RejectUnknownSNI                     bool             `json:"rejectUnknownSni"`
-	PinnedPeerCertificateChainSha256     *[]string        `json:"pinnedPeerCertificateChainSha256"`
-	PinnedPeerCertificatePublicKeySha256 *[]string        `json:"pinnedPeerCertificatePublicKeySha256"`
-	MasterKeyLog                         string           `json:"masterKeyLog"`
+	Insecure                             bool               `json:"allowInsecure"`
+	Certs                                []*TLSCertConfig   `json:"certificates"`
+	ServerName                           string             `json:"serverName"`
+	ALPN                                 *StringList        `json:"alpn"`
+	EnableSessionResumption              bool               `json:"enableSessionResumption"`
+	DisableSystemRoot                    bool               `json:"disableSystemRoot"`
+	MinVersion                           string             `json:"minVersion"`
+	MaxVersion                           string             `json:"maxVersion"`
+	CipherSuites                         string             `json:"cipherSuites"`
+	Fingerprint                          string             `json:"fingerprint"`
+	RejectUnknownSNI                     bool               `json:"rejectUnknownSni"`
+	PinnedPeerCertificateChainSha256     *[]string          `json:"pinnedPeerCertificateChainSha256"`
+	PinnedPeerCertificatePublicKeySha256 *[]string          `json:"pinnedPeerCertificatePublicKeySha256"`
+	MasterKeyLog                         string             `json:"masterKeyLog"`
+	ECHServerKeys                        []*TLSECHKeyConfig `json:"echServerKeys"`
+	ECHConfigList                        *StringList        `json:"echConfigList"`
+	ECHQueryDomain                       string             `json:"echQueryDomain"`
+	ECHForceQuery                        bool               `json:"echForceQuery"`
+}
+
+// TLSECHKeyConfig is an ECH key set, as generated by "xray tls ech".
+type TLSECHKeyConfig struct {
+	KeyFile string   `json:"keyFile"`
+	KeyStr  []string `json:"key"`
+}
+
+// Build implements Buildable.
+func (c *TLSECHKeyConfig) Build() ([]byte, error) {
+	key, err := readFileOrString(c.KeyFile, c.KeyStr)
+	if err != nil {
+		return nil, errors.New("failed to parse ECH key").Base(err)
+	}
+	return decodePEMOrBase64(key, "ECH KEYS")
+}
+
+// decodePEMOrBase64 decodes data as a PEM block of the given type, or as base64 if it isn't PEM.
+func decodePEMOrBase64(data []byte, blockType string) ([]byte, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != blockType {
+			return nil, errors.New(`expecting PEM block "`, blockType, `", got "`, block.Type, `"`)
+		}
+		return block.Bytes, nil
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
 }
 
 // Build implements Buildable.
@@ -504,6 +806,31 @@ func (c *TLSConfig) Build() (proto.Message, error) {
 
 	config.MasterKeyLog = c.MasterKeyLog
 
+	for _, k := range c.ECHServerKeys {
+		key, err := k.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.EchServerKeys = append(config.EchServerKeys, key)
+	}
+	if c.ECHConfigList != nil && c.ECHConfigList.Len() > 0 {
+		echConfigList, err := decodePEMOrBase64([]byte(strings.Join(*c.ECHConfigList, "\n")), "ECH CONFIGS")
+		if err != nil {
+			return nil, errors.New("invalid ECH config list").Base(err)
+		}
+		config.EchConfigList = echConfigList
+	}
+	config.EchQueryDomain = c.ECHQueryDomain
+	config.EchForceQuery = c.ECHForceQuery
+	if len(config.EchConfigList) > 0 || config.EchQueryDomain != "" {
+		if config.Fingerprint != "" {
+			return nil, errors.New(`ECH doesn't work with "fingerprint" yet`)
+		}
+		if (config.MinVersion != "" && config.MinVersion != "1.3") || (config.MaxVersion != "" && config.MaxVersion != "1.3") {
+			return nil, errors.New(`ECH requires TLS 1.3`)
+		}
+	}
+
 	return config, nil
 }
 
@@ -806,6 +1133,35 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+type ShadowTLSConfig struct {
+	Password    string `json:"password"`
+	Dest        string `json:"dest"`
+	ServerName  string `json:"serverName"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Build implements Buildable.
+func (c *ShadowTLSConfig) Build() (proto.Message, error) {
+	if c.Password == "" {
+		return nil, errors.New(`ShadowTLS: empty "password"`)
+	}
+	config := &shadowtls.Config{
+		Password:    c.Password,
+		Dest:        c.Dest,
+		ServerName:  c.ServerName,
+		Fingerprint: strings.ToLower(c.Fingerprint),
+	}
+	if config.Dest != "" {
+		if _, _, err := net.SplitHostPort(config.Dest); err != nil {
+			config.Dest = net.JoinHostPort(config.Dest, "443")
+		}
+	}
+	if config.Fingerprint != "" && tls.GetFingerprint(config.Fingerprint) == nil {
+		return nil, errors.New(`ShadowTLS: unknown "fingerprint": `, config.Fingerprint)
+	}
+	return config, nil
+}
+
 type TransportProtocol string
 
 // Build implements Buildable.
@@ -859,6 +1215,7 @@ type SocketConfig struct {
 	Interface            string                 `json:"interface"`
 	TcpMptcp             bool                   `json:"tcpMptcp"`
 	CustomSockopt        []*CustomSockoptConfig `json:"customSockopt"`
+	InheritedFdName      string                 `json:"inheritedFdName"`
 }
 
 // Build implements Buildable.
@@ -946,6 +1303,7 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		Interface:            c.Interface,
 		TcpMptcp:             c.TcpMptcp,
 		CustomSockopt:        customSockopts,
+		InheritedFdName:      c.InheritedFdName,
 	}, nil
 }
 
@@ -954,6 +1312,7 @@ type StreamConfig struct {
 	Security            string              `json:"security"`
 	TLSSettings         *TLSConfig          `json:"tlsSettings"`
 	REALITYSettings     *REALITYConfig      `json:"realitySettings"`
+	ShadowTLSSettings   *ShadowTLSConfig    `json:"shadowtlsSettings"`
 	TCPSettings         *TCPConfig          `json:"tcpSettings"`
 	KCPSettings         *KCPConfig          `json:"kcpSettings"`
 	WSSettings          *WebSocketConfig    `json:"wsSettings"`
@@ -1007,6 +1366,20 @@ func (c *StreamConfig) Build() (*internet.StreamConfig, error) {
 		tm := serial.ToTypedMessage(ts)
 		config.SecuritySettings = append(config.SecuritySettings, tm)
 		config.SecurityType = tm.Type
+	case "shadowtls":
+		if config.ProtocolName != "tcp" {
+			return nil, errors.New("ShadowTLS only supports TCP for now.")
+		}
+		if c.ShadowTLSSettings == nil {
+			return nil, errors.New(`ShadowTLS: Empty "shadowtlsSettings".`)
+		}
+		ts, err := c.ShadowTLSSettings.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build ShadowTLS config.").Base(err)
+		}
+		tm := serial.ToTypedMessage(ts)
+		config.SecuritySettings = append(config.SecuritySettings, tm)
+		config.SecurityType = tm.Type
 	case "xtls":
 		return nil, errors.New(`Please use VLESS flow "xtls-rprx-vision" with TLS or REALITY.`)
 	default:
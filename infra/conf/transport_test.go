@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/protocol"
 	"github.com/luckyluke-a/xray-core/common/serial"
 	. "github.com/luckyluke-a/xray-core/infra/conf"
@@ -198,6 +199,11 @@ func TestTransportConfig(t *testing.T) {
 					"mtu": 1200,
 					"header": {
 						"type": "none"
+					},
+					"fec": {
+						"dataShards": 10,
+						"parityShards": 3,
+						"adaptive": true
 					}
 				},
 				"wsSettings": {
@@ -264,6 +270,11 @@ func TestTransportConfig(t *testing.T) {
 						Settings: serial.ToTypedMessage(&kcp.Config{
 							Mtu:          &kcp.MTU{Value: 1200},
 							HeaderConfig: serial.ToTypedMessage(&noop.Config{}),
+							Fec: &kcp.FEC{
+								DataShards:   10,
+								ParityShards: 3,
+								Adaptive:     true,
+							},
 						}),
 					},
 					{
@@ -312,3 +323,43 @@ func TestTransportConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestQUICConfigCongestion(t *testing.T) {
+	for _, input := range []string{`{}`, `{"congestion": "cubic"}`, `{"congestion": "Cubic"}`} {
+		config := new(QUICConfig)
+		common.Must(json.Unmarshal([]byte(input), config))
+		if _, err := config.Build(); err != nil {
+			t.Error(input, ": ", err)
+		}
+	}
+	for _, input := range []string{`{"congestion": "bbr"}`, `{"congestion": "reno"}`} {
+		config := new(QUICConfig)
+		common.Must(json.Unmarshal([]byte(input), config))
+		if _, err := config.Build(); err == nil {
+			t.Error(input, ": expected an error")
+		}
+	}
+}
+
+func TestShadowTLSInboundDest(t *testing.T) {
+	build := func(dest string) error {
+		config := new(InboundDetourConfig)
+		common.Must(json.Unmarshal([]byte(`{
+			"protocol": "dokodemo-door",
+			"port": 443,
+			"settings": {"address": "127.0.0.1", "network": "tcp"},
+			"streamSettings": {
+				"security": "shadowtls",
+				"shadowtlsSettings": {"password": "p", "dest": "`+dest+`"}
+			}
+		}`), config))
+		_, err := config.Build()
+		return err
+	}
+	if err := build(""); err == nil {
+		t.Error("expected an error for an inbound without dest")
+	}
+	if err := build("www.example.com"); err != nil {
+		t.Error(err)
+	}
+}
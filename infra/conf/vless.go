@@ -19,6 +19,132 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+type SegaroProfileConfig struct {
+	Name                  string `json:"name"`
+	PaddingSize           uint32 `json:"paddingSize"`
+	SubchunkSize          uint32 `json:"subchunkSize"`
+	SplitPacket           string `json:"splitPacket"`
+	ServerRandPacket      string `json:"serverRandPacket"`
+	ClientRandPacket      string `json:"clientRandPacket"`
+	ServerRandPacketCount string `json:"serverRandPacketCount"`
+	ClientRandPacketCount string `json:"clientRandPacketCount"`
+	ChunkDelay            string `json:"chunkDelay"`
+}
+
+type SegaroFlowConfig struct {
+	SegaroProfileConfig
+	Profiles []*SegaroProfileConfig `json:"profiles"`
+}
+
+// checkSegaroRange checks value is either "n" or "min-max".
+func checkSegaroRange(name, value string) error {
+	if !strings.Contains(value, "-") {
+		if _, err := strconv.Atoi(value); err != nil {
+			return errors.New(`incorrect "` + name + `" value`)
+		}
+		return nil
+	}
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return errors.New(`incorrect range of "` + name + `"`)
+	}
+	min, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return errors.New(`incorrect range of "` + name + `" min value`)
+	}
+	max, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return errors.New(`incorrect range of "` + name + `" max value`)
+	}
+	if min > max {
+		return errors.New(`incorrect "` + name + `" min > max`)
+	}
+	return nil
+}
+
+// Build builds one shaping profile of xtls-segaro-vision.
+func (c *SegaroProfileConfig) Build() (*vless.SegaroProfile, error) {
+	for name, value := range map[string]string{
+		"splitPacket":           c.SplitPacket,
+		"serverRandPacket":      c.ServerRandPacket,
+		"clientRandPacket":      c.ClientRandPacket,
+		"serverRandPacketCount": c.ServerRandPacketCount,
+		"clientRandPacketCount": c.ClientRandPacketCount,
+	} {
+		if err := checkSegaroRange(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if c.ChunkDelay != "" {
+		if err := checkSegaroRange("chunkDelay", c.ChunkDelay); err != nil {
+			return nil, err
+		}
+	}
+	if c.PaddingSize == 0 || c.SubchunkSize == 0 {
+		return nil, errors.New(`"paddingSize" and "subchunkSize" must be positive`)
+	}
+	return &vless.SegaroProfile{
+		Name:                  c.Name,
+		PaddingSize:           c.PaddingSize,
+		SubchunkSize:          c.SubchunkSize,
+		SplitPacket:           c.SplitPacket,
+		ServerRandPacket:      c.ServerRandPacket,
+		ClientRandPacket:      c.ClientRandPacket,
+		ServerRandPacketCount: c.ServerRandPacketCount,
+		ClientRandPacketCount: c.ClientRandPacketCount,
+		ChunkDelay:            c.ChunkDelay,
+	}, nil
+}
+
+// Build builds the xtls-segaro-vision parameters used when the security is not REALITY.
+// The top level parameters, if set, form the "default" profile.
+func (c *SegaroFlowConfig) Build() (*vless.SegaroConfig, error) {
+	config := new(vless.SegaroConfig)
+	if c.PaddingSize != 0 || c.SubchunkSize != 0 || c.SplitPacket != "" || len(c.Profiles) == 0 {
+		if c.Name != "" && c.Name != vless.DefaultSegaroProfile {
+			return nil, errors.New(`VLESS "segaroSettings": the top level profile is always named "` + vless.DefaultSegaroProfile + `"`)
+		}
+		profile, err := c.SegaroProfileConfig.Build()
+		if err != nil {
+			return nil, errors.New(`VLESS "segaroSettings"`).Base(err)
+		}
+		config.PaddingSize = profile.PaddingSize
+		config.SubchunkSize = profile.SubchunkSize
+		config.SplitPacket = profile.SplitPacket
+		config.ServerRandPacket = profile.ServerRandPacket
+		config.ClientRandPacket = profile.ClientRandPacket
+		config.ServerRandPacketCount = profile.ServerRandPacketCount
+		config.ClientRandPacketCount = profile.ClientRandPacketCount
+		config.ChunkDelay = profile.ChunkDelay
+	}
+	names := map[string]bool{vless.DefaultSegaroProfile: config.PaddingSize != 0}
+	ids := make(map[[2]byte]string)
+	if config.PaddingSize != 0 {
+		ids[vless.SegaroProfileID(vless.DefaultSegaroProfile)] = vless.DefaultSegaroProfile
+	}
+	for _, p := range c.Profiles {
+		if p.Name == "" {
+			return nil, errors.New(`VLESS "segaroSettings": every profile needs a "name"`)
+		}
+		if names[p.Name] {
+			return nil, errors.New(`VLESS "segaroSettings": duplicated profile "` + p.Name + `"`)
+		}
+		names[p.Name] = true
+		// the server finds the profile of the client by the id of the name
+		id := vless.SegaroProfileID(p.Name)
+		if name, found := ids[id]; found {
+			return nil, errors.New(`VLESS "segaroSettings": profiles "` + name + `" and "` + p.Name + `" have the same id, rename one of them`)
+		}
+		ids[id] = p.Name
+		profile, err := p.Build()
+		if err != nil {
+			return nil, errors.New(`VLESS "segaroSettings": profile "` + p.Name + `"`).Base(err)
+		}
+		config.Profiles = append(config.Profiles, profile)
+	}
+	return config, nil
+}
+
 type VLessInboundFallback struct {
 	Name string          `json:"name"`
 	Alpn string          `json:"alpn"`
@@ -33,6 +159,7 @@ type VLessInboundConfig struct {
 	Decryption string                  `json:"decryption"`
 	Fallback   *VLessInboundFallback   `json:"fallback"`
 	Fallbacks  []*VLessInboundFallback `json:"fallbacks"`
+	Segaro     *SegaroFlowConfig       `json:"segaroSettings"`
 }
 
 // Build implements Buildable
@@ -74,6 +201,14 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 	}
 	config.Decryption = c.Decryption
 
+	if c.Segaro != nil {
+		segaroConfig, err := c.Segaro.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.SegaroSettings = segaroConfig
+	}
+
 	if c.Fallback != nil {
 		return nil, errors.New(`VLESS settings: please use "fallbacks":[{}] instead of "fallback":{}`)
 	}
@@ -189,6 +324,19 @@ func (c *VLessOutboundConfig) Build() (proto.Message, error) {
 				return nil, errors.New(`VLESS users: please add/set "encryption":"none" for every user`)
 			}
 
+			segaroSettings := new(struct {
+				Segaro *SegaroFlowConfig `json:"segaroSettings"`
+			})
+			if err := json.Unmarshal(rawUser, segaroSettings); err != nil {
+				return nil, errors.New(`VLESS users: invalid user`).Base(err)
+			}
+			account.SegaroSettings = nil
+			if segaroSettings.Segaro != nil {
+				if account.SegaroSettings, err = segaroSettings.Segaro.Build(); err != nil {
+					return nil, err
+				}
+			}
+
 			user.Account = serial.ToTypedMessage(account)
 			spec.User[idx] = user
 		}
@@ -57,6 +57,61 @@ func TestVLessOutbound(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"vnext": [{
+					"address": "example.com",
+					"port": 443,
+					"users": [
+						{
+							"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+							"flow": "xtls-segaro-vision",
+							"encryption": "none",
+							"segaroSettings": {
+								"paddingSize": 200,
+								"subchunkSize": 50,
+								"splitPacket": "100-200",
+								"serverRandPacket": "50-100",
+								"clientRandPacket": "50-100",
+								"serverRandPacketCount": "1-3",
+								"clientRandPacketCount": "2"
+							}
+						}
+					]
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &outbound.Config{
+				Vnext: []*protocol.ServerEndpoint{
+					{
+						Address: &net.IPOrDomain{
+							Address: &net.IPOrDomain_Domain{
+								Domain: "example.com",
+							},
+						},
+						Port: 443,
+						User: []*protocol.User{
+							{
+								Account: serial.ToTypedMessage(&vless.Account{
+									Id:         "27848739-7e62-4138-9fd3-098a63964b6b",
+									Flow:       "xtls-segaro-vision",
+									Encryption: "none",
+									SegaroSettings: &vless.SegaroConfig{
+										PaddingSize:           200,
+										SubchunkSize:          50,
+										SplitPacket:           "100-200",
+										ServerRandPacket:      "50-100",
+										ClientRandPacket:      "50-100",
+										ServerRandPacketCount: "1-3",
+										ClientRandPacketCount: "2",
+									},
+								}),
+							},
+						},
+					},
+				},
+			},
+		},
 	})
 }
 
@@ -130,5 +185,78 @@ func TestVLessInbound(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"clients": [
+					{
+						"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+						"flow": "xtls-segaro-vision"
+					}
+				],
+				"decryption": "none",
+				"segaroSettings": {
+					"profiles": [
+						{
+							"name": "bulk",
+							"paddingSize": 100,
+							"subchunkSize": 300,
+							"splitPacket": "500-1000",
+							"serverRandPacket": "100-200",
+							"clientRandPacket": "100-200",
+							"serverRandPacketCount": "1-2",
+							"clientRandPacketCount": "1-2"
+						},
+						{
+							"name": "slow",
+							"paddingSize": 20,
+							"subchunkSize": 40,
+							"splitPacket": "50-100",
+							"serverRandPacket": "0",
+							"clientRandPacket": "0",
+							"serverRandPacketCount": "0",
+							"clientRandPacketCount": "0",
+							"chunkDelay": "5-20"
+						}
+					]
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &inbound.Config{
+				Clients: []*protocol.User{
+					{
+						Account: serial.ToTypedMessage(&vless.Account{
+							Id:   "27848739-7e62-4138-9fd3-098a63964b6b",
+							Flow: "xtls-segaro-vision",
+						}),
+					},
+				},
+				Decryption: "none",
+				SegaroSettings: &vless.SegaroConfig{
+					Profiles: []*vless.SegaroProfile{
+						{
+							Name:                  "bulk",
+							PaddingSize:           100,
+							SubchunkSize:          300,
+							SplitPacket:           "500-1000",
+							ServerRandPacket:      "100-200",
+							ClientRandPacket:      "100-200",
+							ServerRandPacketCount: "1-2",
+							ClientRandPacketCount: "1-2",
+						},
+						{
+							Name:                  "slow",
+							PaddingSize:           20,
+							SubchunkSize:          40,
+							SplitPacket:           "50-100",
+							ServerRandPacket:      "0",
+							ClientRandPacket:      "0",
+							ServerRandPacketCount: "0",
+							ClientRandPacketCount: "0",
+							ChunkDelay:            "5-20",
+						},
+					},
+				},
+			},
+		},
 	})
 }
@@ -114,6 +114,10 @@ type MuxConfig struct {
 	Concurrency     int16  `json:"concurrency"`
 	XudpConcurrency int16  `json:"xudpConcurrency"`
 	XudpProxyUDP443 string `json:"xudpProxyUDP443"`
+	WarmConnections uint16 `json:"warmConnections"`
+	MaxLifetime     uint32 `json:"maxLifetime"`
+	MaxBytes        uint64 `json:"maxBytes"`
+	KeepAlivePeriod uint32 `json:"keepAlivePeriod"`
 }
 
 // Build creates MultiplexingConfig, Concurrency < 0 completely disables mux.
@@ -130,6 +134,10 @@ func (m *MuxConfig) Build() (*proxyman.MultiplexingConfig, error) {
 		Concurrency:     int32(m.Concurrency),
 		XudpConcurrency: int32(m.XudpConcurrency),
 		XudpProxyUDP443: m.XudpProxyUDP443,
+		WarmConnections: int32(m.WarmConnections),
+		MaxLifetime:     int32(m.MaxLifetime),
+		MaxBytes:        int64(m.MaxBytes),
+		KeepAlivePeriod: int32(m.KeepAlivePeriod),
 	}, nil
 }
 
@@ -240,6 +248,10 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		if err != nil {
 			return nil, err
 		}
+		// the server relays the handshake of dest
+		if strings.ToLower(c.StreamSetting.Security) == "shadowtls" && c.StreamSetting.ShadowTLSSettings.Dest == "" {
+			return nil, errors.New(`ShadowTLS: empty "dest" in inbound settings`)
+		}
 		receiverSettings.StreamSettings = ss
 	}
 	if c.SniffingConfig != nil {
@@ -602,7 +614,11 @@ func (c *Config) Build() (*core.Config, error) {
 
 	var logConfMsg *serial.TypedMessage
 	if c.LogConfig != nil {
-		logConfMsg = serial.ToTypedMessage(c.LogConfig.Build())
+		logConfig, err := c.LogConfig.Build()
+		if err != nil {
+			return nil, errors.New("failed to build log configuration").Base(err)
+		}
+		logConfMsg = serial.ToTypedMessage(logConfig)
 	} else {
 		logConfMsg = serial.ToTypedMessage(DefaultLogConfig())
 	}
@@ -360,6 +360,14 @@ func TestMuxConfig_Build(t *testing.T) {
 			XudpConcurrency: 0,
 			XudpProxyUDP443: "reject",
 		}},
+		{"warm", `{"enabled": true, "warmConnections": 2, "maxLifetime": 600, "maxBytes": 1073741824, "keepAlivePeriod": 30}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			XudpProxyUDP443: "reject",
+			WarmConnections: 2,
+			MaxLifetime:     600,
+			MaxBytes:        1073741824,
+			KeepAlivePeriod: 30,
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
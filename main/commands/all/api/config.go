@@ -0,0 +1,53 @@
+package api
+
+import (
+	"os"
+
+	handlerService "github.com/luckyluke-a/xray-core/app/proxyman/command"
+	"github.com/luckyluke-a/xray-core/infra/conf/serial"
+	"github.com/luckyluke-a/xray-core/main/commands/base"
+)
+
+var cmdGetConfig = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api config [--server=127.0.0.1:8080] [-format json|yaml|toml]",
+	Short:       "Get the running config",
+	Long: `
+Get the config Xray is running with, including the inbounds, outbounds
+and routing rules changed through the API.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+	-f, -format
+		The format of the config: json, yaml or toml. Default json
+Example:
+    {{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -format yaml > config.yaml
+`,
+	Run: executeGetConfig,
+}
+
+func executeGetConfig(cmd *base.Command, args []string) {
+	var format string
+	setSharedFlags(cmd)
+	cmd.Flag.StringVar(&format, "f", "json", "")
+	cmd.Flag.StringVar(&format, "format", "json", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.GetConfig(ctx, &handlerService.GetConfigRequest{})
+	if err != nil {
+		base.Fatalf("failed to get config: %s", err)
+	}
+	content, err := serial.EncodeConfig(resp.Config, format)
+	if err != nil {
+		base.Fatalf("failed to convert config: %s", err)
+	}
+	if _, err := os.Stdout.Write(content); err != nil {
+		base.Fatalf("failed to write config: %s", err)
+	}
+}
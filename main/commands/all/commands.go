@@ -15,6 +15,9 @@ func init() {
 		api.CmdAPI,
 		convert.CmdConvert,
 		tls.CmdTLS,
+		cmdCompletion,
+		cmdInit,
+		cmdLint,
 		cmdUUID,
 		cmdX25519,
 		cmdWG,
@@ -0,0 +1,49 @@
+package all
+
+import (
+	"os"
+
+	"github.com/luckyluke-a/xray-core/main/commands/base"
+)
+
+var cmdCompletion = &base.Command{
+	UsageLine: `{{.Exec}} completion [bash|zsh|fish]`,
+	Short:     `Generate shell completion script`,
+	Long: `
+Generate the completion script of {{.Exec}} for the shell, which completes
+the commands, their flags, and files.
+
+Bash:
+
+    source <({{.Exec}} completion bash)
+
+  or for all sessions:
+
+    {{.Exec}} completion bash > /etc/bash_completion.d/{{.Exec}}
+
+Zsh, with compinit loaded:
+
+    source <({{.Exec}} completion zsh)
+
+  or for all sessions, in a dir of fpath:
+
+    {{.Exec}} completion zsh > "${fpath[1]}/_{{.Exec}}"
+
+Fish:
+
+    {{.Exec}} completion fish > ~/.config/fish/completions/{{.Exec}}.fish
+`,
+}
+
+func init() {
+	cmdCompletion.Run = executeCompletion // break init loop
+}
+
+func executeCompletion(cmd *base.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+	}
+	if err := base.GenerateCompletion(os.Stdout, args[0]); err != nil {
+		base.Fatalf("%s", err)
+	}
+}
@@ -0,0 +1,90 @@
+package convert
+
+import (
+	"os"
+
+	"github.com/luckyluke-a/xray-core/app/commander"
+	"github.com/luckyluke-a/xray-core/common/cmdarg"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/infra/conf/serial"
+	"github.com/luckyluke-a/xray-core/main/commands/base"
+	"google.golang.org/protobuf/proto"
+)
+
+var cmdConfig = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} convert config [-format json|yaml|toml] [-journal file] [config file] [config file] ...",
+	Short:       "Convert configs to an editable config",
+	Long: `
+Convert configs of any format, including protobuf, into one editable
+config. Multiple configs are merged first.
+
+Arguments:
+
+	-f, -format
+		The format of the output: json, yaml or toml. Default json
+
+	-journal
+		The API journal to compact into the config. The changes made
+		through the API are added to the output, and the journal is
+		compacted, so that replaying it on the output changes nothing.
+		Stop Xray first.
+
+Examples:
+
+    {{.Exec}} convert config mix.pb > config.json
+    {{.Exec}} convert config -format yaml c1.json c2.toml > config.yaml
+    {{.Exec}} convert config -journal journal config.json > new.json
+	`,
+	Run: executeConvertConfig,
+}
+
+func executeConvertConfig(cmd *base.Command, args []string) {
+	var format string
+	cmd.Flag.StringVar(&format, "f", "json", "")
+	cmd.Flag.StringVar(&format, "format", "json", "")
+	journal := cmd.Flag.String("journal", "", "")
+	cmd.Flag.Parse(args)
+
+	unnamedArgs := cmdarg.Arg{}
+	for _, v := range cmd.Flag.Args() {
+		unnamedArgs.Set(v)
+	}
+
+	if len(unnamedArgs) < 1 {
+		base.Fatalf("empty config list")
+	}
+
+	pbConfig, err := core.LoadConfig("auto", unnamedArgs)
+	if err != nil {
+		base.Fatalf(err.Error())
+	}
+
+	var journalEntries []*commander.JournalEntry
+	if *journal != "" {
+		entries, err := commander.ReadJournal(*journal)
+		if err != nil {
+			base.Fatalf("failed to read journal: %s", err)
+		}
+		baseConfig := proto.Clone(pbConfig).(*core.Config)
+		if err := commander.ApplyJournal(pbConfig, entries); err != nil {
+			base.Fatalf("failed to apply journal: %s", err)
+		}
+		if journalEntries, err = commander.CompactJournal(baseConfig, pbConfig); err != nil {
+			base.Fatalf("failed to compact journal: %s", err)
+		}
+	}
+
+	content, err := serial.EncodeConfig(pbConfig, format)
+	if err != nil {
+		base.Fatalf("failed to convert config: %s", err)
+	}
+	if _, err := os.Stdout.Write(content); err != nil {
+		base.Fatalf("failed to write config: %s", err)
+	}
+	if *journal != "" {
+		if err := commander.WriteJournal(*journal, journalEntries); err != nil {
+			base.Fatalf("failed to compact journal: %s", err)
+		}
+	}
+}
@@ -31,11 +31,25 @@ func Curve25519Genkey(StdEncoding bool, input_base64 string) {
 		}
 	}
 
+	if privateKey, publicKey, err = x25519KeyPair(privateKey); err != nil {
+		output = err.Error()
+		goto out
+	}
+
+	output = fmt.Sprintf("Private key: %v\nPublic key: %v",
+		encoding.EncodeToString(privateKey),
+		encoding.EncodeToString(publicKey))
+out:
+	fmt.Println(output)
+}
+
+// x25519KeyPair returns the private key and its public key. A random private
+// key is generated if it's nil.
+func x25519KeyPair(privateKey []byte) ([]byte, []byte, error) {
 	if privateKey == nil {
 		privateKey = make([]byte, curve25519.ScalarSize)
-		if _, err = rand.Read(privateKey); err != nil {
-			output = err.Error()
-			goto out
+		if _, err := rand.Read(privateKey); err != nil {
+			return nil, nil, err
 		}
 	}
 
@@ -44,14 +58,9 @@ func Curve25519Genkey(StdEncoding bool, input_base64 string) {
 	privateKey[0] &= 248
 	privateKey[31] &= 127 | 64
 
-	if publicKey, err = curve25519.X25519(privateKey, curve25519.Basepoint); err != nil {
-		output = err.Error()
-		goto out
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	output = fmt.Sprintf("Private key: %v\nPublic key: %v",
-		encoding.EncodeToString(privateKey),
-		encoding.EncodeToString(publicKey))
-out:
-	fmt.Println(output)
+	return privateKey, publicKey, nil
 }
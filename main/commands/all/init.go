@@ -0,0 +1,416 @@
+package all
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/common/uuid"
+	"github.com/luckyluke-a/xray-core/main/commands/base"
+)
+
+var cmdInit = &base.Command{
+	UsageLine: `{{.Exec}} init [-type vless-reality|trojan-tls|ss2022] [-server address] [-port port] [-sni name] [-cert file] [-key file] [-method method] [-name name] [-o dir] [-y]`,
+	Short:     `Generate a pair of server and client configs`,
+	Long: `
+Generate a server config and a matching client config, with new keys, IDs
+and passwords, and print the share link of the server. The options not
+given by flags are asked for, unless -y is given.
+
+Setups:
+
+	vless-reality
+		VLESS with XTLS Vision over REALITY, which looks like a TLS
+		connection to another website, and needs no certificate.
+
+	trojan-tls
+		Trojan over TLS, with the certificate of the domain of the
+		server.
+
+	ss2022
+		Shadowsocks 2022, over TCP and UDP.
+
+Arguments:
+
+	-type
+		The setup. Default vless-reality
+
+	-server
+		The domain or IP of the server that clients connect to.
+
+	-port
+		The port of the server. Default 443, or 8388 for ss2022
+
+	-sni
+		vless-reality: The website to look like. Default www.microsoft.com
+		trojan-tls: The domain of the certificate. Default the server
+
+	-cert, -key
+		trojan-tls: The certificate and key files on the server.
+		Default those of Let's Encrypt for the domain
+
+	-method
+		ss2022: The method. Default 2022-blake3-aes-128-gcm
+
+	-name
+		The name of the share link. Default the setup
+
+	-o
+		The dir to write server.json and client.json to. Default the
+		current dir
+
+	-y
+		Use the defaults of options not given, without asking.
+
+Examples:
+
+    {{.Exec}} init
+    {{.Exec}} init -type vless-reality -server 203.0.113.1 -y
+    {{.Exec}} init -type trojan-tls -server example.com -o /tmp/xray -y
+`,
+}
+
+func init() {
+	cmdInit.Run = executeInit // break init loop
+}
+
+var (
+	initType   = cmdInit.Flag.String("type", "", "")
+	initServer = cmdInit.Flag.String("server", "", "")
+	initPort   = cmdInit.Flag.String("port", "", "")
+	initSNI    = cmdInit.Flag.String("sni", "", "")
+	initCert   = cmdInit.Flag.String("cert", "", "")
+	initKey    = cmdInit.Flag.String("key", "", "")
+	initMethod = cmdInit.Flag.String("method", "", "")
+	initName   = cmdInit.Flag.String("name", "", "")
+	initOutput = cmdInit.Flag.String("o", ".", "")
+	initYes    = cmdInit.Flag.Bool("y", false, "")
+)
+
+// ss2022KeySizes are the key sizes of the Shadowsocks 2022 methods.
+var ss2022KeySizes = map[string]int{
+	"2022-blake3-aes-128-gcm":       16,
+	"2022-blake3-aes-256-gcm":       32,
+	"2022-blake3-chacha20-poly1305": 32,
+}
+
+// initAsker fills the options not given by flags.
+type initAsker struct {
+	cmd    *base.Command
+	reader *bufio.Reader
+}
+
+// ask sets value to the answer of the question, or to def if it's empty.
+// Options given by flags are not asked.
+func (a *initAsker) ask(name string, value *string, question string, def string) {
+	given := false
+	a.cmd.Flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	if given {
+		return
+	}
+	*value = def
+	if *initYes {
+		return
+	}
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, err := a.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		base.Fatalf("failed to read answer: %s", err)
+	}
+	if answer = strings.TrimSpace(answer); answer != "" {
+		*value = answer
+	}
+}
+
+// initSetup is the part of the configs that depends on the setup.
+type initSetup struct {
+	inbound  map[string]interface{}
+	outbound map[string]interface{}
+	link     *url.URL
+}
+
+func executeInit(cmd *base.Command, args []string) {
+	a := &initAsker{cmd: cmd, reader: bufio.NewReader(os.Stdin)}
+
+	a.ask("type", initType, "Setup (vless-reality, trojan-tls, ss2022)", "vless-reality")
+	defaultPort := "443"
+	switch *initType {
+	case "vless-reality", "trojan-tls":
+	case "ss2022":
+		defaultPort = "8388"
+	default:
+		base.Fatalf("unknown setup: %s", *initType)
+	}
+	a.ask("server", initServer, "Domain or IP of the server", "")
+	if *initServer == "" {
+		base.Fatalf("the server is required")
+	}
+	a.ask("port", initPort, "Port", defaultPort)
+	port, err := strconv.Atoi(*initPort)
+	if err != nil || port <= 0 || port > 65535 {
+		base.Fatalf("invalid port: %s", *initPort)
+	}
+
+	var setup *initSetup
+	switch *initType {
+	case "vless-reality":
+		a.ask("sni", initSNI, "Website to look like", "www.microsoft.com")
+		setup, err = initVLESSReality(port)
+	case "trojan-tls":
+		a.ask("sni", initSNI, "Domain of the certificate", *initServer)
+		a.ask("cert", initCert, "Certificate file on the server", "/etc/letsencrypt/live/"+*initSNI+"/fullchain.pem")
+		a.ask("key", initKey, "Key file on the server", "/etc/letsencrypt/live/"+*initSNI+"/privkey.pem")
+		setup, err = initTrojanTLS(port)
+	case "ss2022":
+		a.ask("method", initMethod, "Method", "2022-blake3-aes-128-gcm")
+		setup, err = initSS2022(port)
+	}
+	if err != nil {
+		base.Fatalf("failed to generate config: %s", err)
+	}
+	a.ask("name", initName, "Name of the share link", *initType)
+	setup.link.Host = net.JoinHostPort(*initServer, strconv.Itoa(port))
+	setup.link.Fragment = *initName
+
+	setup.inbound["tag"] = *initType
+	setup.inbound["port"] = port
+	setup.outbound["tag"] = "proxy"
+	server := map[string]interface{}{
+		"log":      map[string]interface{}{"loglevel": "warning"},
+		"inbounds": []interface{}{setup.inbound},
+		"outbounds": []interface{}{
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+			map[string]interface{}{"tag": "block", "protocol": "blackhole"},
+		},
+	}
+	client := map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "warning"},
+		"inbounds": []interface{}{
+			map[string]interface{}{"tag": "socks", "listen": "127.0.0.1", "port": 10808, "protocol": "socks", "settings": map[string]interface{}{"udp": true}},
+			map[string]interface{}{"tag": "http", "listen": "127.0.0.1", "port": 10809, "protocol": "http"},
+		},
+		"outbounds": []interface{}{
+			setup.outbound,
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+		},
+	}
+
+	serverFile := filepath.Join(*initOutput, "server.json")
+	clientFile := filepath.Join(*initOutput, "client.json")
+	for _, name := range []string{serverFile, clientFile} {
+		if _, err := os.Stat(name); err == nil {
+			base.Fatalf("%s already exists", name)
+		}
+	}
+	if err := writeInitConfig(serverFile, server); err != nil {
+		base.Fatalf("failed to write server config: %s", err)
+	}
+	if err := writeInitConfig(clientFile, client); err != nil {
+		base.Fatalf("failed to write client config: %s", err)
+	}
+	if !*initYes {
+		fmt.Println()
+	}
+	fmt.Println("Server config:", serverFile)
+	fmt.Println("Client config:", clientFile)
+	fmt.Println("Share link:")
+	fmt.Println(setup.link.String())
+}
+
+// writeInitConfig writes the config to a new file, which only the owner can
+// read, as it has keys.
+func writeInitConfig(name string, config interface{}) error {
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(content, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func randomBytes(size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func initVLESSReality(port int) (*initSetup, error) {
+	privateKey, publicKey, err := x25519KeyPair(nil)
+	if err != nil {
+		return nil, err
+	}
+	shortID, err := randomBytes(8)
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.New()
+
+	sid := hex.EncodeToString(shortID)
+	pbk := base64.RawURLEncoding.EncodeToString(publicKey)
+	return &initSetup{
+		inbound: map[string]interface{}{
+			"protocol": "vless",
+			"settings": map[string]interface{}{
+				"clients":    []interface{}{map[string]interface{}{"id": id.String(), "flow": "xtls-rprx-vision"}},
+				"decryption": "none",
+			},
+			"streamSettings": map[string]interface{}{
+				"network":  "tcp",
+				"security": "reality",
+				"realitySettings": map[string]interface{}{
+					"dest":        net.JoinHostPort(*initSNI, "443"),
+					"serverNames": []string{*initSNI},
+					"privateKey":  base64.RawURLEncoding.EncodeToString(privateKey),
+					"shortIds":    []string{sid},
+				},
+			},
+			"sniffing": map[string]interface{}{"enabled": true, "destOverride": []string{"http", "tls", "quic"}, "routeOnly": true},
+		},
+		outbound: map[string]interface{}{
+			"protocol": "vless",
+			"settings": map[string]interface{}{
+				"vnext": []interface{}{map[string]interface{}{
+					"address": *initServer,
+					"port":    port,
+					"users":   []interface{}{map[string]interface{}{"id": id.String(), "encryption": "none", "flow": "xtls-rprx-vision"}},
+				}},
+			},
+			"streamSettings": map[string]interface{}{
+				"network":  "tcp",
+				"security": "reality",
+				"realitySettings": map[string]interface{}{
+					"serverName":  *initSNI,
+					"fingerprint": "chrome",
+					"publicKey":   pbk,
+					"shortId":     sid,
+				},
+			},
+		},
+		link: &url.URL{
+			Scheme: "vless",
+			User:   url.User(id.String()),
+			RawQuery: url.Values{
+				"encryption": {"none"},
+				"flow":       {"xtls-rprx-vision"},
+				"security":   {"reality"},
+				"sni":        {*initSNI},
+				"fp":         {"chrome"},
+				"pbk":        {pbk},
+				"sid":        {sid},
+				"type":       {"tcp"},
+			}.Encode(),
+		},
+	}, nil
+}
+
+func initTrojanTLS(port int) (*initSetup, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	password := hex.EncodeToString(b)
+
+	return &initSetup{
+		inbound: map[string]interface{}{
+			"protocol": "trojan",
+			"settings": map[string]interface{}{
+				"clients": []interface{}{map[string]interface{}{"password": password}},
+			},
+			"streamSettings": map[string]interface{}{
+				"network":  "tcp",
+				"security": "tls",
+				"tlsSettings": map[string]interface{}{
+					"alpn":         []string{"h2", "http/1.1"},
+					"certificates": []interface{}{map[string]interface{}{"certificateFile": *initCert, "keyFile": *initKey}},
+				},
+			},
+			"sniffing": map[string]interface{}{"enabled": true, "destOverride": []string{"http", "tls", "quic"}, "routeOnly": true},
+		},
+		outbound: map[string]interface{}{
+			"protocol": "trojan",
+			"settings": map[string]interface{}{
+				"servers": []interface{}{map[string]interface{}{"address": *initServer, "port": port, "password": password}},
+			},
+			"streamSettings": map[string]interface{}{
+				"network":     "tcp",
+				"security":    "tls",
+				"tlsSettings": map[string]interface{}{"serverName": *initSNI, "fingerprint": "chrome"},
+			},
+		},
+		link: &url.URL{
+			Scheme: "trojan",
+			User:   url.User(password),
+			RawQuery: url.Values{
+				"security": {"tls"},
+				"sni":      {*initSNI},
+				"fp":       {"chrome"},
+				"type":     {"tcp"},
+			}.Encode(),
+		},
+	}, nil
+}
+
+func initSS2022(port int) (*initSetup, error) {
+	size, found := ss2022KeySizes[*initMethod]
+	if !found {
+		return nil, fmt.Errorf("unknown method: %s", *initMethod)
+	}
+	key, err := randomBytes(size)
+	if err != nil {
+		return nil, err
+	}
+	password := base64.StdEncoding.EncodeToString(key)
+
+	return &initSetup{
+		inbound: map[string]interface{}{
+			"protocol": "shadowsocks",
+			"settings": map[string]interface{}{
+				"method":   *initMethod,
+				"password": password,
+				"network":  "tcp,udp",
+			},
+		},
+		outbound: map[string]interface{}{
+			"protocol": "shadowsocks",
+			"settings": map[string]interface{}{
+				"servers": []interface{}{map[string]interface{}{"address": *initServer, "port": port, "method": *initMethod, "password": password}},
+			},
+		},
+		// SIP002 link, whose user info of 2022 methods is not base64.
+		link: &url.URL{
+			Scheme: "ss",
+			User:   url.UserPassword(*initMethod, password),
+		},
+	}, nil
+}
@@ -0,0 +1,52 @@
+package all
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/protocol/tls/cert"
+	"github.com/luckyluke-a/xray-core/infra/conf/serial"
+)
+
+func TestInitConfigs(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM := cert.MustGenerate(nil, cert.DNSNames("example.com")).ToPEM()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	common.Must(os.WriteFile(certFile, certPEM, 0o600))
+	common.Must(os.WriteFile(keyFile, keyPEM, 0o600))
+
+	for _, setup := range []string{"vless-reality", "trojan-tls", "ss2022"} {
+		output := filepath.Join(dir, setup)
+		// All the flags are given, as flags given before are not asked
+		// again.
+		common.Must(cmdInit.Flag.Parse([]string{
+			"-type", setup,
+			"-server", "example.com",
+			"-port", "8443",
+			"-sni", "example.com",
+			"-cert", certFile,
+			"-key", keyFile,
+			"-method", "2022-blake3-aes-256-gcm",
+			"-name", setup,
+			"-o", output,
+			"-y",
+		}))
+		executeInit(cmdInit, cmdInit.Flag.Args())
+
+		for _, name := range []string{"server.json", "client.json"} {
+			f, err := os.Open(filepath.Join(output, name))
+			common.Must(err)
+			config, err := serial.DecodeJSONConfig(f)
+			f.Close()
+			if err != nil {
+				t.Fatal(setup, " ", name, ": ", err)
+			}
+			if _, err := config.Build(); err != nil {
+				t.Error(setup, " ", name, ": ", err)
+			}
+		}
+	}
+}
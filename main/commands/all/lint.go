@@ -0,0 +1,143 @@
+package all
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/infra/conf"
+	"github.com/luckyluke-a/xray-core/infra/conf/serial"
+	"github.com/luckyluke-a/xray-core/main/commands/base"
+)
+
+var cmdLint = &base.Command{
+	UsageLine: `{{.Exec}} lint [-format json] [-schema] [file or dir]...`,
+	Short:     `Check config files`,
+	Long: `
+Check config files, and report all the problems found, with the files and
+lines. The files are merged as "{{.Exec}} run" does.
+
+Unknown and deprecated fields, invalid values, duplicate tags, tags not
+found, and routing rules never matched are reported. JSON files have line
+numbers unless they use templates, while YAML and TOML files have paths
+only.
+
+Arguments:
+
+	-format
+		Format of the files: json, yaml or toml. Default by the extensions.
+
+	-schema
+		Print the JSON Schema of the config, which can be used by editors.
+
+The exit code is 1 if errors are found. Warnings don't change it.
+
+Examples:
+
+    {{.Exec}} lint config.json
+    {{.Exec}} lint /etc/xray/confdir
+    {{.Exec}} lint -schema > xray.schema.json
+`,
+}
+
+func init() {
+	cmdLint.Run = executeLint // break init loop
+}
+
+var (
+	lintFormat = cmdLint.Flag.String("format", "", "")
+	lintSchema = cmdLint.Flag.Bool("schema", false, "")
+)
+
+func executeLint(cmd *base.Command, args []string) {
+	if *lintSchema {
+		b, err := json.MarshalIndent(conf.GenerateSchema(), "", "  ")
+		if err != nil {
+			base.Fatalf("failed to generate schema: %s", err)
+		}
+		fmt.Println(string(b))
+		return
+	}
+
+	files, err := lintSources(args)
+	if err != nil {
+		base.Fatalf("%s", err)
+	}
+	if len(files) == 0 {
+		base.Fatalf("no config files")
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, p := range serial.LintConfigFiles(files) {
+		fmt.Println(p)
+		if p.Warning {
+			warningCount++
+		} else {
+			errorCount++
+		}
+	}
+	if errorCount > 0 || warningCount > 0 {
+		fmt.Printf("%d error(s), %d warning(s)\n", errorCount, warningCount)
+	}
+	if errorCount > 0 {
+		base.SetExitStatus(1)
+		base.Exit()
+	}
+}
+
+// lintSources returns the config files of the arguments. The files in a
+// directory are added in the order of names, like -confdir.
+func lintSources(args []string) ([]*core.ConfigSource, error) {
+	var files []*core.ConfigSource
+	add := func(name string, explicit bool) error {
+		format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(name), "."))
+		if name == "stdin:" {
+			format = "json"
+		}
+		if *lintFormat != "" {
+			forced := core.GetFormatByExtension(*lintFormat)
+			if !explicit && format != forced {
+				return nil
+			}
+			format = forced
+		}
+		switch format {
+		case "json", "yaml", "toml":
+			files = append(files, &core.ConfigSource{Name: name, Format: format})
+		case "":
+			if explicit {
+				return fmt.Errorf("unknown format of %s", name)
+			}
+		default:
+			if explicit {
+				return fmt.Errorf("%s config is not supported: %s", format, name)
+			}
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			if err := add(arg, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				if err := add(filepath.Join(arg, entry.Name()), false); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return files, nil
+}
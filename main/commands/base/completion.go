@@ -0,0 +1,235 @@
+package base
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// completionNode is a command in the completion scripts. Path is the
+// command names after the executable, each with a leading space, such as
+// " api adi".
+type completionNode struct {
+	Path     string
+	Commands []*Command
+	Flags    []string
+	Words    []string
+}
+
+var (
+	usageFlagRegexp   = regexp.MustCompile(`(?:^|[\s\[|])--?([a-zA-Z][\w-]*)`)
+	usageChoiceRegexp = regexp.MustCompile(`\[([a-zA-Z][\w-]*(?:\|[a-zA-Z][\w-]*)+)\]`)
+)
+
+// commandFlags returns the flags of the command. Flags parsed by the command
+// itself are only known from its usage line.
+func commandFlags(cmd *Command) []string {
+	found := make(map[string]bool)
+	cmd.Flag.VisitAll(func(f *flag.Flag) {
+		found[f.Name] = true
+	})
+	for _, match := range usageFlagRegexp.FindAllStringSubmatch(cmd.UsageLine, -1) {
+		found[match[1]] = true
+	}
+	flags := make([]string, 0, len(found))
+	for name := range found {
+		flags = append(flags, "-"+name)
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// commandWords returns the choices of arguments in the usage line of the
+// command, such as "[bash|zsh|fish]".
+func commandWords(cmd *Command) []string {
+	var words []string
+	for _, match := range usageChoiceRegexp.FindAllStringSubmatch(cmd.UsageLine, -1) {
+		words = append(words, strings.Split(match[1], "|")...)
+	}
+	return words
+}
+
+// visibleCommands returns the sub commands listed in the help of cmd.
+func visibleCommands(cmd *Command) []*Command {
+	var commands []*Command
+	for _, c := range cmd.Commands {
+		if c.Short != "" && (c.Runnable() || len(c.Commands) > 0) {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func completionNodes() []*completionNode {
+	var nodes []*completionNode
+	var walk func(path string, cmd *Command)
+	walk = func(path string, cmd *Command) {
+		commands := visibleCommands(cmd)
+		if len(commands) == 0 {
+			nodes = append(nodes, &completionNode{Path: path, Flags: commandFlags(cmd), Words: commandWords(cmd)})
+			return
+		}
+		nodes = append(nodes, &completionNode{Path: path, Commands: commands})
+		for _, c := range commands {
+			walk(path+" "+c.Name(), c)
+		}
+	}
+	walk("", RootCommand)
+	// "xray help" completes the commands like "xray".
+	nodes = append(nodes, &completionNode{Path: " help", Commands: visibleCommands(RootCommand)})
+	return nodes
+}
+
+func commandPaths(nodes []*completionNode) []string {
+	paths := make([]string, 0, len(nodes))
+	for _, node := range nodes[1:] {
+		paths = append(paths, node.Path)
+	}
+	return paths
+}
+
+func commandNames(commands []*Command) []string {
+	names := make([]string, 0, len(commands))
+	for _, c := range commands {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func quoteAll(s []string) []string {
+	quoted := make([]string, 0, len(s))
+	for _, v := range s {
+		quoted = append(quoted, quote(v))
+	}
+	return quoted
+}
+
+// GenerateCompletion writes the completion script of the commands for the
+// shell, which is one of bash, zsh and fish.
+func GenerateCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		generateBashCompletion(w)
+	case "zsh":
+		generateZshCompletion(w)
+	case "fish":
+		generateFishCompletion(w)
+	default:
+		return fmt.Errorf("unsupported shell: %s", shell)
+	}
+	return nil
+}
+
+func generateBashCompletion(w io.Writer) {
+	exec := CommandEnv.Exec
+	nodes := completionNodes()
+	fmt.Fprintf(w, "# bash completion for %s\n\n", exec)
+	fmt.Fprintf(w, "_%s() {\n", exec)
+	fmt.Fprintf(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" cmd=\"\" word i\n")
+	fmt.Fprintf(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(w, "\t\tword=\"${COMP_WORDS[i]}\"\n")
+	fmt.Fprintf(w, "\t\tcase \"$cmd $word\" in\n")
+	fmt.Fprintf(w, "\t\t%s) cmd=\"$cmd $word\" ;;\n", strings.Join(quoteAll(commandPaths(nodes)), "|"))
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\tdone\n")
+	fmt.Fprintf(w, "\tcase \"$cmd\" in\n")
+	for _, node := range nodes {
+		words := commandNames(node.Commands)
+		if len(node.Commands) == 0 {
+			// Other arguments are files, completed by "-o default".
+			fmt.Fprintf(w, "\t%s)\n", quote(node.Path))
+			fmt.Fprintf(w, "\t\tif [[ $cur == -* ]]; then\n")
+			fmt.Fprintf(w, "\t\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", quote(strings.Join(node.Flags, " ")))
+			fmt.Fprintf(w, "\t\telse\n")
+			fmt.Fprintf(w, "\t\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", quote(strings.Join(node.Words, " ")))
+			fmt.Fprintf(w, "\t\tfi ;;\n")
+			continue
+		}
+		fmt.Fprintf(w, "\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", quote(node.Path), quote(strings.Join(words, " ")))
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "complete -o default -F _%s %s\n", exec, exec)
+}
+
+func generateZshCompletion(w io.Writer) {
+	exec := CommandEnv.Exec
+	nodes := completionNodes()
+	fmt.Fprintf(w, "#compdef %s\n\n", exec)
+	fmt.Fprintf(w, "_%s() {\n", exec)
+	fmt.Fprintf(w, "\tlocal cmd=\"\" word i\n")
+	fmt.Fprintf(w, "\tlocal -a commands\n")
+	fmt.Fprintf(w, "\tfor ((i = 2; i < CURRENT; i++)); do\n")
+	fmt.Fprintf(w, "\t\tword=\"${words[i]}\"\n")
+	fmt.Fprintf(w, "\t\tcase \"$cmd $word\" in\n")
+	fmt.Fprintf(w, "\t\t(%s) cmd=\"$cmd $word\" ;;\n", strings.Join(quoteAll(commandPaths(nodes)), "|"))
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\tdone\n")
+	fmt.Fprintf(w, "\tcase \"$cmd\" in\n")
+	for _, node := range nodes {
+		if len(node.Commands) == 0 {
+			fmt.Fprintf(w, "\t(%s)\n", quote(node.Path))
+			fmt.Fprintf(w, "\t\tif [[ $PREFIX == -* ]]; then\n")
+			fmt.Fprintf(w, "\t\t\t%s\n", strings.Join(append([]string{"compadd", "--"}, quoteAll(node.Flags)...), " "))
+			fmt.Fprintf(w, "\t\telse\n")
+			if len(node.Words) > 0 {
+				fmt.Fprintf(w, "\t\t\tcompadd -- %s\n", strings.Join(quoteAll(node.Words), " "))
+			} else {
+				fmt.Fprintf(w, "\t\t\t_files\n")
+			}
+			fmt.Fprintf(w, "\t\tfi ;;\n")
+			continue
+		}
+		described := make([]string, 0, len(node.Commands))
+		for _, c := range node.Commands {
+			described = append(described, c.Name()+":"+c.Short)
+		}
+		fmt.Fprintf(w, "\t(%s)\n", quote(node.Path))
+		fmt.Fprintf(w, "\t\tcommands=(%s)\n", strings.Join(quoteAll(described), " "))
+		fmt.Fprintf(w, "\t\t_describe command commands ;;\n")
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "}\n\n")
+	// Works both from fpath and by source.
+	fmt.Fprintf(w, "if [[ $funcstack[1] == _%s ]]; then\n", exec)
+	fmt.Fprintf(w, "\t_%s \"$@\"\n", exec)
+	fmt.Fprintf(w, "else\n")
+	fmt.Fprintf(w, "\tcompdef _%s %s\n", exec, exec)
+	fmt.Fprintf(w, "fi\n")
+}
+
+func generateFishCompletion(w io.Writer) {
+	exec := CommandEnv.Exec
+	nodes := completionNodes()
+	fmt.Fprintf(w, "# fish completion for %s\n\n", exec)
+	fmt.Fprintf(w, "function __%s_command\n", exec)
+	fmt.Fprintf(w, "\tset -l cmd ''\n")
+	fmt.Fprintf(w, "\tfor word in (commandline -opc)[2..-1]\n")
+	fmt.Fprintf(w, "\t\tcontains -- \"$cmd $word\" %s; and set cmd \"$cmd $word\"\n", strings.Join(quoteAll(commandPaths(nodes)), " "))
+	fmt.Fprintf(w, "\tend\n")
+	fmt.Fprintf(w, "\techo $cmd\n")
+	fmt.Fprintf(w, "end\n\n")
+	fmt.Fprintf(w, "function __%s_is\n", exec)
+	fmt.Fprintf(w, "\ttest (__%s_command) = \"$argv[1]\"\n", exec)
+	fmt.Fprintf(w, "end\n\n")
+	fmt.Fprintf(w, "complete -c %s -e\n", exec)
+	for _, node := range nodes {
+		condition := fmt.Sprintf("\"__%s_is '%s'\"", exec, node.Path)
+		for _, c := range node.Commands {
+			fmt.Fprintf(w, "complete -c %s -f -n %s -a %s -d %s\n", exec, condition, quote(c.Name()), quote(c.Short))
+		}
+		for _, f := range node.Flags {
+			fmt.Fprintf(w, "complete -c %s -n %s -o %s\n", exec, condition, quote(strings.TrimPrefix(f, "-")))
+		}
+		for _, word := range node.Words {
+			fmt.Fprintf(w, "complete -c %s -f -n %s -a %s\n", exec, condition, quote(word))
+		}
+	}
+}
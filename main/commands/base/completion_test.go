@@ -0,0 +1,180 @@
+package base
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// withCompletionTree replaces the commands with a small tree while f runs.
+func withCompletionTree(f func()) {
+	saved := RootCommand
+	defer func() { RootCommand = saved }()
+
+	run := func(*Command, []string) {}
+	stats := &Command{UsageLine: "{{.Exec}} api stats [-server host]", Short: "Query stats", Run: run}
+	stats.Flag.Bool("json", false, "")
+	RootCommand = &Command{
+		UsageLine: "xray",
+		Commands: []*Command{
+			{UsageLine: "{{.Exec}} api", Short: "Call an API", Commands: []*Command{stats}},
+			{UsageLine: "{{.Exec}} completion [bash|zsh|fish]", Short: "Generate 'completion'", Run: run},
+			// hidden without a short description
+			{UsageLine: "{{.Exec}} hidden", Run: run},
+		},
+	}
+	f()
+}
+
+func TestGenerateCompletion(t *testing.T) {
+	expected := map[string]string{
+		"bash": `# bash completion for xray
+
+_xray() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" cmd="" word i
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		word="${COMP_WORDS[i]}"
+		case "$cmd $word" in
+		' api'|' api stats'|' completion'|' help') cmd="$cmd $word" ;;
+		esac
+	done
+	case "$cmd" in
+	'') COMPREPLY=($(compgen -W 'api completion' -- "$cur")) ;;
+	' api') COMPREPLY=($(compgen -W 'stats' -- "$cur")) ;;
+	' api stats')
+		if [[ $cur == -* ]]; then
+			COMPREPLY=($(compgen -W '-json -server' -- "$cur"))
+		else
+			COMPREPLY=($(compgen -W '' -- "$cur"))
+		fi ;;
+	' completion')
+		if [[ $cur == -* ]]; then
+			COMPREPLY=($(compgen -W '' -- "$cur"))
+		else
+			COMPREPLY=($(compgen -W 'bash zsh fish' -- "$cur"))
+		fi ;;
+	' help') COMPREPLY=($(compgen -W 'api completion' -- "$cur")) ;;
+	esac
+}
+
+complete -o default -F _xray xray
+`,
+		"zsh": `#compdef xray
+
+_xray() {
+	local cmd="" word i
+	local -a commands
+	for ((i = 2; i < CURRENT; i++)); do
+		word="${words[i]}"
+		case "$cmd $word" in
+		(' api'|' api stats'|' completion'|' help') cmd="$cmd $word" ;;
+		esac
+	done
+	case "$cmd" in
+	('')
+		commands=('api:Call an API' 'completion:Generate '\''completion'\''')
+		_describe command commands ;;
+	(' api')
+		commands=('stats:Query stats')
+		_describe command commands ;;
+	(' api stats')
+		if [[ $PREFIX == -* ]]; then
+			compadd -- '-json' '-server'
+		else
+			_files
+		fi ;;
+	(' completion')
+		if [[ $PREFIX == -* ]]; then
+			compadd --
+		else
+			compadd -- 'bash' 'zsh' 'fish'
+		fi ;;
+	(' help')
+		commands=('api:Call an API' 'completion:Generate '\''completion'\''')
+		_describe command commands ;;
+	esac
+}
+
+if [[ $funcstack[1] == _xray ]]; then
+	_xray "$@"
+else
+	compdef _xray xray
+fi
+`,
+		"fish": `# fish completion for xray
+
+function __xray_command
+	set -l cmd ''
+	for word in (commandline -opc)[2..-1]
+		contains -- "$cmd $word" ' api' ' api stats' ' completion' ' help'; and set cmd "$cmd $word"
+	end
+	echo $cmd
+end
+
+function __xray_is
+	test (__xray_command) = "$argv[1]"
+end
+
+complete -c xray -e
+complete -c xray -f -n "__xray_is ''" -a 'api' -d 'Call an API'
+complete -c xray -f -n "__xray_is ''" -a 'completion' -d 'Generate '\''completion'\'''
+complete -c xray -f -n "__xray_is ' api'" -a 'stats' -d 'Query stats'
+complete -c xray -n "__xray_is ' api stats'" -o 'json'
+complete -c xray -n "__xray_is ' api stats'" -o 'server'
+complete -c xray -f -n "__xray_is ' completion'" -a 'bash'
+complete -c xray -f -n "__xray_is ' completion'" -a 'zsh'
+complete -c xray -f -n "__xray_is ' completion'" -a 'fish'
+complete -c xray -f -n "__xray_is ' help'" -a 'api' -d 'Call an API'
+complete -c xray -f -n "__xray_is ' help'" -a 'completion' -d 'Generate '\''completion'\'''
+`,
+	}
+	withCompletionTree(func() {
+		for _, shell := range []string{"bash", "zsh", "fish"} {
+			var b strings.Builder
+			if err := GenerateCompletion(&b, shell); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != expected[shell] {
+				t.Errorf("unexpected %s completion:\n%s", shell, b.String())
+			}
+		}
+		if err := GenerateCompletion(&strings.Builder{}, "csh"); err == nil {
+			t.Error("expected an error for an unsupported shell")
+		}
+	})
+}
+
+func TestBashCompletion(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("no bash")
+	}
+	var script strings.Builder
+	withCompletionTree(func() {
+		generateBashCompletion(&script)
+	})
+
+	for line, expected := range map[string]string{
+		"xray ":             "api completion",
+		"xray a":            "api",
+		"xray api ":         "stats",
+		"xray api stats -":  "-json -server",
+		"xray completion z": "zsh",
+		"xray help ":        "api completion",
+	} {
+		// the word being completed is the last one, which is empty after
+		// a space
+		check := script.String() + `
+COMP_WORDS=(` + line + `"")
+COMP_CWORD=$((${#COMP_WORDS[@]} - 1))
+_xray
+echo "${COMPREPLY[*]}"
+`
+		output, err := exec.Command("bash", "-c", check).Output()
+		if err != nil {
+			t.Fatal(line, ": ", err)
+		}
+		if actual := strings.TrimSpace(string(output)); actual != expected {
+			t.Errorf("completion of %q: expected %q, but got %q", line, expected, actual)
+		}
+	}
+}
@@ -5,14 +5,9 @@ package external
 import (
 	"bytes"
 	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"strings"
-	"time"
 
-	"github.com/luckyluke-a/xray-core/common/buf"
-	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/platform/ctlcmd"
 	"github.com/luckyluke-a/xray-core/main/confloader"
 )
@@ -37,39 +32,14 @@ func ConfigLoader(arg string) (out io.Reader, err error) {
 	return
 }
 
+// FetchHTTPContent returns the content of a remote config. See RemoteSource
+// for the authentication, signature and cache settings it uses.
 func FetchHTTPContent(target string) ([]byte, error) {
-	parsedTarget, err := url.Parse(target)
+	s, err := GetRemoteSource(target)
 	if err != nil {
-		return nil, errors.New("invalid URL: ", target).Base(err)
-	}
-
-	if s := strings.ToLower(parsedTarget.Scheme); s != "http" && s != "https" {
-		return nil, errors.New("invalid scheme: ", parsedTarget.Scheme)
-	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	resp, err := client.Do(&http.Request{
-		Method: "GET",
-		URL:    parsedTarget,
-		Close:  true,
-	})
-	if err != nil {
-		return nil, errors.New("failed to dial to ", target).Base(err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, errors.New("unexpected HTTP status code: ", resp.StatusCode)
-	}
-
-	content, err := buf.ReadAllToBytes(resp.Body)
-	if err != nil {
-		return nil, errors.New("failed to read HTTP response").Base(err)
+		return nil, err
 	}
-
-	return content, nil
+	return s.Load()
 }
 
 func ExtConfigLoader(files []string, reader io.Reader) (io.Reader, error) {
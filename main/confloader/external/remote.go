@@ -0,0 +1,443 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/buf"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/platform"
+)
+
+const remoteRetries = 3
+
+// remoteSettings are the settings of RemoteSource read from the environment.
+type remoteSettings struct {
+	token     string
+	username  string
+	password  string
+	publicKey ed25519.PublicKey
+	cacheDir  string
+	client    *http.Client
+}
+
+func getEnv(name string) string {
+	return platform.NewEnvFlag(name).GetValue(func() string { return "" })
+}
+
+func loadRemoteSettings() (*remoteSettings, error) {
+	settings := &remoteSettings{
+		token:    getEnv(platform.RemoteConfigToken),
+		username: getEnv(platform.RemoteConfigUsername),
+		password: getEnv(platform.RemoteConfigPassword),
+		cacheDir: getEnv(platform.RemoteConfigCache),
+	}
+
+	if s := getEnv(platform.RemoteConfigPublicKey); s != "" {
+		key, err := decodeBase64(s)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid public key: ", s)
+		}
+		settings.publicKey = key
+	}
+
+	tlsConfig := &tls.Config{}
+	if certFile := getEnv(platform.RemoteConfigCert); certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, getEnv(platform.RemoteConfigKey))
+		if err != nil {
+			return nil, errors.New("failed to load client certificate").Base(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if caFile := getEnv(platform.RemoteConfigCA); caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.New("failed to read CA").Base(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificate in CA file ", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	settings.client = &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+
+	return settings, nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := encoding.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New("invalid base64: ", s)
+}
+
+// RemoteSource is a config fetched over HTTP(S). It keeps the last good
+// content, so that later fetches only download a changed config. It is set
+// up by the environment:
+//
+//	XRAY_REMOTE_TOKEN     bearer token
+//	XRAY_REMOTE_USERNAME  basic auth user, also taken from the URL
+//	XRAY_REMOTE_PASSWORD  basic auth password
+//	XRAY_REMOTE_CERT      client certificate file for mTLS
+//	XRAY_REMOTE_KEY       client key file for mTLS
+//	XRAY_REMOTE_CA        CA file to verify the server with
+//	XRAY_REMOTE_PUBKEY    ed25519 public key in base64. Configs must come with
+//	                      a signature at the URL with ".sig" appended to the path.
+//	XRAY_REMOTE_CACHE     dir to keep the last good configs in, used when the
+//	                      server can't be reached
+//
+// The signature is the version of the config and the base64 ed25519 signature
+// of the version, a newline and the config, separated by a space. The version
+// is a number increased with each config, like a Unix timestamp, so that an
+// older config signed before is rejected. The last version is kept in the
+// cache across restarts.
+type RemoteSource struct {
+	url      *url.URL
+	name     string
+	settings *remoteSettings
+
+	access       sync.Mutex
+	etag         string
+	lastModified string
+	content      []byte
+	version      uint64
+}
+
+var (
+	remoteAccess  sync.Mutex
+	remoteSources = make(map[string]*RemoteSource)
+)
+
+// GetRemoteSource returns the source of the URL, which is shared by all
+// configs loaded from it.
+func GetRemoteSource(target string) (*RemoteSource, error) {
+	remoteAccess.Lock()
+	defer remoteAccess.Unlock()
+
+	if s, found := remoteSources[target]; found {
+		return s, nil
+	}
+
+	parsedTarget, err := url.Parse(target)
+	if err != nil {
+		return nil, errors.New("invalid URL: ", target).Base(err)
+	}
+	if s := strings.ToLower(parsedTarget.Scheme); s != "http" && s != "https" {
+		return nil, errors.New("invalid scheme: ", parsedTarget.Scheme)
+	}
+	settings, err := loadRemoteSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &RemoteSource{
+		url:      parsedTarget,
+		name:     parsedTarget.Redacted(),
+		settings: settings,
+	}
+	if settings.publicKey != nil && settings.cacheDir != "" {
+		// a config older than the cached one is rejected after a restart
+		if _, version, err := s.loadCache(); err == nil {
+			s.version = version
+		}
+	}
+	remoteSources[target] = s
+	return s, nil
+}
+
+func (s *RemoteSource) String() string {
+	return s.name
+}
+
+// Content returns the last good content, or nil if there is none.
+func (s *RemoteSource) Content() []byte {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	return s.content
+}
+
+// get requests the target, which is conditional if etag or lastModified is
+// set. It's called without holding access, as it retries and waits.
+func (s *RemoteSource) get(target *url.URL, etag, lastModified string) (*http.Response, error) {
+	request := &http.Request{
+		Method: "GET",
+		URL:    target,
+		Header: make(http.Header),
+		Close:  true,
+	}
+	switch {
+	case s.settings.token != "":
+		request.Header.Set("Authorization", "Bearer "+s.settings.token)
+	case s.settings.username != "":
+		request.SetBasicAuth(s.settings.username, s.settings.password)
+	}
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		request.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	var err error
+	for i := 0; i < remoteRetries; i++ {
+		if i > 0 {
+			time.Sleep(time.Second << (i - 1))
+		}
+		var resp *http.Response
+		resp, err = s.settings.client.Do(request)
+		if err != nil {
+			err = errors.New("failed to dial to ", s.name).Base(err)
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			resp.Body.Close()
+			err = errors.New("unexpected HTTP status code: ", resp.StatusCode)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+func (s *RemoteSource) read(target *url.URL) ([]byte, error) {
+	resp, err := s.get(target, "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected HTTP status code: ", resp.StatusCode)
+	}
+	content, err := buf.ReadAllToBytes(resp.Body)
+	if err != nil {
+		return nil, errors.New("failed to read HTTP response").Base(err)
+	}
+	return content, nil
+}
+
+// Fetch downloads the config, and returns whether it has changed since the
+// last fetch. A config without a valid signature, or older than the last one,
+// is rejected, if a public key is set.
+func (s *RemoteSource) Fetch() (bool, error) {
+	s.access.Lock()
+	var etag, lastModified string
+	cached := s.content != nil
+	if cached {
+		etag, lastModified = s.etag, s.lastModified
+	}
+	s.access.Unlock()
+
+	resp, err := s.get(s.url, etag, lastModified)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New("unexpected HTTP status code: ", resp.StatusCode)
+	}
+	content, err := buf.ReadAllToBytes(resp.Body)
+	if err != nil {
+		return false, errors.New("failed to read HTTP response").Base(err)
+	}
+
+	var signature []byte
+	var version uint64
+	if s.settings.publicKey != nil {
+		signatureURL := *s.url
+		signatureURL.Path += ".sig"
+		signatureURL.RawPath = ""
+		if signature, err = s.read(&signatureURL); err != nil {
+			return false, errors.New("failed to fetch signature").Base(err)
+		}
+		if version, err = s.verify(content, signature); err != nil {
+			return false, err
+		}
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	changed := !bytes.Equal(content, s.content)
+	// the version is checked here, as another fetch may have finished in the
+	// meantime. It's always 0 without a public key.
+	if version < s.version || (s.settings.publicKey != nil && version == s.version && s.content != nil && changed) {
+		return false, errors.New("rejected config of ", s.name, " with version ", version, ", which is not newer than ", s.version)
+	}
+	resigned := version != s.version
+	s.content = content
+	s.version = version
+	s.etag = resp.Header.Get("ETag")
+	s.lastModified = resp.Header.Get("Last-Modified")
+	if (changed || resigned) && s.settings.cacheDir != "" {
+		if err := s.saveCache(content, signature); err != nil {
+			errors.LogWarningInner(context.Background(), err, "failed to cache config ", s.name)
+		}
+	}
+	return changed, nil
+}
+
+// verify checks the signature of the content, and returns the version signed
+// with it.
+func (s *RemoteSource) verify(content, signature []byte) (uint64, error) {
+	fields := strings.Fields(string(signature))
+	if len(fields) != 2 {
+		return 0, errors.New("invalid signature of ", s.name)
+	}
+	version, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid version in the signature of ", s.name).Base(err)
+	}
+	decoded, err := decodeBase64(fields[1])
+	if err != nil {
+		return 0, errors.New("invalid signature of ", s.name)
+	}
+	if len(decoded) != ed25519.SignatureSize || !ed25519.Verify(s.settings.publicKey, signedContent(version, content), decoded) {
+		return 0, errors.New("signature verification failed for ", s.name)
+	}
+	return version, nil
+}
+
+// signedContent returns what the signature of a config covers.
+func signedContent(version uint64, content []byte) []byte {
+	signed := strconv.AppendUint(nil, version, 10)
+	signed = append(signed, '\n')
+	return append(signed, content...)
+}
+
+func (s *RemoteSource) cachePath() string {
+	sum := sha256.Sum256([]byte(s.url.String()))
+	return filepath.Join(s.settings.cacheDir, hex.EncodeToString(sum[:]))
+}
+
+func writeFileAtomic(path string, content []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *RemoteSource) saveCache(content, signature []byte) error {
+	if err := os.MkdirAll(s.settings.cacheDir, 0o700); err != nil {
+		return err
+	}
+	path := s.cachePath()
+	if signature != nil {
+		if err := writeFileAtomic(path+".sig", signature); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(path, content)
+}
+
+// loadCache returns the cached config and its version, whose signature is
+// verified again in case the cache has been tampered with.
+func (s *RemoteSource) loadCache() ([]byte, uint64, error) {
+	if s.settings.cacheDir == "" {
+		return nil, 0, errors.New("no cache dir")
+	}
+	path := s.cachePath()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	var version uint64
+	if s.settings.publicKey != nil {
+		signature, err := os.ReadFile(path + ".sig")
+		if err != nil {
+			return nil, 0, err
+		}
+		if version, err = s.verify(content, signature); err != nil {
+			return nil, 0, err
+		}
+	}
+	return content, version, nil
+}
+
+// Load returns the content of the config. The config is only fetched if it
+// hasn't been fetched yet, as later fetches are done by WatchRemoteConfigs, so
+// that a reload uses the content it has verified. It falls back to the cache
+// if the config can't be fetched.
+func (s *RemoteSource) Load() ([]byte, error) {
+	if content := s.Content(); content != nil {
+		return content, nil
+	}
+	_, err := s.Fetch()
+	if err == nil {
+		return s.Content(), nil
+	}
+	content, _, cacheErr := s.loadCache()
+	if cacheErr != nil {
+		return nil, err
+	}
+	errors.LogWarningInner(context.Background(), err, "failed to fetch ", s.name, ", using the cached config")
+	return content, nil
+}
+
+// WatchRemoteConfigs fetches the remote configs loaded so far every interval,
+// and calls onChange when any of them has changed. It returns once ctx is
+// done.
+func WatchRemoteConfigs(ctx context.Context, interval time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		remoteAccess.Lock()
+		sources := make([]*RemoteSource, 0, len(remoteSources))
+		for _, s := range remoteSources {
+			sources = append(sources, s)
+		}
+		remoteAccess.Unlock()
+
+		changed := false
+		for _, s := range sources {
+			c, err := s.Fetch()
+			if err != nil {
+				errors.LogWarningInner(ctx, err, "failed to fetch remote config ", s.name)
+				continue
+			}
+			if c {
+				errors.LogInfo(ctx, "remote config ", s.name, " has changed")
+				changed = true
+			}
+		}
+		if changed {
+			onChange()
+		}
+	}
+}
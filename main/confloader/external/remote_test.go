@@ -0,0 +1,207 @@
+package external
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common"
+)
+
+func TestRemoteSource(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	common.Must(err)
+
+	type signedConfig struct {
+		version uint64
+		content []byte
+	}
+	var content atomic.Value
+	content.Store(signedConfig{1, []byte(`{"log": {}}`)})
+	var up atomic.Bool
+	up.Store(true)
+	var forged atomic.Bool
+	blocked := make(chan struct{})
+	close(blocked)
+	var block atomic.Value
+	block.Store(blocked)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		<-block.Load().(chan struct{})
+		c := content.Load().(signedConfig)
+		signed := signedContent(c.version, c.content)
+		etag := `"` + base64.RawURLEncoding.EncodeToString(ed25519.Sign(privateKey, signed)[:8]) + `"`
+		switch r.URL.Path {
+		case "/config.json":
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Write(c.content)
+		case "/config.json.sig":
+			if forged.Load() {
+				signed = []byte("forged")
+			}
+			w.Write([]byte(strconv.FormatUint(c.version, 10) + " " + base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signed))))
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("XRAY_REMOTE_TOKEN", "token")
+	t.Setenv("XRAY_REMOTE_PUBKEY", base64.StdEncoding.EncodeToString(publicKey))
+	t.Setenv("XRAY_REMOTE_CACHE", t.TempDir())
+
+	source, err := GetRemoteSource(server.URL + "/config.json")
+	common.Must(err)
+	if changed, err := source.Fetch(); err != nil || !changed {
+		t.Fatalf("first fetch: changed %v, err %v", changed, err)
+	}
+	if changed, err := source.Fetch(); err != nil || changed {
+		t.Fatalf("unchanged fetch: changed %v, err %v", changed, err)
+	}
+
+	content.Store(signedConfig{2, []byte(`{"log": {"loglevel": "debug"}}`)})
+	forged.Store(true)
+	if _, err := source.Fetch(); err == nil {
+		t.Fatal("expected a signature error")
+	}
+	if string(source.Content()) != `{"log": {}}` {
+		t.Fatalf("unexpected content after a rejected fetch: %s", source.Content())
+	}
+	forged.Store(false)
+	if changed, err := source.Fetch(); err != nil || !changed {
+		t.Fatalf("changed fetch: changed %v, err %v", changed, err)
+	}
+
+	// An older config is rejected, even with a valid signature.
+	content.Store(signedConfig{1, []byte(`{"log": {}}`)})
+	if _, err := source.Fetch(); err == nil {
+		t.Fatal("expected a rollback error")
+	}
+	content.Store(signedConfig{2, []byte(`{"log": {"loglevel": "info"}}`)})
+	if _, err := source.Fetch(); err == nil {
+		t.Fatal("expected an error for another config of the same version")
+	}
+	if string(source.Content()) != `{"log": {"loglevel": "debug"}}` {
+		t.Fatalf("unexpected content after a rollback: %s", source.Content())
+	}
+
+	// The content is available while a fetch is waiting for the server.
+	blocked = make(chan struct{})
+	block.Store(blocked)
+	fetched := make(chan struct{})
+	go func() {
+		source.Fetch()
+		close(fetched)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	read := make(chan []byte)
+	go func() {
+		read <- source.Content()
+	}()
+	select {
+	case c := <-read:
+		if string(c) != `{"log": {"loglevel": "debug"}}` {
+			t.Fatalf("unexpected content during a fetch: %s", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("content is locked during a fetch")
+	}
+	close(blocked)
+	<-fetched
+	content.Store(signedConfig{2, []byte(`{"log": {"loglevel": "debug"}}`)})
+
+	// A new source of the same URL, as in a restarted Xray, starts from the
+	// cache when the server is down.
+	up.Store(false)
+	remoteAccess.Lock()
+	delete(remoteSources, server.URL+"/config.json")
+	remoteAccess.Unlock()
+	source, err = GetRemoteSource(server.URL + "/config.json")
+	common.Must(err)
+	c, err := source.Load()
+	if err != nil || string(c) != `{"log": {"loglevel": "debug"}}` {
+		t.Fatalf("unexpected cached content %s, err %v", c, err)
+	}
+
+	// The version of the cache is kept by a restarted Xray.
+	up.Store(true)
+	content.Store(signedConfig{1, []byte(`{"log": {}}`)})
+	remoteAccess.Lock()
+	delete(remoteSources, server.URL+"/config.json")
+	remoteAccess.Unlock()
+	source, err = GetRemoteSource(server.URL + "/config.json")
+	common.Must(err)
+	if _, err := source.Fetch(); err == nil {
+		t.Fatal("expected a rollback error after a restart")
+	}
+	up.Store(false)
+
+	other, err := GetRemoteSource(server.URL + "/other.json")
+	common.Must(err)
+	if _, err := other.Load(); err == nil {
+		t.Fatal("expected an error without a cached config")
+	}
+}
+
+func TestWatchRemoteConfigs(t *testing.T) {
+	var content atomic.Value
+	content.Store([]byte(`{"log": {}}`))
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(content.Load().([]byte))
+	}))
+	defer server.Close()
+
+	remoteAccess.Lock()
+	remoteSources = make(map[string]*RemoteSource)
+	remoteAccess.Unlock()
+
+	c, err := FetchHTTPContent(server.URL)
+	if err != nil || string(c) != `{"log": {}}` {
+		t.Fatalf("unexpected content %s, err %v", c, err)
+	}
+
+	changes := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchRemoteConfigs(ctx, 10*time.Millisecond, func() {
+		changes <- struct{}{}
+	})
+
+	content.Store([]byte(`{"log": {"loglevel": "debug"}}`))
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not found by the watcher")
+	}
+	cancel()
+
+	// the reload uses the content fetched by the watcher
+	n := requests.Load()
+	c, err = FetchHTTPContent(server.URL)
+	if err != nil || string(c) != `{"log": {"loglevel": "debug"}}` {
+		t.Fatalf("unexpected content %s, err %v", c, err)
+	}
+	if requests.Load() != n {
+		t.Fatal("config fetched again on reload")
+	}
+}
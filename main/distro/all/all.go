@@ -16,6 +16,7 @@ import (
 
 	// Developer preview services
 	_ "github.com/luckyluke-a/xray-core/app/observatory/command"
+	_ "github.com/luckyluke-a/xray-core/app/reverse/command"
 
 	// Other optional features.
 	_ "github.com/luckyluke-a/xray-core/app/dns"
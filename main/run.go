@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -11,15 +12,19 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/luckyluke-a/xray-core/app/proxyman/inbound"
 	"github.com/luckyluke-a/xray-core/common/cmdarg"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	clog "github.com/luckyluke-a/xray-core/common/log"
 	"github.com/luckyluke-a/xray-core/common/platform"
 	"github.com/luckyluke-a/xray-core/core"
 	"github.com/luckyluke-a/xray-core/main/commands/base"
+	"github.com/luckyluke-a/xray-core/main/confloader/external"
+	"github.com/luckyluke-a/xray-core/transport/internet"
 )
 
 var cmdRun = &base.Command{
@@ -40,6 +45,32 @@ The -test flag tells Xray to test config files only,
 without launching the server.
 
 The -dump flag tells Xray to print the merged config.
+
+Config files with the top-level "template": true are templates. In
+strings, "${NAME}" is replaced by the environment variable,
+"${file:path}" by the content of the file, and "${NAME:-default}" by the
+default if it's unset; "$${" is a literal "${". {"$include": "path"} is
+replaced by another config file, and {"$snippet": "name"} by the named
+value in the top-level "snippets". Other fields next to them override the
+included object. Other config files are used as they are.
+
+Config files can be http(s) URLs. The -poll=duration flag makes Xray
+fetch them again at that interval, using ETags, and reload itself once
+any of them changes. The reload uses the configs fetched by the poll, and
+drops the connections of the running config, as it is closed before the
+new one is started. If the new config fails to load or start, the
+previous one is kept. Remote configs are set up by the environment:
+XRAY_REMOTE_TOKEN sets a bearer token, XRAY_REMOTE_USERNAME and
+XRAY_REMOTE_PASSWORD set basic auth, XRAY_REMOTE_CERT, XRAY_REMOTE_KEY
+and XRAY_REMOTE_CA set mTLS, XRAY_REMOTE_PUBKEY sets an ed25519 key to
+verify the signature at the URL with ".sig" appended to the path, and
+XRAY_REMOTE_CACHE sets a dir of the last good configs, used when the
+server can't be reached.
+
+On SIGUSR2, Xray upgrades itself: it starts the binary again with
+its listening sockets, then stops accepting and waits for the
+connections to finish, for at most the time in the -drain flag.
+Listening sockets from systemd socket activation are also adopted.
 	`,
 }
 
@@ -53,6 +84,8 @@ var (
 	dump        = cmdRun.Flag.Bool("dump", false, "Dump merged config only, without launching Xray server.")
 	test        = cmdRun.Flag.Bool("test", false, "Test config file only, without launching Xray server.")
 	format      = cmdRun.Flag.String("format", "auto", "Format of input file.")
+	drain       = cmdRun.Flag.Duration("drain", 5*time.Minute, "Max time to wait for connections to finish after upgrade.")
+	poll        = cmdRun.Flag.Duration("poll", 0, "Interval to fetch remote configs again, and reload once they change.")
 
 	/* We have to do this here because Golang's Test will also need to parse flag, before
 	 * main func in this file is run.
@@ -74,7 +107,8 @@ func executeRun(cmd *base.Command, args []string) {
 	}
 
 	printVersion()
-	server, err := startXray()
+	files := getConfigFilePath(true)
+	config, server, err := startXray(files)
 	if err != nil {
 		fmt.Println("Failed to start:", err)
 		// Configuration error. Exit with a special value to prevent systemd from restarting.
@@ -90,7 +124,20 @@ func executeRun(cmd *base.Command, args []string) {
 		fmt.Println("Failed to start:", err)
 		os.Exit(-1)
 	}
-	defer server.Close()
+	r := &reloader{
+		files:  files,
+		config: config,
+		server: server,
+	}
+	defer r.Close()
+	notifyUpgradeReady()
+
+	if *poll > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// reload in the watcher, so that signals are handled meanwhile
+		go external.WatchRemoteConfigs(ctx, *poll, r.reload)
+	}
 
 	/*
 		conf.FileCache = nil
@@ -105,7 +152,38 @@ func executeRun(cmd *base.Command, args []string) {
 	{
 		osSignals := make(chan os.Signal, 1)
 		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
-		<-osSignals
+		upgradeSignals := make(chan os.Signal, 1)
+		notifyUpgradeSignal(upgradeSignals)
+		for {
+			select {
+			case <-osSignals:
+				return
+			case <-upgradeSignals:
+				if err := upgrade(); err != nil {
+					fmt.Println("Failed to upgrade:", err)
+					continue
+				}
+				drainConnections(osSignals)
+				return
+			}
+		}
+	}
+}
+
+// drainConnections stops accepting, and waits for the connections to finish.
+func drainConnections(osSignals <-chan os.Signal) {
+	internet.CloseListeners()
+	timeout := time.After(*drain)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for inbound.ActiveConnections() > 0 {
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			return
+		case <-osSignals:
+			return
+		}
 	}
 }
 
@@ -211,20 +289,80 @@ func getConfigFormat() string {
 	return f
 }
 
-func startXray() (core.Server, error) {
-	configFiles := getConfigFilePath(true)
-
+func startXray(configFiles cmdarg.Arg) (*core.Config, core.Server, error) {
 	// config, err := core.LoadConfig(getConfigFormat(), configFiles[0], configFiles)
 
 	c, err := core.LoadConfig(getConfigFormat(), configFiles)
 	if err != nil {
-		return nil, errors.New("failed to load config files: [", configFiles.String(), "]").Base(err)
+		return nil, nil, errors.New("failed to load config files: [", configFiles.String(), "]").Base(err)
 	}
 
 	server, err := core.New(c)
 	if err != nil {
-		return nil, errors.New("failed to create server").Base(err)
+		return nil, nil, errors.New("failed to create server").Base(err)
 	}
 
-	return server, nil
+	return c, server, nil
+}
+
+// reloader replaces the running server once the config files change.
+type reloader struct {
+	files cmdarg.Arg
+
+	access sync.Mutex
+	config *core.Config
+	server core.Server
+	closed bool
+}
+
+// reload replaces the running server with one of the current config files.
+// Remote configs are not fetched again, but the content the watcher has just
+// fetched and verified is used. The running config is started again if the
+// new one fails to start.
+//
+// The running server is closed before the new one is started, as they
+// listen on the same ports, so the connections of the running server are
+// dropped.
+func (r *reloader) reload() {
+	ctx := context.Background()
+	newConfig, newServer, err := startXray(r.files)
+	if err != nil {
+		errors.LogWarningInner(ctx, err, "failed to reload, keeping the running config")
+		return
+	}
+
+	r.access.Lock()
+	defer r.access.Unlock()
+	if r.closed {
+		newServer.Close()
+		return
+	}
+
+	r.server.Close()
+	if err = newServer.Start(); err == nil {
+		errors.LogInfo(ctx, "reloaded config")
+		r.config, r.server = newConfig, newServer
+		return
+	}
+	errors.LogErrorInner(ctx, err, "failed to start the new config, restoring the running config")
+	newServer.Close()
+
+	server, err := core.New(r.config)
+	if err == nil {
+		err = server.Start()
+	}
+	if err != nil {
+		fmt.Println("Failed to restore config:", err)
+		os.Exit(-1)
+	}
+	r.server = server
+}
+
+// Close closes the running server. Reloads after it are discarded.
+func (r *reloader) Close() error {
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	r.closed = true
+	return r.server.Close()
 }
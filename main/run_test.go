@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/cmdarg"
+)
+
+func TestReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	writeConfig := func(content string) {
+		common.Must(os.WriteFile(file, []byte(content), 0o600))
+	}
+	writeConfig(`{"outbounds": [{"tag": "a", "protocol": "freedom"}]}`)
+
+	files := cmdarg.Arg{file}
+	config, server, err := startXray(files)
+	common.Must(err)
+	common.Must(server.Start())
+	r := &reloader{
+		files:  files,
+		config: config,
+		server: server,
+	}
+
+	writeConfig(`{"outbounds": [{"tag": "b", "protocol": "freedom"}]}`)
+	r.reload()
+	if tag := r.config.Outbound[0].Tag; tag != "b" || r.server == server {
+		t.Fatal("config not reloaded, tag ", tag)
+	}
+
+	// an invalid config keeps the running one
+	server = r.server
+	writeConfig(`{"outbounds": [{"tag": "c", "protocol": "unknown"}]}`)
+	r.reload()
+	if tag := r.config.Outbound[0].Tag; tag != "b" || r.server != server {
+		t.Fatal("running config not kept, tag ", tag)
+	}
+
+	// reloads after closing are discarded
+	common.Must(r.Close())
+	writeConfig(`{"outbounds": [{"tag": "d", "protocol": "freedom"}]}`)
+	r.reload()
+	if tag := r.config.Outbound[0].Tag; tag != "b" {
+		t.Fatal("reloaded after closing, tag ", tag)
+	}
+}
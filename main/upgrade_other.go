@@ -0,0 +1,103 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/transport/internet"
+)
+
+// upgradeReadyEnv is the fd of a pipe, which the new process writes to once it
+// has started.
+const upgradeReadyEnv = "XRAY_UPGRADE_READY_FD"
+
+const upgradeTimeout = 30 * time.Second
+
+func notifyUpgradeSignal(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR2)
+}
+
+// upgrade starts the binary again with the listening sockets, and returns once
+// the new process has started.
+func upgrade() error {
+	files, names, err := internet.ListenerFiles()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "LISTEN_") && !strings.HasPrefix(env, upgradeReadyEnv+"=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	cmd.Env = append(cmd.Env,
+		"LISTEN_FDS="+strconv.Itoa(len(files)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+		upgradeReadyEnv+"="+strconv.Itoa(3+len(files)),
+	)
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return errors.New("failed to start new process").Base(err)
+	}
+	go cmd.Wait()
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		ready <- err
+	}()
+	select {
+	case err := <-ready:
+		if err != nil {
+			return errors.New("new process exited before ready").Base(err)
+		}
+	case <-time.After(upgradeTimeout):
+		cmd.Process.Kill()
+		return errors.New("new process is not ready in ", upgradeTimeout)
+	}
+	errors.LogInfo(context.Background(), "upgraded to process ", cmd.Process.Pid)
+	return nil
+}
+
+// notifyUpgradeReady tells the previous process that this one has started.
+func notifyUpgradeReady() {
+	fd, err := strconv.Atoi(os.Getenv(upgradeReadyEnv))
+	os.Unsetenv(upgradeReadyEnv)
+	if err != nil {
+		return
+	}
+	f := os.NewFile(uintptr(fd), "upgrade")
+	f.Write([]byte{1})
+	f.Close()
+}
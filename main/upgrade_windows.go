@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+)
+
+func notifyUpgradeSignal(c chan<- os.Signal) {}
+
+func upgrade() error {
+	return errors.New("upgrade is not supported on Windows")
+}
+
+func notifyUpgradeReady() {}
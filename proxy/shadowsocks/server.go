@@ -143,10 +143,11 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dis
 				if inbound.Source.IsValid() {
 					errors.LogInfoInner(ctx, err, "dropping invalid UDP packet from: ", inbound.Source)
 					log.Record(&log.AccessMessage{
-						From:   inbound.Source,
-						To:     "",
-						Status: log.AccessRejected,
-						Reason: err,
+						From:       inbound.Source,
+						To:         "",
+						Status:     log.AccessRejected,
+						InboundTag: session.InboundTagFromContext(ctx),
+						Reason:     err,
 					})
 				}
 				payload.Release()
@@ -191,10 +192,11 @@ func (s *Server) handleConnection(ctx context.Context, conn stat.Connection, dis
 	request, bodyReader, err := ReadTCPSession(s.validator, &bufferedReader)
 	if err != nil {
 		log.Record(&log.AccessMessage{
-			From:   conn.RemoteAddr(),
-			To:     "",
-			Status: log.AccessRejected,
-			Reason: err,
+			From:       conn.RemoteAddr(),
+			To:         "",
+			Status:     log.AccessRejected,
+			InboundTag: session.InboundTagFromContext(ctx),
+			Reason:     err,
 		})
 		return errors.New("failed to create request from: ", conn.RemoteAddr()).Base(err)
 	}
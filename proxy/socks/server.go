@@ -127,10 +127,11 @@ func (s *Server) processTCP(ctx context.Context, conn stat.Connection, dispatche
 	if err != nil {
 		if inbound.Source.IsValid() {
 			log.Record(&log.AccessMessage{
-				From:   inbound.Source,
-				To:     "",
-				Status: log.AccessRejected,
-				Reason: err,
+				From:       inbound.Source,
+				To:         "",
+				Status:     log.AccessRejected,
+				InboundTag: session.InboundTagFromContext(ctx),
+				Reason:     err,
 			})
 		}
 		return errors.New("failed to read request").Base(err)
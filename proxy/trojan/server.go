@@ -166,10 +166,11 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 		// invalid protocol
 		err = errors.New("not trojan protocol")
 		log.Record(&log.AccessMessage{
-			From:   conn.RemoteAddr(),
-			To:     "",
-			Status: log.AccessRejected,
-			Reason: err,
+			From:       conn.RemoteAddr(),
+			To:         "",
+			Status:     log.AccessRejected,
+			InboundTag: session.InboundTagFromContext(ctx),
+			Reason:     err,
 		})
 
 		shouldFallback = true
@@ -179,10 +180,11 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 			// invalid user, let's fallback
 			err = errors.New("not a valid user")
 			log.Record(&log.AccessMessage{
-				From:   conn.RemoteAddr(),
-				To:     "",
-				Status: log.AccessRejected,
-				Reason: err,
+				From:       conn.RemoteAddr(),
+				To:         "",
+				Status:     log.AccessRejected,
+				InboundTag: session.InboundTagFromContext(ctx),
+				Reason:     err,
 			})
 
 			shouldFallback = true
@@ -198,10 +200,11 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	clientReader := &ConnReader{Reader: bufferedReader}
 	if err := clientReader.ParseHeader(); err != nil {
 		log.Record(&log.AccessMessage{
-			From:   conn.RemoteAddr(),
-			To:     "",
-			Status: log.AccessRejected,
-			Reason: err,
+			From:       conn.RemoteAddr(),
+			To:         "",
+			Status:     log.AccessRejected,
+			InboundTag: session.InboundTagFromContext(ctx),
+			Reason:     err,
 		})
 		return errors.New("failed to create request from: ", conn.RemoteAddr()).Base(err)
 	}
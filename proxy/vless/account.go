@@ -1,6 +1,9 @@
 package vless
 
 import (
+	"crypto/sha256"
+
+	"github.com/luckyluke-a/xray-core/common/dice"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/protocol"
 	"github.com/luckyluke-a/xray-core/common/uuid"
@@ -16,6 +19,7 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 		ID:         protocol.NewID(id),
 		Flow:       a.Flow,       // needs parser here?
 		Encryption: a.Encryption, // needs parser here?
+		Segaro:     a.SegaroSettings,
 	}, nil
 }
 
@@ -27,6 +31,8 @@ type MemoryAccount struct {
 	Flow string
 	// Encryption of the account. Used for client connections, and only accepts "none" for now.
 	Encryption string
+	// Segaro holds the "xtls-segaro-vision" parameters for securities other than REALITY. Used for client connections.
+	Segaro *SegaroConfig
 }
 
 // Equals implements protocol.Account.Equals().
@@ -37,3 +43,51 @@ func (a *MemoryAccount) Equals(account protocol.Account) bool {
 	}
 	return a.ID.Equals(vlessAccount.ID)
 }
+
+// DefaultSegaroProfile is the name of the profile formed by the top level parameters of SegaroConfig.
+const DefaultSegaroProfile = "default"
+
+// AllProfiles returns the default profile, if set, followed by the named profiles.
+func (c *SegaroConfig) AllProfiles() []*SegaroProfile {
+	var profiles []*SegaroProfile
+	if c.PaddingSize != 0 {
+		profiles = append(profiles, &SegaroProfile{
+			Name:                  DefaultSegaroProfile,
+			PaddingSize:           c.PaddingSize,
+			SubchunkSize:          c.SubchunkSize,
+			SplitPacket:           c.SplitPacket,
+			ServerRandPacket:      c.ServerRandPacket,
+			ClientRandPacket:      c.ClientRandPacket,
+			ServerRandPacketCount: c.ServerRandPacketCount,
+			ClientRandPacketCount: c.ClientRandPacketCount,
+			ChunkDelay:            c.ChunkDelay,
+		})
+	}
+	return append(profiles, c.Profiles...)
+}
+
+// SegaroProfileID returns the id of the named profile, which the client sends
+// for the server to use the same profile.
+func SegaroProfileID(name string) [2]byte {
+	sum := sha256.Sum256([]byte(name))
+	return [2]byte{sum[0], sum[1]}
+}
+
+// ProfileByID returns the profile of the id, or nil if there is none.
+func (c *SegaroConfig) ProfileByID(id [2]byte) *SegaroProfile {
+	for _, profile := range c.AllProfiles() {
+		if SegaroProfileID(profile.Name) == id {
+			return profile
+		}
+	}
+	return nil
+}
+
+// PickProfile returns a random profile for a new connection.
+func (c *SegaroConfig) PickProfile() *SegaroProfile {
+	profiles := c.AllProfiles()
+	if len(profiles) == 0 {
+		return nil
+	}
+	return profiles[dice.Roll(len(profiles))]
+}
@@ -31,6 +31,9 @@ type Account struct {
 	Flow string `protobuf:"bytes,2,opt,name=flow,proto3" json:"flow,omitempty"`
 	// Encryption settings. Only applies to client side, and only accepts "none" for now.
 	Encryption string `protobuf:"bytes,3,opt,name=encryption,proto3" json:"encryption,omitempty"`
+	// Parameters of "xtls-segaro-vision" when the stream security isn't REALITY.
+	// Only applies to client side.
+	SegaroSettings *SegaroConfig `protobuf:"bytes,4,opt,name=segaro_settings,json=segaroSettings,proto3" json:"segaro_settings,omitempty"`
 }
 
 func (x *Account) Reset() {
@@ -86,23 +89,316 @@ func (x *Account) GetEncryption() string {
 	return ""
 }
 
+func (x *Account) GetSegaroSettings() *SegaroConfig {
+	if x != nil {
+		return x.SegaroSettings
+	}
+	return nil
+}
+
+// SegaroConfig holds the padding and packet shaping parameters of
+// "xtls-segaro-vision". The top level parameters form the "default" profile.
+type SegaroConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaddingSize  uint32 `protobuf:"varint,1,opt,name=padding_size,json=paddingSize,proto3" json:"padding_size,omitempty"`
+	SubchunkSize uint32 `protobuf:"varint,2,opt,name=subchunk_size,json=subchunkSize,proto3" json:"subchunk_size,omitempty"`
+	// Size range of the chunks, e.g. "100-200".
+	SplitPacket string `protobuf:"bytes,3,opt,name=split_packet,json=splitPacket,proto3" json:"split_packet,omitempty"`
+	// Size and count ranges of the random packets sent by each side.
+	ServerRandPacket      string `protobuf:"bytes,4,opt,name=server_rand_packet,json=serverRandPacket,proto3" json:"server_rand_packet,omitempty"`
+	ClientRandPacket      string `protobuf:"bytes,5,opt,name=client_rand_packet,json=clientRandPacket,proto3" json:"client_rand_packet,omitempty"`
+	ServerRandPacketCount string `protobuf:"bytes,6,opt,name=server_rand_packet_count,json=serverRandPacketCount,proto3" json:"server_rand_packet_count,omitempty"`
+	ClientRandPacketCount string `protobuf:"bytes,7,opt,name=client_rand_packet_count,json=clientRandPacketCount,proto3" json:"client_rand_packet_count,omitempty"`
+	// Additional named profiles. A client picks one of them randomly for each
+	// connection, a server accepts any of them.
+	Profiles []*SegaroProfile `protobuf:"bytes,8,rep,name=profiles,proto3" json:"profiles,omitempty"`
+	// Delay range between the chunks in milliseconds, e.g. "5-20".
+	ChunkDelay string `protobuf:"bytes,9,opt,name=chunk_delay,json=chunkDelay,proto3" json:"chunk_delay,omitempty"`
+}
+
+func (x *SegaroConfig) Reset() {
+	*x = SegaroConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proxy_vless_account_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SegaroConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SegaroConfig) ProtoMessage() {}
+
+func (x *SegaroConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_proxy_vless_account_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SegaroConfig.ProtoReflect.Descriptor instead.
+func (*SegaroConfig) Descriptor() ([]byte, []int) {
+	return file_proxy_vless_account_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SegaroConfig) GetPaddingSize() uint32 {
+	if x != nil {
+		return x.PaddingSize
+	}
+	return 0
+}
+
+func (x *SegaroConfig) GetSubchunkSize() uint32 {
+	if x != nil {
+		return x.SubchunkSize
+	}
+	return 0
+}
+
+func (x *SegaroConfig) GetSplitPacket() string {
+	if x != nil {
+		return x.SplitPacket
+	}
+	return ""
+}
+
+func (x *SegaroConfig) GetServerRandPacket() string {
+	if x != nil {
+		return x.ServerRandPacket
+	}
+	return ""
+}
+
+func (x *SegaroConfig) GetClientRandPacket() string {
+	if x != nil {
+		return x.ClientRandPacket
+	}
+	return ""
+}
+
+func (x *SegaroConfig) GetServerRandPacketCount() string {
+	if x != nil {
+		return x.ServerRandPacketCount
+	}
+	return ""
+}
+
+func (x *SegaroConfig) GetClientRandPacketCount() string {
+	if x != nil {
+		return x.ClientRandPacketCount
+	}
+	return ""
+}
+
+func (x *SegaroConfig) GetProfiles() []*SegaroProfile {
+	if x != nil {
+		return x.Profiles
+	}
+	return nil
+}
+
+func (x *SegaroConfig) GetChunkDelay() string {
+	if x != nil {
+		return x.ChunkDelay
+	}
+	return ""
+}
+
+// SegaroProfile is a named set of "xtls-segaro-vision" shaping parameters.
+type SegaroProfile struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name                  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	PaddingSize           uint32 `protobuf:"varint,2,opt,name=padding_size,json=paddingSize,proto3" json:"padding_size,omitempty"`
+	SubchunkSize          uint32 `protobuf:"varint,3,opt,name=subchunk_size,json=subchunkSize,proto3" json:"subchunk_size,omitempty"`
+	SplitPacket           string `protobuf:"bytes,4,opt,name=split_packet,json=splitPacket,proto3" json:"split_packet,omitempty"`
+	ServerRandPacket      string `protobuf:"bytes,5,opt,name=server_rand_packet,json=serverRandPacket,proto3" json:"server_rand_packet,omitempty"`
+	ClientRandPacket      string `protobuf:"bytes,6,opt,name=client_rand_packet,json=clientRandPacket,proto3" json:"client_rand_packet,omitempty"`
+	ServerRandPacketCount string `protobuf:"bytes,7,opt,name=server_rand_packet_count,json=serverRandPacketCount,proto3" json:"server_rand_packet_count,omitempty"`
+	ClientRandPacketCount string `protobuf:"bytes,8,opt,name=client_rand_packet_count,json=clientRandPacketCount,proto3" json:"client_rand_packet_count,omitempty"`
+	ChunkDelay            string `protobuf:"bytes,9,opt,name=chunk_delay,json=chunkDelay,proto3" json:"chunk_delay,omitempty"`
+}
+
+func (x *SegaroProfile) Reset() {
+	*x = SegaroProfile{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proxy_vless_account_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SegaroProfile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SegaroProfile) ProtoMessage() {}
+
+func (x *SegaroProfile) ProtoReflect() protoreflect.Message {
+	mi := &file_proxy_vless_account_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SegaroProfile.ProtoReflect.Descriptor instead.
+func (*SegaroProfile) Descriptor() ([]byte, []int) {
+	return file_proxy_vless_account_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SegaroProfile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SegaroProfile) GetPaddingSize() uint32 {
+	if x != nil {
+		return x.PaddingSize
+	}
+	return 0
+}
+
+func (x *SegaroProfile) GetSubchunkSize() uint32 {
+	if x != nil {
+		return x.SubchunkSize
+	}
+	return 0
+}
+
+func (x *SegaroProfile) GetSplitPacket() string {
+	if x != nil {
+		return x.SplitPacket
+	}
+	return ""
+}
+
+func (x *SegaroProfile) GetServerRandPacket() string {
+	if x != nil {
+		return x.ServerRandPacket
+	}
+	return ""
+}
+
+func (x *SegaroProfile) GetClientRandPacket() string {
+	if x != nil {
+		return x.ClientRandPacket
+	}
+	return ""
+}
+
+func (x *SegaroProfile) GetServerRandPacketCount() string {
+	if x != nil {
+		return x.ServerRandPacketCount
+	}
+	return ""
+}
+
+func (x *SegaroProfile) GetClientRandPacketCount() string {
+	if x != nil {
+		return x.ClientRandPacketCount
+	}
+	return ""
+}
+
+func (x *SegaroProfile) GetChunkDelay() string {
+	if x != nil {
+		return x.ChunkDelay
+	}
+	return ""
+}
+
 var File_proxy_vless_account_proto protoreflect.FileDescriptor
 
 var file_proxy_vless_account_proto_rawDesc = []byte{
 	0x0a, 0x19, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2f, 0x76, 0x6c, 0x65, 0x73, 0x73, 0x2f, 0x61, 0x63,
 	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x10, 0x78, 0x72, 0x61,
-	0x79, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x76, 0x6c, 0x65, 0x73, 0x73, 0x22, 0x4d, 0x0a,
-	0x07, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x6c, 0x6f, 0x77,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x6c, 0x6f, 0x77, 0x12, 0x1e, 0x0a, 0x0a,
-	0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x0a, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x59, 0x0a, 0x14,
-	0x63, 0x6f, 0x6d, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x76,
-	0x6c, 0x65, 0x73, 0x73, 0x50, 0x01, 0x5a, 0x2c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x6c, 0x75, 0x63, 0x6b, 0x79, 0x6c, 0x75, 0x6b, 0x65, 0x2d, 0x61, 0x2f, 0x78,
-	0x72, 0x61, 0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2f, 0x76,
-	0x6c, 0x65, 0x73, 0x73, 0xaa, 0x02, 0x10, 0x58, 0x72, 0x61, 0x79, 0x2e, 0x50, 0x72, 0x6f, 0x78,
-	0x79, 0x2e, 0x56, 0x6c, 0x65, 0x73, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x79, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x76, 0x6c, 0x65, 0x73, 0x73, 0x22, 0x96, 0x01,
+	0x0a, 0x07, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x6c, 0x6f,
+	0x77, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x6c, 0x6f, 0x77, 0x12, 0x1e, 0x0a,
+	0x0a, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x47, 0x0a,
+	0x0f, 0x73, 0x65, 0x67, 0x61, 0x72, 0x6f, 0x5f, 0x73, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x70, 0x72,
+	0x6f, 0x78, 0x79, 0x2e, 0x76, 0x6c, 0x65, 0x73, 0x73, 0x2e, 0x53, 0x65, 0x67, 0x61, 0x72, 0x6f,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x0e, 0x73, 0x65, 0x67, 0x61, 0x72, 0x6f, 0x53, 0x65,
+	0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x22, 0xa5, 0x03, 0x0a, 0x0c, 0x53, 0x65, 0x67, 0x61, 0x72,
+	0x6f, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x64, 0x64, 0x69,
+	0x6e, 0x67, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x70,
+	0x61, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x75,
+	0x62, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x0c, 0x73, 0x75, 0x62, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x12,
+	0x21, 0x0a, 0x0c, 0x73, 0x70, 0x6c, 0x69, 0x74, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x70, 0x6c, 0x69, 0x74, 0x50, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x12, 0x2c, 0x0a, 0x12, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x72, 0x61, 0x6e,
+	0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x52, 0x61, 0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74,
+	0x12, 0x2c, 0x0a, 0x12, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x5f,
+	0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x63, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x52, 0x61, 0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x37,
+	0x0a, 0x18, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x5f, 0x70, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x15, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x52, 0x61, 0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x37, 0x0a, 0x18, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x15, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x52, 0x61, 0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x12, 0x3b, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x08, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e,
+	0x76, 0x6c, 0x65, 0x73, 0x73, 0x2e, 0x53, 0x65, 0x67, 0x61, 0x72, 0x6f, 0x50, 0x72, 0x6f, 0x66,
+	0x69, 0x6c, 0x65, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x1f, 0x0a,
+	0x0b, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x22, 0xfd,
+	0x02, 0x0a, 0x0d, 0x53, 0x65, 0x67, 0x61, 0x72, 0x6f, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x5f,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x70, 0x61, 0x64, 0x64,
+	0x69, 0x6e, 0x67, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x75, 0x62, 0x63, 0x68,
+	0x75, 0x6e, 0x6b, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c,
+	0x73, 0x75, 0x62, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x21, 0x0a, 0x0c,
+	0x73, 0x70, 0x6c, 0x69, 0x74, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x73, 0x70, 0x6c, 0x69, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12,
+	0x2c, 0x0a, 0x12, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x5f, 0x70,
+	0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x52, 0x61, 0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x2c, 0x0a,
+	0x12, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x5f, 0x70, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x52, 0x61, 0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x37, 0x0a, 0x18, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x15, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x52, 0x61, 0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x43,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x37, 0x0a, 0x18, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x72,
+	0x61, 0x6e, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x15, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x52, 0x61,
+	0x6e, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1f, 0x0a,
+	0x0b, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x42, 0x59,
+	0x0a, 0x14, 0x63, 0x6f, 0x6d, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79,
+	0x2e, 0x76, 0x6c, 0x65, 0x73, 0x73, 0x50, 0x01, 0x5a, 0x2c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6c, 0x75, 0x63, 0x6b, 0x79, 0x6c, 0x75, 0x6b, 0x65, 0x2d, 0x61,
+	0x2f, 0x78, 0x72, 0x61, 0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x78, 0x79,
+	0x2f, 0x76, 0x6c, 0x65, 0x73, 0x73, 0xaa, 0x02, 0x10, 0x58, 0x72, 0x61, 0x79, 0x2e, 0x50, 0x72,
+	0x6f, 0x78, 0x79, 0x2e, 0x56, 0x6c, 0x65, 0x73, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -117,16 +413,20 @@ func file_proxy_vless_account_proto_rawDescGZIP() []byte {
 	return file_proxy_vless_account_proto_rawDescData
 }
 
-var file_proxy_vless_account_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proxy_vless_account_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_proxy_vless_account_proto_goTypes = []any{
-	(*Account)(nil), // 0: xray.proxy.vless.Account
+	(*Account)(nil),       // 0: xray.proxy.vless.Account
+	(*SegaroConfig)(nil),  // 1: xray.proxy.vless.SegaroConfig
+	(*SegaroProfile)(nil), // 2: xray.proxy.vless.SegaroProfile
 }
 var file_proxy_vless_account_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: xray.proxy.vless.Account.segaro_settings:type_name -> xray.proxy.vless.SegaroConfig
+	2, // 1: xray.proxy.vless.SegaroConfig.profiles:type_name -> xray.proxy.vless.SegaroProfile
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proxy_vless_account_proto_init() }
@@ -147,6 +447,30 @@ func file_proxy_vless_account_proto_init() {
 				return nil
 			}
 		}
+		file_proxy_vless_account_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*SegaroConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proxy_vless_account_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*SegaroProfile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -154,7 +478,7 @@ func file_proxy_vless_account_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proxy_vless_account_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
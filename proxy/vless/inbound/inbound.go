@@ -30,6 +30,7 @@ import (
 	feature_inbound "github.com/luckyluke-a/xray-core/features/inbound"
 	"github.com/luckyluke-a/xray-core/features/policy"
 	"github.com/luckyluke-a/xray-core/features/routing"
+	"github.com/luckyluke-a/xray-core/features/stats"
 	"github.com/luckyluke-a/xray-core/proxy"
 	"github.com/luckyluke-a/xray-core/proxy/vless"
 	"github.com/luckyluke-a/xray-core/proxy/vless/encoding"
@@ -59,6 +60,8 @@ type Handler struct {
 	validator             *vless.Validator
 	dns                   dns.Client
 	fallbacks             map[string]map[string]map[string]*Fallback // or nil
+	segaroConfig          *vless.SegaroConfig                        // or nil
+	stats                 stats.Manager
 	// regexps               map[string]*regexp.Regexp       // or nil
 }
 
@@ -70,6 +73,8 @@ func New(ctx context.Context, config *Config, dc dns.Client) (*Handler, error) {
 		policyManager:         v.GetFeature(policy.ManagerType()).(policy.Manager),
 		validator:             new(vless.Validator),
 		dns:                   dc,
+		segaroConfig:          config.SegaroSettings,
+		stats:                 v.GetFeature(stats.ManagerType()).(stats.Manager),
 	}
 
 	for _, user := range config.Clients {
@@ -237,6 +242,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 					return err
 				}
 			}
+			fakePadding := first.BytesTo(fakePaddingLength)
 			first.Advance(fakePaddingLength)
 
 			// Skip chunk length
@@ -261,19 +267,41 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 				}
 			}
 
-			var realityConfig *goReality.Config
-			realityConfig, err = segaro.GetRealityServerConfig(&inbound.Conn)
-			if err != nil {
-				return errors.New("can not get goReality.Config")
+			var candidates []*segaro.SegaroConfig
+			if h.segaroConfig != nil {
+				// Profile from the VLESS settings, works on any security and transport
+				if profile := segaro.ClientProfile(h.segaroConfig, fakePadding); profile != nil {
+					candidates = append(candidates, &segaro.SegaroConfig{Profile: profile})
+				}
+			} else {
+				var realityConfig *goReality.Config
+				realityConfig, err = segaro.GetRealityServerConfig(&inbound.Conn)
+				if err != nil {
+					return errors.New("can not get goReality.Config")
+				}
+				candidates = append(candidates, &segaro.SegaroConfig{GoRealityConfig: realityConfig})
 			}
-			segaroConfig = &segaro.SegaroConfig{GoRealityConfig: realityConfig}
-			paddingSize := int(segaroConfig.GetPaddingSize())
-			subChunkSize := int(segaroConfig.GetSubChunkSize())
 
-			decodedBuff := segaro.SegaroRemovePadding(buf.MultiBuffer{first}, paddingSize, subChunkSize)
-
-			decodedBuff.Advance(2) // Skip requestHeader length
-			request, requestAddons, isfb, err = encoding.DecodeRequestHeader(isfb, decodedBuff, decodedBuff, h.validator)
+			// Decode the request header with the parameters of the client
+			chunkStartPosition := first.GetStart()
+			fallbackAllowed := isfb
+			for _, candidate := range candidates {
+				first.ResetStart()
+				first.Advance(chunkStartPosition)
+				paddingSize := int(candidate.GetPaddingSize())
+				subChunkSize := int(candidate.GetSubChunkSize())
+
+				decodedBuff := segaro.SegaroRemovePadding(buf.MultiBuffer{first}, paddingSize, subChunkSize)
+
+				decodedBuff.Advance(2) // Skip requestHeader length
+				request, requestAddons, isfb, err = encoding.DecodeRequestHeader(fallbackAllowed, decodedBuff, decodedBuff, h.validator)
+				decodedBuff.Release()
+				decodedBuff = nil
+				if err == nil {
+					segaroConfig = candidate
+					break
+				}
+			}
 			first.ResetStart()
 			if err != nil {
 				// Decode fail, Revert back
@@ -281,9 +309,15 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 			} else {
 				// Decode success
 				requestAddons.Flow = vless.XSV
+				if segaroConfig.Profile != nil {
+					segaroConfig.AuthKey = segaro.FlowAuthKey(request.User.Account.(*vless.MemoryAccount).ID.Bytes())
+					// The nonce is checked once the user is authenticated, so that only valid requests are recorded
+					segaroConfig.Nonce, err = segaro.ClientNonce(fakePadding)
+					if err == nil {
+						segaroConfig.CountProfile(h.stats, "inbound")
+					}
+				}
 			}
-			decodedBuff.Release()
-			decodedBuff = nil
 		}
 	}
 
@@ -491,10 +525,11 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 
 		if errors.Cause(err) != io.EOF {
 			log.Record(&log.AccessMessage{
-				From:   connection.RemoteAddr(),
-				To:     "",
-				Status: log.AccessRejected,
-				Reason: err,
+				From:       connection.RemoteAddr(),
+				To:         "",
+				Status:     log.AccessRejected,
+				InboundTag: session.InboundTagFromContext(ctx),
+				Reason:     err,
 			})
 			err = errors.New("invalid request from ", connection.RemoteAddr()).Base(err).AtInfo()
 		}
@@ -606,7 +641,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 
 		case vless.XSV:
 			clientReader = segaro.NewSegaroReader(clientReader, trafficState)
-			err = segaro.SegaroRead(clientReader, serverWriter, timer, connection, trafficState, true, segaroConfig, xsvCanContinue)
+			err = segaro.SegaroRead(clientReader, serverWriter, timer, connection, trafficState, true, segaroConfig, xsvCanContinue, ctx)
 
 		default:
 			// from clientReader.ReadMultiBuffer to serverWriter.WriteMultiBuffer
@@ -654,7 +689,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 		case vless.XRV:
 			err = encoding.XtlsWrite(serverReader, clientWriter, timer, connection, trafficState, nil, ctx)
 		case vless.XSV:
-			err = segaro.SegaroWrite(serverReader, clientWriter, timer, connection, true, segaroConfig, nil)
+			err = segaro.SegaroWrite(serverReader, clientWriter, timer, connection, true, segaroConfig, nil, ctx)
 		default:
 			// from serverReader.ReadMultiBuffer to clientWriter.WriteMultiBuffer
 			err = buf.Copy(serverReader, clientWriter, buf.UpdateActivity(timer))
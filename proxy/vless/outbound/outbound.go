@@ -23,6 +23,7 @@ import (
 	"github.com/Rolka111111/xray-core/common/xudp"
 	"github.com/Rolka111111/xray-core/core"
 	"github.com/Rolka111111/xray-core/features/policy"
+	"github.com/Rolka111111/xray-core/features/stats"
 	"github.com/Rolka111111/xray-core/proxy"
 	"github.com/Rolka111111/xray-core/proxy/vless"
 	"github.com/Rolka111111/xray-core/proxy/vless/encoding"
@@ -46,6 +47,7 @@ type Handler struct {
 	serverList    *protocol.ServerList
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
+	stats         stats.Manager
 	cone          bool
 }
 
@@ -65,6 +67,7 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		serverList:    serverList,
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		stats:         v.GetFeature(stats.ManagerType()).(stats.Manager),
 		cone:          ctx.Value("cone").(bool),
 	}
 
@@ -175,23 +178,33 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 				return errors.New("XTLS rejected UDP/443 traffic").AtInfo()
 			}
 		}
-		outboundHandler, ok := dialer.(*proxymanOutbound.Handler)
-		if !ok {
-			return errors.New("failed to get reality proxymanOutbound.Handler")
-		}
-		streamSettings, err := segaro.GetPrivateField(outboundHandler, "streamSettings")
-		if err != nil {
-			return errors.New("failed to get reality streamSettings")
-		}
-		memoryStreamConfig, ok := streamSettings.(*internet.MemoryStreamConfig)
-		if !ok {
-			return errors.New("failed to get reality memoryStreamConfig")
-		}
-		realityConfig := reality.ConfigFromStreamSettings(memoryStreamConfig)
-		if realityConfig == nil {
-			return errors.New("failed to get reality ConfigFromStreamSettings")
+		if account.Segaro != nil {
+			// Profile from the VLESS settings, works on any security and transport
+			profile := account.Segaro.PickProfile()
+			if profile == nil {
+				return errors.New("no segaro profile is configured")
+			}
+			segaroConfig = &segaro.SegaroConfig{Profile: profile, AuthKey: segaro.FlowAuthKey(account.ID.Bytes()), Nonce: segaro.NewFlowNonce()}
+			segaroConfig.CountProfile(h.stats, "outbound")
+		} else {
+			outboundHandler, ok := dialer.(*proxymanOutbound.Handler)
+			if !ok {
+				return errors.New("failed to get reality proxymanOutbound.Handler")
+			}
+			streamSettings, err := segaro.GetPrivateField(outboundHandler, "streamSettings")
+			if err != nil {
+				return errors.New("failed to get reality streamSettings")
+			}
+			memoryStreamConfig, ok := streamSettings.(*internet.MemoryStreamConfig)
+			if !ok {
+				return errors.New("failed to get reality memoryStreamConfig")
+			}
+			realityConfig := reality.ConfigFromStreamSettings(memoryStreamConfig)
+			if realityConfig == nil {
+				return errors.New("failed to get reality ConfigFromStreamSettings")
+			}
+			segaroConfig = &segaro.SegaroConfig{RealityConfig: realityConfig}
 		}
-		segaroConfig = &segaro.SegaroConfig{RealityConfig: realityConfig}
 		xsvCanContinue = make(chan bool, 1)
 
 	default:
@@ -277,7 +290,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 			ctx1 := session.ContextWithInbound(ctx, nil) // TODO enable splice
 			err = encoding.XtlsWrite(clientReader, serverWriter, timer, conn, trafficState, ob, ctx1)
 		case vless.XSV:
-			err = segaro.SegaroWrite(clientReader, serverWriter, timer, conn, false, segaroConfig, xsvCanContinue)
+			err = segaro.SegaroWrite(clientReader, serverWriter, timer, conn, false, segaroConfig, xsvCanContinue, ctx)
 		default:
 			// from clientReader.ReadMultiBuffer to serverWriter.WriteMultiBuffer
 			err = buf.Copy(clientReader, serverWriter, buf.UpdateActivity(timer))
@@ -322,7 +335,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		case vless.XRV:
 			err = encoding.XtlsRead(serverReader, clientWriter, timer, conn, input, rawInput, trafficState, ob, ctx)
 		case vless.XSV:
-			err = segaro.SegaroRead(serverReader, clientWriter, timer, conn, trafficState, false, segaroConfig, xsvCanContinue)
+			err = segaro.SegaroRead(serverReader, clientWriter, timer, conn, trafficState, false, segaroConfig, xsvCanContinue, ctx)
 		default:
 			// from serverReader.ReadMultiBuffer to clientWriter.WriteMultiBuffer
 			err = buf.Copy(serverReader, clientWriter, buf.UpdateActivity(timer))
@@ -229,10 +229,11 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 	if err != nil {
 		if errors.Cause(err) != io.EOF {
 			log.Record(&log.AccessMessage{
-				From:   connection.RemoteAddr(),
-				To:     "",
-				Status: log.AccessRejected,
-				Reason: err,
+				From:       connection.RemoteAddr(),
+				To:         "",
+				Status:     log.AccessRejected,
+				InboundTag: session.InboundTagFromContext(ctx),
+				Reason:     err,
 			})
 			err = errors.New("invalid request from ", connection.RemoteAddr()).Base(err).AtInfo()
 		}
@@ -2,6 +2,7 @@ package internet
 
 import (
 	"context"
+	"time"
 
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/dice"
@@ -176,3 +177,13 @@ func InitSystemDialer(dc dns.Client, om outbound.Manager) {
 	dnsClient = dc
 	obm = om
 }
+
+// LookupECHConfig queries the ECHConfigList of the given domain through the DNS client, and
+// returns it with its TTL.
+func LookupECHConfig(domain string) ([]byte, time.Duration, error) {
+	lookup, ok := dnsClient.(dns.ECHConfigLookup)
+	if !ok {
+		return nil, 0, errors.New("DNS client doesn't support HTTPS records")
+	}
+	return lookup.LookupECHConfig(domain)
+}
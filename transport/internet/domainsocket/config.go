@@ -26,9 +26,13 @@ func (c *Config) GetUnixAddr() (*net.UnixAddr, error) {
 		copy(addr, raw)
 		path = string(addr)
 	}
+	network := "unix"
+	if c.Seqpacket {
+		network = "unixpacket"
+	}
 	return &net.UnixAddr{
 		Name: path,
-		Net:  "unix",
+		Net:  network,
 	}, nil
 }
 
@@ -22,10 +22,14 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		return nil, err
 	}
 
-	conn, err := net.DialUnix("unix", nil, addr)
+	unixConn, err := net.DialUnix(addr.Net, nil, addr)
 	if err != nil {
 		return nil, errors.New("failed to dial unix: ", settings.Path).Base(err).AtWarning()
 	}
+	var conn net.Conn = unixConn
+	if settings.Seqpacket {
+		conn = newSeqpacketConn(unixConn)
+	}
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		return tls.Client(conn, config.GetTLSConfig(tls.WithDestination(dest))), nil
@@ -7,6 +7,9 @@ import (
 	"context"
 	gotls "crypto/tls"
 	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	goreality "github.com/LuckyLuke-a/reality"
@@ -28,6 +31,7 @@ type Listener struct {
 	config        *Config
 	addConn       internet.ConnHandler
 	locker        *fileLocker
+	unlink        bool
 }
 
 func Listen(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, handler internet.ConnHandler) (internet.Listener, error) {
@@ -37,7 +41,21 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 		return nil, err
 	}
 
-	unixListener, err := net.ListenUnix("unix", addr)
+	private := !settings.Abstract && (settings.Mode != 0 || settings.Owner != "" || settings.Group != "")
+	var match func(*net.UnixAddr) bool
+	listen := func() (*net.UnixListener, error) {
+		return net.ListenUnix(addr.Net, addr)
+	}
+	if private {
+		match = func(a *net.UnixAddr) bool {
+			return isPrivateAddr(a, settings)
+		}
+		listen = func() (*net.UnixListener, error) {
+			return listenPrivate(addr, settings)
+		}
+	}
+	// an inherited socket is still locked by the previous process
+	unixListener, inherited, err := internet.ListenSystemUnix(addr, streamSettings.SocketSettings, match, listen)
 	if err != nil {
 		return nil, errors.New("failed to listen domain socket").Base(err).AtWarning()
 	}
@@ -47,14 +65,18 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 		ln:      unixListener,
 		config:  settings,
 		addConn: handler,
+		unlink:  private,
 	}
 
-	if !settings.Abstract {
+	if !settings.Abstract && !inherited {
 		ln.locker = &fileLocker{
 			path: settings.Path + ".lock",
 		}
 		if err := ln.locker.Acquire(); err != nil {
 			unixListener.Close()
+			if private {
+				os.Remove(settings.Path)
+			}
 			return nil, err
 		}
 	}
@@ -79,7 +101,11 @@ func (ln *Listener) Close() error {
 	if ln.locker != nil {
 		ln.locker.Release()
 	}
-	return ln.ln.Close()
+	err := ln.ln.Close()
+	if ln.unlink {
+		os.Remove(ln.config.Path)
+	}
+	return err
 }
 
 func (ln *Listener) run() {
@@ -93,6 +119,19 @@ func (ln *Listener) run() {
 			continue
 		}
 		go func() {
+			unixConn := conn.(*net.UnixConn)
+			cred, err := authorize(unixConn, ln.config)
+			if err != nil {
+				errors.LogWarningInner(context.Background(), err, "rejected domain socket connection")
+				conn.Close()
+				return
+			}
+			if ln.config.Seqpacket {
+				conn = newSeqpacketConn(unixConn)
+			}
+			if cred != nil && ln.config.PeerAttributes {
+				conn = &peerConn{Conn: conn, cred: cred}
+			}
 			if ln.tlsConfig != nil {
 				conn = tls.Server(conn, ln.tlsConfig)
 			} else if ln.realityConfig != nil {
@@ -106,6 +145,100 @@ func (ln *Listener) run() {
 	}
 }
 
+// listenPrivate listens on a socket in a private dir, which is only linked to
+// the path of the config once it has the configured mode and ownership, so that
+// no one can connect to it before. As with listening on the path, it fails if
+// the path exists.
+func listenPrivate(addr *net.UnixAddr, config *Config) (*net.UnixListener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(config.Path), privateDirPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// the socket keeps the name of the path, so that it's found by
+	// isPrivateAddr when it's inherited
+	path := filepath.Join(dir, filepath.Base(config.Path))
+	unixListener, err := net.ListenUnix(addr.Net, &net.UnixAddr{Name: path, Net: addr.Net})
+	if err != nil {
+		return nil, err
+	}
+	// the socket is unlinked from the path of the config by the Listener
+	unixListener.SetUnlinkOnClose(false)
+	if err := setFileAttributes(path, config); err != nil {
+		unixListener.Close()
+		return nil, errors.New("failed to set attributes of domain socket").Base(err)
+	}
+	if err := os.Link(path, config.Path); err != nil {
+		unixListener.Close()
+		return nil, err
+	}
+	return unixListener, nil
+}
+
+const privateDirPrefix = ".xray-"
+
+// isPrivateAddr reports whether the address is of a socket listened by
+// listenPrivate for the config, which is the address read back from the socket.
+func isPrivateAddr(addr *net.UnixAddr, config *Config) bool {
+	dir := filepath.Dir(addr.Name)
+	return filepath.Base(addr.Name) == filepath.Base(config.Path) &&
+		strings.HasPrefix(filepath.Base(dir), privateDirPrefix) &&
+		filepath.Dir(dir) == filepath.Dir(config.Path)
+}
+
+// setFileAttributes applies the configured mode and ownership to the socket file.
+func setFileAttributes(path string, config *Config) error {
+	if config.Mode != 0 {
+		if err := os.Chmod(path, os.FileMode(config.Mode)); err != nil {
+			return err
+		}
+	}
+	if config.Owner == "" && config.Group == "" {
+		return nil
+	}
+	uid, gid := -1, -1
+	if config.Owner != "" {
+		id, err := lookupID(config.Owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return errors.New("unknown owner ", config.Owner).Base(err)
+		}
+		uid = id
+	}
+	if config.Group != "" {
+		id, err := lookupID(config.Group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return errors.New("unknown group ", config.Group).Base(err)
+		}
+		gid = id
+	}
+	return os.Chown(path, uid, gid)
+}
+
+// lookupID accepts either a numeric ID or a name to look up.
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	id, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
 type fileLocker struct {
 	path string
 	file *os.File
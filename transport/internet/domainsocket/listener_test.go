@@ -5,7 +5,10 @@ package domainsocket_test
 
 import (
 	"context"
+	"io"
+	"os"
 	"runtime"
+	"strconv"
 	"testing"
 
 	"github.com/luckyluke-a/xray-core/common"
@@ -92,3 +95,121 @@ func TestListenAbstract(t *testing.T) {
 		t.Error("expected response as 'RequestResponse' but got ", b.String())
 	}
 }
+
+func TestListenSeqpacket(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	ctx := context.Background()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "domainsocket",
+		ProtocolSettings: &Config{
+			Path:           "/tmp/ts4",
+			Seqpacket:      true,
+			AllowedUids:    []uint32{uint32(os.Getuid())},
+			PeerAttributes: true,
+			Mode:           0o600,
+		},
+	}
+	listener, err := Listen(ctx, nil, net.Port(0), streamSettings, func(conn stat.Connection) {
+		defer conn.Close()
+
+		b := make([]byte, 1024)
+		n, err := conn.Read(b)
+		common.Must(err)
+		attributes := stat.AttributesFromConnection(conn)
+		common.Must2(conn.Write(append(b[:n], attributes[":uid"]...)))
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	info, err := os.Stat("/tmp/ts4")
+	common.Must(err)
+	if info.Mode().Perm() != 0o600 {
+		t.Error("expected mode 0600 but got ", info.Mode().Perm())
+	}
+
+	conn, err := Dial(ctx, net.Destination{}, streamSettings)
+	common.Must(err)
+	defer conn.Close()
+
+	common.Must2(conn.Write([]byte("Request")))
+
+	b := make([]byte, 1024)
+	n, err := conn.Read(b)
+	common.Must(err)
+	if expected := "Request" + strconv.Itoa(os.Getuid()); string(b[:n]) != expected {
+		t.Error("expected response as '", expected, "' but got ", string(b[:n]))
+	}
+}
+
+func TestListenSeqpacketTruncated(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	ctx := context.Background()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "domainsocket",
+		ProtocolSettings: &Config{
+			Path:      "/tmp/ts6",
+			Seqpacket: true,
+			Mode:      0o600,
+		},
+	}
+	readErr := make(chan error, 1)
+	listener, err := Listen(ctx, nil, net.Port(0), streamSettings, func(conn stat.Connection) {
+		defer conn.Close()
+
+		b := make([]byte, 2*buf.Size)
+		_, err := conn.Read(b)
+		readErr <- err
+	})
+	common.Must(err)
+
+	// a message larger than the one the listener splits them into
+	conn, err := net.DialUnix("unixpacket", nil, &net.UnixAddr{Name: "/tmp/ts6", Net: "unixpacket"})
+	common.Must(err)
+	defer conn.Close()
+	common.Must2(conn.Write(make([]byte, buf.Size+1)))
+
+	if err := <-readErr; err == nil {
+		t.Error("expected the truncated message to fail the read")
+	}
+
+	listener.Close()
+	if _, err := os.Stat("/tmp/ts6"); !os.IsNotExist(err) {
+		t.Error("expected the socket to be removed, but got ", err)
+	}
+}
+
+func TestListenRejectPeer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	ctx := context.Background()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "domainsocket",
+		ProtocolSettings: &Config{
+			Path:        "/tmp/ts5",
+			AllowedUids: []uint32{uint32(os.Getuid()) + 1},
+		},
+	}
+	listener, err := Listen(ctx, nil, net.Port(0), streamSettings, func(conn stat.Connection) {
+		defer conn.Close()
+		t.Error("unexpected connection")
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	conn, err := Dial(ctx, net.Destination{}, streamSettings)
+	common.Must(err)
+	defer conn.Close()
+
+	b := make([]byte, 1024)
+	if _, err := conn.Read(b); err != io.EOF {
+		t.Error("expected EOF but got ", err)
+	}
+}
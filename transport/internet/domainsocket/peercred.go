@@ -0,0 +1,63 @@
+//go:build !windows && !wasm
+// +build !windows,!wasm
+
+package domainsocket
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/transport/internet/stat"
+)
+
+type peerCredentials struct {
+	uid uint32
+	gid uint32
+	pid int32
+}
+
+func (c *peerCredentials) String() string {
+	return "uid=" + strconv.FormatUint(uint64(c.uid), 10) + " gid=" + strconv.FormatUint(uint64(c.gid), 10) + " pid=" + strconv.Itoa(int(c.pid))
+}
+
+// authorize checks the credentials of the peer against the allow-lists, and
+// attaches them to the connection for routing if configured.
+func authorize(conn *net.UnixConn, config *Config) (*peerCredentials, error) {
+	if len(config.AllowedUids) == 0 && len(config.AllowedGids) == 0 && !config.PeerAttributes {
+		return nil, nil
+	}
+	cred, err := getPeerCredentials(conn)
+	if err != nil {
+		return nil, errors.New("failed to get peer credentials").Base(err)
+	}
+	if len(config.AllowedUids) == 0 && len(config.AllowedGids) == 0 {
+		return cred, nil
+	}
+	if slices.Contains(config.AllowedUids, cred.uid) || slices.Contains(config.AllowedGids, cred.gid) {
+		return cred, nil
+	}
+	return nil, errors.New("peer (", cred, ") is not allowed")
+}
+
+var _ stat.AttributedConnection = (*peerConn)(nil)
+
+// peerConn exposes the credentials of the peer as attributes.
+type peerConn struct {
+	net.Conn
+	cred *peerCredentials
+}
+
+func (c *peerConn) Attributes() map[string]string {
+	return map[string]string{
+		":uid": strconv.FormatUint(uint64(c.cred.uid), 10),
+		":gid": strconv.FormatUint(uint64(c.cred.gid), 10),
+		":pid": strconv.Itoa(int(c.cred.pid)),
+	}
+}
+
+// NetConn returns the socket.
+func (c *peerConn) NetConn() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package domainsocket
+
+import (
+	"github.com/luckyluke-a/xray-core/common/net"
+	"golang.org/x/sys/unix"
+)
+
+func getPeerCredentials(conn *net.UnixConn) (*peerCredentials, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var ucred *unix.Ucred
+	if err := rawConn.Control(func(fd uintptr) {
+		ucred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &peerCredentials{
+		uid: ucred.Uid,
+		gid: ucred.Gid,
+		pid: ucred.Pid,
+	}, nil
+}
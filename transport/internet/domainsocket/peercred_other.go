@@ -0,0 +1,15 @@
+//go:build !linux && !windows && !wasm
+// +build !linux,!windows,!wasm
+
+package domainsocket
+
+import (
+	"runtime"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+func getPeerCredentials(conn *net.UnixConn) (*peerCredentials, error) {
+	return nil, errors.New("peer credentials are not supported on ", runtime.GOOS)
+}
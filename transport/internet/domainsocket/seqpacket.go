@@ -0,0 +1,69 @@
+//go:build !windows && !wasm
+// +build !windows,!wasm
+
+package domainsocket
+
+import (
+	"io"
+
+	"github.com/luckyluke-a/xray-core/common/buf"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"golang.org/x/sys/unix"
+)
+
+// seqpacketConn keeps every message of a SOCK_SEQPACKET socket within
+// buf.Size, and reads whole messages, so that none of them is truncated by a
+// small read. A message larger than buf.Size from a peer that doesn't split
+// them fails the read, as the kernel drops the rest of it.
+type seqpacketConn struct {
+	net.Conn
+	unixConn *net.UnixConn
+	buffer   []byte
+	pending  []byte
+}
+
+func newSeqpacketConn(conn *net.UnixConn) *seqpacketConn {
+	return &seqpacketConn{
+		Conn:     conn,
+		unixConn: conn,
+		buffer:   make([]byte, buf.Size),
+	}
+}
+
+func (c *seqpacketConn) Read(b []byte) (int, error) {
+	if len(c.pending) == 0 {
+		n, _, flags, _, err := c.unixConn.ReadMsgUnix(c.buffer, nil)
+		if flags&unix.MSG_TRUNC != 0 {
+			return 0, errors.New("message larger than ", len(c.buffer), " bytes truncated")
+		}
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		c.pending = c.buffer[:n]
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *seqpacketConn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n := min(len(b), buf.Size)
+		if _, err := c.Conn.Write(b[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// NetConn returns the socket.
+func (c *seqpacketConn) NetConn() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,210 @@
+package httpfallback
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+// Handler serves the requests which are not for the transport, by forwarding
+// them to a web server or serving a directory.
+type Handler struct {
+	config  *Config
+	handler http.Handler
+}
+
+// New creates a Handler, or returns nil if config is nil.
+func New(config *Config) (*Handler, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	h := &Handler{
+		config: config,
+	}
+	switch config.Type {
+	case "dir":
+		h.handler = http.FileServer(noListingFileSystem{http.Dir(config.Dest)})
+	case "tcp", "unix":
+		proxy := httputil.NewSingleHostReverseProxy(&url.URL{
+			Scheme: "http",
+			Host:   "localhost",
+		})
+		proxy.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return h.dial(ctx)
+			},
+			MaxIdleConnsPerHost: 16,
+			IdleConnTimeout:     time.Minute,
+		}
+		proxy.ErrorHandler = func(writer http.ResponseWriter, request *http.Request, err error) {
+			errors.LogInfoInner(request.Context(), err, "failed to forward request to fallback ", config.Dest)
+			writer.WriteHeader(http.StatusBadGateway)
+		}
+		h.handler = proxy
+	default:
+		return nil, errors.New("unknown fallback type: ", config.Type)
+	}
+	return h, nil
+}
+
+// noListingFileSystem hides the dirs without an index.html, whose listings
+// would tell the fallback apart from a real website. They are not found
+// instead.
+type noListingFileSystem struct {
+	http.FileSystem
+}
+
+func (fs noListingFileSystem) Open(name string) (http.File, error) {
+	file, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		index, err := fs.FileSystem.Open(path.Join(name, "index.html"))
+		if err != nil {
+			file.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+	return file, nil
+}
+
+func (h *Handler) dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, h.config.Type, h.config.Dest)
+}
+
+// ServeHTTP implements http.Handler, for listeners based on http.Server.
+func (h *Handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	h.handler.ServeHTTP(writer, request)
+}
+
+// ServeConn takes over a connection of a listener which parses requests by
+// itself. consumed is everything read from conn so far. Requests are
+// forwarded as raw bytes.
+func (h *Handler) ServeConn(conn net.Conn, consumed []byte) {
+	if h.config.Type == "dir" {
+		listener := &singleConnListener{
+			conn: &replayConn{
+				Conn:   conn,
+				reader: io.MultiReader(bytes.NewReader(consumed), conn),
+			},
+			done: make(chan struct{}),
+		}
+		server := &http.Server{
+			Handler:           h.handler,
+			ReadHeaderTimeout: 4 * time.Second,
+			ConnState: func(_ net.Conn, state http.ConnState) {
+				if state == http.StateClosed || state == http.StateHijacked {
+					listener.Close()
+				}
+			},
+		}
+		server.Serve(listener)
+		return
+	}
+
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+	dest, err := h.dial(ctx)
+	cancel()
+	if err != nil {
+		errors.LogInfoInner(context.Background(), err, "failed to dial fallback ", h.config.Dest)
+		return
+	}
+	defer dest.Close()
+
+	if _, err := dest.Write(consumed); err != nil {
+		return
+	}
+
+	responseDone := make(chan struct{})
+	go func() {
+		io.Copy(conn, dest)
+		conn.Close()
+		close(responseDone)
+	}()
+	io.Copy(dest, conn)
+	if closer, ok := dest.(interface{ CloseWrite() error }); ok {
+		closer.CloseWrite()
+	}
+	<-responseDone
+}
+
+// RecordingReader records everything read from a connection, so that it can
+// be replayed to ServeConn.
+type RecordingReader struct {
+	io.Reader
+	buffer bytes.Buffer
+}
+
+func NewRecordingReader(reader io.Reader) *RecordingReader {
+	r := &RecordingReader{}
+	r.Reader = io.TeeReader(reader, &r.buffer)
+	return r
+}
+
+// Bytes returns everything read so far.
+func (r *RecordingReader) Bytes() []byte {
+	return r.buffer.Bytes()
+}
+
+type replayConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+type singleConnListener struct {
+	sync.Mutex
+	conn net.Conn
+	done chan struct{}
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	l.Lock()
+	conn := l.conn
+	l.conn = nil
+	l.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	<-l.done
+	return nil, io.EOF
+}
+
+func (l *singleConnListener) Close() error {
+	l.Lock()
+	defer l.Unlock()
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr {
+	return &net.TCPAddr{}
+}
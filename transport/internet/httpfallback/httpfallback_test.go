@@ -0,0 +1,128 @@
+package httpfallback_test
+
+import (
+	"bufio"
+	"io"
+	gonet "net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	. "github.com/luckyluke-a/xray-core/transport/internet/httpfallback"
+)
+
+func newWebServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("web " + request.Host + request.URL.Path))
+	}))
+}
+
+func TestServeHTTP(t *testing.T) {
+	web := newWebServer()
+	defer web.Close()
+
+	handler, err := New(&Config{
+		Type: "tcp",
+		Dest: web.Listener.Addr().String(),
+	})
+	common.Must(err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/index.html", nil))
+	if body := recorder.Body.String(); body != "web example.com/index.html" {
+		t.Error("unexpected response: ", body)
+	}
+}
+
+func TestServeConn(t *testing.T) {
+	web := newWebServer()
+	defer web.Close()
+
+	handler, err := New(&Config{
+		Type: "tcp",
+		Dest: web.Listener.Addr().String(),
+	})
+	common.Must(err)
+
+	client, server := gonet.Pipe()
+	defer client.Close()
+
+	// the first request was consumed by the transport
+	go handler.ServeConn(server, []byte("GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+
+	reader := bufio.NewReader(client)
+	response, err := http.ReadResponse(reader, nil)
+	common.Must(err)
+	body, err := io.ReadAll(response.Body)
+	common.Must(err)
+	if string(body) != "web example.com/a" {
+		t.Error("unexpected response: ", string(body))
+	}
+
+	common.Must2(client.Write([]byte("GET /b HTTP/1.1\r\nHost: example.com\r\n\r\n")))
+	response, err = http.ReadResponse(reader, nil)
+	common.Must(err)
+	body, err = io.ReadAll(response.Body)
+	common.Must(err)
+	if string(body) != "web example.com/b" {
+		t.Error("unexpected response: ", string(body))
+	}
+}
+
+func TestServeDir(t *testing.T) {
+	dir := t.TempDir()
+	common.Must(os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>hello</html>"), 0o644))
+
+	handler, err := New(&Config{
+		Type: "dir",
+		Dest: dir,
+	})
+	common.Must(err)
+
+	client, server := gonet.Pipe()
+	defer client.Close()
+
+	go handler.ServeConn(server, []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+
+	response, err := http.ReadResponse(bufio.NewReader(client), nil)
+	common.Must(err)
+	body, err := io.ReadAll(response.Body)
+	common.Must(err)
+	if !strings.Contains(string(body), "hello") {
+		t.Error("unexpected response: ", string(body))
+	}
+}
+
+func TestServeDirWithoutListing(t *testing.T) {
+	dir := t.TempDir()
+	common.Must(os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>hello</html>"), 0o644))
+	common.Must(os.Mkdir(filepath.Join(dir, "files"), 0o755))
+	common.Must(os.WriteFile(filepath.Join(dir, "files", "a.txt"), []byte("a"), 0o644))
+
+	handler, err := New(&Config{
+		Type: "dir",
+		Dest: dir,
+	})
+	common.Must(err)
+
+	for path, status := range map[string]int{
+		"/":            http.StatusOK,
+		"/files/":      http.StatusNotFound,
+		"/files":       http.StatusNotFound,
+		"/files/a.txt": http.StatusOK,
+		"/missing/":    http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Code != status {
+			t.Errorf("%s: expected status %d, but got %d", path, status, recorder.Code)
+		}
+		if strings.Contains(recorder.Body.String(), "a.txt") && path != "/files/a.txt" {
+			t.Errorf("%s: dir listed: %s", path, recorder.Body.String())
+		}
+	}
+}
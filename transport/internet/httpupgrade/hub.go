@@ -12,6 +12,7 @@ import (
 	"github.com/luckyluke-a/xray-core/common/net"
 	http_proto "github.com/luckyluke-a/xray-core/common/protocol/http"
 	"github.com/luckyluke-a/xray-core/transport/internet"
+	"github.com/luckyluke-a/xray-core/transport/internet/httpfallback"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
 	v2tls "github.com/luckyluke-a/xray-core/transport/internet/tls"
 )
@@ -20,6 +21,7 @@ type server struct {
 	config         *Config
 	addConn        internet.ConnHandler
 	innnerListener net.Listener
+	fallback       *httpfallback.Handler
 }
 
 func (s *server) Close() error {
@@ -31,7 +33,14 @@ func (s *server) Addr() net.Addr {
 }
 
 func (s *server) Handle(conn net.Conn) (stat.Connection, error) {
-	connReader := bufio.NewReader(conn)
+	var recorder *httpfallback.RecordingReader
+	var connReader *bufio.Reader
+	if s.fallback != nil {
+		recorder = httpfallback.NewRecordingReader(conn)
+		connReader = bufio.NewReader(recorder)
+	} else {
+		connReader = bufio.NewReader(conn)
+	}
 	req, err := http.ReadRequest(connReader)
 	if err != nil {
 		return nil, err
@@ -40,19 +49,21 @@ func (s *server) Handle(conn net.Conn) (stat.Connection, error) {
 	if s.config != nil {
 		host := req.Host
 		if len(s.config.Host) > 0 && !internet.IsValidHTTPHost(host, s.config.Host) {
-			return nil, errors.New("bad host: ", host)
+			return nil, s.reject(conn, recorder, errors.New("bad host: ", host))
 		}
 		path := s.config.GetNormalizedPath()
 		if req.URL.Path != path {
-			return nil, errors.New("bad path: ", req.URL.Path)
+			return nil, s.reject(conn, recorder, errors.New("bad path: ", req.URL.Path))
 		}
 	}
 
 	connection := strings.ToLower(req.Header.Get("Connection"))
 	upgrade := strings.ToLower(req.Header.Get("Upgrade"))
 	if connection != "upgrade" || upgrade != "websocket" {
-		_ = conn.Close()
-		return nil, errors.New("unrecognized request")
+		if s.fallback == nil {
+			_ = conn.Close()
+		}
+		return nil, s.reject(conn, recorder, errors.New("unrecognized request"))
 	}
 	resp := &http.Response{
 		Status:     "101 Switching Protocols",
@@ -82,6 +93,16 @@ func (s *server) Handle(conn net.Conn) (stat.Connection, error) {
 	return stat.Connection(newConnection(conn, remoteAddr)), nil
 }
 
+// reject hands a connection with a request which is not for the transport
+// over to the fallback, if any.
+func (s *server) reject(conn net.Conn, recorder *httpfallback.RecordingReader, err error) error {
+	if s.fallback != nil {
+		go s.fallback.ServeConn(conn, recorder.Bytes())
+		return errors.New("falling back").Base(err)
+	}
+	return err
+}
+
 func (s *server) keepAccepting() {
 	for {
 		conn, err := s.innnerListener.Accept()
@@ -99,6 +120,10 @@ func (s *server) keepAccepting() {
 
 func ListenHTTPUpgrade(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
 	transportConfiguration := streamSettings.ProtocolSettings.(*Config)
+	fallback, err := httpfallback.New(transportConfiguration.GetFallback())
+	if err != nil {
+		return nil, errors.New("failed to create fallback (for HttpUpgrade)").Base(err)
+	}
 	if transportConfiguration != nil {
 		if streamSettings.SocketSettings == nil {
 			streamSettings.SocketSettings = &internet.SocketConfig{}
@@ -106,7 +131,6 @@ func ListenHTTPUpgrade(ctx context.Context, address net.Address, port net.Port,
 		streamSettings.SocketSettings.AcceptProxyProtocol = transportConfiguration.AcceptProxyProtocol || streamSettings.SocketSettings.AcceptProxyProtocol
 	}
 	var listener net.Listener
-	var err error
 	if port == net.Port(0) { // unix
 		listener, err = internet.ListenSystem(ctx, &net.UnixAddr{
 			Name: address.Domain(),
@@ -141,6 +165,7 @@ func ListenHTTPUpgrade(ctx context.Context, address net.Address, port net.Port,
 		config:         transportConfiguration,
 		addConn:        addConn,
 		innnerListener: listener,
+		fallback:       fallback,
 	}
 	go serverInstance.keepAccepting()
 	return serverInstance, nil
@@ -10,6 +10,8 @@ import (
 	"github.com/luckyluke-a/xray-core/common/dice"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/core"
+	"github.com/luckyluke-a/xray-core/features/stats"
 	"github.com/luckyluke-a/xray-core/transport/internet"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
 	"github.com/luckyluke-a/xray-core/transport/internet/tls"
@@ -64,15 +66,27 @@ func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet
 	if err != nil {
 		return nil, errors.New("failed to create security").Base(err)
 	}
-	reader := &KCPPacketReader{
+	var reader PacketReader = &KCPPacketReader{
 		Header:   header,
 		Security: security,
 	}
-	writer := &KCPPacketWriter{
+	var writer PacketWriter = &KCPPacketWriter{
 		Header:   header,
 		Security: security,
 		Writer:   rawConn,
 	}
+	if kcpSettings.Fec != nil {
+		decoder := NewFECDecoder(statsManager(ctx))
+		reader = &FECPacketReader{
+			KCPPacketReader: reader.(*KCPPacketReader),
+			Decoder:         decoder,
+		}
+		writer = &FECPacketWriter{
+			Writer: writer,
+			Config: kcpSettings.Fec,
+			Loss:   decoder.Loss,
+		}
+	}
 
 	conv := uint16(atomic.AddUint32(&globalConv, 1))
 	session := NewConnection(ConnMetadata{
@@ -92,6 +106,15 @@ func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet
 	return iConn, nil
 }
 
+func statsManager(ctx context.Context) stats.Manager {
+	if v := core.FromContext(ctx); v != nil {
+		if m, ok := v.GetFeature(stats.ManagerType()).(stats.Manager); ok {
+			return m
+		}
+	}
+	return nil
+}
+
 func init() {
 	common.Must(internet.RegisterTransportDialer(protocolName, DialKCP))
 }
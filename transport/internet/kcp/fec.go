@@ -0,0 +1,437 @@
+package kcp
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/dice"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/features/stats"
+)
+
+const (
+	// fecHeaderSize is the size of group ID, shard index, data shard count, parity shard count and
+	// the loss rate observed by the writer side.
+	fecHeaderSize = 8
+	// fecMaxGroups is the number of shard groups kept by FECDecoder for reassembly.
+	fecMaxGroups = 16
+	// fecFlushTimeout is the time after which parity shards are sent for a partial group, so that
+	// the last packets of a burst can be recovered too.
+	fecFlushTimeout = 20 * time.Millisecond
+)
+
+// GetDataShardsValue returns the number of data shards per FEC group.
+func (c *FEC) GetDataShardsValue() int {
+	if c.GetDataShards() == 0 {
+		return 10
+	}
+	return int(c.GetDataShards())
+}
+
+// GetParityShardsValue returns the minimum number of parity shards per FEC group.
+func (c *FEC) GetParityShardsValue() int {
+	if c.GetParityShards() == 0 {
+		return 3
+	}
+	return int(c.GetParityShards())
+}
+
+// GetMaxParityShardsValue returns the maximum number of parity shards per FEC group when adaptive.
+func (c *FEC) GetMaxParityShardsValue() int {
+	parity := int(c.GetMaxParityShards())
+	if parity == 0 {
+		parity = c.GetDataShardsValue()
+	}
+	if parity < c.GetParityShardsValue() {
+		parity = c.GetParityShardsValue()
+	}
+	if c.GetDataShardsValue()+parity > 255 {
+		parity = 255 - c.GetDataShardsValue()
+	}
+	return parity
+}
+
+// FECLoss is the packet loss rate observed by a FECDecoder, shared with the FECPacketWriters
+// of the same peer. The writers report it to the peer, and adapt their parity to the loss
+// rate reported back by the peer.
+type FECLoss struct {
+	bits atomic.Uint64
+	peer atomic.Uint32
+}
+
+// Rate returns the loss rate of the packets received from the peer, in [0, 1].
+func (l *FECLoss) Rate() float64 {
+	return math.Float64frombits(l.bits.Load())
+}
+
+// PeerRate returns the loss rate of the packets sent to the peer, as reported by the peer.
+func (l *FECLoss) PeerRate() float64 {
+	return float64(l.peer.Load()) / math.MaxUint8
+}
+
+func (l *FECLoss) update(sample float64) {
+	// Exponentially weighted, so a few groups don't swing the parity too much.
+	l.bits.Store(math.Float64bits(l.Rate()*0.875 + sample*0.125))
+}
+
+// report returns the loss rate to report to the peer, in a byte.
+func (l *FECLoss) report() byte {
+	if l == nil {
+		return 0
+	}
+	return byte(math.Round(l.Rate() * math.MaxUint8))
+}
+
+// FECPacketWriter groups the packets of a connection and sends parity packets after every group,
+// so that the peer can recover lost packets without retransmission. A group that isn't full
+// after fecFlushTimeout is closed with the packets in it.
+type FECPacketWriter struct {
+	sync.Mutex
+	Writer PacketWriter
+	Config *FEC
+	Loss   *FECLoss
+
+	codecs  map[[2]int]*ReedSolomon
+	group   uint32
+	parity  int
+	shards  [][]byte
+	buffer  []byte
+	timer   *time.Timer
+	started bool
+}
+
+func (w *FECPacketWriter) Overhead() int {
+	return w.Writer.Overhead() + fecHeaderSize + 2
+}
+
+func (w *FECPacketWriter) parityShards(dataShards int) int {
+	parity := w.Config.GetParityShardsValue()
+	if !w.Config.GetAdaptive() || w.Loss == nil {
+		return parity
+	}
+	// Twice the expected number of lost shards, as losses come in bursts.
+	if adaptive := int(math.Ceil(float64(dataShards) * w.Loss.PeerRate() * 2)); adaptive > parity {
+		parity = adaptive
+	}
+	if maxParity := w.Config.GetMaxParityShardsValue(); parity > maxParity {
+		parity = maxParity
+	}
+	return parity
+}
+
+func (w *FECPacketWriter) codec(dataShards, parityShards int) (*ReedSolomon, error) {
+	key := [2]int{dataShards, parityShards}
+	if rs, found := w.codecs[key]; found {
+		return rs, nil
+	}
+	rs, err := NewReedSolomon(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+	if w.codecs == nil {
+		w.codecs = make(map[[2]int]*ReedSolomon)
+	}
+	w.codecs[key] = rs
+	return rs, nil
+}
+
+func (w *FECPacketWriter) writeShard(index, dataShards, parityShards int, shard []byte) error {
+	w.buffer = append(w.buffer[:0], make([]byte, fecHeaderSize)...)
+	binary.BigEndian.PutUint32(w.buffer, w.group)
+	w.buffer[4] = byte(index)
+	w.buffer[5] = byte(dataShards)
+	w.buffer[6] = byte(parityShards)
+	w.buffer[7] = w.Loss.report()
+	w.buffer = append(w.buffer, shard...)
+	_, err := w.Writer.Write(w.buffer)
+	return err
+}
+
+func (w *FECPacketWriter) Write(b []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if !w.started {
+		w.group = uint32(dice.RollUint64())
+		w.started = true
+	}
+
+	dataShards := w.Config.GetDataShardsValue()
+	index := len(w.shards)
+	if index == 0 {
+		w.parity = w.parityShards(dataShards)
+		group := w.group
+		w.timer = time.AfterFunc(fecFlushTimeout, func() {
+			w.flush(group)
+		})
+	}
+
+	shard := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(shard, uint16(len(b)))
+	copy(shard[2:], b)
+	w.shards = append(w.shards, shard)
+
+	if err := w.writeShard(index, dataShards, w.parity, shard); err != nil {
+		return 0, err
+	}
+	if len(w.shards) < dataShards {
+		return len(b), nil
+	}
+	w.timer.Stop()
+	if err := w.writeParity(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// flush closes the group if it's still partial.
+func (w *FECPacketWriter) flush(group uint32) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.group != group || len(w.shards) == 0 {
+		return
+	}
+	if err := w.writeParity(); err != nil {
+		errors.LogDebugInner(context.Background(), err, "failed to write FEC parity")
+	}
+}
+
+// writeParity sends the parity shards of the data shards in the group, and starts the next
+// group. The parity shards carry the number of data shards, which is less than the configured
+// one for a partial group.
+func (w *FECPacketWriter) writeParity() error {
+	dataShards := len(w.shards)
+	parityShards := w.parity
+	defer func() {
+		w.shards = w.shards[:0]
+		w.group++
+	}()
+
+	rs, err := w.codec(dataShards, parityShards)
+	if err != nil {
+		return err
+	}
+	size := 0
+	for _, shard := range w.shards {
+		if len(shard) > size {
+			size = len(shard)
+		}
+	}
+	shards := make([][]byte, dataShards+parityShards)
+	for i, shard := range w.shards {
+		shards[i] = make([]byte, size)
+		copy(shards[i], shard)
+	}
+	for i := dataShards; i < len(shards); i++ {
+		shards[i] = make([]byte, size)
+	}
+	rs.Encode(shards)
+	for i := dataShards; i < len(shards); i++ {
+		if err := w.writeShard(i, dataShards, parityShards, shards[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fecGroup struct {
+	// dataShards and parityShards are set by the parity shards, as a partial group has less data
+	// shards than the data shards carry.
+	dataShards   int
+	parityShards int
+	shards       [][]byte
+	complete     bool
+}
+
+// received returns the number of shards received in the group.
+func (g *fecGroup) received() int {
+	n := 0
+	for i, shard := range g.shards {
+		if shard != nil && (g.dataShards == 0 || i < g.dataShards+g.parityShards) {
+			n++
+		}
+	}
+	return n
+}
+
+// FECDecoder reassembles the packets written by a FECPacketWriter, recovering lost packets from
+// the parity packets.
+type FECDecoder struct {
+	sync.Mutex
+	Loss *FECLoss
+
+	codecs    map[[2]int]*ReedSolomon
+	groups    map[uint32]*fecGroup
+	order     []uint32
+	recovered stats.Counter
+	lost      stats.Counter
+}
+
+// NewFECDecoder creates a FECDecoder. Recovered and lost packets are counted in the given stats manager, if any.
+func NewFECDecoder(m stats.Manager) *FECDecoder {
+	d := &FECDecoder{
+		Loss:   new(FECLoss),
+		codecs: make(map[[2]int]*ReedSolomon),
+		groups: make(map[uint32]*fecGroup),
+	}
+	if m != nil {
+		d.recovered, _ = stats.GetOrRegisterCounter(m, "mkcp>>>fec>>>recovered")
+		d.lost, _ = stats.GetOrRegisterCounter(m, "mkcp>>>fec>>>lost")
+	}
+	return d
+}
+
+// Decode takes a FEC packet and returns the packets that are received or recovered by it.
+func (d *FECDecoder) Decode(b []byte) ([][]byte, error) {
+	if len(b) < fecHeaderSize {
+		return nil, errors.New("invalid FEC packet size: ", len(b))
+	}
+	id := binary.BigEndian.Uint32(b)
+	index := int(b[4])
+	dataShards := int(b[5])
+	parityShards := int(b[6])
+	shard := b[fecHeaderSize:]
+	if dataShards == 0 || parityShards == 0 || index >= dataShards+parityShards {
+		return nil, errors.New("invalid FEC shard ", index, " of ", dataShards, "+", parityShards)
+	}
+	d.Loss.peer.Store(uint32(b[7]))
+
+	d.Lock()
+	defer d.Unlock()
+
+	group, found := d.groups[id]
+	if !found {
+		group = &fecGroup{}
+		d.groups[id] = group
+		d.order = append(d.order, id)
+		for len(d.order) > fecMaxGroups {
+			d.evict(d.order[0])
+			d.order = d.order[1:]
+		}
+	}
+	if index >= dataShards {
+		if group.dataShards == 0 {
+			group.dataShards = dataShards
+			group.parityShards = parityShards
+		} else if group.dataShards != dataShards || group.parityShards != parityShards {
+			return nil, errors.New("mismatched FEC shard ", index, " of ", dataShards, "+", parityShards)
+		}
+	}
+	for len(group.shards) <= index {
+		group.shards = append(group.shards, nil)
+	}
+	if group.shards[index] != nil {
+		return nil, nil
+	}
+	group.shards[index] = append([]byte(nil), shard...)
+
+	var output [][]byte
+	if index < dataShards {
+		if payload := fecPayload(shard); payload != nil {
+			output = append(output, payload)
+		}
+	}
+	dataShards, parityShards = group.dataShards, group.parityShards
+	if group.complete || dataShards == 0 || group.received() < dataShards {
+		return output, nil
+	}
+	group.complete = true
+
+	missing := 0
+	size := 0
+	for len(group.shards) < dataShards+parityShards {
+		group.shards = append(group.shards, nil)
+	}
+	for i, shard := range group.shards[:dataShards+parityShards] {
+		if shard == nil {
+			if i < dataShards {
+				missing++
+			}
+			continue
+		}
+		if len(shard) > size {
+			size = len(shard)
+		}
+	}
+	if missing == 0 {
+		return output, nil
+	}
+
+	shards := make([][]byte, dataShards+parityShards)
+	for i, shard := range group.shards[:dataShards+parityShards] {
+		if shard != nil {
+			shards[i] = make([]byte, size)
+			copy(shards[i], shard)
+		}
+	}
+	rs, err := d.codec(dataShards, parityShards)
+	if err != nil {
+		return output, err
+	}
+	if err := rs.Reconstruct(shards); err != nil {
+		return output, err
+	}
+	for i := 0; i < dataShards; i++ {
+		if group.shards[i] != nil {
+			continue
+		}
+		if payload := fecPayload(shards[i]); payload != nil {
+			output = append(output, payload)
+			if d.recovered != nil {
+				d.recovered.Add(1)
+			}
+		}
+	}
+	return output, nil
+}
+
+func (d *FECDecoder) codec(dataShards, parityShards int) (*ReedSolomon, error) {
+	key := [2]int{dataShards, parityShards}
+	if rs, found := d.codecs[key]; found {
+		return rs, nil
+	}
+	rs, err := NewReedSolomon(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+	d.codecs[key] = rs
+	return rs, nil
+}
+
+// evict drops a group, accounting its losses. Groups without any parity shard are not accounted,
+// as the writer may have not finished them.
+func (d *FECDecoder) evict(id uint32) {
+	group := d.groups[id]
+	delete(d.groups, id)
+
+	if group.dataShards == 0 {
+		return
+	}
+	total := group.dataShards + group.parityShards
+	d.Loss.update(float64(total-group.received()) / float64(total))
+	if !group.complete && d.lost != nil {
+		lost := 0
+		for i := 0; i < group.dataShards; i++ {
+			if i >= len(group.shards) || group.shards[i] == nil {
+				lost++
+			}
+		}
+		d.lost.Add(int64(lost))
+	}
+}
+
+func fecPayload(shard []byte) []byte {
+	if len(shard) < 2 {
+		return nil
+	}
+	size := int(binary.BigEndian.Uint16(shard))
+	if size > len(shard)-2 {
+		return nil
+	}
+	return shard[2 : 2+size]
+}
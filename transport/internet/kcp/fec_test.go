@@ -0,0 +1,218 @@
+package kcp_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common"
+	. "github.com/luckyluke-a/xray-core/transport/internet/kcp"
+)
+
+func TestReedSolomon(t *testing.T) {
+	rs, err := NewReedSolomon(10, 4)
+	common.Must(err)
+
+	shards := make([][]byte, 14)
+	for i := range shards {
+		shards[i] = make([]byte, 100)
+		if i < 10 {
+			common.Must2(rand.Read(shards[i]))
+		}
+	}
+	rs.Encode(shards)
+
+	for _, lost := range [][]int{{0}, {3, 9}, {0, 1, 2, 3}, {2, 5, 10, 13}, {10, 11, 12, 13}} {
+		received := make([][]byte, len(shards))
+		copy(received, shards)
+		for _, i := range lost {
+			received[i] = nil
+		}
+		if err := rs.Reconstruct(received); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			if !bytes.Equal(received[i], shards[i]) {
+				t.Error("shard ", i, " not recovered when losing ", lost)
+			}
+		}
+	}
+
+	received := make([][]byte, len(shards))
+	copy(received, shards)
+	for i := 0; i < 5; i++ {
+		received[i] = nil
+	}
+	if err := rs.Reconstruct(received); err == nil {
+		t.Error("expect error when losing more shards than parity")
+	}
+}
+
+type packetRecorder struct {
+	sync.Mutex
+	packets [][]byte
+}
+
+func (r *packetRecorder) Overhead() int {
+	return 0
+}
+
+func (r *packetRecorder) Write(b []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.packets = append(r.packets, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+// take returns the packets written so far, and clears them.
+func (r *packetRecorder) take() [][]byte {
+	r.Lock()
+	defer r.Unlock()
+
+	packets := r.packets
+	r.packets = nil
+	return packets
+}
+
+func TestFECRecovery(t *testing.T) {
+	recorder := &packetRecorder{}
+	writer := &FECPacketWriter{
+		Writer: recorder,
+		Config: &FEC{DataShards: 4, ParityShards: 2},
+	}
+
+	var payloads [][]byte
+	for i := 0; i < 8; i++ {
+		payload := make([]byte, 10+i*7)
+		common.Must2(rand.Read(payload))
+		payloads = append(payloads, payload)
+		common.Must2(writer.Write(payload))
+	}
+	if len(recorder.packets) != 12 {
+		t.Fatal("expect 12 packets, but got ", len(recorder.packets))
+	}
+
+	decoder := NewFECDecoder(nil)
+	var output [][]byte
+	for i, packet := range recorder.packets {
+		// Lose two data packets of the first group, and one data and one parity packet of the second.
+		if i == 0 || i == 2 || i == 7 || i == 11 {
+			continue
+		}
+		out, err := decoder.Decode(packet)
+		common.Must(err)
+		output = append(output, out...)
+	}
+
+	if len(output) != len(payloads) {
+		t.Fatal("expect ", len(payloads), " packets, but got ", len(output))
+	}
+	for _, payload := range payloads {
+		found := false
+		for _, out := range output {
+			if bytes.Equal(out, payload) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Error("packet not recovered: ", payload)
+		}
+	}
+}
+
+func TestFECFlushPartialGroup(t *testing.T) {
+	recorder := &packetRecorder{}
+	writer := &FECPacketWriter{
+		Writer: recorder,
+		Config: &FEC{DataShards: 4, ParityShards: 2},
+	}
+
+	payloads := [][]byte{[]byte("first"), []byte("second")}
+	for _, payload := range payloads {
+		common.Must2(writer.Write(payload))
+	}
+	if n := len(recorder.take()); n != 2 {
+		t.Fatal("expect 2 packets before the flush, but got ", n)
+	}
+	common.Must2(writer.Write([]byte("third")))
+	payloads = append(payloads, []byte("third"))
+	time.Sleep(200 * time.Millisecond)
+	packets := recorder.take()
+	if len(packets) != 3 {
+		t.Fatal("expect 1 data and 2 parity packets of the partial group, but got ", len(packets))
+	}
+
+	// Lose the first two data packets, which are recovered from the parity of the partial group.
+	decoder := NewFECDecoder(nil)
+	var output [][]byte
+	for _, packet := range packets {
+		out, err := decoder.Decode(packet)
+		common.Must(err)
+		output = append(output, out...)
+	}
+	if len(output) != 3 {
+		t.Fatal("expect 3 packets, but got ", len(output))
+	}
+	for i, payload := range payloads {
+		found := false
+		for _, out := range output {
+			found = found || bytes.Equal(out, payload)
+		}
+		if !found {
+			t.Error("packet ", i, " not recovered")
+		}
+	}
+}
+
+func TestFECPeerFeedback(t *testing.T) {
+	config := &FEC{DataShards: 4, ParityShards: 2, Adaptive: true}
+	// a and b are the two sides of a connection
+	aDecoder, bDecoder := NewFECDecoder(nil), NewFECDecoder(nil)
+	aRecorder, bRecorder := &packetRecorder{}, &packetRecorder{}
+	aWriter := &FECPacketWriter{Writer: aRecorder, Config: config, Loss: aDecoder.Loss}
+	bWriter := &FECPacketWriter{Writer: bRecorder, Config: config, Loss: bDecoder.Loss}
+
+	// parity shards of the next group of the writer
+	parity := func(writer *FECPacketWriter, recorder *packetRecorder) int {
+		recorder.take()
+		for i := 0; i < 4; i++ {
+			common.Must2(writer.Write([]byte("data")))
+		}
+		return len(recorder.take()) - 4
+	}
+
+	// The packets from a to b lose 2 of 6 shards in every group.
+	for i := 0; i < 64; i++ {
+		for j := 0; j < 4; j++ {
+			common.Must2(aWriter.Write([]byte("data")))
+		}
+		for j, packet := range aRecorder.take() {
+			if j%3 != 0 {
+				common.Must2(bDecoder.Decode(packet))
+			}
+		}
+	}
+	if bDecoder.Loss.Rate() < 0.3 {
+		t.Fatal("loss not observed by the decoder: ", bDecoder.Loss.Rate())
+	}
+	// b's own packets aren't lost, so its parity doesn't change
+	if p := parity(bWriter, bRecorder); p != 2 {
+		t.Error("expect the parity of b not changed by the loss of a's packets, but got ", p)
+	}
+	if p := parity(aWriter, aRecorder); p != 2 {
+		t.Error("expect the parity of a not changed before the feedback, but got ", p)
+	}
+
+	// b reports the loss to a in its packets
+	common.Must2(bWriter.Write([]byte("data")))
+	for _, packet := range bRecorder.take() {
+		common.Must2(aDecoder.Decode(packet))
+	}
+	if p := parity(aWriter, aRecorder); p != 3 {
+		t.Error("expect the parity of a raised by the feedback of b, but got ", p)
+	}
+}
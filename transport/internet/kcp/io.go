@@ -25,6 +25,11 @@ type KCPPacketReader struct {
 }
 
 func (r *KCPPacketReader) Read(b []byte) []Segment {
+	return readSegments(r.open(b))
+}
+
+// open strips the header and decrypts the packet, returning nil if the packet is invalid.
+func (r *KCPPacketReader) open(b []byte) []byte {
 	if r.Header != nil {
 		if int32(len(b)) <= r.Header.Size() {
 			return nil
@@ -43,6 +48,10 @@ func (r *KCPPacketReader) Read(b []byte) []Segment {
 		}
 		b = out
 	}
+	return b
+}
+
+func readSegments(b []byte) []Segment {
 	var result []Segment
 	for len(b) > 0 {
 		seg, x := ReadSegment(b)
@@ -55,6 +64,28 @@ func (r *KCPPacketReader) Read(b []byte) []Segment {
 	return result
 }
 
+// FECPacketReader reads packets written by a FECPacketWriter.
+type FECPacketReader struct {
+	*KCPPacketReader
+	Decoder *FECDecoder
+}
+
+func (r *FECPacketReader) Read(b []byte) []Segment {
+	b = r.open(b)
+	if b == nil {
+		return nil
+	}
+	payloads, err := r.Decoder.Decode(b)
+	if err != nil {
+		return nil
+	}
+	var result []Segment
+	for _, payload := range payloads {
+		result = append(result, readSegments(payload)...)
+	}
+	return result
+}
+
 type KCPPacketWriter struct {
 	Header   internet.PacketHeader
 	Security cipher.AEAD
@@ -10,6 +10,7 @@ import (
 	"github.com/luckyluke-a/xray-core/common/buf"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/features/stats"
 	"github.com/luckyluke-a/xray-core/transport/internet"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
 	"github.com/luckyluke-a/xray-core/transport/internet/tls"
@@ -22,16 +23,23 @@ type ConnectionID struct {
 	Conv   uint16
 }
 
+type fecSource struct {
+	Remote net.Address
+	Port   net.Port
+}
+
 // Listener defines a server listening for connections
 type Listener struct {
 	sync.Mutex
 	sessions  map[ConnectionID]*Connection
+	decoders  map[fecSource]*FECDecoder
 	hub       *udp.Hub
 	tlsConfig *gotls.Config
 	config    *Config
-	reader    PacketReader
+	reader    *KCPPacketReader
 	header    internet.PacketHeader
 	security  cipher.AEAD
+	stats     stats.Manager
 	addConn   internet.ConnHandler
 }
 
@@ -53,7 +61,9 @@ func NewListener(ctx context.Context, address net.Address, port net.Port, stream
 			Security: security,
 		},
 		sessions: make(map[ConnectionID]*Connection),
+		decoders: make(map[fecSource]*FECDecoder),
 		config:   kcpSettings,
+		stats:    statsManager(ctx),
 		addConn:  addConn,
 	}
 
@@ -83,7 +93,25 @@ func (l *Listener) handlePackets() {
 }
 
 func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
-	segments := l.reader.Read(payload.Bytes())
+	var segments []Segment
+	var decoder *FECDecoder
+	if l.config.Fec != nil {
+		source := fecSource{Remote: src.Address, Port: src.Port}
+		l.Lock()
+		decoder = l.decoders[source]
+		l.Unlock()
+		if decoder == nil {
+			decoder = NewFECDecoder(l.stats)
+		}
+		segments = (&FECPacketReader{KCPPacketReader: l.reader, Decoder: decoder}).Read(payload.Bytes())
+		if len(segments) > 0 {
+			l.Lock()
+			l.decoders[source] = decoder
+			l.Unlock()
+		}
+	} else {
+		segments = l.reader.Read(payload.Bytes())
+	}
 	payload.Release()
 
 	if len(segments) == 0 {
@@ -120,15 +148,23 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 			Port: int(src.Port),
 		}
 		localAddr := l.hub.Addr()
+		var packetWriter PacketWriter = &KCPPacketWriter{
+			Header:   l.header,
+			Security: l.security,
+			Writer:   writer,
+		}
+		if decoder != nil {
+			packetWriter = &FECPacketWriter{
+				Writer: packetWriter,
+				Config: l.config.Fec,
+				Loss:   decoder.Loss,
+			}
+		}
 		conn = NewConnection(ConnMetadata{
 			LocalAddr:    localAddr,
 			RemoteAddr:   remoteAddr,
 			Conversation: conv,
-		}, &KCPPacketWriter{
-			Header:   l.header,
-			Security: l.security,
-			Writer:   writer,
-		}, writer, l.config)
+		}, packetWriter, writer, l.config)
 		var netConn stat.Connection = conn
 		if l.tlsConfig != nil {
 			netConn = tls.Server(conn, l.tlsConfig)
@@ -142,8 +178,15 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 
 func (l *Listener) Remove(id ConnectionID) {
 	l.Lock()
+	defer l.Unlock()
+
 	delete(l.sessions, id)
-	l.Unlock()
+	for other := range l.sessions {
+		if other.Remote == id.Remote && other.Port == id.Port {
+			return
+		}
+	}
+	delete(l.decoders, fecSource{Remote: id.Remote, Port: id.Port})
 }
 
 // Close stops listening on the UDP address. Already Accepted connections are not closed.
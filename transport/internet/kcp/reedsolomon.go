@@ -0,0 +1,170 @@
+package kcp
+
+import (
+	"github.com/luckyluke-a/xray-core/common/errors"
+)
+
+// Arithmetic in GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1.
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfMulAdd sets dst[i] ^= c * src[i].
+func gfMulAdd(dst, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	logC := int(gfLog[c])
+	for i, v := range src {
+		if v != 0 {
+			dst[i] ^= gfExp[logC+int(gfLog[v])]
+		}
+	}
+}
+
+// ReedSolomon is a systematic Reed-Solomon erasure code, using a Cauchy matrix
+// for the parity shards so that any DataShards of the shards recover the data.
+type ReedSolomon struct {
+	DataShards   int
+	ParityShards int
+	parity       [][]byte
+}
+
+// NewReedSolomon creates a ReedSolomon with the given shard counts.
+func NewReedSolomon(dataShards, parityShards int) (*ReedSolomon, error) {
+	if dataShards <= 0 || parityShards <= 0 || dataShards+parityShards > 256 {
+		return nil, errors.New("invalid FEC shards: ", dataShards, "+", parityShards)
+	}
+	r := &ReedSolomon{
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		parity:       make([][]byte, parityShards),
+	}
+	for i := range r.parity {
+		r.parity[i] = make([]byte, dataShards)
+		for j := range r.parity[i] {
+			r.parity[i][j] = gfInv(byte(dataShards+i) ^ byte(j))
+		}
+	}
+	return r, nil
+}
+
+// Encode computes the parity shards, shards[DataShards:], from the data shards.
+// All shards must have the same size.
+func (r *ReedSolomon) Encode(shards [][]byte) {
+	for i, row := range r.parity {
+		out := shards[r.DataShards+i]
+		clear(out)
+		for j, c := range row {
+			gfMulAdd(out, shards[j], c)
+		}
+	}
+}
+
+// Reconstruct recovers the missing (nil) data shards, if at least DataShards shards are present.
+// Present shards must have the same size. Missing parity shards are not recovered.
+func (r *ReedSolomon) Reconstruct(shards [][]byte) error {
+	size := 0
+	var rows [][]byte
+	var inputs [][]byte
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		size = len(shard)
+		if len(rows) == r.DataShards {
+			break
+		}
+		row := make([]byte, r.DataShards)
+		if i < r.DataShards {
+			row[i] = 1
+		} else {
+			copy(row, r.parity[i-r.DataShards])
+		}
+		rows = append(rows, row)
+		inputs = append(inputs, shard)
+	}
+	if len(rows) < r.DataShards {
+		return errors.New("too few FEC shards: ", len(rows), "/", r.DataShards)
+	}
+
+	decode, err := invertMatrix(rows)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < r.DataShards; i++ {
+		if shards[i] != nil {
+			continue
+		}
+		out := make([]byte, size)
+		for j, c := range decode[i] {
+			gfMulAdd(out, inputs[j], c)
+		}
+		shards[i] = out
+	}
+	return nil
+}
+
+// invertMatrix inverts a square matrix with Gauss-Jordan elimination.
+func invertMatrix(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	work := make([][]byte, n)
+	for i := range m {
+		work[i] = make([]byte, 2*n)
+		copy(work[i], m[i])
+		work[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && work[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errors.New("singular FEC matrix")
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+		if c := work[col][col]; c != 1 {
+			inv := gfInv(c)
+			for j := range work[col] {
+				work[col][j] = gfMul(work[col][j], inv)
+			}
+		}
+		for row := 0; row < n; row++ {
+			if row != col && work[row][col] != 0 {
+				gfMulAdd(work[row], work[col], work[row][col])
+			}
+		}
+	}
+	inverse := make([][]byte, n)
+	for i := range work {
+		inverse[i] = work[i][n:]
+	}
+	return inverse, nil
+}
@@ -7,6 +7,16 @@ type MemoryStreamConfig struct {
 	SecurityType     string
 	SecuritySettings interface{}
 	SocketSettings   *SocketConfig
+	// DownloadSettings are the stream settings of the download requests of
+	// splithttp. They are parsed once with the config, so that the HTTP
+	// clients of download requests are reused across connections.
+	DownloadSettings *MemoryStreamConfig
+}
+
+// downloadStreamConfig is a transport config with separate stream settings
+// of downloads.
+type downloadStreamConfig interface {
+	GetDownloadStreamSettings() *StreamConfig
 }
 
 // ToMemoryStreamConfig converts a StreamConfig to MemoryStreamConfig. It returns a default non-nil MemoryStreamConfig for nil input.
@@ -25,6 +35,12 @@ func ToMemoryStreamConfig(s *StreamConfig) (*MemoryStreamConfig, error) {
 		mss.SocketSettings = s.SocketSettings
 	}
 
+	if d, ok := ets.(downloadStreamConfig); ok && d.GetDownloadStreamSettings() != nil {
+		if mss.DownloadSettings, err = ToMemoryStreamConfig(d.GetDownloadStreamSettings()); err != nil {
+			return nil, err
+		}
+	}
+
 	if s != nil && s.HasSecuritySettings() {
 		ess, err := s.GetEffectiveSecuritySettings()
 		if err != nil {
@@ -1,14 +1,19 @@
 package quic
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/sha256"
 	"errors"
+	"time"
 
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/protocol"
 	"github.com/luckyluke-a/xray-core/transport/internet"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/qlog"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
@@ -46,3 +51,49 @@ func getHeader(config *Config) (internet.PacketHeader, error) {
 
 	return internet.CreatePacketHeader(msg)
 }
+
+const (
+	defaultMaxIdleTimeout     = 300 * time.Second
+	defaultMaxIncomingStreams = 32
+	defaultHopInterval        = 30 * time.Second
+)
+
+// getQuicConfig returns the quic-go config of the client, or of the server if
+// server is true.
+func (c *Config) getQuicConfig(server bool) *quic.Config {
+	quicConfig := &quic.Config{
+		HandshakeIdleTimeout:           time.Second * 8,
+		MaxIdleTimeout:                 defaultMaxIdleTimeout,
+		KeepAlivePeriod:                time.Duration(c.KeepAlivePeriod) * time.Second,
+		InitialStreamReceiveWindow:     c.InitialStreamReceiveWindow,
+		MaxStreamReceiveWindow:         c.MaxStreamReceiveWindow,
+		InitialConnectionReceiveWindow: c.InitialConnectionReceiveWindow,
+		MaxConnectionReceiveWindow:     c.MaxConnectionReceiveWindow,
+		DisablePathMTUDiscovery:        c.DisablePathMtuDiscovery,
+		Allow0RTT:                      c.ZeroRtt,
+		Tracer: func(ctx context.Context, p logging.Perspective, ci quic.ConnectionID) *logging.ConnectionTracer {
+			return logging.NewMultiplexedConnectionTracer(
+				qlog.NewConnectionTracer(&QlogWriter{connID: ci}, p, ci),
+				newStatsTracer(ctx, p, ci),
+			)
+		},
+	}
+	if c.MaxIdleTimeout > 0 {
+		quicConfig.MaxIdleTimeout = time.Duration(c.MaxIdleTimeout) * time.Second
+	}
+	if server {
+		quicConfig.MaxIncomingStreams = defaultMaxIncomingStreams
+		if c.MaxIncomingStreams != 0 {
+			quicConfig.MaxIncomingStreams = c.MaxIncomingStreams
+		}
+		quicConfig.MaxIncomingUniStreams = -1
+	}
+	return quicConfig
+}
+
+func (c *Config) getHopInterval() time.Duration {
+	if c.HopInterval == 0 {
+		return defaultHopInterval
+	}
+	return time.Duration(c.HopInterval) * time.Second
+}
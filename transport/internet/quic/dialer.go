@@ -6,8 +6,6 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go"
-	"github.com/quic-go/quic-go/logging"
-	"github.com/quic-go/quic-go/qlog"
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/net"
@@ -18,7 +16,7 @@ import (
 )
 
 type connectionContext struct {
-	rawConn *sysConn
+	rawConn net.PacketConn
 	conn    quic.Connection
 }
 
@@ -139,14 +137,7 @@ func (s *clientConnections) openConnection(ctx context.Context, destAddr net.Add
 		return nil, errors.New("failed to dial to dest: ", err).AtWarning().Base(err)
 	}
 
-	quicConfig := &quic.Config{
-		KeepAlivePeriod:      0,
-		HandshakeIdleTimeout: time.Second * 8,
-		MaxIdleTimeout:       time.Second * 300,
-		Tracer: func(ctx context.Context, p logging.Perspective, ci quic.ConnectionID) *logging.ConnectionTracer {
-			return qlog.NewConnectionTracer(&QlogWriter{connID: ci}, p, ci)
-		},
-	}
+	quicConfig := config.getQuicConfig(false)
 
 	var udpConn *net.UDPConn
 	switch conn := rawConn.(type) {
@@ -165,19 +156,31 @@ func (s *clientConnections) openConnection(ctx context.Context, destAddr net.Add
 		rawConn.Close()
 		return nil, err
 	}
+	var packetConn net.PacketConn = sysConn
+	if len(config.HopPorts.GetRange()) > 0 {
+		packetConn = newHopConn(sysConn, destAddr.(*net.UDPAddr), config.HopPorts, config.getHopInterval())
+	}
 	tr := quic.Transport{
 		ConnectionIDLength: 12,
-		Conn:               sysConn,
+		Conn:               packetConn,
+	}
+	goTLSConfig := tlsConfig.GetTLSConfig(tls.WithDestination(dest))
+	var conn quic.Connection
+	if config.ZeroRtt {
+		// resumes the session with 0-RTT, if there is a ticket of the server
+		goTLSConfig.SessionTicketsDisabled = false
+		conn, err = tr.DialEarly(context.Background(), destAddr, goTLSConfig, quicConfig)
+	} else {
+		conn, err = tr.Dial(context.Background(), destAddr, goTLSConfig, quicConfig)
 	}
-	conn, err := tr.Dial(context.Background(), destAddr, tlsConfig.GetTLSConfig(tls.WithDestination(dest)), quicConfig)
 	if err != nil {
-		sysConn.Close()
+		packetConn.Close()
 		return nil, err
 	}
 
 	context := &connectionContext{
 		conn:    conn,
-		rawConn: sysConn,
+		rawConn: packetConn,
 	}
 	s.conns[dest] = append(conns, context)
 	return context.openStream(destAddr)
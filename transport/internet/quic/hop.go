@@ -0,0 +1,93 @@
+package quic
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/dice"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/common/signal/done"
+)
+
+// hopConn sends the packets of a client to a server port picked from a list,
+// which changes every interval. The QUIC connection only sees the original
+// address, so it survives the hops.
+type hopConn struct {
+	net.PacketConn
+	addr    *net.UDPAddr
+	ports   net.MemoryPortList
+	current atomic.Pointer[net.UDPAddr]
+	done    *done.Instance
+}
+
+func newHopConn(conn net.PacketConn, addr *net.UDPAddr, ports *net.PortList, interval time.Duration) *hopConn {
+	c := &hopConn{
+		PacketConn: conn,
+		addr:       addr,
+		ports:      net.PortListFromProto(ports),
+		done:       done.New(),
+	}
+	c.hop()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.hop()
+			case <-c.done.Wait():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *hopConn) hop() {
+	total := 0
+	for _, r := range c.ports {
+		total += int(r.To) - int(r.From) + 1
+	}
+	n := dice.Roll(total)
+	port := c.addr.Port
+	for _, r := range c.ports {
+		size := int(r.To) - int(r.From) + 1
+		if n < size {
+			port = int(r.From) + n
+			break
+		}
+		n -= size
+	}
+	c.current.Store(&net.UDPAddr{
+		IP:   c.addr.IP,
+		Port: port,
+		Zone: c.addr.Zone,
+	})
+}
+
+func (c *hopConn) isServer(addr *net.UDPAddr) bool {
+	if !addr.IP.Equal(c.addr.IP) {
+		return false
+	}
+	return addr.Port == c.addr.Port || c.ports.Contains(net.Port(addr.Port))
+}
+
+func (c *hopConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(p)
+	if udpAddr, ok := addr.(*net.UDPAddr); ok && c.isServer(udpAddr) {
+		addr = c.addr
+	}
+	return n, addr, err
+}
+
+func (c *hopConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok && c.isServer(udpAddr) {
+		addr = c.current.Load()
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func (c *hopConn) Close() error {
+	c.done.Close()
+	return c.PacketConn.Close()
+}
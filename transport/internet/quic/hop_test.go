@@ -0,0 +1,62 @@
+package quic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+func TestHopConn(t *testing.T) {
+	var servers []*net.UDPConn
+	ports := &net.PortList{}
+	for i := 0; i < 2; i++ {
+		server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+		common.Must(err)
+		defer server.Close()
+		servers = append(servers, server)
+		port := uint32(server.LocalAddr().(*net.UDPAddr).Port)
+		ports.Range = append(ports.Range, &net.PortRange{From: port, To: port})
+	}
+
+	rawConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	addr := &net.UDPAddr{IP: net.LocalHostIP.IP(), Port: 443}
+	conn := newHopConn(rawConn, addr, ports, time.Hour)
+	defer conn.Close()
+
+	received := make(map[int]bool)
+	for i := 0; i < 64 && len(received) < 2; i++ {
+		conn.hop()
+		common.Must2(conn.WriteTo([]byte("ping"), addr))
+
+		current := conn.current.Load()
+		for j, server := range servers {
+			if server.LocalAddr().(*net.UDPAddr).Port != current.Port {
+				continue
+			}
+			b := make([]byte, 16)
+			server.SetReadDeadline(time.Now().Add(time.Second))
+			n, from, err := server.ReadFrom(b)
+			common.Must(err)
+			if string(b[:n]) != "ping" {
+				t.Fatal("unexpected packet: ", string(b[:n]))
+			}
+			received[j] = true
+
+			common.Must2(server.WriteTo([]byte("pong"), from))
+			n, from, err = conn.ReadFrom(b)
+			common.Must(err)
+			if string(b[:n]) != "pong" {
+				t.Fatal("unexpected packet: ", string(b[:n]))
+			}
+			if from.String() != addr.String() {
+				t.Error("expect the original address, but got ", from)
+			}
+		}
+	}
+	if len(received) != 2 {
+		t.Error("expect packets on both ports, but got ", received)
+	}
+}
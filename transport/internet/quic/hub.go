@@ -5,8 +5,6 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go"
-	"github.com/quic-go/quic-go/logging"
-	"github.com/quic-go/quic-go/qlog"
 	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/net"
@@ -19,11 +17,18 @@ import (
 // Listener is an internet.Listener that listens for TCP connections.
 type Listener struct {
 	rawConn  *sysConn
-	listener *quic.Listener
+	listener quicListener
+	accept   func(context.Context) (quic.Connection, error)
 	done     *done.Instance
 	addConn  internet.ConnHandler
 }
 
+// quicListener is either a quic.Listener or a quic.EarlyListener.
+type quicListener interface {
+	Addr() net.Addr
+	Close() error
+}
+
 func (l *Listener) acceptStreams(conn quic.Connection) {
 	for {
 		stream, err := conn.AcceptStream(context.Background())
@@ -55,7 +60,7 @@ func (l *Listener) acceptStreams(conn quic.Connection) {
 
 func (l *Listener) keepAccepting() {
 	for {
-		conn, err := l.listener.Accept(context.Background())
+		conn, err := l.accept(context.Background())
 		if err != nil {
 			errors.LogInfoInner(context.Background(), err, "failed to accept QUIC connection")
 			if l.done.Done() {
@@ -103,16 +108,7 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 		return nil, err
 	}
 
-	quicConfig := &quic.Config{
-		KeepAlivePeriod:       0,
-		HandshakeIdleTimeout:  time.Second * 8,
-		MaxIdleTimeout:        time.Second * 300,
-		MaxIncomingStreams:    32,
-		MaxIncomingUniStreams: -1,
-		Tracer: func(ctx context.Context, p logging.Perspective, ci quic.ConnectionID) *logging.ConnectionTracer {
-			return qlog.NewConnectionTracer(&QlogWriter{connID: ci}, p, ci)
-		},
-	}
+	quicConfig := config.getQuicConfig(true)
 
 	conn, err := wrapSysConn(rawConn.(*net.UDPConn), config)
 	if err != nil {
@@ -123,17 +119,32 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 		ConnectionIDLength: 12,
 		Conn:               conn,
 	}
-	qListener, err := tr.Listen(tlsConfig.GetTLSConfig(), quicConfig)
-	if err != nil {
-		conn.Close()
-		return nil, err
-	}
-
 	listener := &Listener{
-		done:     done.New(),
-		rawConn:  conn,
-		listener: qListener,
-		addConn:  handler,
+		done:    done.New(),
+		rawConn: conn,
+		addConn: handler,
+	}
+	goTLSConfig := tlsConfig.GetTLSConfig()
+	if config.ZeroRtt {
+		// 0-RTT needs session tickets
+		goTLSConfig.SessionTicketsDisabled = false
+		qListener, err := tr.ListenEarly(goTLSConfig, quicConfig)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		listener.listener = qListener
+		listener.accept = func(ctx context.Context) (quic.Connection, error) {
+			return qListener.Accept(ctx)
+		}
+	} else {
+		qListener, err := tr.Listen(goTLSConfig, quicConfig)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		listener.listener = qListener
+		listener.accept = qListener.Accept
 	}
 
 	go listener.keepAccepting()
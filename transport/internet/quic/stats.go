@@ -0,0 +1,69 @@
+package quic
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// connStats counts the traffic of a QUIC connection, which is logged when the
+// connection is closed.
+type connStats struct {
+	bytesSent       atomic.Uint64
+	packetsSent     atomic.Uint64
+	bytesReceived   atomic.Uint64
+	packetsReceived atomic.Uint64
+	packetsLost     atomic.Uint64
+	smoothedRTT     atomic.Int64
+	cwnd            atomic.Uint64
+}
+
+func (s *connStats) String() string {
+	return fmt.Sprintf("sent %d bytes in %d packets, received %d bytes in %d packets, lost %d packets, rtt %v, cwnd %d",
+		s.bytesSent.Load(), s.packetsSent.Load(),
+		s.bytesReceived.Load(), s.packetsReceived.Load(),
+		s.packetsLost.Load(), time.Duration(s.smoothedRTT.Load()), s.cwnd.Load())
+}
+
+func (s *connStats) sent(size logging.ByteCount) {
+	s.bytesSent.Add(uint64(size))
+	s.packetsSent.Add(1)
+}
+
+func (s *connStats) received(size logging.ByteCount) {
+	s.bytesReceived.Add(uint64(size))
+	s.packetsReceived.Add(1)
+}
+
+func newStatsTracer(ctx context.Context, p logging.Perspective, ci quic.ConnectionID) *logging.ConnectionTracer {
+	s := &connStats{}
+	return &logging.ConnectionTracer{
+		SentLongHeaderPacket: func(_ *logging.ExtendedHeader, size logging.ByteCount, _ logging.ECN, _ *logging.AckFrame, _ []logging.Frame) {
+			s.sent(size)
+		},
+		SentShortHeaderPacket: func(_ *logging.ShortHeader, size logging.ByteCount, _ logging.ECN, _ *logging.AckFrame, _ []logging.Frame) {
+			s.sent(size)
+		},
+		ReceivedLongHeaderPacket: func(_ *logging.ExtendedHeader, size logging.ByteCount, _ logging.ECN, _ []logging.Frame) {
+			s.received(size)
+		},
+		ReceivedShortHeaderPacket: func(_ *logging.ShortHeader, size logging.ByteCount, _ logging.ECN, _ []logging.Frame) {
+			s.received(size)
+		},
+		LostPacket: func(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+			s.packetsLost.Add(1)
+		},
+		UpdatedMetrics: func(rttStats *logging.RTTStats, cwnd, _ logging.ByteCount, _ int) {
+			s.smoothedRTT.Store(int64(rttStats.SmoothedRTT()))
+			s.cwnd.Store(uint64(cwnd))
+		},
+		ClosedConnection: func(err error) {
+			errors.LogInfo(ctx, "QUIC ", p, " connection ", ci, " closed: ", s)
+		},
+	}
+}
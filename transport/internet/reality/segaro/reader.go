@@ -1,6 +1,7 @@
 package segaro
 
 import (
+	"context"
 	"encoding/binary"
 	"io"
 	"net"
@@ -33,12 +34,12 @@ func NewSegaroReader(reader buf.Reader, state *proxy.TrafficState) *SegaroReader
 }
 
 // SegaroRead filter and read xtls-segaro-vision
-func SegaroRead(reader buf.Reader, writer buf.Writer, timer *signal.ActivityTimer, conn net.Conn, trafficState *proxy.TrafficState, fromInbound bool, segaroConfig *SegaroConfig, xsvCanContinue chan bool) error {
+func SegaroRead(reader buf.Reader, writer buf.Writer, timer *signal.ActivityTimer, conn net.Conn, trafficState *proxy.TrafficState, fromInbound bool, segaroConfig *SegaroConfig, xsvCanContinue chan bool, ctx context.Context) error {
 	defer func() {
 		xsvCanContinue <- false
 	}()
 
-	authKey, clientTime, err := getRealityAuthkey(&conn, fromInbound)
+	authKey, clientTime, err := segaroConfig.getAuthKey(&conn, fromInbound)
 	if err != nil {
 		return err
 	}
@@ -97,8 +98,14 @@ func SegaroRead(reader buf.Reader, writer buf.Writer, timer *signal.ActivityTime
 									return err
 								}
 								// Send cached buffers
-								for _, buff := range trafficState.CacheBuffer {
-									for _, innerBuff := range buff {
+								minDelay, maxDelay := segaroConfig.GetChunkDelay()
+								for i, buff := range trafficState.CacheBuffer {
+									for j, innerBuff := range buff {
+										if maxDelay > 0 && (i > 0 || j > 0) {
+											if err := sleepContext(ctx, randomDelay(minDelay, maxDelay)); err != nil {
+												return err
+											}
+										}
 										if _, err := conn.Write(innerBuff.Bytes()); err != nil {
 											return err
 										}
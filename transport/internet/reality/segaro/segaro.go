@@ -1,6 +1,7 @@
 package segaro
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -66,6 +67,46 @@ func addRandomPaddingAndSplit(chunk []byte, paddingSize, subChunkSize int) *buf.
 	return paddedChunk
 }
 
+// randomDelay returns a random duration in [min, max]
+func randomDelay(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(mathRand.Int63n(int64(max-min)+1))
+}
+
+// sleepContext waits for d, and returns early with the error of ctx once it's done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeChunks writes the chunks one by one, with a random delay between them if the profile has one
+func writeChunks(ctx context.Context, writer buf.Writer, chunks buf.MultiBuffer, minDelay, maxDelay time.Duration) error {
+	if maxDelay <= 0 || len(chunks) < 2 {
+		return writer.WriteMultiBuffer(chunks)
+	}
+	for i, chunk := range chunks {
+		if i > 0 {
+			if err := sleepContext(ctx, randomDelay(minDelay, maxDelay)); err != nil {
+				buf.ReleaseMulti(chunks[i:])
+				return err
+			}
+		}
+		if err := writer.WriteMultiBuffer(buf.MultiBuffer{chunk}); err != nil {
+			buf.ReleaseMulti(chunks[i+1:])
+			return err
+		}
+	}
+	return nil
+}
+
 // generate padding and update paddingBuffer
 func generatePadding(paddingBuffer []byte) {
 	for i := range paddingBuffer {
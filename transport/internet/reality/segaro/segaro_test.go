@@ -0,0 +1,106 @@
+package segaro
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/buf"
+	"github.com/luckyluke-a/xray-core/proxy"
+	"github.com/luckyluke-a/xray-core/proxy/vless"
+)
+
+// writeFirstPacket returns the first packet of a client using the profile.
+func writeFirstPacket(profile *vless.SegaroProfile) []byte {
+	var out bytes.Buffer
+	bufferWriter := buf.NewBufferedWriter(buf.NewWriter(&out))
+	common.Must2(bufferWriter.Write([]byte("request header")))
+	writer := NewSegaroWriter(bufferWriter, proxy.NewTrafficState(nil), &SegaroConfig{Profile: profile, Nonce: NewFlowNonce()}, nil, context.Background())
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("payload"))}))
+	common.Must(bufferWriter.SetBuffered(false))
+	return out.Bytes()
+}
+
+func TestClientProfile(t *testing.T) {
+	// the request header can be decoded with either of the profiles
+	config := &vless.SegaroConfig{
+		PaddingSize:  8,
+		SubchunkSize: 16,
+		SplitPacket:  "100-200",
+		Profiles: []*vless.SegaroProfile{
+			{Name: "delayed", PaddingSize: 8, SubchunkSize: 16, SplitPacket: "100-200", ChunkDelay: "10-20"},
+			{Name: "short", PaddingSize: 8, SubchunkSize: 16, SplitPacket: "10-20"},
+		},
+	}
+	for _, profile := range config.AllProfiles() {
+		packet := writeFirstPacket(profile)
+		fakePaddingLength := binary.BigEndian.Uint16(packet[2:])
+		if actual := ClientProfile(config, packet[4:4+fakePaddingLength]); actual == nil || actual.Name != profile.Name {
+			t.Error("expected profile ", profile.Name, ", but got ", actual)
+		}
+	}
+	if actual := ClientProfile(config, []byte{0}); actual != nil {
+		t.Error("expected no profile without an id, but got ", actual.Name)
+	}
+}
+
+func TestClientNonceReplay(t *testing.T) {
+	profile := &vless.SegaroProfile{Name: "replay", PaddingSize: 8, SubchunkSize: 16, SplitPacket: "100-200"}
+	packet := writeFirstPacket(profile)
+	fakePaddingLength := binary.BigEndian.Uint16(packet[2:])
+	fakePadding := packet[4 : 4+fakePaddingLength]
+
+	nonce, err := ClientNonce(fakePadding)
+	if err != nil {
+		t.Fatal("unexpected error of the first packet: ", err)
+	}
+	if _, err := ClientNonce(fakePadding); err == nil {
+		t.Error("expected the replayed first packet to be rejected")
+	}
+
+	// a first packet out of the time window is rejected
+	stale := append([]byte(nil), fakePadding...)
+	binary.BigEndian.PutUint64(stale[2:], uint64(time.Now().Add(-time.Hour).Unix()))
+	if _, err := ClientNonce(stale); err == nil {
+		t.Error("expected the stale first packet to be rejected")
+	}
+
+	// random packets of a connection are invalid on another one
+	authKey := FlowAuthKey([]byte("id"))
+	config := &SegaroConfig{Profile: profile, AuthKey: authKey, Nonce: nonce}
+	key, timeBase, err := config.getAuthKey(nil, true)
+	common.Must(err)
+	var out bytes.Buffer
+	writer := buf.Writer(buf.NewWriter(&out))
+	common.Must(sendMultipleFakePacket(key, nil, &writer, timeBase, 20, 20, 1, 1, false))
+	randomPacket := buf.MultiBuffer{buf.FromBytes(out.Bytes()[4:])}
+	if err := isFakePacketsValid(&randomPacket, key, timeBase, 20); err != nil {
+		t.Fatal("unexpected error of the random packets of the connection: ", err)
+	}
+	otherKey, otherTimeBase, err := (&SegaroConfig{Profile: profile, AuthKey: authKey, Nonce: NewFlowNonce()}).getAuthKey(nil, true)
+	common.Must(err)
+	if err := isFakePacketsValid(&randomPacket, otherKey, otherTimeBase, 20); err == nil {
+		t.Error("expected the random packets to be rejected on another connection")
+	}
+}
+
+func TestWriteChunksCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	var out bytes.Buffer
+	chunks := buf.MultiBuffer{buf.FromBytes([]byte("a")), buf.FromBytes([]byte("b"))}
+	start := time.Now()
+	if err := writeChunks(ctx, buf.NewWriter(&out), chunks, time.Minute, time.Minute); err != context.Canceled {
+		t.Error("expected the delay to be canceled, but got ", err)
+	}
+	if time.Since(start) > 10*time.Second || out.String() != "a" {
+		t.Error("unexpected chunks written before canceled: ", out.String())
+	}
+}
@@ -2,18 +2,26 @@ package segaro
 
 import (
 	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"math/rand"
 	"net"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
+	"github.com/luckyluke-a/xray-core/common"
 	"github.com/luckyluke-a/xray-core/common/buf"
 	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/features/stats"
 	"github.com/luckyluke-a/xray-core/proxy"
+	"github.com/luckyluke-a/xray-core/proxy/vless"
 	"github.com/luckyluke-a/xray-core/transport/internet/grpc/encoding"
 	"github.com/luckyluke-a/xray-core/transport/internet/reality"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
@@ -24,9 +32,81 @@ import (
 type SegaroConfig struct {
 	RealityConfig   *reality.Config
 	GoRealityConfig *goReality.Config
+	// Profile carries the parameters negotiated from the VLESS settings, for securities other than REALITY.
+	// It takes precedence over the REALITY configs.
+	Profile *vless.SegaroProfile
+	// AuthKey replaces the REALITY auth key when Profile is used.
+	AuthKey []byte
+	// Nonce is sent by the client when Profile is used, and replaces the REALITY client time, so that
+	// random packets can't be replayed on another connection.
+	Nonce []byte
+}
+
+const (
+	// flowNonceSize is the size of the nonce after the profile id in the fake padding of the first
+	// packet of a client: the unix time in 8 bytes, then 8 random bytes.
+	flowNonceSize = 16
+	// flowNonceWindow is the max difference of the time in a nonce from the time of the server.
+	flowNonceWindow = 2 * time.Minute
+)
+
+var (
+	nonceAccess sync.Mutex
+	// nonces are the nonces received in the window, by their expire time.
+	nonces       = make(map[[flowNonceSize]byte]time.Time)
+	noncesPruned time.Time
+)
+
+// FlowAuthKey derives the key of random packets from the VLESS user ID, for securities other than REALITY.
+func FlowAuthKey(id []byte) []byte {
+	h := hmac.New(sha256.New, id)
+	h.Write([]byte("xtls-segaro-vision"))
+	return h.Sum(nil)
+}
+
+// NewFlowNonce returns a nonce for a connection of a client.
+func NewFlowNonce() []byte {
+	nonce := make([]byte, flowNonceSize)
+	binary.BigEndian.PutUint64(nonce, uint64(time.Now().Unix()))
+	common.Must2(crand.Read(nonce[8:]))
+	return nonce
+}
+
+// ClientNonce returns the nonce of the client after the profile id in the fake padding of its first packet.
+// A nonce out of the time window, or received before, is rejected as a replay.
+func ClientNonce(fakePadding []byte) ([]byte, error) {
+	if len(fakePadding) < 2+flowNonceSize {
+		return nil, errors.New("missing segaro nonce")
+	}
+	var nonce [flowNonceSize]byte
+	copy(nonce[:], fakePadding[2:])
+	now := time.Now()
+	nonceTime := time.Unix(int64(binary.BigEndian.Uint64(nonce[:8])), 0)
+	if nonceTime.Before(now.Add(-flowNonceWindow)) || nonceTime.After(now.Add(flowNonceWindow)) {
+		return nil, errors.New("segaro nonce out of the time window: ", nonceTime)
+	}
+
+	nonceAccess.Lock()
+	defer nonceAccess.Unlock()
+	if now.Sub(noncesPruned) > flowNonceWindow {
+		for n, expire := range nonces {
+			if now.After(expire) {
+				delete(nonces, n)
+			}
+		}
+		noncesPruned = now
+	}
+	if _, found := nonces[nonce]; found {
+		return nil, errors.New("replayed segaro nonce")
+	}
+	nonces[nonce] = nonceTime.Add(flowNonceWindow)
+	return nonce[:], nil
 }
 
 func (sc *SegaroConfig) GetPaddingSize() uint32 {
+	if sc.Profile != nil {
+		return sc.Profile.PaddingSize
+	}
 	if sc.RealityConfig != nil {
 		return sc.RealityConfig.PaddingSize
 	}
@@ -34,105 +114,124 @@ func (sc *SegaroConfig) GetPaddingSize() uint32 {
 }
 
 func (sc *SegaroConfig) GetSubChunkSize() uint32 {
+	if sc.Profile != nil {
+		return sc.Profile.SubchunkSize
+	}
 	if sc.RealityConfig != nil {
 		return sc.RealityConfig.SubchunkSize
 	}
 	return sc.GoRealityConfig.SubChunkSize
 }
 
-func (sc *SegaroConfig) GetServerRandPacketSize() (int, int) {
-	var randomPacket string
-	if sc.RealityConfig != nil {
-		randomPacket = sc.RealityConfig.ServerRandPacket
-	} else {
-		randomPacket = sc.GoRealityConfig.ServerRandPacket
-	}
-	randPacket := strings.Split(randomPacket, "-")
-	if len(randPacket) == 0 {
+// parseRange parses "min-max" or "value" into a range.
+func parseRange(value string) (int, int) {
+	parts := strings.Split(value, "-")
+	if len(parts) == 0 {
 		return 0, 0
 	}
-	min, _ := strconv.Atoi(randPacket[0])
-	if len(randPacket) == 1 {
+	min, _ := strconv.Atoi(parts[0])
+	if len(parts) == 1 {
 		return min, min
 	}
-	max, _ := strconv.Atoi(randPacket[1])
+	max, _ := strconv.Atoi(parts[1])
 	return min, max
 }
 
-func (sc *SegaroConfig) GetClientRandPacketSize() (int, int) {
-	var randomPacket string
-	if sc.RealityConfig != nil {
-		randomPacket = sc.RealityConfig.ClientRandPacket
-	} else {
-		randomPacket = sc.GoRealityConfig.ClientRandPacket
-	}
-	randPacket := strings.Split(randomPacket, "-")
-	if len(randPacket) == 0 {
-		return 0, 0
+func (sc *SegaroConfig) GetServerRandPacketSize() (int, int) {
+	switch {
+	case sc.Profile != nil:
+		return parseRange(sc.Profile.ServerRandPacket)
+	case sc.RealityConfig != nil:
+		return parseRange(sc.RealityConfig.ServerRandPacket)
 	}
-	min, _ := strconv.Atoi(randPacket[0])
-	if len(randPacket) == 1 {
-		return min, min
+	return parseRange(sc.GoRealityConfig.ServerRandPacket)
+}
+
+func (sc *SegaroConfig) GetClientRandPacketSize() (int, int) {
+	switch {
+	case sc.Profile != nil:
+		return parseRange(sc.Profile.ClientRandPacket)
+	case sc.RealityConfig != nil:
+		return parseRange(sc.RealityConfig.ClientRandPacket)
 	}
-	max, _ := strconv.Atoi(randPacket[1])
-	return min, max
+	return parseRange(sc.GoRealityConfig.ClientRandPacket)
 }
 
 func (sc *SegaroConfig) GetServerRandPacketCount() (int, int) {
-	var randomPacketCount string
-	if sc.RealityConfig != nil {
-		randomPacketCount = sc.RealityConfig.ServerRandPacketCount
-	} else {
-		randomPacketCount = sc.GoRealityConfig.ServerRandPacketCount
-	}
-	randPacket := strings.Split(randomPacketCount, "-")
-	if len(randPacket) == 0 {
-		return 0, 0
-	}
-	min, _ := strconv.Atoi(randPacket[0])
-	if len(randPacket) == 1 {
-		return min, min
+	switch {
+	case sc.Profile != nil:
+		return parseRange(sc.Profile.ServerRandPacketCount)
+	case sc.RealityConfig != nil:
+		return parseRange(sc.RealityConfig.ServerRandPacketCount)
 	}
-	max, _ := strconv.Atoi(randPacket[1])
-	return min, max
+	return parseRange(sc.GoRealityConfig.ServerRandPacketCount)
 }
 
 func (sc *SegaroConfig) GetClientRandPacketCount() (int, int) {
-	var randomPacketCount string
-	if sc.RealityConfig != nil {
-		randomPacketCount = sc.RealityConfig.ClientRandPacketCount
-	} else {
-		randomPacketCount = sc.GoRealityConfig.ClientRandPacketCount
+	switch {
+	case sc.Profile != nil:
+		return parseRange(sc.Profile.ClientRandPacketCount)
+	case sc.RealityConfig != nil:
+		return parseRange(sc.RealityConfig.ClientRandPacketCount)
 	}
-	randPacket := strings.Split(randomPacketCount, "-")
-	if len(randPacket) == 0 {
-		return 0, 0
-	}
-	min, _ := strconv.Atoi(randPacket[0])
-	if len(randPacket) == 1 {
-		return min, min
-	}
-	max, _ := strconv.Atoi(randPacket[1])
-	return min, max
+	return parseRange(sc.GoRealityConfig.ClientRandPacketCount)
 }
 
 func (sc *SegaroConfig) GetSplitSize() (int, int) {
-	var splitPacket string
-	if sc.RealityConfig != nil {
-		splitPacket = sc.RealityConfig.SplitPacket
-	} else {
-		splitPacket = sc.GoRealityConfig.SplitPacket
+	switch {
+	case sc.Profile != nil:
+		return parseRange(sc.Profile.SplitPacket)
+	case sc.RealityConfig != nil:
+		return parseRange(sc.RealityConfig.SplitPacket)
 	}
-	splitedPacket := strings.Split(splitPacket, "-")
-	if len(splitedPacket) == 0 {
+	return parseRange(sc.GoRealityConfig.SplitPacket)
+}
+
+// GetChunkDelay returns the delay range between chunks, REALITY configs have no delay.
+func (sc *SegaroConfig) GetChunkDelay() (time.Duration, time.Duration) {
+	if sc.Profile == nil || sc.Profile.ChunkDelay == "" {
 		return 0, 0
 	}
-	min, _ := strconv.Atoi(splitedPacket[0])
-	if len(splitedPacket) == 1 {
-		return min, min
+	min, max := parseRange(sc.Profile.ChunkDelay)
+	return time.Duration(min) * time.Millisecond, time.Duration(max) * time.Millisecond
+}
+
+// CountProfile increases the connection counter of the negotiated profile, e.g. "segaro>>>default>>>inbound>>>connections".
+func (sc *SegaroConfig) CountProfile(m stats.Manager, direction string) {
+	if sc.Profile == nil || m == nil {
+		return
 	}
-	max, _ := strconv.Atoi(splitedPacket[1])
-	return min, max
+	name := "segaro>>>" + sc.Profile.Name + ">>>" + direction + ">>>connections"
+	if c, _ := stats.GetOrRegisterCounter(m, name); c != nil {
+		c.Add(1)
+	}
+}
+
+// ClientProfile returns the profile picked by the client, by the id at the start of the fake padding of its first packet.
+func ClientProfile(config *vless.SegaroConfig, fakePadding []byte) *vless.SegaroProfile {
+	var id [2]byte
+	if len(fakePadding) < len(id) {
+		return nil
+	}
+	copy(id[:], fakePadding)
+	return config.ProfileByID(id)
+}
+
+// getAuthKey returns the key and time base of the random packets.
+func (sc *SegaroConfig) getAuthKey(conn *net.Conn, fromInbound bool) ([]byte, *time.Time, error) {
+	if sc.Profile != nil {
+		if sc.AuthKey == nil {
+			return nil, nil, errors.New("missing segaro auth key")
+		}
+		if len(sc.Nonce) != flowNonceSize {
+			return nil, nil, errors.New("missing segaro nonce")
+		}
+		h := hmac.New(sha256.New, sc.AuthKey)
+		h.Write(sc.Nonce)
+		timeBase := time.Unix(int64(binary.BigEndian.Uint64(sc.Nonce)), 0)
+		return h.Sum(nil), &timeBase, nil
+	}
+	return getRealityAuthkey(conn, fromInbound)
 }
 
 // GetPrivateField, returns the private fieldName from v object
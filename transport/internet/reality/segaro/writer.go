@@ -2,6 +2,7 @@ package segaro
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"math/rand"
 	"net"
@@ -10,6 +11,7 @@ import (
 	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/common/signal"
 	"github.com/luckyluke-a/xray-core/proxy"
+	"github.com/luckyluke-a/xray-core/proxy/vless"
 )
 
 // SegaroWriter is used to write xtls-segaro-vision
@@ -19,14 +21,16 @@ type SegaroWriter struct {
 	segaroConfig *SegaroConfig
 	conn         net.Conn
 	initCall     bool
+	ctx          context.Context
 }
 
-func NewSegaroWriter(writer buf.Writer, state *proxy.TrafficState, segaroConfig *SegaroConfig, conn net.Conn) *SegaroWriter {
+func NewSegaroWriter(writer buf.Writer, state *proxy.TrafficState, segaroConfig *SegaroConfig, conn net.Conn, context context.Context) *SegaroWriter {
 	return &SegaroWriter{
 		Writer:       writer,
 		trafficState: state,
 		segaroConfig: segaroConfig,
 		conn:         conn,
+		ctx:          context,
 	}
 }
 
@@ -62,7 +66,7 @@ func (w *SegaroWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 			// Server side
 			minServerRandSize, maxServerRandSize := w.segaroConfig.GetServerRandPacketSize()
 			minServerRandCount, maxServerRandCount := w.segaroConfig.GetServerRandPacketCount()
-			authKey, clientTime, err := getRealityAuthkey(&w.conn, true)
+			authKey, clientTime, err := w.segaroConfig.getAuthKey(&w.conn, true)
 			if err != nil {
 				return err
 			}
@@ -91,7 +95,8 @@ func (w *SegaroWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 					return err
 				}
 
-				if err := w.Writer.WriteMultiBuffer(cacheBuffer); err != nil {
+				minDelay, maxDelay := w.segaroConfig.GetChunkDelay()
+				if err := writeChunks(w.ctx, w.Writer, cacheBuffer, minDelay, maxDelay); err != nil {
 					return err
 				}
 
@@ -127,29 +132,38 @@ func (w *SegaroWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 						}
 					}
 
-					if i == 0{
+					if i == 0 {
+						var paddingBytes []byte
 						if int(cacheBuffer[0].Len()) < minSplitSize {
 							// Use the long padding, to hide the first packet real length
 							paddingLength := rand.Intn(maxSplitSize-minSplitSize+1) + minSplitSize
-							var paddingBytes []byte
 							if paddingLength+int(cacheBuffer[0].Len()) > maxSplitSize {
 								paddingBytes = make([]byte, paddingLength-int(cacheBuffer[0].Len()))
 							} else {
 								paddingBytes = make([]byte, paddingLength)
 							}
 							generatePadding(paddingBytes)
-							if _, err := cacheBuffer[0].WriteAtBeginning(paddingBytes); err != nil {
-								return err
-							}
-							if _, err := cacheBuffer[0].WriteAtBeginning([]byte{byte(len(paddingBytes) >> 8), byte(len(paddingBytes))}); err != nil {
-								return err
+						}
+						if profile := w.segaroConfig.Profile; profile != nil {
+							// The fake padding starts with the id of the profile, for the server to use the same one,
+							// then the nonce of the connection
+							id := vless.SegaroProfileID(profile.Name)
+							if len(w.segaroConfig.Nonce) != flowNonceSize {
+								return errors.New("missing segaro nonce")
 							}
-							paddingBytes = nil
-						} else {
-							if _, err := cacheBuffer[0].WriteAtBeginning([]byte{0, 0}); err != nil {
-								return err
+							if len(paddingBytes) < len(id)+flowNonceSize {
+								paddingBytes = make([]byte, len(id)+flowNonceSize)
 							}
+							copy(paddingBytes, id[:])
+							copy(paddingBytes[len(id):], w.segaroConfig.Nonce)
+						}
+						if _, err := cacheBuffer[0].WriteAtBeginning(paddingBytes); err != nil {
+							return err
+						}
+						if _, err := cacheBuffer[0].WriteAtBeginning([]byte{byte(len(paddingBytes) >> 8), byte(len(paddingBytes))}); err != nil {
+							return err
 						}
+						paddingBytes = nil
 					}
 					if _, err := cacheBuffer[0].WriteAtBeginning([]byte{byte(cacheBuffer.Len() >> 8), byte(cacheBuffer.Len())}); err != nil {
 						return err
@@ -179,7 +193,7 @@ func (w *SegaroWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 }
 
 // SegaroWrite filter and write xtls-segaro-vision
-func SegaroWrite(reader buf.Reader, writer buf.Writer, timer signal.ActivityUpdater, conn net.Conn, fromInbound bool, segaroConfig *SegaroConfig, xsvCanContinue chan bool) error {
+func SegaroWrite(reader buf.Reader, writer buf.Writer, timer signal.ActivityUpdater, conn net.Conn, fromInbound bool, segaroConfig *SegaroConfig, xsvCanContinue chan bool, ctx context.Context) error {
 	if xsvCanContinue != nil {
 		if canContinue := <-xsvCanContinue; !canContinue {
 			return errors.New("close conn received from xsv.SegaroRead")
@@ -187,6 +201,7 @@ func SegaroWrite(reader buf.Reader, writer buf.Writer, timer signal.ActivityUpda
 	}
 	minSplitSize, maxSplitSize := segaroConfig.GetSplitSize()
 	paddingSize, subChunkSize := int(segaroConfig.GetPaddingSize()), int(segaroConfig.GetSubChunkSize())
+	minDelay, maxDelay := segaroConfig.GetChunkDelay()
 
 	var minRandSize, maxRandSize, minRandCount, maxRandCount int
 	if fromInbound {
@@ -206,7 +221,7 @@ func SegaroWrite(reader buf.Reader, writer buf.Writer, timer signal.ActivityUpda
 					if b.Len() > 2 && isHandshakeMessage(b.BytesTo(3)) {
 						newBuff := segaroAddPadding(b, minSplitSize, maxSplitSize, paddingSize, subChunkSize)
 
-						authKey, clientTime, err := getRealityAuthkey(&conn, fromInbound)
+						authKey, clientTime, err := segaroConfig.getAuthKey(&conn, fromInbound)
 						if err != nil {
 							return err
 						}
@@ -220,7 +235,7 @@ func SegaroWrite(reader buf.Reader, writer buf.Writer, timer signal.ActivityUpda
 						if _, err := newBuff[0].WriteAtBeginning([]byte{byte(newBuff.Len() >> 8), byte(newBuff.Len())}); err != nil {
 							return err
 						}
-						if err = writer.WriteMultiBuffer(newBuff); err != nil {
+						if err = writeChunks(ctx, writer, newBuff, minDelay, maxDelay); err != nil {
 							return err
 						}
 
@@ -0,0 +1,119 @@
+package shadowtls
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+
+	utls "github.com/Rolka111111/utls"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/transport/internet/tls"
+)
+
+// handshakeConn unmasks the handshake records modified by the server, and
+// records whether there was any, which proves that the server knows the password.
+type handshakeConn struct {
+	net.Conn
+	password      string
+	serverRandom  []byte
+	auth          *authenticator
+	key           []byte
+	authenticated bool
+	buffer        []byte
+}
+
+// Read returns no more than one record each time, so that nothing after the
+// handshake is consumed by the TLS client.
+func (c *handshakeConn) Read(b []byte) (int, error) {
+	if len(c.buffer) == 0 {
+		record, err := readRecord(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+		c.buffer = c.process(record)
+	}
+	n := copy(b, c.buffer)
+	c.buffer = c.buffer[n:]
+	return n, nil
+}
+
+func (c *handshakeConn) process(record []byte) []byte {
+	switch record[0] {
+	case typeHandshake:
+		if c.serverRandom == nil && len(record) >= recordHeaderLen+4+2+32 && record[recordHeaderLen] == handshakeTypeServerHello {
+			c.serverRandom = append([]byte(nil), record[recordHeaderLen+4+2:recordHeaderLen+4+2+32]...)
+			c.auth = newAuthenticator(c.password, c.serverRandom)
+			c.key = xorKey(c.password, c.serverRandom)
+		}
+	case typeApplicationData:
+		if c.auth == nil || len(record) < recordHeaderLen+tagLen {
+			break
+		}
+		tag, payload := record[recordHeaderLen:recordHeaderLen+tagLen], record[recordHeaderLen+tagLen:]
+		if c.auth.Verify(tag, payload) {
+			c.authenticated = true
+			xor(payload, c.key)
+			unmasked := record[tagLen:]
+			copy(unmasked, record[:3])
+			binary.BigEndian.PutUint16(unmasked[3:], uint16(len(payload)))
+			return unmasked
+		}
+	}
+	return record
+}
+
+// UClient performs a real TLS 1.3 handshake with the site behind the server,
+// and returns the connection to carry data with.
+func UClient(c net.Conn, config *Config, ctx context.Context, dest net.Destination) (net.Conn, error) {
+	hc := &handshakeConn{
+		Conn:     c,
+		password: config.Password,
+	}
+	utlsConfig := &utls.Config{
+		ServerName: config.ServerName,
+		// the server is authenticated by the password instead
+		InsecureSkipVerify:     true,
+		SessionTicketsDisabled: true,
+		MinVersion:             utls.VersionTLS13,
+	}
+	if utlsConfig.ServerName == "" {
+		utlsConfig.ServerName = dest.Address.String()
+	}
+	fingerprintName := config.Fingerprint
+	if fingerprintName == "" {
+		fingerprintName = "chrome"
+	}
+	fingerprint := tls.GetFingerprint(fingerprintName)
+	if fingerprint == nil {
+		return nil, errors.New("ShadowTLS: failed to get fingerprint ", fingerprintName).AtError()
+	}
+	uConn := utls.UClient(hc, utlsConfig, *fingerprint)
+	if err := uConn.BuildHandshakeState(); err != nil {
+		return nil, errors.New("ShadowTLS: failed to build ClientHello").Base(err)
+	}
+	hello := uConn.HandshakeState.Hello
+	sessionID := make([]byte, sessionIDLen)
+	rand.Read(sessionID[:sessionIDLen-tagLen])
+	// hello.Raw doesn't have the record header
+	raw := hello.Raw
+	copy(raw[sessionIDOffset-recordHeaderLen:], sessionID)
+	record := append(make([]byte, recordHeaderLen), raw...)
+	copy(sessionID[sessionIDLen-tagLen:], clientHelloTag(config.Password, record))
+	copy(raw[sessionIDOffset-recordHeaderLen:], sessionID)
+	hello.SessionId = sessionID
+
+	if err := uConn.HandshakeContext(ctx); err != nil {
+		return nil, errors.New("ShadowTLS: handshake failed").Base(err)
+	}
+	if !hc.authenticated {
+		return nil, errors.New("ShadowTLS: failed to authenticate the server")
+	}
+	errors.LogDebug(ctx, "ShadowTLS: handshake with ", utlsConfig.ServerName, " finished")
+	return &Conn{
+		Conn:        c,
+		readAuth:    newAuthenticator(config.Password, hc.serverRandom, []byte("S")),
+		writeAuth:   newAuthenticator(config.Password, hc.serverRandom, []byte("C")),
+		discardAuth: hc.auth,
+	}, nil
+}
@@ -0,0 +1,16 @@
+package shadowtls
+
+import (
+	"github.com/luckyluke-a/xray-core/transport/internet"
+)
+
+func ConfigFromStreamSettings(settings *internet.MemoryStreamConfig) *Config {
+	if settings == nil {
+		return nil
+	}
+	config, ok := settings.SecuritySettings.(*Config)
+	if !ok {
+		return nil
+	}
+	return config
+}
@@ -0,0 +1,244 @@
+package shadowtls
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const handshakeTimeout = 16 * time.Second
+
+// Server relays the handshake between the client and the handshake server,
+// until the client sends a record tagged by the password. Connections failing
+// the authentication are relayed to the handshake server as they are. conn is
+// closed if an error is returned.
+//
+// The returned Conn doesn't encrypt the data, see Conn.
+func Server(conn net.Conn, config *Config) (net.Conn, error) {
+	c, err := handshakeServer(conn, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func handshakeServer(conn net.Conn, config *Config) (net.Conn, error) {
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	clientHello, err := readRecord(conn)
+	if err != nil {
+		return nil, errors.New("ShadowTLS: failed to read ClientHello").Base(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	var dialer net.Dialer
+	dest, err := dialer.DialContext(ctx, "tcp", config.Dest)
+	cancel()
+	if err != nil {
+		return nil, errors.New("ShadowTLS: failed to dial handshake server ", config.Dest).Base(err)
+	}
+
+	if !verifyClientHello(config.Password, clientHello) {
+		relay(conn, dest, clientHello)
+		return nil, errors.New("ShadowTLS: processed invalid connection")
+	}
+	if _, err := dest.Write(clientHello); err != nil {
+		dest.Close()
+		return nil, errors.New("ShadowTLS: failed to write ClientHello").Base(err)
+	}
+
+	dest.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	serverHello, err := readRecord(dest)
+	if err != nil {
+		dest.Close()
+		return nil, errors.New("ShadowTLS: failed to read ServerHello").Base(err)
+	}
+	dest.SetReadDeadline(time.Time{})
+	serverRandom, ok := parseServerHello(serverHello)
+	if !ok {
+		relay(conn, dest, nil, serverHello)
+		return nil, errors.New("ShadowTLS: handshake server ", config.Dest, " doesn't support TLS 1.3")
+	}
+	if _, err := conn.Write(serverHello); err != nil {
+		dest.Close()
+		return nil, err
+	}
+
+	h := &serverHandshake{
+		conn: conn,
+		dest: dest,
+		auth: newAuthenticator(config.Password, serverRandom),
+		key:  xorKey(config.Password, serverRandom),
+	}
+	go h.relayServer()
+
+	clientAuth := newAuthenticator(config.Password, serverRandom, []byte("C"))
+	for {
+		record, err := readRecord(conn)
+		if err != nil {
+			dest.Close()
+			return nil, errors.New("ShadowTLS: failed to read from client").Base(err)
+		}
+		if record[0] == typeApplicationData && len(record) >= recordHeaderLen+tagLen &&
+			clientAuth.Verify(record[recordHeaderLen:recordHeaderLen+tagLen], record[recordHeaderLen+tagLen:]) {
+			h.switchToClient()
+			conn.SetReadDeadline(time.Time{})
+			return &Conn{
+				Conn:       conn,
+				readAuth:   clientAuth,
+				writeAuth:  newAuthenticator(config.Password, serverRandom, []byte("S")),
+				readBuffer: record[recordHeaderLen+tagLen:],
+			}, nil
+		}
+		if _, err := dest.Write(record); err != nil {
+			dest.Close()
+			return nil, errors.New("ShadowTLS: failed to write to handshake server").Base(err)
+		}
+	}
+}
+
+// serverHandshake relays the records from the handshake server to the client,
+// with the application data records masked and tagged.
+type serverHandshake struct {
+	sync.Mutex
+	conn     net.Conn
+	dest     net.Conn
+	auth     *authenticator
+	key      []byte
+	switched bool
+}
+
+func (h *serverHandshake) relayServer() {
+	for {
+		record, err := readRecord(h.dest)
+		if err != nil {
+			break
+		}
+		if record[0] == typeApplicationData {
+			payload := record[recordHeaderLen:]
+			xor(payload, h.key)
+			record = newRecord(h.auth.Seal(payload), payload)
+		}
+		h.Lock()
+		if h.switched {
+			h.Unlock()
+			return
+		}
+		_, err = h.conn.Write(record)
+		h.Unlock()
+		if err != nil {
+			break
+		}
+	}
+	h.Lock()
+	if !h.switched {
+		h.conn.Close()
+	}
+	h.Unlock()
+}
+
+// switchToClient stops the relay, so that the connection belongs to the client.
+func (h *serverHandshake) switchToClient() {
+	h.Lock()
+	h.switched = true
+	h.Unlock()
+	h.dest.Close()
+}
+
+// relay connects the client to the handshake server directly, after writing
+// what was read from each of them.
+func relay(conn net.Conn, dest net.Conn, fromClient []byte, fromServer ...[]byte) {
+	defer conn.Close()
+	defer dest.Close()
+	conn.SetReadDeadline(time.Time{})
+
+	if _, err := dest.Write(fromClient); err != nil {
+		return
+	}
+	for _, b := range fromServer {
+		if _, err := conn.Write(b); err != nil {
+			return
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		io.Copy(conn, dest)
+		conn.Close()
+		close(done)
+	}()
+	io.Copy(dest, conn)
+	dest.Close()
+	<-done
+}
+
+func verifyClientHello(password string, record []byte) bool {
+	if len(record) < sessionIDOffset+sessionIDLen || record[0] != typeHandshake ||
+		record[recordHeaderLen] != handshakeTypeClientHello || record[sessionIDOffset-1] != sessionIDLen {
+		return false
+	}
+	tagOffset := sessionIDOffset + sessionIDLen - tagLen
+	tag := append([]byte(nil), record[tagOffset:tagOffset+tagLen]...)
+	zeroed := append([]byte(nil), record...)
+	copy(zeroed[tagOffset:tagOffset+tagLen], make([]byte, tagLen))
+	return bytes.Equal(clientHelloTag(password, zeroed), tag)
+}
+
+// parseServerHello returns the server random of a TLS 1.3 ServerHello.
+func parseServerHello(record []byte) ([]byte, bool) {
+	if record[0] != typeHandshake {
+		return nil, false
+	}
+	s := cryptobyte.String(record[recordHeaderLen:])
+	var (
+		messageType  uint8
+		message      cryptobyte.String
+		version      uint16
+		serverRandom []byte
+		sessionID    cryptobyte.String
+		extensions   cryptobyte.String
+	)
+	if !s.ReadUint8(&messageType) || messageType != handshakeTypeServerHello ||
+		!s.ReadUint24LengthPrefixed(&message) ||
+		!message.ReadUint16(&version) ||
+		!message.ReadBytes(&serverRandom, 32) ||
+		!message.ReadUint8LengthPrefixed(&sessionID) ||
+		!message.Skip(3) || // cipher suite and compression method
+		!message.ReadUint16LengthPrefixed(&extensions) {
+		return nil, false
+	}
+	if bytes.Equal(serverRandom, helloRetryRequestRandom) {
+		return nil, false
+	}
+	for !extensions.Empty() {
+		var (
+			extension uint16
+			data      cryptobyte.String
+		)
+		if !extensions.ReadUint16(&extension) || !extensions.ReadUint16LengthPrefixed(&data) {
+			return nil, false
+		}
+		if extension == extensionSupportedVersions {
+			var selected uint16
+			return serverRandom, data.ReadUint16(&selected) && selected == versionTLS13
+		}
+	}
+	return nil, false
+}
+
+const (
+	extensionSupportedVersions = 43
+	versionTLS13               = 0x0304
+)
+
+var helloRetryRequestRandom = []byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
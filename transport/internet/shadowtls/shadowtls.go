@@ -0,0 +1,202 @@
+package shadowtls
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"hash"
+	"io"
+	"net"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+)
+
+const (
+	recordHeaderLen = 5
+	tagLen          = 4
+	maxRecordLen    = 16384 + 2048
+
+	// maxPayloadLen keeps the records within the size of real TLS records.
+	maxPayloadLen = 16384 - tagLen
+
+	typeHandshake       = 22
+	typeApplicationData = 23
+
+	handshakeTypeClientHello = 1
+	handshakeTypeServerHello = 2
+
+	// sessionIDOffset is the fixed location of `Session ID` in a ClientHello record.
+	sessionIDOffset = recordHeaderLen + 4 + 2 + 32 + 1
+	sessionIDLen    = 32
+)
+
+// readRecord reads a whole TLS record, including the header.
+func readRecord(reader io.Reader) ([]byte, error) {
+	header := make([]byte, recordHeaderLen)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[3:]))
+	if length > maxRecordLen {
+		return nil, errors.New("ShadowTLS: record too long: ", length)
+	}
+	record := make([]byte, recordHeaderLen+length)
+	copy(record, header)
+	if _, err := io.ReadFull(reader, record[recordHeaderLen:]); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// newRecord returns an application data record of tag and payload.
+func newRecord(tag []byte, payload []byte) []byte {
+	record := make([]byte, recordHeaderLen+tagLen+len(payload))
+	record[0] = typeApplicationData
+	record[1] = 3
+	record[2] = 3
+	binary.BigEndian.PutUint16(record[3:], uint16(tagLen+len(payload)))
+	copy(record[recordHeaderLen:], tag)
+	copy(record[recordHeaderLen+tagLen:], payload)
+	return record
+}
+
+// authenticator computes the tags of records. Each tag is the truncated
+// HMAC-SHA1 of all the payloads so far.
+type authenticator struct {
+	inner hash.Hash
+	outer []byte
+}
+
+func newAuthenticator(password string, parts ...[]byte) *authenticator {
+	key := []byte(password)
+	if len(key) > sha1.BlockSize {
+		sum := sha1.Sum(key)
+		key = sum[:]
+	}
+	ipad := make([]byte, sha1.BlockSize)
+	opad := make([]byte, sha1.BlockSize)
+	copy(ipad, key)
+	copy(opad, key)
+	for i := range ipad {
+		ipad[i] ^= 0x36
+		opad[i] ^= 0x5c
+	}
+	a := &authenticator{
+		inner: sha1.New(),
+		outer: opad,
+	}
+	a.inner.Write(ipad)
+	for _, part := range parts {
+		a.inner.Write(part)
+	}
+	return a
+}
+
+// next returns the tag after payload, and the state to continue with.
+func (a *authenticator) next(payload []byte) ([]byte, hash.Hash) {
+	state, _ := a.inner.(encoding.BinaryMarshaler).MarshalBinary()
+	inner := sha1.New()
+	inner.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+	inner.Write(payload)
+
+	outer := sha1.New()
+	outer.Write(a.outer)
+	outer.Write(inner.Sum(nil))
+	return outer.Sum(nil)[:tagLen], inner
+}
+
+// Seal returns the tag of payload.
+func (a *authenticator) Seal(payload []byte) []byte {
+	tag, inner := a.next(payload)
+	a.inner = inner
+	return tag
+}
+
+// Verify checks the tag of payload. The state moves on only if it matches.
+func (a *authenticator) Verify(tag []byte, payload []byte) bool {
+	expected, inner := a.next(payload)
+	if !hmac.Equal(expected, tag) {
+		return false
+	}
+	a.inner = inner
+	return true
+}
+
+// xorKey returns the key to mask the handshake records of the server with,
+// so that they can't be used by anyone not knowing the password.
+func xorKey(password string, serverRandom []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(password))
+	h.Write(serverRandom)
+	return h.Sum(nil)
+}
+
+func xor(data []byte, key []byte) {
+	for i := range data {
+		data[i] ^= key[i%len(key)]
+	}
+}
+
+// clientHelloTag returns the tag carried by the last bytes of the session ID.
+// record is the ClientHello record, with the tag zeroed.
+func clientHelloTag(password string, record []byte) []byte {
+	h := hmac.New(sha1.New, []byte(password))
+	h.Write(record[recordHeaderLen:])
+	return h.Sum(nil)[:tagLen]
+}
+
+// Conn is the connection after the handshake. Data is carried by application
+// data records, each tagged to tell it from the records of the handshake server.
+// The records are not encrypted, so the data is plaintext on the wire unless
+// the protocol on top encrypts it, like Shadowsocks or VMess.
+type Conn struct {
+	net.Conn
+	readAuth  *authenticator
+	writeAuth *authenticator
+	// discardAuth recognizes the records relayed from the handshake server
+	// before the server switched, which are dropped.
+	discardAuth *authenticator
+	readBuffer  []byte
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	for len(c.readBuffer) == 0 {
+		record, err := readRecord(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+		if record[0] != typeApplicationData || len(record) < recordHeaderLen+tagLen {
+			return 0, errors.New("ShadowTLS: unexpected record type ", record[0])
+		}
+		tag, payload := record[recordHeaderLen:recordHeaderLen+tagLen], record[recordHeaderLen+tagLen:]
+		switch {
+		case c.readAuth.Verify(tag, payload):
+			c.discardAuth = nil
+			c.readBuffer = payload
+		case c.discardAuth != nil && c.discardAuth.Verify(tag, payload):
+		default:
+			return 0, errors.New("ShadowTLS: bad record tag")
+		}
+	}
+	n := copy(b, c.readBuffer)
+	c.readBuffer = c.readBuffer[n:]
+	return n, nil
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		payload := b
+		if len(payload) > maxPayloadLen {
+			payload = payload[:maxPayloadLen]
+		}
+		if _, err := c.Conn.Write(newRecord(c.writeAuth.Seal(payload), payload)); err != nil {
+			return written, err
+		}
+		written += len(payload)
+		b = b[len(payload):]
+	}
+	return written, nil
+}
@@ -0,0 +1,154 @@
+package shadowtls_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"io"
+	gonet "net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/net"
+	. "github.com/luckyluke-a/xray-core/transport/internet/shadowtls"
+)
+
+func newHandshakeServer() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("real site"))
+	}))
+}
+
+// newServer starts a ShadowTLS server echoing the data of the clients.
+func newServer(config *Config) gonet.Listener {
+	listener, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn, err := Server(conn, config)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+func dial(listener gonet.Listener, config *Config) (net.Conn, error) {
+	conn, err := gonet.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return UClient(conn, config, ctx, net.TCPDestination(net.DomainAddress("example.com"), 443))
+}
+
+func TestShadowTLS(t *testing.T) {
+	handshakeServer := newHandshakeServer()
+	defer handshakeServer.Close()
+
+	listener := newServer(&Config{
+		Password: "password",
+		Dest:     handshakeServer.Listener.Addr().String(),
+	})
+	defer listener.Close()
+
+	conn, err := dial(listener, &Config{
+		Password: "password",
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	payload := make([]byte, 100*1024)
+	common.Must2(rand.Read(payload))
+	go conn.Write(payload)
+
+	response := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, response))
+	if !bytes.Equal(response, payload) {
+		t.Error("unexpected response")
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	handshakeServer := newHandshakeServer()
+	defer handshakeServer.Close()
+
+	listener := newServer(&Config{
+		Password: "password",
+		Dest:     handshakeServer.Listener.Addr().String(),
+	})
+	defer listener.Close()
+
+	if _, err := dial(listener, &Config{
+		Password: "wrong",
+	}); err == nil {
+		t.Error("expect the authentication to fail")
+	}
+}
+
+func TestRelayToHandshakeServer(t *testing.T) {
+	handshakeServer := newHandshakeServer()
+	defer handshakeServer.Close()
+
+	listener := newServer(&Config{
+		Password: "password",
+		Dest:     handshakeServer.Listener.Addr().String(),
+	})
+	defer listener.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			DialContext: func(ctx context.Context, network, _ string) (gonet.Conn, error) {
+				var dialer gonet.Dialer
+				return dialer.DialContext(ctx, network, listener.Addr().String())
+			},
+		},
+	}
+	response, err := client.Get("https://example.com/")
+	common.Must(err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	common.Must(err)
+	if string(body) != "real site" {
+		t.Error("unexpected response: ", string(body))
+	}
+}
+
+func TestServerClosesConnOnError(t *testing.T) {
+	unreachable, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	dest := unreachable.Addr().String()
+	unreachable.Close()
+
+	listener := newServer(&Config{
+		Password: "password",
+		Dest:     dest,
+	})
+	defer listener.Close()
+
+	conn, err := gonet.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	defer conn.Close()
+	// a record, which can't be relayed as the handshake server is down
+	common.Must2(conn.Write([]byte{22, 3, 1, 0, 1, 1}))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Error("expect the connection to be closed, got ", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	gonet "net"
 
+	"github.com/luckyluke-a/xray-core/common/errors"
 	"github.com/luckyluke-a/xray-core/transport/internet/browser_dialer"
 	"github.com/luckyluke-a/xray-core/transport/internet/websocket"
 )
@@ -24,6 +25,10 @@ func (c *BrowserDialerClient) OpenDownload(ctx context.Context, baseURL string)
 	return websocket.NewConnection(conn, dummyAddr, nil), conn.RemoteAddr(), conn.LocalAddr(), nil
 }
 
+func (c *BrowserDialerClient) OpenStream(ctx context.Context, baseURL string, body io.Reader) (io.ReadCloser, gonet.Addr, gonet.Addr, error) {
+	return nil, nil, nil, errors.New("browser dialer does not support streaming requests")
+}
+
 func (c *BrowserDialerClient) SendUploadRequest(ctx context.Context, url string, payload io.ReadWriteCloser, contentLength int64) error {
 	bytes, err := ioutil.ReadAll(payload)
 	if err != nil {
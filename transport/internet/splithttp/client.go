@@ -24,6 +24,11 @@ type DialerClient interface {
 	// (ctx, baseURL) -> (downloadReader, remoteAddr, localAddr)
 	// baseURL already contains sessionId
 	OpenDownload(context.Context, string) (io.ReadCloser, net.Addr, net.Addr, error)
+
+	// (ctx, baseURL, body) -> (responseReader, remoteAddr, localAddr)
+	// a single POST request streaming the body, used by stream-up and
+	// stream-one modes
+	OpenStream(context.Context, string, io.Reader) (io.ReadCloser, net.Addr, net.Addr, error)
 }
 
 // implements splithttp.DialerClient in terms of direct network connections
@@ -39,6 +44,17 @@ type DefaultDialerClient struct {
 }
 
 func (c *DefaultDialerClient) OpenDownload(ctx context.Context, baseURL string) (io.ReadCloser, gonet.Addr, gonet.Addr, error) {
+	return c.openRequest(ctx, "GET", baseURL, nil)
+}
+
+func (c *DefaultDialerClient) OpenStream(ctx context.Context, baseURL string, body io.Reader) (io.ReadCloser, gonet.Addr, gonet.Addr, error) {
+	if !c.isH2 && !c.isH3 {
+		return nil, nil, nil, errors.New("streaming requests require HTTP/2 or HTTP/3")
+	}
+	return c.openRequest(ctx, "POST", baseURL, body)
+}
+
+func (c *DefaultDialerClient) openRequest(ctx context.Context, method string, baseURL string, body io.Reader) (io.ReadCloser, gonet.Addr, gonet.Addr, error) {
 	var remoteAddr gonet.Addr
 	var localAddr gonet.Addr
 	// this is done when the TCP/UDP connection to the server was established,
@@ -67,29 +83,33 @@ func (c *DefaultDialerClient) OpenDownload(ctx context.Context, baseURL string)
 
 		req, err := http.NewRequestWithContext(
 			ctx,
-			"GET",
+			method,
 			baseURL,
-			nil,
+			body,
 		)
 		if err != nil {
-			errors.LogInfoInner(ctx, err, "failed to construct download http request")
+			errors.LogInfoInner(ctx, err, "failed to construct ", method, " http request")
 			gotDownResponse.Close()
 			return
 		}
 
 		req.Header = c.transportConfig.GetRequestHeader()
 
-		response, err := c.download.Do(req)
+		client := c.download
+		if body != nil {
+			client = c.upload
+		}
+		response, err := client.Do(req)
 		gotConn.Close()
 		if err != nil {
-			errors.LogInfoInner(ctx, err, "failed to send download http request")
+			errors.LogInfoInner(ctx, err, "failed to send ", method, " http request")
 			gotDownResponse.Close()
 			return
 		}
 
 		if response.StatusCode != 200 {
 			response.Body.Close()
-			errors.LogInfo(ctx, "invalid status code on download:", response.Status)
+			errors.LogInfo(ctx, "invalid status code on ", method, ":", response.Status)
 			gotDownResponse.Close()
 			return
 		}
@@ -186,6 +206,12 @@ func (c *DefaultDialerClient) SendUploadRequest(ctx context.Context, url string,
 	return nil
 }
 
+// CloseIdleConnections closes the connections which have no active requests.
+func (c *DefaultDialerClient) CloseIdleConnections() {
+	c.download.CloseIdleConnections()
+	c.upload.CloseIdleConnections()
+}
+
 type downloadBody struct {
 	io.Reader
 	cancel context.CancelFunc
@@ -7,9 +7,19 @@ import (
 	"strings"
 
 	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/transport/internet"
 )
 
+const (
+	// upload with many POST requests, download with one GET request
+	ModePacketUp = "packet-up"
+	// upload with one streaming POST request, download with one GET request
+	ModeStreamUp = "stream-up"
+	// upload and download within one streaming POST request
+	ModeStreamOne = "stream-one"
+)
+
 func (c *Config) GetNormalizedPath() string {
 	pathAndQuery := strings.SplitN(c.Path, "?", 2)
 	path := pathAndQuery[0]
@@ -105,6 +115,65 @@ func (c *Config) GetNormalizedXPaddingBytes() RandRangeConfig {
 	return *c.XPaddingBytes
 }
 
+// RollMaxConcurrency rolls the max concurrent sessions per connection, 0 for unlimited.
+func (m *Multiplexing) RollMaxConcurrency() int32 {
+	if m == nil || m.MaxConcurrency == nil {
+		return 0
+	}
+	return m.MaxConcurrency.roll()
+}
+
+// RollMaxConnections rolls the number of parallel connections, 0 for unlimited.
+func (m *Multiplexing) RollMaxConnections() int32 {
+	if m == nil || m.MaxConnections == nil {
+		return 0
+	}
+	return m.MaxConnections.roll()
+}
+
+// RollCMaxReuseTimes rolls the max total sessions of a connection, 0 for unlimited.
+func (m *Multiplexing) RollCMaxReuseTimes() int32 {
+	if m == nil || m.CMaxReuseTimes == nil {
+		return 0
+	}
+	return m.CMaxReuseTimes.roll()
+}
+
+// RollCMaxLifetimeMs rolls the max lifetime of a connection in milliseconds, 0 for unlimited.
+func (m *Multiplexing) RollCMaxLifetimeMs() int32 {
+	if m == nil || m.CMaxLifetimeMs == nil {
+		return 0
+	}
+	return m.CMaxLifetimeMs.roll()
+}
+
+// GetNormalizedMode returns the mode used by the client. "auto" stays with
+// packet-up, which works across any CDN and HTTP version.
+func (c *Config) GetNormalizedMode() string {
+	switch c.Mode {
+	case "", "auto":
+		return ModePacketUp
+	default:
+		return c.Mode
+	}
+}
+
+// IsModeAllowed returns whether the server accepts requests of the mode.
+func (c *Config) IsModeAllowed(mode string) bool {
+	return c.Mode == "" || c.Mode == "auto" || c.Mode == mode
+}
+
+// GetDestination returns the destination of download requests.
+func (c *DownloadConfig) GetDestination() net.Destination {
+	return net.TCPDestination(c.Address.AsAddress(), net.Port(c.Port))
+}
+
+// GetDownloadStreamSettings returns the stream settings of download requests,
+// which are parsed into MemoryStreamConfig.DownloadSettings.
+func (c *Config) GetDownloadStreamSettings() *internet.StreamConfig {
+	return c.GetDownloadSettings().GetStreamSettings()
+}
+
 func init() {
 	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
 		return new(Config)
@@ -3,6 +3,7 @@ package splithttp
 import (
 	"context"
 	gotls "crypto/tls"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -41,33 +42,47 @@ type dialerConf struct {
 }
 
 var (
-	globalDialerMap    map[dialerConf]DialerClient
+	globalDialerMap    map[dialerConf]*muxManager
 	globalDialerAccess sync.Mutex
 )
 
-func getHTTPClient(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) DialerClient {
+func getHTTPClient(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (DialerClient, *muxResource) {
 	if browser_dialer.HasBrowserDialer() {
-		return &BrowserDialerClient{}
+		return &BrowserDialerClient{}, nil
 	}
 
 	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
-	isH2 := tlsConfig != nil && !(len(tlsConfig.NextProtocol) == 1 && tlsConfig.NextProtocol[0] == "http/1.1")
 	isH3 := tlsConfig != nil && (len(tlsConfig.NextProtocol) == 1 && tlsConfig.NextProtocol[0] == "h3")
 
 	globalDialerAccess.Lock()
 	defer globalDialerAccess.Unlock()
 
 	if globalDialerMap == nil {
-		globalDialerMap = make(map[dialerConf]DialerClient)
+		globalDialerMap = make(map[dialerConf]*muxManager)
 	}
 
 	if isH3 {
 		dest.Network = net.Network_UDP
 	}
-	if client, found := globalDialerMap[dialerConf{dest, streamSettings}]; found {
-		return client
+
+	manager, found := globalDialerMap[dialerConf{dest, streamSettings}]
+	if !found {
+		transportConfig := streamSettings.ProtocolSettings.(*Config)
+		manager = newMuxManager(transportConfig.Xmux, func() DialerClient {
+			return createHTTPClient(dest, streamSettings)
+		})
+		globalDialerMap[dialerConf{dest, streamSettings}] = manager
 	}
 
+	resource := manager.GetResource()
+	return resource.Resource, resource
+}
+
+func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStreamConfig) DialerClient {
+	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
+	isH2 := tlsConfig != nil && !(len(tlsConfig.NextProtocol) == 1 && tlsConfig.NextProtocol[0] == "http/1.1")
+	isH3 := tlsConfig != nil && (len(tlsConfig.NextProtocol) == 1 && tlsConfig.NextProtocol[0] == "h3")
+
 	var gotlsConfig *gotls.Config
 
 	if tlsConfig != nil {
@@ -189,7 +204,6 @@ func getHTTPClient(ctx context.Context, dest net.Destination, streamSettings *in
 		dialUploadConn: dialContext,
 	}
 
-	globalDialerMap[dialerConf{dest, streamSettings}] = client
 	return client
 }
 
@@ -197,33 +211,134 @@ func init() {
 	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
 }
 
-func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (stat.Connection, error) {
-	errors.LogInfo(ctx, "dialing splithttp to ", dest)
-
+func getRequestURL(dest net.Destination, streamSettings *internet.MemoryStreamConfig) url.URL {
 	var requestURL url.URL
 
-	transportConfiguration := streamSettings.ProtocolSettings.(*Config)
-	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
-
-	scMaxConcurrentPosts := transportConfiguration.GetNormalizedScMaxConcurrentPosts()
-	scMaxEachPostBytes := transportConfiguration.GetNormalizedScMaxEachPostBytes()
-	scMinPostsIntervalMs := transportConfiguration.GetNormalizedScMinPostsIntervalMs()
-
-	if tlsConfig != nil {
+	if tls.ConfigFromStreamSettings(streamSettings) != nil {
 		requestURL.Scheme = "https"
 	} else {
 		requestURL.Scheme = "http"
 	}
-	requestURL.Host = transportConfiguration.Host
+	requestURL.Host = streamSettings.ProtocolSettings.(*Config).Host
 	if requestURL.Host == "" {
 		requestURL.Host = dest.NetAddr()
 	}
 
+	return requestURL
+}
+
+func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (stat.Connection, error) {
+	errors.LogInfo(ctx, "dialing splithttp to ", dest)
+
+	transportConfiguration := streamSettings.ProtocolSettings.(*Config)
+	mode := transportConfiguration.GetNormalizedMode()
+
+	if mode == ModeStreamOne && transportConfiguration.DownloadSettings != nil {
+		return nil, errors.New("download settings are not supported in stream-one mode")
+	}
+
+	requestURL := getRequestURL(dest, streamSettings)
+	httpClient, muxRes := getHTTPClient(ctx, dest, streamSettings)
+	closeSession := muxRes.sessionCloser()
+
+	if mode == ModeStreamOne {
+		requestURL.Path = transportConfiguration.GetNormalizedPath()
+		requestURL.RawQuery = transportConfiguration.GetNormalizedQuery()
+
+		uploadReader, uploadWriter := io.Pipe()
+		reader, remoteAddr, localAddr, err := httpClient.OpenStream(context.WithoutCancel(ctx), requestURL.String(), uploadReader)
+		if err != nil {
+			closeSession()
+			return nil, err
+		}
+
+		conn := splitConn{
+			writer:     uploadWriter,
+			reader:     &stripOkReader{ReadCloser: reader},
+			remoteAddr: remoteAddr,
+			localAddr:  localAddr,
+			onClose:    closeSession,
+		}
+
+		return stat.Connection(&conn), nil
+	}
+
 	sessionIdUuid := uuid.New()
 	requestURL.Path = transportConfiguration.GetNormalizedPath() + sessionIdUuid.String()
 	requestURL.RawQuery = transportConfiguration.GetNormalizedQuery()
 
-	httpClient := getHTTPClient(ctx, dest, streamSettings)
+	downloadURL := requestURL
+	downloadClient := httpClient
+	if downloadConfig := transportConfiguration.DownloadSettings; downloadConfig != nil {
+		downloadDest := downloadConfig.GetDestination()
+		downloadSettings := streamSettings.DownloadSettings
+		if downloadSettings == nil {
+			closeSession()
+			return nil, errors.New("download settings are not parsed with the stream settings")
+		}
+		downloadTransportConfiguration, ok := downloadSettings.ProtocolSettings.(*Config)
+		if !ok {
+			closeSession()
+			return nil, errors.New("download settings must use splithttp")
+		}
+
+		downloadURL = getRequestURL(downloadDest, downloadSettings)
+		downloadURL.Path = downloadTransportConfiguration.GetNormalizedPath() + sessionIdUuid.String()
+		downloadURL.RawQuery = downloadTransportConfiguration.GetNormalizedQuery()
+		var downloadMuxRes *muxResource
+		downloadClient, downloadMuxRes = getHTTPClient(ctx, downloadDest, downloadSettings)
+		closeUploadSession, closeDownloadSession := closeSession, downloadMuxRes.sessionCloser()
+		closeSession = func() {
+			closeUploadSession()
+			closeDownloadSession()
+		}
+		errors.LogInfo(ctx, "downloading splithttp from ", downloadDest)
+	}
+
+	var writer io.WriteCloser
+	if mode == ModeStreamUp {
+		uploadReader, uploadWriter := io.Pipe()
+		response, _, _, err := httpClient.OpenStream(context.WithoutCancel(ctx), requestURL.String(), uploadReader)
+		if err != nil {
+			closeSession()
+			return nil, err
+		}
+		go func() {
+			// the server responds when the upload is finished
+			io.Copy(io.Discard, response)
+			response.Close()
+		}()
+		writer = uploadWriter
+	} else {
+		writer = dialPacketUpload(ctx, requestURL, transportConfiguration, httpClient)
+	}
+
+	lazyRawDownload, remoteAddr, localAddr, err := downloadClient.OpenDownload(context.WithoutCancel(ctx), downloadURL.String())
+	if err != nil {
+		writer.Close()
+		closeSession()
+		return nil, err
+	}
+
+	reader := &stripOkReader{ReadCloser: lazyRawDownload}
+
+	conn := splitConn{
+		writer:     writer,
+		reader:     reader,
+		remoteAddr: remoteAddr,
+		localAddr:  localAddr,
+		onClose:    closeSession,
+	}
+
+	return stat.Connection(&conn), nil
+}
+
+// dialPacketUpload uploads everything written to the returned writer with
+// many POST requests.
+func dialPacketUpload(ctx context.Context, requestURL url.URL, transportConfiguration *Config, httpClient DialerClient) io.WriteCloser {
+	scMaxConcurrentPosts := transportConfiguration.GetNormalizedScMaxConcurrentPosts()
+	scMaxEachPostBytes := transportConfiguration.GetNormalizedScMaxEachPostBytes()
+	scMinPostsIntervalMs := transportConfiguration.GetNormalizedScMinPostsIntervalMs()
 
 	maxUploadSize := scMaxEachPostBytes.roll()
 	// WithSizeLimit(0) will still allow single bytes to pass, and a lot of
@@ -285,26 +400,10 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		}
 	}()
 
-	lazyRawDownload, remoteAddr, localAddr, err := httpClient.OpenDownload(context.WithoutCancel(ctx), requestURL.String())
-	if err != nil {
-		return nil, err
-	}
-
-	reader := &stripOkReader{ReadCloser: lazyRawDownload}
-
-	writer := uploadWriter{
+	return uploadWriter{
 		uploadPipeWriter,
 		maxUploadSize,
 	}
-
-	conn := splitConn{
-		writer:     writer,
-		reader:     reader,
-		remoteAddr: remoteAddr,
-		localAddr:  localAddr,
-	}
-
-	return stat.Connection(&conn), nil
 }
 
 // A wrapper around pipe that ensures the size limit is exactly honored.
@@ -19,6 +19,7 @@ import (
 	http_proto "github.com/luckyluke-a/xray-core/common/protocol/http"
 	"github.com/luckyluke-a/xray-core/common/signal/done"
 	"github.com/luckyluke-a/xray-core/transport/internet"
+	"github.com/luckyluke-a/xray-core/transport/internet/httpfallback"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
 	v2tls "github.com/luckyluke-a/xray-core/transport/internet/tls"
 	"golang.org/x/net/http2"
@@ -33,6 +34,7 @@ type requestHandler struct {
 	sessionMu *sync.Mutex
 	sessions  sync.Map
 	localAddr gonet.TCPAddr
+	fallback  *httpfallback.Handler
 }
 
 type httpSession struct {
@@ -88,13 +90,13 @@ func (h *requestHandler) upsertSession(sessionId string) *httpSession {
 func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	if len(h.host) > 0 && !internet.IsValidHTTPHost(request.Host, h.host) {
 		errors.LogInfo(context.Background(), "failed to validate host, request:", request.Host, ", config:", h.host)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request, http.StatusNotFound)
 		return
 	}
 
 	if !strings.HasPrefix(request.URL.Path, h.path) {
 		errors.LogInfo(context.Background(), "failed to validate path, request:", request.URL.Path, ", config:", h.path)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request, http.StatusNotFound)
 		return
 	}
 
@@ -104,9 +106,9 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		sessionId = subpath[0]
 	}
 
-	if sessionId == "" {
+	if sessionId == "" && request.Method != "POST" {
 		errors.LogInfo(context.Background(), "no sessionid on request:", request.URL.Path)
-		writer.WriteHeader(http.StatusBadRequest)
+		h.reject(writer, request, http.StatusBadRequest)
 		return
 	}
 
@@ -122,6 +124,22 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		}
 	}
 
+	if sessionId == "" {
+		if !h.config.IsModeAllowed(ModeStreamOne) {
+			errors.LogInfo(context.Background(), "stream-one mode is not allowed, request:", request.URL.Path)
+			h.reject(writer, request, http.StatusForbidden)
+			return
+		}
+
+		// the request body may only be read concurrently with the response
+		// with HTTP/1.1 if full duplex is enabled.
+		http.NewResponseController(writer).EnableFullDuplex()
+
+		body := newStreamBody(request.Body)
+		h.serveDownload(writer, request, body, remoteAddr)
+		return
+	}
+
 	currentSession := h.upsertSession(sessionId)
 	scMaxEachPostBytes := int(h.ln.config.GetNormalizedScMaxEachPostBytes().To)
 
@@ -132,8 +150,40 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		}
 
 		if seq == "" {
-			errors.LogInfo(context.Background(), "no seq on request:", request.URL.Path)
-			writer.WriteHeader(http.StatusBadRequest)
+			if !h.config.IsModeAllowed(ModeStreamUp) {
+				errors.LogInfo(context.Background(), "stream-up mode is not allowed, request:", request.URL.Path)
+				writer.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			body := newStreamBody(request.Body)
+			err = currentSession.uploadQueue.Push(Packet{
+				Reader: body,
+			})
+			if err != nil {
+				errors.LogInfoInner(context.Background(), err, "failed to upload")
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			h.config.WriteResponseHeader(writer)
+			writer.WriteHeader(http.StatusOK)
+			if responseFlusher, ok := writer.(http.Flusher); ok {
+				responseFlusher.Flush()
+			}
+
+			// "A ResponseWriter may not be used after [Handler.ServeHTTP] has returned."
+			// neither may the request body, so wait for it to be consumed.
+			select {
+			case <-request.Context().Done():
+			case <-body.done.Wait():
+			}
+			return
+		}
+
+		if !h.config.IsModeAllowed(ModePacketUp) {
+			errors.LogInfo(context.Background(), "packet-up mode is not allowed, request:", request.URL.Path)
+			writer.WriteHeader(http.StatusForbidden)
 			return
 		}
 
@@ -172,65 +222,107 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		h.config.WriteResponseHeader(writer)
 		writer.WriteHeader(http.StatusOK)
 	} else if request.Method == "GET" {
-		responseFlusher, ok := writer.(http.Flusher)
-		if !ok {
-			panic("expected http.ResponseWriter to be an http.Flusher")
-		}
-
 		// after GET is done, the connection is finished. disable automatic
 		// session reaping, and handle it in defer
 		currentSession.isFullyConnected.Close()
 		defer h.sessions.Delete(sessionId)
 
-		// magic header instructs nginx + apache to not buffer response body
-		writer.Header().Set("X-Accel-Buffering", "no")
-		// A web-compliant header telling all middleboxes to disable caching.
-		// Should be able to prevent overloading the cache, or stop CDNs from
-		// teeing the response stream into their cache, causing slowdowns.
-		writer.Header().Set("Cache-Control", "no-store")
-		if !h.config.NoSSEHeader {
-			// magic header to make the HTTP middle box consider this as SSE to disable buffer
-			writer.Header().Set("Content-Type", "text/event-stream")
-		}
+		h.serveDownload(writer, request, currentSession.uploadQueue, remoteAddr)
+	} else {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
 
-		h.config.WriteResponseHeader(writer)
+// reject answers a request which is not for the transport.
+func (h *requestHandler) reject(writer http.ResponseWriter, request *http.Request, status int) {
+	if h.fallback != nil {
+		h.fallback.ServeHTTP(writer, request)
+		return
+	}
+	writer.WriteHeader(status)
+}
 
-		writer.WriteHeader(http.StatusOK)
-		if _, ok := request.URL.Query()["x_padding"]; !ok {
-			// in earlier versions, this initial body data was used to immediately
-			// start a 200 OK on all CDN. but xray client since 1.8.16 does not
-			// actually require an immediate 200 OK, but now requires these
-			// additional bytes "ok". xray client 1.8.24+ doesn't require "ok"
-			// anymore, and so this line should be removed in later versions.
-			writer.Write([]byte("ok"))
-		}
+// serveDownload streams the download of a connection in the response, and
+// hands the connection over to the listener.
+func (h *requestHandler) serveDownload(writer http.ResponseWriter, request *http.Request, reader io.ReadCloser, remoteAddr gonet.Addr) {
+	responseFlusher, ok := writer.(http.Flusher)
+	if !ok {
+		panic("expected http.ResponseWriter to be an http.Flusher")
+	}
 
-		responseFlusher.Flush()
+	// magic header instructs nginx + apache to not buffer response body
+	writer.Header().Set("X-Accel-Buffering", "no")
+	// A web-compliant header telling all middleboxes to disable caching.
+	// Should be able to prevent overloading the cache, or stop CDNs from
+	// teeing the response stream into their cache, causing slowdowns.
+	writer.Header().Set("Cache-Control", "no-store")
+	if !h.config.NoSSEHeader {
+		// magic header to make the HTTP middle box consider this as SSE to disable buffer
+		writer.Header().Set("Content-Type", "text/event-stream")
+	}
 
-		downloadDone := done.New()
+	h.config.WriteResponseHeader(writer)
 
-		conn := splitConn{
-			writer: &httpResponseBodyWriter{
-				responseWriter:  writer,
-				downloadDone:    downloadDone,
-				responseFlusher: responseFlusher,
-			},
-			reader:     currentSession.uploadQueue,
-			remoteAddr: remoteAddr,
-		}
+	writer.WriteHeader(http.StatusOK)
+	if _, ok := request.URL.Query()["x_padding"]; !ok {
+		// in earlier versions, this initial body data was used to immediately
+		// start a 200 OK on all CDN. but xray client since 1.8.16 does not
+		// actually require an immediate 200 OK, but now requires these
+		// additional bytes "ok". xray client 1.8.24+ doesn't require "ok"
+		// anymore, and so this line should be removed in later versions.
+		writer.Write([]byte("ok"))
+	}
 
-		h.ln.addConn(stat.Connection(&conn))
+	responseFlusher.Flush()
 
-		// "A ResponseWriter may not be used after [Handler.ServeHTTP] has returned."
-		select {
-		case <-request.Context().Done():
-		case <-downloadDone.Wait():
-		}
+	downloadDone := done.New()
 
-		conn.Close()
-	} else {
-		writer.WriteHeader(http.StatusMethodNotAllowed)
+	conn := splitConn{
+		writer: &httpResponseBodyWriter{
+			responseWriter:  writer,
+			downloadDone:    downloadDone,
+			responseFlusher: responseFlusher,
+		},
+		reader:     reader,
+		remoteAddr: remoteAddr,
 	}
+
+	h.ln.addConn(stat.Connection(&conn))
+
+	// "A ResponseWriter may not be used after [Handler.ServeHTTP] has returned."
+	select {
+	case <-request.Context().Done():
+	case <-downloadDone.Wait():
+	}
+
+	conn.Close()
+}
+
+// streamBody is the request body of stream-up and stream-one requests,
+// signaling when it is finished.
+type streamBody struct {
+	io.ReadCloser
+	done *done.Instance
+}
+
+func newStreamBody(body io.ReadCloser) *streamBody {
+	return &streamBody{
+		ReadCloser: body,
+		done:       done.New(),
+	}
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.done.Close()
+	}
+	return n, err
+}
+
+func (b *streamBody) Close() error {
+	b.done.Close()
+	return b.ReadCloser.Close()
 }
 
 type httpResponseBodyWriter struct {
@@ -277,13 +369,16 @@ func ListenSH(ctx context.Context, address net.Address, port net.Port, streamSet
 	}
 	shSettings := streamSettings.ProtocolSettings.(*Config)
 	l.config = shSettings
+	fallback, err := httpfallback.New(shSettings.Fallback)
+	if err != nil {
+		return nil, errors.New("failed to create fallback (for SH)").Base(err)
+	}
 	if l.config != nil {
 		if streamSettings.SocketSettings == nil {
 			streamSettings.SocketSettings = &internet.SocketConfig{}
 		}
 	}
 	var listener net.Listener
-	var err error
 	var localAddr = gonet.TCPAddr{}
 	handler := &requestHandler{
 		config:    shSettings,
@@ -293,6 +388,7 @@ func ListenSH(ctx context.Context, address net.Address, port net.Port, streamSet
 		sessionMu: &sync.Mutex{},
 		sessions:  sync.Map{},
 		localAddr: localAddr,
+		fallback:  fallback,
 	}
 	tlsConfig := getTLSConfig(streamSettings)
 	l.isH3 = len(tlsConfig.NextProtos) == 1 && tlsConfig.NextProtos[0] == "h3"
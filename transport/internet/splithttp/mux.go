@@ -0,0 +1,129 @@
+package splithttp
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// muxResource is an HTTP client, which keeps one connection to the server,
+// shared by sessions.
+type muxResource struct {
+	Resource     DialerClient
+	OpenSessions atomic.Int32
+
+	// the remaining number of sessions, negative for unlimited
+	leftUsage int32
+	// zero for unlimited
+	expirationTime time.Time
+}
+
+// sessionCloser returns the function to call when the session opened by
+// GetResource is closed.
+func (r *muxResource) sessionCloser() func() {
+	if r == nil {
+		return func() {}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.OpenSessions.Add(-1)
+		})
+	}
+}
+
+func (r *muxResource) usable(now time.Time) bool {
+	return r.leftUsage != 0 && (r.expirationTime.IsZero() || now.Before(r.expirationTime))
+}
+
+// muxManager distributes sessions over HTTP clients according to the
+// multiplexing limits.
+type muxManager struct {
+	sync.Mutex
+	newResourceFn func() DialerClient
+	config        *Multiplexing
+	concurrency   int32
+	connections   int32
+	instances     []*muxResource
+}
+
+func newMuxManager(config *Multiplexing, newResource func() DialerClient) *muxManager {
+	return &muxManager{
+		newResourceFn: newResource,
+		config:        config,
+		concurrency:   config.RollMaxConcurrency(),
+		connections:   config.RollMaxConnections(),
+	}
+}
+
+// GetResource returns the client for a new session, and counts the session on it.
+func (m *muxManager) GetResource() *muxResource {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	m.removeExpired(now)
+
+	var candidates []*muxResource
+	for _, r := range m.instances {
+		if m.concurrency > 0 && r.OpenSessions.Load() >= m.concurrency {
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+
+	var r *muxResource
+	switch {
+	case len(candidates) == 0:
+		r = m.newResource(now)
+	case m.connections > 0 && int32(len(m.instances)) < m.connections:
+		r = m.newResource(now)
+	case m.concurrency > 0 || m.connections > 0:
+		r = candidates[randIntn(len(candidates))]
+	default:
+		r = candidates[0]
+	}
+
+	if r.leftUsage > 0 {
+		r.leftUsage--
+	}
+	r.OpenSessions.Add(1)
+	return r
+}
+
+func (m *muxManager) newResource(now time.Time) *muxResource {
+	r := &muxResource{
+		Resource:  m.newResourceFn(),
+		leftUsage: -1,
+	}
+	if reuseTimes := m.config.RollCMaxReuseTimes(); reuseTimes > 0 {
+		r.leftUsage = reuseTimes
+	}
+	if lifetime := m.config.RollCMaxLifetimeMs(); lifetime > 0 {
+		r.expirationTime = now.Add(time.Duration(lifetime) * time.Millisecond)
+	}
+	m.instances = append(m.instances, r)
+	return r
+}
+
+// removeExpired drops the clients which shouldn't take new sessions. Their
+// connections are kept for the sessions already on them.
+func (m *muxManager) removeExpired(now time.Time) {
+	instances := m.instances[:0]
+	for _, r := range m.instances {
+		if r.usable(now) {
+			instances = append(instances, r)
+		} else if closer, ok := r.Resource.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+	clear(m.instances[len(instances):])
+	m.instances = instances
+}
+
+func randIntn(n int) int {
+	bigInt, _ := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	return int(bigInt.Int64())
+}
@@ -0,0 +1,92 @@
+package splithttp
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeDialerClient struct {
+	DialerClient
+}
+
+func newFakeClient() DialerClient {
+	return &fakeDialerClient{}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	manager := newMuxManager(&Multiplexing{
+		MaxConcurrency: &RandRangeConfig{From: 2, To: 2},
+	}, newFakeClient)
+
+	r1 := manager.GetResource()
+	r2 := manager.GetResource()
+	if r1 != r2 {
+		t.Error("expect the same client for 2 sessions")
+	}
+	r3 := manager.GetResource()
+	if r3 == r1 {
+		t.Error("expect a new client for the 3rd session")
+	}
+
+	r1.sessionCloser()()
+	if r4 := manager.GetResource(); r4 != r1 && r4 != r3 {
+		t.Error("expect an existing client after a session is closed")
+	}
+	if len(manager.instances) != 2 {
+		t.Error("expect 2 clients, but got ", len(manager.instances))
+	}
+}
+
+func TestMaxConnections(t *testing.T) {
+	manager := newMuxManager(&Multiplexing{
+		MaxConnections: &RandRangeConfig{From: 3, To: 3},
+	}, newFakeClient)
+
+	clients := make(map[*muxResource]bool)
+	for i := 0; i < 10; i++ {
+		clients[manager.GetResource()] = true
+	}
+	if len(clients) != 3 {
+		t.Error("expect 3 clients, but got ", len(clients))
+	}
+}
+
+func TestCMaxReuseTimes(t *testing.T) {
+	manager := newMuxManager(&Multiplexing{
+		CMaxReuseTimes: &RandRangeConfig{From: 2, To: 2},
+	}, newFakeClient)
+
+	r1 := manager.GetResource()
+	if manager.GetResource() != r1 {
+		t.Error("expect the same client for 2 sessions")
+	}
+	if manager.GetResource() == r1 {
+		t.Error("expect a new client after 2 sessions")
+	}
+}
+
+func TestCMaxLifetime(t *testing.T) {
+	manager := newMuxManager(&Multiplexing{
+		CMaxLifetimeMs: &RandRangeConfig{From: 50, To: 50},
+	}, newFakeClient)
+
+	r1 := manager.GetResource()
+	if manager.GetResource() != r1 {
+		t.Error("expect the same client within the lifetime")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if manager.GetResource() == r1 {
+		t.Error("expect a new client after the lifetime")
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	manager := newMuxManager(nil, newFakeClient)
+
+	r1 := manager.GetResource()
+	for i := 0; i < 100; i++ {
+		if manager.GetResource() != r1 {
+			t.Fatal("expect the same client")
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/luckyluke-a/xray-core/common/buf"
 	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/common/protocol/tls/cert"
+	"github.com/luckyluke-a/xray-core/common/serial"
 	"github.com/luckyluke-a/xray-core/testing/servers/tcp"
 	"github.com/luckyluke-a/xray-core/testing/servers/udp"
 	"github.com/luckyluke-a/xray-core/transport/internet"
@@ -470,3 +471,140 @@ func Test_maxUpload(t *testing.T) {
 
 	common.Must(listen.Close())
 }
+
+func Test_listenSHAndDial_StreamModes(t *testing.T) {
+	if runtime.GOARCH == "arm64" {
+		return
+	}
+
+	listenPort := tcp.PickPort()
+
+	securitySettings := &tls.Config{
+		AllowInsecure: true,
+		Certificate:   []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil, cert.CommonName("localhost")))},
+	}
+	listen, err := ListenSH(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path: "shs",
+		},
+		SecurityType:     "tls",
+		SecuritySettings: securitySettings,
+	}, func(conn stat.Connection) {
+		go func() {
+			defer conn.Close()
+
+			var b [1024]byte
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, err := conn.Read(b[:])
+			if err != nil {
+				return
+			}
+
+			common.Must2(conn.Write(append([]byte("Response to "), b[:n]...)))
+		}()
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	downloadSettings := &DownloadConfig{
+		Address: net.NewIPOrDomain(net.LocalHostIP),
+		Port:    uint32(listenPort),
+		StreamSettings: &internet.StreamConfig{
+			ProtocolName: "splithttp",
+			TransportSettings: []*internet.TransportConfig{
+				{
+					ProtocolName: "splithttp",
+					Settings:     serial.ToTypedMessage(&Config{Path: "shs"}),
+				},
+			},
+			SecurityType:     serial.GetMessageType(securitySettings),
+			SecuritySettings: []*serial.TypedMessage{serial.ToTypedMessage(securitySettings)},
+		},
+	}
+
+	testCases := []*Config{
+		{Path: "shs", Mode: ModeStreamUp},
+		{Path: "shs", Mode: ModeStreamOne},
+		{Path: "shs", Mode: ModeStreamUp, DownloadSettings: downloadSettings},
+		{Path: "shs", Mode: ModePacketUp, DownloadSettings: downloadSettings},
+	}
+	for _, config := range testCases {
+		streamSettings := &internet.MemoryStreamConfig{
+			ProtocolName:     "splithttp",
+			ProtocolSettings: config,
+			SecurityType:     "tls",
+			SecuritySettings: securitySettings,
+		}
+		if config.DownloadSettings != nil {
+			streamSettings.DownloadSettings, err = internet.ToMemoryStreamConfig(config.GetDownloadStreamSettings())
+			common.Must(err)
+		}
+		conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), listenPort), streamSettings)
+		common.Must(err)
+
+		_, err = conn.Write([]byte(config.Mode))
+		common.Must(err)
+
+		var b [1024]byte
+		n, _ := io.ReadFull(conn, b[:])
+		if string(b[:n]) != "Response to "+config.Mode {
+			t.Error("response in ", config.Mode, ": ", string(b[:n]))
+		}
+		conn.Close()
+	}
+}
+
+func Test_modeNotAllowed(t *testing.T) {
+	listenPort := tcp.PickPort()
+
+	listen, err := ListenSH(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path: "shs",
+			Mode: ModeStreamUp,
+		},
+	}, func(conn stat.Connection) {
+		conn.Close()
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	resp, err := http.Post("http://"+net.LocalHostIP.String()+":"+listenPort.String()+"/shs/abcd/0", "", nil)
+	common.Must(err)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error("Expected 403 for packet-up but got:", resp.StatusCode)
+	}
+}
+
+func TestDownloadStreamSettings(t *testing.T) {
+	config := &Config{
+		Path: "up",
+		DownloadSettings: &DownloadConfig{
+			Address: net.NewIPOrDomain(net.LocalHostIP),
+			Port:    443,
+			StreamSettings: &internet.StreamConfig{
+				ProtocolName: "splithttp",
+				TransportSettings: []*internet.TransportConfig{{
+					ProtocolName: "splithttp",
+					Settings:     serial.ToTypedMessage(&Config{Path: "down"}),
+				}},
+			},
+		},
+	}
+	streamSettings, err := internet.ToMemoryStreamConfig(&internet.StreamConfig{
+		ProtocolName: "splithttp",
+		TransportSettings: []*internet.TransportConfig{{
+			ProtocolName: "splithttp",
+			Settings:     serial.ToTypedMessage(config),
+		}},
+	})
+	common.Must(err)
+	if streamSettings.DownloadSettings == nil {
+		t.Fatal("download settings are not parsed")
+	}
+	if c, ok := streamSettings.DownloadSettings.ProtocolSettings.(*Config); !ok || c.Path != "down" {
+		t.Error("unexpected download settings: ", streamSettings.DownloadSettings.ProtocolSettings)
+	}
+}
@@ -14,10 +14,14 @@ import (
 type Packet struct {
 	Payload []byte
 	Seq     uint64
+	// Reader is set instead of Payload for stream-up uploads, and supersedes
+	// any packets of the session.
+	Reader io.ReadCloser
 }
 
 type uploadQueue struct {
 	pushedPackets   chan Packet
+	reader          io.ReadCloser
 	writeCloseMutex sync.Mutex
 	heap            uploadHeap
 	nextSeq         uint64
@@ -55,10 +59,32 @@ func (h *uploadQueue) Close() error {
 		h.closed = true
 		close(h.pushedPackets)
 	}
+	if h.reader != nil {
+		return h.reader.Close()
+	}
 	return nil
 }
 
+func (h *uploadQueue) getReader() io.ReadCloser {
+	h.writeCloseMutex.Lock()
+	defer h.writeCloseMutex.Unlock()
+	return h.reader
+}
+
+func (h *uploadQueue) setReader(reader io.ReadCloser) {
+	h.writeCloseMutex.Lock()
+	defer h.writeCloseMutex.Unlock()
+	if h.closed {
+		reader.Close()
+	}
+	h.reader = reader
+}
+
 func (h *uploadQueue) Read(b []byte) (int, error) {
+	if reader := h.getReader(); reader != nil {
+		return reader.Read(b)
+	}
+
 	if h.closed {
 		return 0, io.EOF
 	}
@@ -68,6 +94,10 @@ func (h *uploadQueue) Read(b []byte) (int, error) {
 		if !more {
 			return 0, io.EOF
 		}
+		if packet.Reader != nil {
+			h.setReader(packet.Reader)
+			return packet.Reader.Read(b)
+		}
 		heap.Push(&h.heap, packet)
 	}
 
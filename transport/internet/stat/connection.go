@@ -32,3 +32,27 @@ func (c *CounterConnection) Write(b []byte) (int, error) {
 	}
 	return nBytes, err
 }
+
+// AttributedConnection is a connection with attributes for routing, like the
+// credentials of its peer.
+type AttributedConnection interface {
+	Attributes() map[string]string
+}
+
+// AttributesFromConnection returns the attributes of the connection, or of the
+// connections it wraps.
+func AttributesFromConnection(conn net.Conn) map[string]string {
+	for conn != nil {
+		switch c := conn.(type) {
+		case AttributedConnection:
+			return c.Attributes()
+		case *CounterConnection:
+			conn = c.Connection
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
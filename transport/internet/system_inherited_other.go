@@ -0,0 +1,281 @@
+//go:build !windows
+// +build !windows
+
+package internet
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+// inheritedSocket is a socket passed by systemd socket activation, or by the
+// previous process during an upgrade.
+type inheritedSocket struct {
+	name       string
+	listener   net.Listener
+	packetConn net.PacketConn
+	taken      bool
+}
+
+var inherited struct {
+	sync.Mutex
+	once    sync.Once
+	sockets []*inheritedSocket
+}
+
+// tracked are the sockets listened by this process, to be passed on upgrade.
+var tracked struct {
+	sync.Mutex
+	sockets []trackedSocket
+}
+
+type trackedSocket struct {
+	name   string
+	socket fileSocket
+}
+
+// fileSocket is a *net.TCPListener, *net.UnixListener or *net.UDPConn.
+type fileSocket interface {
+	File() (*os.File, error)
+	SyscallConn() (syscall.RawConn, error)
+	Close() error
+}
+
+// loadInheritedSockets reads the sockets in the LISTEN_FDS protocol of
+// systemd. LISTEN_PID is checked if it's set.
+func loadInheritedSockets() {
+	pid := os.Getenv("LISTEN_PID")
+	fds := os.Getenv("LISTEN_FDS")
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if fds == "" || (pid != "" && pid != strconv.Itoa(os.Getpid())) {
+		return
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "invalid LISTEN_FDS: ", fds)
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		fd := 3 + i
+		syscall.CloseOnExec(fd)
+		socket := &inheritedSocket{}
+		if i < len(names) {
+			socket.name = names[i]
+		}
+		file := os.NewFile(uintptr(fd), socket.name)
+		if l, err := net.FileListener(file); err == nil {
+			socket.listener = l
+		} else if pc, err := net.FilePacketConn(file); err == nil {
+			socket.packetConn = pc
+		} else {
+			errors.LogWarningInner(context.Background(), err, "unsupported inherited socket ", fd)
+			file.Close()
+			continue
+		}
+		file.Close()
+		errors.LogInfo(context.Background(), "inherited socket ", fd, " (", socket.name, ")")
+		inherited.sockets = append(inherited.sockets, socket)
+	}
+}
+
+// takeInherited returns an inherited socket by the name, or by the address if
+// the name is empty.
+func takeInherited(same func(net.Addr) bool, sockopt *SocketConfig, match func(*inheritedSocket) net.Addr) (*inheritedSocket, error) {
+	inherited.once.Do(loadInheritedSockets)
+	inherited.Lock()
+	defer inherited.Unlock()
+
+	name := sockopt.GetInheritedFdName()
+	for _, socket := range inherited.sockets {
+		if socket.taken {
+			continue
+		}
+		socketAddr := match(socket)
+		if socketAddr == nil {
+			continue
+		}
+		if (name != "" && socket.name == name) || (name == "" && same(socketAddr)) {
+			socket.taken = true
+			errors.LogInfo(context.Background(), "adopting inherited socket ", socketAddr)
+			return socket, nil
+		}
+	}
+	if name != "" {
+		return nil, errors.New("no inherited socket named ", name)
+	}
+	return nil, nil
+}
+
+func takeInheritedListener(addr net.Addr, sockopt *SocketConfig) (net.Listener, string, error) {
+	return takeInheritedListenerFunc(func(a net.Addr) bool { return isSameAddr(a, addr) }, sockopt)
+}
+
+func takeInheritedListenerFunc(same func(net.Addr) bool, sockopt *SocketConfig) (net.Listener, string, error) {
+	socket, err := takeInherited(same, sockopt, func(s *inheritedSocket) net.Addr {
+		if s.listener == nil {
+			return nil
+		}
+		return s.listener.Addr()
+	})
+	if socket == nil {
+		return nil, "", err
+	}
+	return socket.listener, socket.name, nil
+}
+
+func takeInheritedPacketConn(addr net.Addr, sockopt *SocketConfig) (net.PacketConn, string, error) {
+	same := func(a net.Addr) bool { return isSameAddr(a, addr) }
+	socket, err := takeInherited(same, sockopt, func(s *inheritedSocket) net.Addr {
+		if s.packetConn == nil {
+			return nil
+		}
+		return s.packetConn.LocalAddr()
+	})
+	if socket == nil {
+		return nil, "", err
+	}
+	return socket.packetConn, socket.name, nil
+}
+
+func isSameAddr(a, b net.Addr) bool {
+	isSameIP := func(a, b net.IP) bool {
+		return a.Equal(b) || ((a == nil || a.IsUnspecified()) && (b == nil || b.IsUnspecified()))
+	}
+	switch a := a.(type) {
+	case *net.TCPAddr:
+		b, ok := b.(*net.TCPAddr)
+		return ok && a.Port == b.Port && isSameIP(a.IP, b.IP)
+	case *net.UDPAddr:
+		b, ok := b.(*net.UDPAddr)
+		return ok && a.Port == b.Port && isSameIP(a.IP, b.IP)
+	case *net.UnixAddr:
+		b, ok := b.(*net.UnixAddr)
+		return ok && unixAddrName(a.Name) == unixAddrName(b.Name)
+	}
+	return false
+}
+
+// unixAddrName returns the name of a unix socket as it's read back from the
+// socket, which is without the permission suffix, or the padding of an
+// abstract socket.
+func unixAddrName(name string) string {
+	if !strings.HasPrefix(name, "@") {
+		return strings.Split(name, ",")[0]
+	}
+	if strings.HasPrefix(name, "@@") {
+		name = name[1:]
+	}
+	return strings.TrimRight(name, "\x00")
+}
+
+// ListenSystemUnix returns the inherited unix listener of the address, or the one
+// from listen if there isn't. It's for the transports setting up unix sockets
+// by themselves, whose listeners are passed on upgrade as those of
+// ListenSystem. The address of an inherited listener is checked by match
+// instead if it's not nil.
+func ListenSystemUnix(addr *net.UnixAddr, sockopt *SocketConfig, match func(*net.UnixAddr) bool, listen func() (*net.UnixListener, error)) (l *net.UnixListener, inherited bool, err error) {
+	same := func(a net.Addr) bool {
+		if match == nil {
+			return isSameAddr(a, addr)
+		}
+		unixAddr, ok := a.(*net.UnixAddr)
+		return ok && match(unixAddr)
+	}
+	if l, name, err := takeInheritedListenerFunc(same, sockopt); l != nil || err != nil {
+		if err != nil {
+			return nil, false, err
+		}
+		unixListener, ok := l.(*net.UnixListener)
+		if !ok {
+			return nil, false, errors.New("inherited socket is not a unix listener: ", l.Addr())
+		}
+		trackSocket(unixListener, name)
+		return unixListener, true, nil
+	}
+	l, err = listen()
+	if err != nil {
+		return nil, false, err
+	}
+	trackSocket(l, "")
+	return l, false, nil
+}
+
+// trackSocket remembers a listening socket to be passed on upgrade.
+func trackSocket(socket interface{}, name string) {
+	s, ok := socket.(fileSocket)
+	if !ok {
+		return
+	}
+	tracked.Lock()
+	defer tracked.Unlock()
+	tracked.sockets = append(pruneClosed(tracked.sockets), trackedSocket{name: name, socket: s})
+}
+
+func pruneClosed(sockets []trackedSocket) []trackedSocket {
+	opened := sockets[:0]
+	for _, s := range sockets {
+		rawConn, err := s.socket.SyscallConn()
+		if err != nil {
+			continue
+		}
+		if rawConn.Control(func(uintptr) {}) != nil {
+			continue
+		}
+		opened = append(opened, s)
+	}
+	return opened
+}
+
+// ListenerFiles returns duplicates of the listening sockets in use, with
+// their names for LISTEN_FDNAMES. They are to be passed to a new process,
+// which adopts them by address.
+func ListenerFiles() ([]*os.File, []string, error) {
+	tracked.Lock()
+	defer tracked.Unlock()
+	tracked.sockets = pruneClosed(tracked.sockets)
+
+	var files []*os.File
+	var names []string
+	for _, s := range tracked.sockets {
+		file, err := s.socket.File()
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, nil, errors.New("failed to duplicate listening socket").Base(err)
+		}
+		name := s.name
+		if name == "" {
+			name = "xray"
+		}
+		files = append(files, file)
+		names = append(names, name)
+	}
+	return files, names, nil
+}
+
+// CloseListeners stops accepting on all listening sockets, while the accepted
+// connections keep going. It's used to drain after upgrade.
+func CloseListeners() {
+	tracked.Lock()
+	defer tracked.Unlock()
+	for _, s := range tracked.sockets {
+		if l, ok := s.socket.(*net.UnixListener); ok {
+			// the socket file is used by the next process
+			l.SetUnlinkOnClose(false)
+		}
+		s.socket.Close()
+	}
+	tracked.sockets = nil
+}
@@ -0,0 +1,102 @@
+//go:build !windows
+// +build !windows
+
+package internet
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+func TestInheritedListener(t *testing.T) {
+	inherited.once.Do(func() {})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer l.Close()
+	named, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer named.Close()
+	inherited.Lock()
+	inherited.sockets = append(inherited.sockets,
+		&inheritedSocket{listener: l},
+		&inheritedSocket{name: "web", listener: named},
+	)
+	inherited.Unlock()
+
+	adopted, err := ListenSystem(context.Background(), &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: l.Addr().(*net.TCPAddr).Port}, nil)
+	common.Must(err)
+	if adopted != l {
+		t.Error("expect the inherited listener, but got ", adopted.Addr())
+	}
+
+	adopted, err = ListenSystem(context.Background(), &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}, &SocketConfig{InheritedFdName: "web"})
+	common.Must(err)
+	if adopted != named {
+		t.Error("expect the named listener, but got ", adopted.Addr())
+	}
+
+	if _, err := ListenSystem(context.Background(), &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}, &SocketConfig{InheritedFdName: "web"}); err == nil {
+		t.Error("expect error for a taken name")
+	}
+
+	files, names, err := ListenerFiles()
+	common.Must(err)
+	for _, f := range files {
+		f.Close()
+	}
+	if len(files) != 2 || names[0] != "xray" || names[1] != "web" {
+		t.Error("unexpected listener files: ", names)
+	}
+}
+
+func TestIsSameUnixAddr(t *testing.T) {
+	padded := make([]byte, 108)
+	copy(padded, "@xray")
+	cases := []struct {
+		socket, config string
+		same           bool
+	}{
+		{"/tmp/xray.sock", "/tmp/xray.sock", true},
+		{"/tmp/xray.sock", "/tmp/xray.sock,0666", true},
+		{"@xray", "@xray", true},
+		{"@xray", string(padded), true},
+		{"@xray", "@@xray", true},
+		{"@xray", "@xray2", false},
+		{"/tmp/xray.sock", "@xray", false},
+	}
+	for _, c := range cases {
+		if same := isSameAddr(&net.UnixAddr{Name: c.socket, Net: "unix"}, &net.UnixAddr{Name: c.config, Net: "unix"}); same != c.same {
+			t.Errorf("isSameAddr(%q, %q) = %v", c.socket, c.config, same)
+		}
+	}
+}
+
+func TestListenSystemUnix(t *testing.T) {
+	inherited.once.Do(func() {})
+
+	path := filepath.Join(t.TempDir(), "s")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	common.Must(err)
+	defer l.Close()
+	inherited.Lock()
+	inherited.sockets = append(inherited.sockets, &inheritedSocket{listener: l})
+	inherited.Unlock()
+
+	listen := func() (*net.UnixListener, error) {
+		return nil, errors.New("listen is not expected")
+	}
+	if _, _, err := ListenSystemUnix(&net.UnixAddr{Name: path, Net: "unix"}, nil, func(*net.UnixAddr) bool { return false }, listen); err == nil {
+		t.Error("expect no inherited listener to be matched")
+	}
+	adopted, ok, err := ListenSystemUnix(&net.UnixAddr{Name: path + ",0666", Net: "unix"}, nil, nil, listen)
+	common.Must(err)
+	if !ok || adopted != l {
+		t.Error("expect the inherited listener, but got ", adopted)
+	}
+}
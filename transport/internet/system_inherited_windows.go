@@ -0,0 +1,33 @@
+package internet
+
+import (
+	"os"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+)
+
+func takeInheritedListener(addr net.Addr, sockopt *SocketConfig) (net.Listener, string, error) {
+	if sockopt.GetInheritedFdName() != "" {
+		return nil, "", errors.New("inherited sockets are not supported on Windows")
+	}
+	return nil, "", nil
+}
+
+func takeInheritedPacketConn(addr net.Addr, sockopt *SocketConfig) (net.PacketConn, string, error) {
+	if sockopt.GetInheritedFdName() != "" {
+		return nil, "", errors.New("inherited sockets are not supported on Windows")
+	}
+	return nil, "", nil
+}
+
+func trackSocket(socket interface{}, name string) {}
+
+// ListenerFiles returns duplicates of the listening sockets in use, with
+// their names for LISTEN_FDNAMES.
+func ListenerFiles() ([]*os.File, []string, error) {
+	return nil, nil, errors.New("passing listening sockets is not supported on Windows")
+}
+
+// CloseListeners stops accepting on all listening sockets.
+func CloseListeners() {}
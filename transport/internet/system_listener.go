@@ -55,6 +55,14 @@ func getControlFunc(ctx context.Context, sockopt *SocketConfig, controllers []co
 }
 
 func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *SocketConfig) (l net.Listener, err error) {
+	if l, name, err := takeInheritedListener(addr, sockopt); l != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		trackSocket(l, name)
+		return wrapProxyProtocol(l, sockopt), nil
+	}
+
 	var lc net.ListenConfig
 	var network, address string
 	// callback is called after the Listen function returns
@@ -130,20 +138,45 @@ func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *S
 	}
 
 	l, err = lc.Listen(ctx, network, address)
+	if err == nil {
+		trackSocket(l, "")
+	}
 	l, err = callback(l, err)
+	if err != nil {
+		return l, err
+	}
+	return wrapProxyProtocol(l, sockopt), nil
+}
+
+func wrapProxyProtocol(l net.Listener, sockopt *SocketConfig) net.Listener {
 	if sockopt != nil && sockopt.AcceptProxyProtocol {
 		policyFunc := func(upstream net.Addr) (proxyproto.Policy, error) { return proxyproto.REQUIRE, nil }
 		l = &proxyproto.Listener{Listener: l, Policy: policyFunc}
 	}
-	return l, err
+	return l
 }
 
 func (dl *DefaultListener) ListenPacket(ctx context.Context, addr net.Addr, sockopt *SocketConfig) (net.PacketConn, error) {
+	if pc, name, err := takeInheritedPacketConn(addr, sockopt); pc != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		trackSocket(pc, name)
+		return pc, nil
+	}
+
 	var lc net.ListenConfig
 
 	lc.Control = getControlFunc(ctx, sockopt, dl.controllers)
 
-	return lc.ListenPacket(ctx, addr.Network(), addr.String())
+	pc, err := lc.ListenPacket(ctx, addr.Network(), addr.String())
+	// sockets of outbounds are not tracked, which use random ports
+	if err == nil {
+		if udpAddr, ok := addr.(*net.UDPAddr); ok && udpAddr.Port != 0 {
+			trackSocket(pc, "")
+		}
+	}
+	return pc, err
 }
 
 // RegisterListenerController adds a controller to the effective system listener.
@@ -8,6 +8,7 @@ import (
 	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/transport/internet"
 	"github.com/luckyluke-a/xray-core/transport/internet/reality"
+	"github.com/luckyluke-a/xray-core/transport/internet/shadowtls"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
 	"github.com/luckyluke-a/xray-core/transport/internet/tls"
 )
@@ -34,6 +35,10 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		if conn, err = reality.UClient(conn, config, ctx, dest); err != nil {
 			return nil, err
 		}
+	} else if config := shadowtls.ConfigFromStreamSettings(streamSettings); config != nil {
+		if conn, err = shadowtls.UClient(conn, config, ctx, dest); err != nil {
+			return nil, err
+		}
 	}
 
 	tcpSettings := streamSettings.ProtocolSettings.(*Config)
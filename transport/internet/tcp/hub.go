@@ -12,18 +12,20 @@ import (
 	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/transport/internet"
 	"github.com/luckyluke-a/xray-core/transport/internet/reality"
+	"github.com/luckyluke-a/xray-core/transport/internet/shadowtls"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
 	"github.com/luckyluke-a/xray-core/transport/internet/tls"
 )
 
 // Listener is an internet.Listener that listens for TCP connections.
 type Listener struct {
-	listener      net.Listener
-	tlsConfig     *gotls.Config
-	realityConfig *goreality.Config
-	authConfig    internet.ConnectionAuthenticator
-	config        *Config
-	addConn       internet.ConnHandler
+	listener        net.Listener
+	tlsConfig       *gotls.Config
+	realityConfig   *goreality.Config
+	shadowtlsConfig *shadowtls.Config
+	authConfig      internet.ConnectionAuthenticator
+	config          *Config
+	addConn         internet.ConnHandler
 }
 
 // ListenTCP creates a new Listener based on configurations.
@@ -73,6 +75,9 @@ func ListenTCP(ctx context.Context, address net.Address, port net.Port, streamSe
 	if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
 		l.realityConfig = config.GetREALITYConfig()
 	}
+	if config := shadowtls.ConfigFromStreamSettings(streamSettings); config != nil {
+		l.shadowtlsConfig = config
+	}
 
 	if tcpSettings.HeaderSettings != nil {
 		headerConfig, err := tcpSettings.HeaderSettings.GetInstance()
@@ -112,6 +117,14 @@ func (v *Listener) keepAccepting() {
 					errors.LogInfo(context.Background(), err.Error())
 					return
 				}
+			} else if v.shadowtlsConfig != nil {
+				shadowtlsConn, err := shadowtls.Server(conn, v.shadowtlsConfig)
+				if err != nil {
+					conn.Close()
+					errors.LogInfo(context.Background(), err.Error())
+					return
+				}
+				conn = shadowtlsConn
 			}
 			if v.authConfig != nil {
 				conn = v.authConfig.Server(conn)
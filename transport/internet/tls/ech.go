@@ -0,0 +1,144 @@
+//go:build go1.24
+// +build go1.24
+
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/OmarTariq612/goech"
+	"github.com/cloudflare/circl/hpke"
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/transport/internet"
+)
+
+// ParseECHKeys converts the key sets generated by "xray tls ech" into crypto/tls keys.
+// The first key is sent as the retry config.
+func ParseECHKeys(keySets [][]byte) ([]tls.EncryptedClientHelloKey, error) {
+	var keys []tls.EncryptedClientHelloKey
+	for _, keySet := range keySets {
+		var list goech.ECHKeySetList
+		if err := list.UnmarshalBinary(keySet); err != nil {
+			return nil, errors.New("invalid ECH key set").Base(err)
+		}
+		for _, k := range list {
+			if k.ECHConfig.KEM != hpke.KEM_X25519_HKDF_SHA256 {
+				return nil, errors.New("unsupported ECH KEM ", uint16(k.ECHConfig.KEM), ", only X25519 is supported")
+			}
+			privateKey, err := k.PrivateKey.MarshalBinary()
+			if err != nil {
+				return nil, errors.New("invalid ECH private key").Base(err)
+			}
+			// ECHConfig.MarshalBinary returns a list of one config, crypto/tls wants the config only.
+			config, err := k.ECHConfig.MarshalBinary()
+			if err != nil {
+				return nil, errors.New("invalid ECH config").Base(err)
+			}
+			keys = append(keys, tls.EncryptedClientHelloKey{
+				Config:      config[2:],
+				PrivateKey:  privateKey,
+				SendAsRetry: len(keys) == 0,
+			})
+		}
+	}
+	return keys, nil
+}
+
+// echRetryInterval is the time before querying the ECH config of a domain again after a failure.
+const echRetryInterval = 5 * time.Minute
+
+type echCacheEntry struct {
+	config []byte
+	err    error
+	expire time.Time
+}
+
+var (
+	echCacheAccess sync.Mutex
+	echCache       = make(map[string]*echCacheEntry)
+
+	lookupECHConfig = internet.LookupECHConfig
+)
+
+// queryECHConfig returns the ECH config list of the domain. The list is cached by the TTL of
+// the record, and a failure for echRetryInterval, so that a connection doesn't wait for a query
+// that keeps failing, for example without a DOH server. failed is true if the query has just
+// started failing, so that the failure is logged once.
+func queryECHConfig(domain string) (config []byte, failed bool, err error) {
+	echCacheAccess.Lock()
+	entry := echCache[domain]
+	echCacheAccess.Unlock()
+	if entry != nil && time.Now().Before(entry.expire) {
+		return entry.config, false, entry.err
+	}
+
+	config, ttl, err := lookupECHConfig(domain)
+	failed = err != nil && (entry == nil || entry.err == nil)
+	if err != nil {
+		ttl = echRetryInterval
+	}
+	echCacheAccess.Lock()
+	echCache[domain] = &echCacheEntry{
+		config: config,
+		err:    err,
+		expire: time.Now().Add(ttl),
+	}
+	echCacheAccess.Unlock()
+	return config, failed, err
+}
+
+func (c *Config) applyECH(config *tls.Config) {
+	if len(c.EchServerKeys) > 0 {
+		keys, err := ParseECHKeys(c.EchServerKeys)
+		if err != nil {
+			errors.LogErrorInner(context.Background(), err, "failed to load ECH keys")
+		} else {
+			config.EncryptedClientHelloKeys = keys
+		}
+	}
+
+	echConfigList := c.EchConfigList
+	if len(echConfigList) == 0 && c.EchQueryDomain != "" {
+		var failed bool
+		var err error
+		echConfigList, failed, err = queryECHConfig(c.EchQueryDomain)
+		if err != nil {
+			if c.EchForceQuery {
+				// An empty list makes the handshake fail before any Client Hello is sent.
+				echConfigList = []byte{0, 0}
+			}
+			switch {
+			case !failed:
+				errors.LogDebugInner(context.Background(), err, "no ECH config of ", c.EchQueryDomain)
+			case c.EchForceQuery:
+				errors.LogErrorInner(context.Background(), err, "failed to query ECH config of ", c.EchQueryDomain, ", refusing to connect without ECH")
+			default:
+				errors.LogWarningInner(context.Background(), err, "failed to query ECH config of ", c.EchQueryDomain, ", connecting without ECH")
+			}
+		}
+	}
+	if len(echConfigList) > 0 {
+		config.EncryptedClientHelloConfigList = echConfigList
+		if config.MinVersion < tls.VersionTLS13 {
+			config.MinVersion = tls.VersionTLS13
+		}
+	}
+
+	if config.EncryptedClientHelloKeys != nil || config.EncryptedClientHelloConfigList != nil {
+		isClient := config.EncryptedClientHelloConfigList != nil
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			switch {
+			case cs.ECHAccepted:
+				errors.LogInfo(context.Background(), "ECH accepted for ", cs.ServerName)
+			case isClient:
+				errors.LogWarning(context.Background(), "ECH rejected by ", cs.ServerName)
+			default:
+				errors.LogDebug(context.Background(), "ECH not used for ", cs.ServerName)
+			}
+			return nil
+		}
+	}
+}
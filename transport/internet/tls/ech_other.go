@@ -0,0 +1,17 @@
+//go:build !go1.24
+// +build !go1.24
+
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+)
+
+func (c *Config) applyECH(config *tls.Config) {
+	if len(c.EchServerKeys) > 0 || len(c.EchConfigList) > 0 || c.EchQueryDomain != "" {
+		errors.LogError(context.Background(), "ECH requires Xray to be built with Go 1.24 or later, ignoring ECH settings")
+	}
+}
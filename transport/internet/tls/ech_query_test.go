@@ -0,0 +1,60 @@
+//go:build go1.24
+// +build go1.24
+
+package tls
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryECHConfig(t *testing.T) {
+	defer func(lookup func(string) ([]byte, time.Duration, error)) {
+		lookupECHConfig = lookup
+	}(lookupECHConfig)
+
+	queries := 0
+	var result []byte
+	var resultErr error
+	lookupECHConfig = func(domain string) ([]byte, time.Duration, error) {
+		queries++
+		return result, time.Hour, resultErr
+	}
+
+	// a failure is cached and reported once
+	resultErr = errors.New("no DOH server")
+	for i := 0; i < 3; i++ {
+		_, failed, err := queryECHConfig("failed.example.com")
+		if err == nil || failed != (i == 0) {
+			t.Fatal("unexpected failure ", failed, " of query ", i, ": ", err)
+		}
+	}
+	if queries != 1 {
+		t.Fatal("expected 1 query of a failing domain, but got ", queries)
+	}
+
+	// a config is cached by the TTL
+	result, resultErr = []byte{1, 2}, nil
+	for i := 0; i < 3; i++ {
+		config, _, err := queryECHConfig("ok.example.com")
+		if err != nil || !bytes.Equal(config, result) {
+			t.Fatal("unexpected config ", config, ": ", err)
+		}
+	}
+	if queries != 2 {
+		t.Fatal("expected 1 query of a domain with a config, but got ", queries-1)
+	}
+
+	// the query is sent again once expired
+	echCacheAccess.Lock()
+	echCache["failed.example.com"].expire = time.Now()
+	echCacheAccess.Unlock()
+	if config, failed, err := queryECHConfig("failed.example.com"); err != nil || failed || !bytes.Equal(config, result) {
+		t.Fatal("unexpected config ", config, " after expired: ", failed, err)
+	}
+	if queries != 3 {
+		t.Fatal("expected a new query once expired, but got ", queries)
+	}
+}
@@ -0,0 +1,67 @@
+//go:build go1.24
+// +build go1.24
+
+package tls_test
+
+import (
+	gotls "crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/OmarTariq612/goech"
+	"github.com/cloudflare/circl/hpke"
+	"github.com/luckyluke-a/xray-core/common"
+	"github.com/luckyluke-a/xray-core/common/protocol/tls/cert"
+	. "github.com/luckyluke-a/xray-core/transport/internet/tls"
+)
+
+func TestECHHandshake(t *testing.T) {
+	oldKey, err := goech.GenerateECHKeySet(0, "public.example.com", hpke.KEM_X25519_HKDF_SHA256)
+	common.Must(err)
+	newKey, err := goech.GenerateECHKeySet(1, "public.example.com", hpke.KEM_X25519_HKDF_SHA256)
+	common.Must(err)
+	newKeyBytes, err := newKey.MarshalBinary()
+	common.Must(err)
+	oldKeyBytes, err := oldKey.MarshalBinary()
+	common.Must(err)
+
+	keys, err := ParseECHKeys([][]byte{newKeyBytes, oldKeyBytes})
+	common.Must(err)
+	if len(keys) != 2 || !keys[0].SendAsRetry || keys[1].SendAsRetry {
+		t.Fatal("unexpected keys: ", keys)
+	}
+
+	serverConfig := (&Config{
+		Certificate:   []*Certificate{ParseCertificate(cert.MustGenerate(nil, cert.CommonName("www.example.com"), cert.DNSNames("www.example.com")))},
+		EchServerKeys: [][]byte{newKeyBytes, oldKeyBytes},
+	}).GetTLSConfig()
+
+	// A client still holding the old config is accepted
+	oldConfigList, err := oldKey.ECHConfig.MarshalBinary()
+	common.Must(err)
+	clientConfig := (&Config{
+		ServerName:    "www.example.com",
+		AllowInsecure: true,
+		EchConfigList: oldConfigList,
+	}).GetTLSConfig()
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	errc := make(chan error, 1)
+	server := gotls.Server(serverConn, serverConfig)
+	go func() {
+		errc <- server.Handshake()
+	}()
+	client := gotls.Client(clientConn, clientConfig)
+	common.Must(client.Handshake())
+	common.Must(<-errc)
+
+	if !client.ConnectionState().ECHAccepted {
+		t.Error("ECH is not accepted by client")
+	}
+	if !server.ConnectionState().ECHAccepted {
+		t.Error("ECH is not accepted by server")
+	}
+}
@@ -47,6 +47,15 @@ func init() {
 func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig, ed []byte) (net.Conn, error) {
 	wsSettings := streamSettings.ProtocolSettings.(*Config)
 
+	if !browser_dialer.HasBrowserDialer() {
+		switch extendedConnectProto(tls.ConfigFromStreamSettings(streamSettings)) {
+		case "h2":
+			return dialH2(ctx, dest, streamSettings, ed)
+		case "h3":
+			return dialH3(ctx, dest, streamSettings, ed)
+		}
+	}
+
 	dialer := &websocket.Dialer{
 		NetDial: func(network, addr string) (net.Conn, error) {
 			return internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
@@ -0,0 +1,329 @@
+package websocket
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"encoding/base64"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"github.com/luckyluke-a/xray-core/common/signal/done"
+	"github.com/luckyluke-a/xray-core/transport/internet"
+	"github.com/luckyluke-a/xray-core/transport/internet/tls"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2/hpack"
+)
+
+// WebSocket over HTTP/2 (RFC 8441) and HTTP/3 (RFC 9220) is bootstrapped by an
+// extended CONNECT request, so that many WebSocket streams share a connection.
+
+type dialerConf struct {
+	net.Destination
+	*internet.MemoryStreamConfig
+}
+
+type h2Client struct {
+	sync.Mutex
+	conn *h2Conn
+}
+
+var (
+	globalH2Clients    map[dialerConf]*h2Client
+	globalH3Clients    map[dialerConf]*http3.RoundTripper
+	globalDialerAccess sync.Mutex
+)
+
+// extendedConnectProto returns the HTTP version to carry WebSocket with, which
+// is picked by ALPN like splithttp does. An empty string means HTTP/1.1.
+func extendedConnectProto(config *tls.Config) string {
+	if config == nil || len(config.NextProtocol) != 1 {
+		return ""
+	}
+	switch p := config.NextProtocol[0]; p {
+	case "h2", "h3":
+		return p
+	}
+	return ""
+}
+
+func extendedConnectHeader(wsSettings *Config, ed []byte) http.Header {
+	header := wsSettings.GetRequestHeader()
+	header.Set("Sec-WebSocket-Version", "13")
+	if ed != nil {
+		header.Set("Sec-WebSocket-Protocol", base64.RawURLEncoding.EncodeToString(ed))
+	}
+	return header
+}
+
+func extendedConnectAuthority(wsSettings *Config, dest net.Destination) string {
+	if wsSettings.Host != "" {
+		return wsSettings.Host
+	}
+	if dest.Port == 443 {
+		return dest.Address.String()
+	}
+	return dest.NetAddr()
+}
+
+func getH2Conn(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (*h2Conn, error) {
+	key := dialerConf{dest, streamSettings}
+	globalDialerAccess.Lock()
+	if globalH2Clients == nil {
+		globalH2Clients = make(map[dialerConf]*h2Client)
+	}
+	client, found := globalH2Clients[key]
+	if !found {
+		client = &h2Client{}
+		globalH2Clients[key] = client
+	}
+	globalDialerAccess.Unlock()
+
+	client.Lock()
+	defer client.Unlock()
+	if client.conn != nil && client.conn.usable() {
+		return client.conn, nil
+	}
+
+	config := tls.ConfigFromStreamSettings(streamSettings)
+	tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
+	pconn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	if err != nil {
+		return nil, err
+	}
+	var cn tls.Interface
+	if fingerprint := tls.GetFingerprint(config.Fingerprint); fingerprint != nil {
+		cn = tls.UClient(pconn, tlsConfig, fingerprint).(*tls.UConn)
+	} else {
+		cn = tls.Client(pconn, tlsConfig).(*tls.Conn)
+	}
+	if err := cn.HandshakeContext(ctx); err != nil {
+		pconn.Close()
+		return nil, err
+	}
+	if !tlsConfig.InsecureSkipVerify {
+		if err := cn.VerifyHostname(tlsConfig.ServerName); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if p := cn.NegotiatedProtocol(); p != "h2" {
+		cn.Close()
+		return nil, errors.New("unexpected ALPN ", p, " for WebSocket over HTTP/2")
+	}
+
+	if client.conn, err = newH2Conn(cn, false, nil); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	return client.conn, nil
+}
+
+func dialH2(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig, ed []byte) (net.Conn, error) {
+	wsSettings := streamSettings.ProtocolSettings.(*Config)
+	conn, err := getH2Conn(ctx, dest, streamSettings)
+	if err != nil {
+		return nil, errors.New("failed to dial HTTP/2 connection to ", dest).Base(err)
+	}
+
+	fields := []hpack.HeaderField{
+		{Name: ":method", Value: http.MethodConnect},
+		{Name: ":protocol", Value: "websocket"},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":authority", Value: extendedConnectAuthority(wsSettings, dest)},
+		{Name: ":path", Value: wsSettings.GetNormalizedPath()},
+	}
+	header := extendedConnectHeader(wsSettings, ed)
+	header.Del("Host")
+	for k, vs := range header {
+		k = strings.ToLower(k)
+		for _, v := range vs {
+			fields = append(fields, hpack.HeaderField{Name: k, Value: v})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*8)
+	defer cancel()
+	stream, err := conn.openStream(ctx, fields)
+	if err != nil {
+		return nil, errors.New("failed to open WebSocket stream to ", dest).Base(err)
+	}
+	return newFrameConn(stream, false, conn.conn.LocalAddr(), conn.conn.RemoteAddr(), nil), nil
+}
+
+func getH3RoundTripper(dest net.Destination, streamSettings *internet.MemoryStreamConfig) *http3.RoundTripper {
+	key := dialerConf{dest, streamSettings}
+	globalDialerAccess.Lock()
+	defer globalDialerAccess.Unlock()
+	if globalH3Clients == nil {
+		globalH3Clients = make(map[dialerConf]*http3.RoundTripper)
+	}
+	if roundTripper, found := globalH3Clients[key]; found {
+		return roundTripper
+	}
+
+	roundTripper := &http3.RoundTripper{
+		QUICConfig: &quic.Config{
+			MaxIdleTimeout:     300 * time.Second,
+			MaxIncomingStreams: -1,
+			KeepAlivePeriod:    10 * time.Second,
+		},
+		TLSClientConfig: tls.ConfigFromStreamSettings(streamSettings).GetTLSConfig(tls.WithDestination(dest)),
+		Dial: func(ctx context.Context, addr string, tlsCfg *gotls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			conn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+			if err != nil {
+				return nil, err
+			}
+
+			var udpConn net.PacketConn
+			var udpAddr *net.UDPAddr
+
+			switch c := conn.(type) {
+			case *internet.PacketConnWrapper:
+				var ok bool
+				udpConn, ok = c.Conn.(*net.UDPConn)
+				if !ok {
+					return nil, errors.New("PacketConnWrapper does not contain a UDP connection")
+				}
+				udpAddr, err = net.ResolveUDPAddr("udp", c.Dest.String())
+			case *net.UDPConn:
+				udpConn = c
+				udpAddr, err = net.ResolveUDPAddr("udp", c.RemoteAddr().String())
+			default:
+				udpConn = &internet.FakePacketConn{Conn: c}
+				udpAddr, err = net.ResolveUDPAddr("udp", c.RemoteAddr().String())
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			return quic.DialEarly(ctx, udpConn, udpAddr, tlsCfg, cfg)
+		},
+	}
+	globalH3Clients[key] = roundTripper
+	return roundTripper
+}
+
+func dialH3(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig, ed []byte) (net.Conn, error) {
+	wsSettings := streamSettings.ProtocolSettings.(*Config)
+	dest.Network = net.Network_UDP
+	roundTripper := getH3RoundTripper(dest, streamSettings)
+
+	// the stream is canceled with the context of the request, so it must
+	// outlive the dialing
+	streamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	reader, writer := io.Pipe()
+	request, err := http.NewRequestWithContext(streamCtx, http.MethodConnect, "https://"+extendedConnectAuthority(wsSettings, dest)+wsSettings.GetNormalizedPath(), reader)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	request.Proto = "websocket"
+	request.Header = extendedConnectHeader(wsSettings, ed)
+	request.Header.Del("Host")
+
+	dialCtx, dialCancel := context.WithTimeout(ctx, time.Second*8)
+	defer dialCancel()
+	stop := context.AfterFunc(dialCtx, cancel)
+	response, err := roundTripper.RoundTrip(request)
+	if !stop() {
+		err = errors.New("timeout").Base(dialCtx.Err())
+	}
+	if err != nil {
+		cancel()
+		return nil, errors.New("failed to open WebSocket stream to ", dest).Base(err)
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		cancel()
+		return nil, errors.New("unexpected status ", response.Status)
+	}
+
+	stream := &clientStream{
+		ReadCloser: response.Body,
+		writer:     writer,
+		cancel:     cancel,
+	}
+	return newFrameConn(stream, false, &net.UDPAddr{}, &net.UDPAddr{}, nil), nil
+}
+
+// clientStream is the stream of an HTTP/3 extended CONNECT request.
+type clientStream struct {
+	io.ReadCloser
+	writer *io.PipeWriter
+	cancel context.CancelFunc
+}
+
+func (s *clientStream) Write(b []byte) (int, error) {
+	return s.writer.Write(b)
+}
+
+func (s *clientStream) Close() error {
+	s.writer.Close()
+	s.ReadCloser.Close()
+	s.cancel()
+	return nil
+}
+
+// serverStream is the stream of an extended CONNECT request, which lives until
+// it is closed or the request is canceled.
+type serverStream struct {
+	reader     io.Reader
+	writer     http.ResponseWriter
+	controller *http.ResponseController
+	access     sync.Mutex
+	closed     bool
+	done       *done.Instance
+}
+
+func newServerStream(writer http.ResponseWriter, request *http.Request) *serverStream {
+	return &serverStream{
+		reader:     request.Body,
+		writer:     writer,
+		controller: http.NewResponseController(writer),
+		done:       done.New(),
+	}
+}
+
+func (s *serverStream) Read(b []byte) (int, error) {
+	return s.reader.Read(b)
+}
+
+func (s *serverStream) Write(b []byte) (int, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := s.writer.Write(b)
+	if err == nil {
+		err = s.controller.Flush()
+	}
+	return n, err
+}
+
+func (s *serverStream) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.closed = true
+	return s.done.Close()
+}
+
+func (s *serverStream) SetReadDeadline(t time.Time) error {
+	if err := s.controller.SetReadDeadline(t); err != nil && !goerrors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// isExtendedConnect returns whether the request bootstraps WebSocket over
+// HTTP/2 or HTTP/3, where the :protocol pseudo-header is in Proto.
+func isExtendedConnect(request *http.Request) bool {
+	return request.Method == http.MethodConnect && request.Proto == "websocket"
+}
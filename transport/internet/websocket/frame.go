@@ -0,0 +1,241 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/buf"
+	"github.com/luckyluke-a/xray-core/common/errors"
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	finalBit = 0x80
+	maskBit  = 0x80
+)
+
+var _ buf.Writer = (*frameConn)(nil)
+
+// frameConn carries WebSocket frames (RFC 6455) over the stream of an extended
+// CONNECT request. The stream is ready for frames once the request is
+// accepted, so there is no handshake here.
+type frameConn struct {
+	stream     io.ReadWriteCloser
+	reader     *bufio.Reader
+	extra      io.Reader
+	isServer   bool
+	localAddr  net.Addr
+	remoteAddr net.Addr
+
+	// state of the data frame being read
+	remaining uint64
+	masked    bool
+	mask      [4]byte
+	maskPos   int
+
+	writeAccess sync.Mutex
+	closeOnce   sync.Once
+}
+
+func newFrameConn(s io.ReadWriteCloser, isServer bool, localAddr, remoteAddr net.Addr, extraReader io.Reader) *frameConn {
+	return &frameConn{
+		stream:     s,
+		reader:     bufio.NewReader(s),
+		extra:      extraReader,
+		isServer:   isServer,
+		localAddr:  localAddr,
+		remoteAddr: remoteAddr,
+	}
+}
+
+// Read implements net.Conn.Read()
+func (c *frameConn) Read(b []byte) (int, error) {
+	if c.extra != nil {
+		n, err := c.extra.Read(b)
+		if err == io.EOF {
+			c.extra = nil
+			if n == 0 {
+				return c.Read(b)
+			}
+			err = nil
+		}
+		return n, err
+	}
+	for c.remaining == 0 {
+		if err := c.nextFrame(); err != nil {
+			return 0, err
+		}
+	}
+	if uint64(len(b)) > c.remaining {
+		b = b[:c.remaining]
+	}
+	n, err := c.reader.Read(b)
+	if c.masked {
+		for i := range b[:n] {
+			b[i] ^= c.mask[c.maskPos&3]
+			c.maskPos++
+		}
+	}
+	c.remaining -= uint64(n)
+	return n, err
+}
+
+// nextFrame reads the header of the next frame, and handles control frames.
+func (c *frameConn) nextFrame() error {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return err
+	}
+	opcode := header[0] & 0x0f
+	c.masked = header[1]&maskBit != 0
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.reader, b[:]); err != nil {
+			return err
+		}
+		length = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.reader, b[:]); err != nil {
+			return err
+		}
+		length = binary.BigEndian.Uint64(b[:])
+	}
+	if c.masked {
+		if _, err := io.ReadFull(c.reader, c.mask[:]); err != nil {
+			return err
+		}
+		c.maskPos = 0
+	}
+
+	switch opcode {
+	case opContinuation, opText, opBinary:
+		c.remaining = length
+		return nil
+	case opClose, opPing, opPong:
+		if length > 125 {
+			return errors.New("WebSocket control frame too long: ", length)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return err
+		}
+		if c.masked {
+			for i := range payload {
+				payload[i] ^= c.mask[i&3]
+			}
+		}
+		switch opcode {
+		case opPing:
+			return c.writeFrame(opPong, payload)
+		case opClose:
+			c.closeOnce.Do(func() {
+				c.writeFrame(opClose, nil)
+			})
+			return io.EOF
+		}
+		return nil
+	default:
+		return errors.New("unknown WebSocket opcode: ", opcode)
+	}
+}
+
+func (c *frameConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, finalBit|opcode)
+	var lengthMask byte
+	if !c.isServer {
+		lengthMask = maskBit
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, lengthMask|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, lengthMask|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, lengthMask|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	if c.isServer {
+		frame = append(frame, payload...)
+	} else {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range frame[start:] {
+			frame[start+i] ^= mask[i&3]
+		}
+	}
+
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+	_, err := c.stream.Write(frame)
+	return err
+}
+
+// Write implements io.Writer.
+func (c *frameConn) Write(b []byte) (int, error) {
+	if err := c.writeFrame(opBinary, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *frameConn) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	mb = buf.Compact(mb)
+	mb, err := buf.WriteMultiBuffer(c, mb)
+	buf.ReleaseMulti(mb)
+	return err
+}
+
+func (c *frameConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.writeFrame(opClose, nil)
+	})
+	return c.stream.Close()
+}
+
+func (c *frameConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *frameConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *frameConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *frameConn) SetReadDeadline(t time.Time) error {
+	if s, ok := c.stream.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return s.SetReadDeadline(t)
+	}
+	return nil
+}
+
+func (c *frameConn) SetWriteDeadline(t time.Time) error {
+	if s, ok := c.stream.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return s.SetWriteDeadline(t)
+	}
+	return nil
+}
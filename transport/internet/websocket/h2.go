@@ -0,0 +1,1020 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	gotls "crypto/tls"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luckyluke-a/xray-core/common/errors"
+	"github.com/luckyluke-a/xray-core/common/net"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// This is a minimal HTTP/2 implementation, for WebSocket over extended
+// CONNECT (RFC 8441), which golang.org/x/net/http2 doesn't support.
+
+const (
+	settingEnableConnectProtocol http2.SettingID = 0x8
+
+	h2DefaultWindow     = 65535
+	h2MaxWindow         = 1<<31 - 1
+	h2StreamWindow      = 1 << 20
+	h2ConnWindow        = 1 << 24
+	h2MaxStreams        = 250
+	h2MaxHeaderListSize = 1 << 16
+	// CONTINUATION frames of a header block beyond this size are rejected,
+	// even if the fields decoded from them are small
+	h2MaxHeaderBlockSize = 4 * h2MaxHeaderListSize
+)
+
+var (
+	// h2PrefaceTimeout limits the time of the client to send the preface
+	h2PrefaceTimeout = time.Second * 4
+	// h2IdleTimeout is the time the server keeps a connection without streams
+	h2IdleTimeout = time.Second * 300
+)
+
+var errH2Closed = errors.New("HTTP/2 connection closed")
+
+// h2Conn is an HTTP/2 connection of either side. Streams are opened by the
+// client with openStream, and handed to handler on the server.
+type h2Conn struct {
+	conn     net.Conn
+	framer   *http2.Framer
+	isServer bool
+	handler  func(*h2Stream)
+
+	writeAccess sync.Mutex
+	encoder     *hpack.Encoder
+	encoderBuf  bytes.Buffer
+
+	// used by the read loop only
+	decoder         *hpack.Decoder
+	headerStreamID  uint32
+	headerFields    []hpack.HeaderField
+	headerListSize  uint32
+	headerBlockSize int
+	headerEndStream bool
+
+	access            sync.Mutex
+	cond              *sync.Cond
+	streams           map[uint32]*h2Stream
+	nextStreamID      uint32
+	lastStreamID      uint32
+	sendWindow        int64
+	recvWindow        int64
+	unacked           uint32
+	peerInitialWindow int64
+	peerMaxFrameSize  uint32
+	peerMaxStreams    uint32
+	idleTimeout       time.Duration
+	idleTimer         *time.Timer
+	extendedConnect   bool
+	goAway            bool
+	err               error
+
+	settings     chan struct{}
+	settingsOnce sync.Once
+	done         chan struct{}
+}
+
+func newH2Conn(conn net.Conn, isServer bool, handler func(*h2Stream)) (*h2Conn, error) {
+	c := &h2Conn{
+		conn:              conn,
+		framer:            http2.NewFramer(conn, conn),
+		isServer:          isServer,
+		handler:           handler,
+		streams:           make(map[uint32]*h2Stream),
+		nextStreamID:      1,
+		sendWindow:        h2DefaultWindow,
+		recvWindow:        h2ConnWindow,
+		peerInitialWindow: h2DefaultWindow,
+		peerMaxFrameSize:  16384,
+		peerMaxStreams:    h2MaxStreams,
+		settings:          make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+	c.cond = sync.NewCond(&c.access)
+	c.encoder = hpack.NewEncoder(&c.encoderBuf)
+	c.decoder = hpack.NewDecoder(4096, c.emitHeaderField)
+	c.decoder.SetMaxStringLength(h2MaxHeaderListSize)
+
+	settings := []http2.Setting{
+		{ID: http2.SettingInitialWindowSize, Val: h2StreamWindow},
+		{ID: http2.SettingMaxHeaderListSize, Val: h2MaxHeaderListSize},
+	}
+	if isServer {
+		conn.SetReadDeadline(time.Now().Add(h2PrefaceTimeout))
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(conn, preface); err != nil {
+			return nil, err
+		}
+		if string(preface) != http2.ClientPreface {
+			return nil, errors.New("invalid HTTP/2 preface")
+		}
+		conn.SetReadDeadline(time.Time{})
+		settings = append(settings,
+			http2.Setting{ID: http2.SettingMaxConcurrentStreams, Val: h2MaxStreams},
+			http2.Setting{ID: settingEnableConnectProtocol, Val: 1},
+		)
+	} else {
+		if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+			return nil, err
+		}
+		settings = append(settings, http2.Setting{ID: http2.SettingEnablePush, Val: 0})
+	}
+	if err := c.framer.WriteSettings(settings...); err != nil {
+		return nil, err
+	}
+	if err := c.framer.WriteWindowUpdate(0, h2ConnWindow-h2DefaultWindow); err != nil {
+		return nil, err
+	}
+
+	if isServer {
+		c.idleTimeout = h2IdleTimeout
+		c.idleTimer = time.AfterFunc(c.idleTimeout, c.closeIdle)
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *h2Conn) writeFrame(write func(*http2.Framer) error) error {
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+	return write(c.framer)
+}
+
+// writeHeaders must be called with writeAccess held.
+func (c *h2Conn) writeHeaders(streamID uint32, fields []hpack.HeaderField, endStream bool) error {
+	c.encoderBuf.Reset()
+	for _, field := range fields {
+		if err := c.encoder.WriteField(field); err != nil {
+			return err
+		}
+	}
+	c.access.Lock()
+	maxFrameSize := int(c.peerMaxFrameSize)
+	c.access.Unlock()
+
+	block := c.encoderBuf.Bytes()
+	first := true
+	for first || len(block) > 0 {
+		fragment := block
+		if len(fragment) > maxFrameSize {
+			fragment = fragment[:maxFrameSize]
+		}
+		block = block[len(fragment):]
+		var err error
+		if first {
+			err = c.framer.WriteHeaders(http2.HeadersFrameParam{
+				StreamID:      streamID,
+				BlockFragment: fragment,
+				EndStream:     endStream,
+				EndHeaders:    len(block) == 0,
+			})
+			first = false
+		} else {
+			err = c.framer.WriteContinuation(streamID, len(block) == 0, fragment)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *h2Conn) readLoop() {
+	var err error
+	for {
+		var frame http2.Frame
+		frame, err = c.framer.ReadFrame()
+		if err == nil {
+			err = c.processFrame(frame)
+		}
+		var streamErr http2.StreamError
+		if goerrors.As(err, &streamErr) {
+			err = c.resetStream(streamErr)
+		}
+		if err != nil {
+			break
+		}
+	}
+	var connErr http2.ConnectionError
+	if goerrors.As(err, &connErr) {
+		c.goAwayWithError(http2.ErrCode(connErr), err)
+		return
+	}
+	c.closeWithError(err)
+}
+
+// goAwayWithError tells the peer the error of the connection, and closes it.
+func (c *h2Conn) goAwayWithError(code http2.ErrCode, err error) {
+	c.access.Lock()
+	lastStreamID := c.lastStreamID
+	c.access.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.writeFrame(func(framer *http2.Framer) error {
+		return framer.WriteGoAway(lastStreamID, code, nil)
+	})
+	c.closeWithError(err)
+}
+
+// resetStream resets the stream for an error of the peer on it.
+func (c *h2Conn) resetStream(err http2.StreamError) error {
+	c.access.Lock()
+	var update uint32
+	if s := c.streams[err.StreamID]; s != nil {
+		update = c.resetLocked(s, err)
+	}
+	c.access.Unlock()
+	return c.writeFrame(func(framer *http2.Framer) error {
+		if update > 0 {
+			if err := framer.WriteWindowUpdate(0, update); err != nil {
+				return err
+			}
+		}
+		return framer.WriteRSTStream(err.StreamID, err.Code)
+	})
+}
+
+// closeIdle closes the connection of the server if no streams are opened in
+// h2IdleTimeout.
+func (c *h2Conn) closeIdle() {
+	c.access.Lock()
+	idle := len(c.streams) == 0 && c.err == nil
+	c.access.Unlock()
+	if idle {
+		c.goAwayWithError(http2.ErrCodeNo, errors.New("HTTP/2 connection idle timeout"))
+	}
+}
+
+func (c *h2Conn) emitHeaderField(field hpack.HeaderField) {
+	c.headerListSize += field.Size()
+	if c.headerListSize <= h2MaxHeaderListSize {
+		c.headerFields = append(c.headerFields, field)
+	}
+}
+
+// decodeHeaders decodes a fragment of the header block as it arrives, so that
+// the memory is limited by the size of the decoded fields.
+func (c *h2Conn) decodeHeaders(fragment []byte, ended bool) error {
+	c.headerBlockSize += len(fragment)
+	if c.headerBlockSize > h2MaxHeaderBlockSize {
+		return http2.ConnectionError(http2.ErrCodeEnhanceYourCalm)
+	}
+	if _, err := c.decoder.Write(fragment); err != nil {
+		return http2.ConnectionError(http2.ErrCodeCompression)
+	}
+	if !ended {
+		return nil
+	}
+	if err := c.decoder.Close(); err != nil {
+		return http2.ConnectionError(http2.ErrCodeCompression)
+	}
+	if c.headerListSize > h2MaxHeaderListSize {
+		// more than advertised in SETTINGS_MAX_HEADER_LIST_SIZE
+		return http2.ConnectionError(http2.ErrCodeEnhanceYourCalm)
+	}
+	fields := c.headerFields
+	c.headerFields, c.headerListSize, c.headerBlockSize = nil, 0, 0
+	return c.processHeaders(c.headerStreamID, fields)
+}
+
+// validSetting checks the value of a setting of the peer.
+func validSetting(s http2.Setting) error {
+	if s.ID == settingEnableConnectProtocol && s.Val > 1 {
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+	return s.Valid()
+}
+
+// idleLocked returns whether the stream hasn't been opened yet.
+func (c *h2Conn) idleLocked(id uint32) bool {
+	if (id%2 == 1) == c.isServer {
+		// opened by the peer
+		return id > c.lastStreamID
+	}
+	return id >= c.nextStreamID
+}
+
+func (c *h2Conn) processFrame(frame http2.Frame) error {
+	switch f := frame.(type) {
+	case *http2.SettingsFrame:
+		if f.IsAck() {
+			return nil
+		}
+		if err := f.ForeachSetting(validSetting); err != nil {
+			return err
+		}
+		c.access.Lock()
+		err := f.ForeachSetting(func(s http2.Setting) error {
+			switch s.ID {
+			case http2.SettingMaxConcurrentStreams:
+				c.peerMaxStreams = s.Val
+			case http2.SettingInitialWindowSize:
+				delta := int64(s.Val) - c.peerInitialWindow
+				c.peerInitialWindow = int64(s.Val)
+				for _, stream := range c.streams {
+					if stream.sendWindow+delta > h2MaxWindow {
+						return http2.ConnectionError(http2.ErrCodeFlowControl)
+					}
+					stream.sendWindow += delta
+				}
+			case http2.SettingMaxFrameSize:
+				c.peerMaxFrameSize = s.Val
+			case settingEnableConnectProtocol:
+				c.extendedConnect = s.Val == 1
+			}
+			return nil
+		})
+		c.cond.Broadcast()
+		c.access.Unlock()
+		if err != nil {
+			return err
+		}
+		c.settingsOnce.Do(func() {
+			close(c.settings)
+		})
+		return c.writeFrame(func(framer *http2.Framer) error {
+			return framer.WriteSettingsAck()
+		})
+	case *http2.HeadersFrame:
+		// the framer makes sure that CONTINUATION frames follow on the stream
+		c.headerStreamID = f.StreamID
+		c.headerEndStream = f.StreamEnded()
+		return c.decodeHeaders(f.HeaderBlockFragment(), f.HeadersEnded())
+	case *http2.ContinuationFrame:
+		return c.decodeHeaders(f.HeaderBlockFragment(), f.HeadersEnded())
+	case *http2.DataFrame:
+		length := f.Header().Length
+		c.access.Lock()
+		if int64(length) > c.recvWindow {
+			c.access.Unlock()
+			return http2.ConnectionError(http2.ErrCodeFlowControl)
+		}
+		c.recvWindow -= int64(length)
+		s := c.streams[f.StreamID]
+		if s == nil && c.idleLocked(f.StreamID) {
+			c.access.Unlock()
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		var update uint32
+		var streamErr error
+		if s != nil && !s.remoteClosed {
+			if int64(length) > s.recvWindow {
+				c.access.Unlock()
+				return http2.ConnectionError(http2.ErrCodeFlowControl)
+			}
+			s.recvWindow -= int64(length)
+			s.buffer.Write(f.Data())
+			// the padding is given back at once, and the data once it is read
+			padding := length - uint32(len(f.Data()))
+			s.unacked += padding
+			update = c.consumedLocked(padding)
+			if f.StreamEnded() {
+				s.remoteClosed = true
+			}
+			c.cond.Broadcast()
+		} else {
+			// the stream is closed, but the data still counts for the connection
+			update = c.consumedLocked(length)
+			streamErr = http2.StreamError{StreamID: f.StreamID, Code: http2.ErrCodeStreamClosed}
+		}
+		c.access.Unlock()
+		if update > 0 {
+			if err := c.writeFrame(func(framer *http2.Framer) error {
+				return framer.WriteWindowUpdate(0, update)
+			}); err != nil {
+				return err
+			}
+		}
+		return streamErr
+	case *http2.WindowUpdateFrame:
+		c.access.Lock()
+		defer c.access.Unlock()
+		increment := int64(f.Increment)
+		if f.StreamID == 0 {
+			if c.sendWindow+increment > h2MaxWindow {
+				return http2.ConnectionError(http2.ErrCodeFlowControl)
+			}
+			c.sendWindow += increment
+		} else if s := c.streams[f.StreamID]; s != nil {
+			if s.sendWindow+increment > h2MaxWindow {
+				return http2.StreamError{StreamID: f.StreamID, Code: http2.ErrCodeFlowControl}
+			}
+			s.sendWindow += increment
+		} else if c.idleLocked(f.StreamID) {
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		c.cond.Broadcast()
+	case *http2.RSTStreamFrame:
+		c.access.Lock()
+		var update uint32
+		if s := c.streams[f.StreamID]; s != nil {
+			update = c.resetLocked(s, errors.New("HTTP/2 stream reset: ", f.ErrCode))
+		} else if c.idleLocked(f.StreamID) {
+			c.access.Unlock()
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		c.access.Unlock()
+		if update > 0 {
+			return c.writeFrame(func(framer *http2.Framer) error {
+				return framer.WriteWindowUpdate(0, update)
+			})
+		}
+	case *http2.PingFrame:
+		if !f.IsAck() {
+			return c.writeFrame(func(framer *http2.Framer) error {
+				return framer.WritePing(true, f.Data)
+			})
+		}
+	case *http2.GoAwayFrame:
+		c.access.Lock()
+		c.goAway = true
+		c.access.Unlock()
+	}
+	return nil
+}
+
+func (c *h2Conn) processHeaders(streamID uint32, fields []hpack.HeaderField) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	s := c.streams[streamID]
+	if s == nil {
+		if !c.isServer || streamID%2 == 0 || streamID <= c.lastStreamID || c.err != nil {
+			return nil
+		}
+		c.lastStreamID = streamID
+		if len(c.streams) >= h2MaxStreams {
+			c.access.Unlock()
+			err := c.writeFrame(func(framer *http2.Framer) error {
+				return framer.WriteRSTStream(streamID, http2.ErrCodeRefusedStream)
+			})
+			c.access.Lock()
+			return err
+		}
+		s = c.newStreamLocked(streamID)
+		s.header = fields
+		s.remoteClosed = c.headerEndStream
+		go c.handler(s)
+		return nil
+	}
+	if s.header == nil {
+		if status := headerValue(fields, ":status"); len(status) == 3 && status[0] == '1' {
+			// informational response
+			return nil
+		}
+		s.header = fields
+		s.headersOnce.Do(func() {
+			close(s.headers)
+		})
+	}
+	if c.headerEndStream {
+		s.remoteClosed = true
+	}
+	c.cond.Broadcast()
+	return nil
+}
+
+func (c *h2Conn) newStreamLocked(id uint32) *h2Stream {
+	s := &h2Stream{
+		conn:       c,
+		id:         id,
+		headers:    make(chan struct{}),
+		sendWindow: c.peerInitialWindow,
+		recvWindow: h2StreamWindow,
+	}
+	c.streams[id] = s
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+	return s
+}
+
+// resetLocked ends the stream with err, and returns the update of the
+// connection window for its data that won't be read.
+func (c *h2Conn) resetLocked(s *h2Stream, err error) uint32 {
+	s.reset = err
+	update := c.removeStreamLocked(s)
+	s.headersOnce.Do(func() {
+		close(s.headers)
+	})
+	c.cond.Broadcast()
+	return update
+}
+
+// removeStreamLocked removes the stream from the connection, and returns the
+// update of the connection window for its data that won't be read.
+func (c *h2Conn) removeStreamLocked(s *h2Stream) uint32 {
+	if c.streams[s.id] != s {
+		return 0
+	}
+	delete(c.streams, s.id)
+	if len(c.streams) == 0 && c.idleTimer != nil {
+		c.idleTimer.Reset(c.idleTimeout)
+	}
+	unread := uint32(s.buffer.Len())
+	s.buffer.Reset()
+	return c.consumedLocked(unread)
+}
+
+// consumedLocked gives n bytes back to the receive window of the connection,
+// and returns the update to send, which is batched.
+func (c *h2Conn) consumedLocked(n uint32) uint32 {
+	c.unacked += n
+	if c.unacked < h2ConnWindow/4 {
+		return 0
+	}
+	update := c.unacked
+	c.unacked = 0
+	c.recvWindow += int64(update)
+	return update
+}
+
+// openStream sends a request, and returns the stream once the response
+// headers are received.
+func (c *h2Conn) openStream(ctx context.Context, fields []hpack.HeaderField) (*h2Stream, error) {
+	select {
+	case <-c.settings:
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.writeAccess.Lock()
+	c.access.Lock()
+	if c.err != nil || c.goAway {
+		c.access.Unlock()
+		c.writeAccess.Unlock()
+		return nil, errH2Closed
+	}
+	if !c.extendedConnect {
+		c.access.Unlock()
+		c.writeAccess.Unlock()
+		return nil, errors.New("HTTP/2 server doesn't support extended CONNECT")
+	}
+	id := c.nextStreamID
+	c.nextStreamID += 2
+	s := c.newStreamLocked(id)
+	c.access.Unlock()
+	err := c.writeHeaders(id, fields, false)
+	c.writeAccess.Unlock()
+	if err != nil {
+		c.closeWithError(err)
+		return nil, err
+	}
+
+	select {
+	case <-s.headers:
+	case <-ctx.Done():
+		s.Close()
+		return nil, ctx.Err()
+	}
+	c.access.Lock()
+	header, reset, connErr := s.header, s.reset, c.err
+	c.access.Unlock()
+	switch {
+	case header != nil:
+	case reset != nil:
+		return nil, reset
+	default:
+		return nil, connErr
+	}
+	if status := headerValue(header, ":status"); status != "200" {
+		s.Close()
+		return nil, errors.New("unexpected status ", status)
+	}
+	return s, nil
+}
+
+// usable returns whether new streams can be opened.
+func (c *h2Conn) usable() bool {
+	c.access.Lock()
+	defer c.access.Unlock()
+	return c.err == nil && !c.goAway && c.nextStreamID < 1<<31-1 && uint32(len(c.streams)) < c.peerMaxStreams
+}
+
+func (c *h2Conn) closeWithError(err error) {
+	c.access.Lock()
+	if c.err == nil {
+		if err == nil || err == io.EOF {
+			err = errH2Closed
+		}
+		c.err = err
+		close(c.done)
+		if c.idleTimer != nil {
+			c.idleTimer.Stop()
+		}
+		for _, s := range c.streams {
+			s.headersOnce.Do(func() {
+				close(s.headers)
+			})
+		}
+		c.cond.Broadcast()
+	}
+	c.access.Unlock()
+	c.conn.Close()
+}
+
+func (c *h2Conn) Close() error {
+	c.closeWithError(nil)
+	return nil
+}
+
+// h2Stream is an HTTP/2 stream, with the states guarded by conn.access.
+type h2Stream struct {
+	conn        *h2Conn
+	id          uint32
+	header      []hpack.HeaderField
+	headers     chan struct{}
+	headersOnce sync.Once
+
+	buffer       bytes.Buffer
+	unacked      uint32
+	sendWindow   int64
+	recvWindow   int64
+	remoteClosed bool
+	localClosed  bool
+	reset        error
+
+	deadlineTimer      *time.Timer
+	deadlineGeneration int
+	timedOut           bool
+}
+
+func (s *h2Stream) Read(b []byte) (int, error) {
+	c := s.conn
+	c.access.Lock()
+	for s.buffer.Len() == 0 && !s.remoteClosed && s.reset == nil && c.err == nil && !s.timedOut {
+		c.cond.Wait()
+	}
+	if s.buffer.Len() > 0 {
+		n, _ := s.buffer.Read(b)
+		s.unacked += uint32(n)
+		var update uint32
+		if s.unacked >= h2StreamWindow/4 && !s.remoteClosed {
+			update, s.unacked = s.unacked, 0
+			s.recvWindow += int64(update)
+		}
+		connUpdate := c.consumedLocked(uint32(n))
+		c.access.Unlock()
+		if update > 0 || connUpdate > 0 {
+			c.writeFrame(func(framer *http2.Framer) error {
+				if update > 0 {
+					if err := framer.WriteWindowUpdate(s.id, update); err != nil {
+						return err
+					}
+				}
+				if connUpdate > 0 {
+					return framer.WriteWindowUpdate(0, connUpdate)
+				}
+				return nil
+			})
+		}
+		return n, nil
+	}
+	defer c.access.Unlock()
+	switch {
+	case s.reset != nil:
+		return 0, s.reset
+	case s.remoteClosed:
+		return 0, io.EOF
+	case c.err != nil:
+		return 0, c.err
+	default:
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (s *h2Stream) Write(b []byte) (int, error) {
+	c := s.conn
+	written := 0
+	for len(b) > 0 {
+		c.access.Lock()
+		for (s.sendWindow <= 0 || c.sendWindow <= 0) && s.reset == nil && c.err == nil && !s.localClosed {
+			c.cond.Wait()
+		}
+		switch {
+		case s.reset != nil:
+			c.access.Unlock()
+			return written, s.reset
+		case c.err != nil:
+			c.access.Unlock()
+			return written, c.err
+		case s.localClosed:
+			c.access.Unlock()
+			return written, io.ErrClosedPipe
+		}
+		n := int64(len(b))
+		n = min(n, s.sendWindow, c.sendWindow, int64(c.peerMaxFrameSize))
+		s.sendWindow -= n
+		c.sendWindow -= n
+		c.access.Unlock()
+
+		if err := c.writeFrame(func(framer *http2.Framer) error {
+			return framer.WriteData(s.id, false, b[:n])
+		}); err != nil {
+			return written, err
+		}
+		written += int(n)
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// Close ends the stream on this side, and resets it if the peer hasn't ended it.
+func (s *h2Stream) Close() error {
+	c := s.conn
+	c.access.Lock()
+	if s.localClosed {
+		c.access.Unlock()
+		return nil
+	}
+	s.localClosed = true
+	reset, remoteClosed := s.reset != nil, s.remoteClosed
+	update := c.removeStreamLocked(s)
+	if s.deadlineTimer != nil {
+		s.deadlineTimer.Stop()
+	}
+	c.cond.Broadcast()
+	c.access.Unlock()
+	return c.writeFrame(func(framer *http2.Framer) error {
+		if update > 0 {
+			if err := framer.WriteWindowUpdate(0, update); err != nil {
+				return err
+			}
+		}
+		if reset {
+			return nil
+		}
+		if err := framer.WriteData(s.id, true, nil); err != nil {
+			return err
+		}
+		if !remoteClosed {
+			return framer.WriteRSTStream(s.id, http2.ErrCodeCancel)
+		}
+		return nil
+	})
+}
+
+func (s *h2Stream) SetReadDeadline(t time.Time) error {
+	c := s.conn
+	c.access.Lock()
+	defer c.access.Unlock()
+	if s.deadlineTimer != nil {
+		s.deadlineTimer.Stop()
+		s.deadlineTimer = nil
+	}
+	s.deadlineGeneration++
+	s.timedOut = false
+	if t.IsZero() {
+		return nil
+	}
+	d := time.Until(t)
+	if d <= 0 {
+		s.timedOut = true
+		c.cond.Broadcast()
+		return nil
+	}
+	generation := s.deadlineGeneration
+	s.deadlineTimer = time.AfterFunc(d, func() {
+		c.access.Lock()
+		if s.deadlineGeneration == generation {
+			s.timedOut = true
+			c.cond.Broadcast()
+		}
+		c.access.Unlock()
+	})
+	return nil
+}
+
+// serve hands a request stream to handler on the server.
+func (s *h2Stream) serve(handler http.Handler) {
+	request, err := s.request()
+	if err != nil {
+		errors.LogInfoInner(context.Background(), err, "invalid HTTP/2 request")
+		s.Close()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), http.LocalAddrContextKey, s.conn.conn.LocalAddr()))
+	defer cancel()
+	go func() {
+		select {
+		case <-s.conn.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	request = request.WithContext(ctx)
+
+	writer := &h2ResponseWriter{
+		stream: s,
+		header: http.Header{},
+	}
+	handler.ServeHTTP(writer, request)
+	writer.WriteHeader(http.StatusOK)
+	s.Close()
+}
+
+func (s *h2Stream) request() (*http.Request, error) {
+	var method, scheme, authority, path, protocol string
+	header := http.Header{}
+	for _, field := range s.header {
+		switch field.Name {
+		case ":method":
+			method = field.Value
+		case ":scheme":
+			scheme = field.Value
+		case ":authority":
+			authority = field.Value
+		case ":path":
+			path = field.Value
+		case ":protocol":
+			protocol = field.Value
+		default:
+			if strings.HasPrefix(field.Name, ":") {
+				return nil, errors.New("unknown pseudo-header ", field.Name)
+			}
+			header.Add(http.CanonicalHeaderKey(field.Name), field.Value)
+		}
+	}
+	if method == "" || path == "" {
+		return nil, errors.New("missing :method or :path")
+	}
+	u, err := url.ParseRequestURI(path)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = scheme
+	u.Host = authority
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+
+	// like quic-go/http3, the protocol of extended CONNECT is in Proto
+	proto := "HTTP/2.0"
+	if protocol != "" {
+		proto = protocol
+	}
+	request := &http.Request{
+		Method:        method,
+		URL:           u,
+		Proto:         proto,
+		ProtoMajor:    2,
+		Header:        header,
+		Body:          &h2RequestBody{stream: s},
+		ContentLength: -1,
+		Host:          authority,
+		RequestURI:    path,
+		RemoteAddr:    s.conn.conn.RemoteAddr().String(),
+	}
+	if tlsConn, ok := s.conn.conn.(*gotls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		request.TLS = &state
+	}
+	return request, nil
+}
+
+type h2RequestBody struct {
+	stream *h2Stream
+}
+
+func (b *h2RequestBody) Read(p []byte) (int, error) {
+	return b.stream.Read(p)
+}
+
+// Close does nothing, as the stream is closed after the response.
+func (b *h2RequestBody) Close() error {
+	return nil
+}
+
+type h2ResponseWriter struct {
+	stream      *h2Stream
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *h2ResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *h2ResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	fields := []hpack.HeaderField{{Name: ":status", Value: strconv.Itoa(statusCode)}}
+	for k, vs := range w.header {
+		k = strings.ToLower(k)
+		switch k {
+		case "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade":
+			continue
+		}
+		for _, v := range vs {
+			fields = append(fields, hpack.HeaderField{Name: k, Value: v})
+		}
+	}
+	c := w.stream.conn
+	c.writeAccess.Lock()
+	err := c.writeHeaders(w.stream.id, fields, false)
+	c.writeAccess.Unlock()
+	if err != nil {
+		c.closeWithError(err)
+	}
+}
+
+func (w *h2ResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.stream.Write(b)
+}
+
+// SetReadDeadline is used by http.ResponseController.
+func (w *h2ResponseWriter) SetReadDeadline(t time.Time) error {
+	return w.stream.SetReadDeadline(t)
+}
+
+// Flush implements http.Flusher. Nothing is buffered.
+func (w *h2ResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// h2ConnSet is the HTTP/2 connections served by a listener, which are closed
+// with it.
+type h2ConnSet struct {
+	access sync.Mutex
+	conns  map[*h2Conn]struct{}
+	closed bool
+}
+
+func (s *h2ConnSet) add(c *h2Conn) bool {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[*h2Conn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *h2ConnSet) remove(c *h2Conn) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	delete(s.conns, c)
+}
+
+func (s *h2ConnSet) Close() error {
+	s.access.Lock()
+	s.closed = true
+	conns := s.conns
+	s.conns = nil
+	s.access.Unlock()
+	for c := range conns {
+		c.goAwayWithError(http2.ErrCodeNo, errH2Closed)
+	}
+	return nil
+}
+
+// serveH2 serves an HTTP/2 connection until it is closed. The connection is
+// added to conns if it isn't nil.
+func serveH2(conn net.Conn, handler http.Handler, conns *h2ConnSet) {
+	c, err := newH2Conn(conn, true, func(s *h2Stream) {
+		s.serve(handler)
+	})
+	if err != nil {
+		errors.LogInfoInner(context.Background(), err, "failed to serve HTTP/2")
+		conn.Close()
+		return
+	}
+	if conns != nil {
+		if !conns.add(c) {
+			c.Close()
+			return
+		}
+		defer conns.remove(c)
+	}
+	<-c.done
+}
+
+func headerValue(fields []hpack.HeaderField, name string) string {
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return ""
+}
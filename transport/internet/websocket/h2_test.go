@@ -0,0 +1,331 @@
+package websocket
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// h2TestClient is a client sending raw frames to serveH2.
+type h2TestClient struct {
+	t       *testing.T
+	conn    net.Conn
+	framer  *http2.Framer
+	frames  chan http2.Frame
+	encoder *hpack.Encoder
+	buf     bytes.Buffer
+}
+
+func newH2TestClient(t *testing.T, handler http.Handler) *h2TestClient {
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+	})
+	go serveH2(serverConn, handler, nil)
+
+	c := &h2TestClient{
+		t:      t,
+		conn:   clientConn,
+		framer: http2.NewFramer(clientConn, clientConn),
+		frames: make(chan http2.Frame, 16),
+	}
+	c.encoder = hpack.NewEncoder(&c.buf)
+	go func() {
+		defer close(c.frames)
+		for {
+			frame, err := c.framer.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch frame.(type) {
+			case *http2.RSTStreamFrame, *http2.GoAwayFrame:
+				c.frames <- frame
+			}
+		}
+	}()
+	return c
+}
+
+func (c *h2TestClient) handshake() {
+	if _, err := c.conn.Write([]byte(http2.ClientPreface)); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.framer.WriteSettings(); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *h2TestClient) headerBlock(fields ...hpack.HeaderField) []byte {
+	c.buf.Reset()
+	for _, field := range fields {
+		c.encoder.WriteField(field)
+	}
+	return bytes.Clone(c.buf.Bytes())
+}
+
+func (c *h2TestClient) openStream(id uint32) error {
+	return c.framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID: id,
+		BlockFragment: c.headerBlock(
+			hpack.HeaderField{Name: ":method", Value: "GET"},
+			hpack.HeaderField{Name: ":scheme", Value: "https"},
+			hpack.HeaderField{Name: ":authority", Value: "example.com"},
+			hpack.HeaderField{Name: ":path", Value: "/"},
+		),
+		EndHeaders: true,
+	})
+}
+
+// expectFrame returns the first RST_STREAM or GOAWAY frame, or nil if the
+// connection is closed before.
+func (c *h2TestClient) expectFrame() http2.Frame {
+	select {
+	case frame := <-c.frames:
+		return frame
+	case <-time.After(time.Second * 5):
+		c.t.Fatal("timeout waiting for the server")
+		return nil
+	}
+}
+
+func (c *h2TestClient) expectGoAway(code http2.ErrCode) {
+	frame, ok := c.expectFrame().(*http2.GoAwayFrame)
+	if !ok || frame.ErrCode != code {
+		c.t.Fatalf("expected GOAWAY %v, got %v", code, frame)
+	}
+	if _, ok := <-c.frames; ok {
+		c.t.Fatal("connection not closed after GOAWAY")
+	}
+}
+
+func blockingHandler(t *testing.T) http.Handler {
+	release := make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+	})
+	return http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	})
+}
+
+func TestH2ContinuationFlood(t *testing.T) {
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+
+	// never indexed, so that each fragment is large
+	field := hpack.HeaderField{Name: "x-flood", Value: strings.Repeat("a", 1000), Sensitive: true}
+	if err := c.framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: c.headerBlock(field),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			if err := c.framer.WriteContinuation(1, false, c.headerBlock(field)); err != nil {
+				return
+			}
+		}
+	}()
+	c.expectGoAway(http2.ErrCodeEnhanceYourCalm)
+}
+
+func TestH2MaxStreams(t *testing.T) {
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+
+	for i := uint32(0); i < h2MaxStreams; i++ {
+		if err := c.openStream(i*2 + 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refused := uint32(h2MaxStreams*2 + 1)
+	if err := c.openStream(refused); err != nil {
+		t.Fatal(err)
+	}
+	frame, ok := c.expectFrame().(*http2.RSTStreamFrame)
+	if !ok || frame.StreamID != refused || frame.ErrCode != http2.ErrCodeRefusedStream {
+		t.Fatalf("expected RST_STREAM REFUSED_STREAM of stream %d, got %v", refused, frame)
+	}
+}
+
+func TestH2FlowControl(t *testing.T) {
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+
+	if err := c.openStream(1); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 16384)
+	go func() {
+		// the handler doesn't read, so one more frame than the stream
+		// window is a flow control error
+		for i := 0; i <= h2StreamWindow/len(data); i++ {
+			if err := c.framer.WriteData(1, false, data); err != nil {
+				return
+			}
+		}
+	}()
+	c.expectGoAway(http2.ErrCodeFlowControl)
+}
+
+func TestH2PrefaceTimeout(t *testing.T) {
+	defer func(timeout time.Duration) {
+		h2PrefaceTimeout = timeout
+	}(h2PrefaceTimeout)
+	h2PrefaceTimeout = time.Millisecond * 100
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	done := make(chan struct{})
+	go func() {
+		serveH2(serverConn, blockingHandler(t), nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("connection without preface not closed")
+	}
+}
+
+func TestH2IdleTimeout(t *testing.T) {
+	defer func(timeout time.Duration) {
+		h2IdleTimeout = timeout
+	}(h2IdleTimeout)
+	h2IdleTimeout = time.Millisecond * 100
+
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+	c.expectGoAway(http2.ErrCodeNo)
+}
+
+func TestH2InvalidSettings(t *testing.T) {
+	for _, test := range []struct {
+		setting http2.Setting
+		code    http2.ErrCode
+	}{
+		{http2.Setting{ID: http2.SettingMaxFrameSize, Val: 0}, http2.ErrCodeProtocol},
+		{http2.Setting{ID: http2.SettingMaxFrameSize, Val: 1 << 24}, http2.ErrCodeProtocol},
+		{http2.Setting{ID: http2.SettingInitialWindowSize, Val: 1 << 31}, http2.ErrCodeFlowControl},
+		{http2.Setting{ID: http2.SettingEnablePush, Val: 2}, http2.ErrCodeProtocol},
+		{http2.Setting{ID: settingEnableConnectProtocol, Val: 2}, http2.ErrCodeProtocol},
+	} {
+		c := newH2TestClient(t, blockingHandler(t))
+		c.handshake()
+		if err := c.framer.WriteSettings(test.setting); err != nil {
+			t.Fatal(err)
+		}
+		c.expectGoAway(test.code)
+	}
+}
+
+func TestH2InitialWindowOverflow(t *testing.T) {
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+
+	if err := c.openStream(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.framer.WriteWindowUpdate(1, h2MaxWindow-h2DefaultWindow); err != nil {
+		t.Fatal(err)
+	}
+	// the window of the stream would be past the max
+	if err := c.framer.WriteSettings(http2.Setting{ID: http2.SettingInitialWindowSize, Val: h2DefaultWindow + 1}); err != nil {
+		t.Fatal(err)
+	}
+	c.expectGoAway(http2.ErrCodeFlowControl)
+}
+
+func TestH2WindowUpdateOverflow(t *testing.T) {
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+
+	if err := c.openStream(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.framer.WriteWindowUpdate(1, h2MaxWindow); err != nil {
+		t.Fatal(err)
+	}
+	frame, ok := c.expectFrame().(*http2.RSTStreamFrame)
+	if !ok || frame.StreamID != 1 || frame.ErrCode != http2.ErrCodeFlowControl {
+		t.Fatalf("expected RST_STREAM FLOW_CONTROL_ERROR of stream 1, got %v", frame)
+	}
+
+	if err := c.framer.WriteWindowUpdate(0, h2MaxWindow); err != nil {
+		t.Fatal(err)
+	}
+	c.expectGoAway(http2.ErrCodeFlowControl)
+}
+
+func TestH2DataOnIdleStream(t *testing.T) {
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+
+	if err := c.framer.WriteData(1, false, []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	c.expectGoAway(http2.ErrCodeProtocol)
+}
+
+func TestH2DataOnClosedStream(t *testing.T) {
+	c := newH2TestClient(t, blockingHandler(t))
+	c.handshake()
+
+	if err := c.openStream(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.framer.WriteData(1, true, []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.framer.WriteData(1, false, []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	frame, ok := c.expectFrame().(*http2.RSTStreamFrame)
+	if !ok || frame.StreamID != 1 || frame.ErrCode != http2.ErrCodeStreamClosed {
+		t.Fatalf("expected RST_STREAM STREAM_CLOSED of stream 1, got %v", frame)
+	}
+}
+
+func TestH2ConnSetClose(t *testing.T) {
+	conns := &h2ConnSet{}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	done := make(chan struct{})
+	go func() {
+		serveH2(serverConn, blockingHandler(t), conns)
+		close(done)
+	}()
+
+	c := &h2TestClient{t: t, conn: clientConn, framer: http2.NewFramer(clientConn, clientConn)}
+	go func() {
+		for {
+			if _, err := c.framer.ReadFrame(); err != nil {
+				return
+			}
+		}
+	}()
+	c.handshake()
+	for {
+		conns.access.Lock()
+		added := len(conns.conns) == 1
+		conns.access.Unlock()
+		if added {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	conns.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("connection not closed with the listener")
+	}
+}
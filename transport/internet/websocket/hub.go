@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -17,13 +18,17 @@ import (
 	"github.com/luckyluke-a/xray-core/common/net"
 	http_proto "github.com/luckyluke-a/xray-core/common/protocol/http"
 	"github.com/luckyluke-a/xray-core/transport/internet"
+	"github.com/luckyluke-a/xray-core/transport/internet/httpfallback"
 	v2tls "github.com/luckyluke-a/xray-core/transport/internet/tls"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 )
 
 type requestHandler struct {
-	host string
-	path string
-	ln   *Listener
+	host     string
+	path     string
+	ln       *Listener
+	fallback *httpfallback.Handler
 }
 
 var replacer = strings.NewReplacer("+", "-", "/", "_", "=", "")
@@ -40,22 +45,28 @@ var upgrader = &websocket.Upgrader{
 func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	if len(h.host) > 0 && !internet.IsValidHTTPHost(request.Host, h.host) {
 		errors.LogInfo(context.Background(), "failed to validate host, request:", request.Host, ", config:", h.host)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request)
 		return
 	}
 	if request.URL.Path != h.path {
 		errors.LogInfo(context.Background(), "failed to validate path, request:", request.URL.Path, ", config:", h.path)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request)
+		return
+	}
+	if isExtendedConnect(request) {
+		h.serveExtendedConnect(writer, request)
+		return
+	}
+	if h.fallback != nil && !websocket.IsWebSocketUpgrade(request) {
+		errors.LogInfo(context.Background(), "not a WebSocket request, falling back")
+		h.fallback.ServeHTTP(writer, request)
 		return
 	}
 
-	var extraReader io.Reader
+	extraReader, protocol := decodeEarlyData(request)
 	responseHeader := http.Header{}
-	if str := request.Header.Get("Sec-WebSocket-Protocol"); str != "" {
-		if ed, err := base64.RawURLEncoding.DecodeString(replacer.Replace(str)); err == nil && len(ed) > 0 {
-			extraReader = bytes.NewReader(ed)
-			responseHeader.Set("Sec-WebSocket-Protocol", str)
-		}
+	if protocol != "" {
+		responseHeader.Set("Sec-WebSocket-Protocol", protocol)
 	}
 
 	conn, err := upgrader.Upgrade(writer, request, responseHeader)
@@ -64,24 +75,81 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
+	h.ln.addConn(NewConnection(conn, forwardedRemoteAddr(request, conn.RemoteAddr()), extraReader))
+}
+
+// serveExtendedConnect accepts WebSocket over HTTP/2 or HTTP/3, whose stream
+// lives as long as the request.
+func (h *requestHandler) serveExtendedConnect(writer http.ResponseWriter, request *http.Request) {
+	extraReader, protocol := decodeEarlyData(request)
+	if protocol != "" {
+		writer.Header().Set("Sec-WebSocket-Protocol", protocol)
+	}
+	writer.WriteHeader(http.StatusOK)
+	stream := newServerStream(writer, request)
+	if err := stream.controller.Flush(); err != nil {
+		errors.LogInfoInner(request.Context(), err, "failed to accept WebSocket stream")
+		return
+	}
+
+	var remoteAddr net.Addr = &net.TCPAddr{}
+	if addr, err := net.ResolveTCPAddr("tcp", request.RemoteAddr); err == nil {
+		remoteAddr = addr
+	}
+	var localAddr net.Addr = &net.TCPAddr{}
+	if addr, ok := request.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		localAddr = addr
+	}
+	h.ln.addConn(newFrameConn(stream, true, localAddr, forwardedRemoteAddr(request, remoteAddr), extraReader))
+
+	select {
+	case <-stream.done.Wait():
+	case <-request.Context().Done():
+		stream.Close()
+	}
+}
+
+// decodeEarlyData returns the early data in Sec-WebSocket-Protocol, and the
+// header to echo.
+func decodeEarlyData(request *http.Request) (io.Reader, string) {
+	if str := request.Header.Get("Sec-WebSocket-Protocol"); str != "" {
+		if ed, err := base64.RawURLEncoding.DecodeString(replacer.Replace(str)); err == nil && len(ed) > 0 {
+			return bytes.NewReader(ed), str
+		}
+	}
+	return nil, ""
+}
+
+func forwardedRemoteAddr(request *http.Request, remoteAddr net.Addr) net.Addr {
 	forwardedAddrs := http_proto.ParseXForwardedFor(request.Header)
-	remoteAddr := conn.RemoteAddr()
 	if len(forwardedAddrs) > 0 && forwardedAddrs[0].Family().IsIP() {
-		remoteAddr = &net.TCPAddr{
+		return &net.TCPAddr{
 			IP:   forwardedAddrs[0].IP(),
 			Port: int(0),
 		}
 	}
+	return remoteAddr
+}
 
-	h.ln.addConn(NewConnection(conn, remoteAddr, extraReader))
+// reject answers a request which is not for the transport.
+func (h *requestHandler) reject(writer http.ResponseWriter, request *http.Request) {
+	if h.fallback != nil {
+		h.fallback.ServeHTTP(writer, request)
+		return
+	}
+	writer.WriteHeader(http.StatusNotFound)
 }
 
 type Listener struct {
 	sync.Mutex
-	server   http.Server
-	listener net.Listener
-	config   *Config
-	addConn  internet.ConnHandler
+	server     http.Server
+	h2conns    h2ConnSet
+	h3server   *http3.Server
+	listener   net.Listener
+	h3conn     net.PacketConn
+	h3listener *quic.EarlyListener
+	config     *Config
+	addConn    internet.ConnHandler
 }
 
 func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
@@ -90,14 +158,56 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 	}
 	wsSettings := streamSettings.ProtocolSettings.(*Config)
 	l.config = wsSettings
+	fallback, err := httpfallback.New(wsSettings.Fallback)
+	if err != nil {
+		return nil, errors.New("failed to create fallback (for WS)").Base(err)
+	}
 	if l.config != nil {
 		if streamSettings.SocketSettings == nil {
 			streamSettings.SocketSettings = &internet.SocketConfig{}
 		}
 		streamSettings.SocketSettings.AcceptProxyProtocol = l.config.AcceptProxyProtocol || streamSettings.SocketSettings.AcceptProxyProtocol
 	}
+	handler := &requestHandler{
+		host:     wsSettings.Host,
+		path:     wsSettings.GetNormalizedPath(),
+		ln:       l,
+		fallback: fallback,
+	}
+	var tlsConfig *tls.Config
+	if config := v2tls.ConfigFromStreamSettings(streamSettings); config != nil {
+		tlsConfig = config.GetTLSConfig()
+	}
+
+	if tlsConfig != nil && len(tlsConfig.NextProtos) == 1 && tlsConfig.NextProtos[0] == "h3" {
+		conn, err := internet.ListenSystemPacket(context.Background(), &net.UDPAddr{
+			IP:   address.IP(),
+			Port: int(port),
+		}, streamSettings.SocketSettings)
+		if err != nil {
+			return nil, errors.New("failed to listen UDP(for WS3) on ", address, ":", port).Base(err)
+		}
+		h3listener, err := quic.ListenEarly(conn, tlsConfig, nil)
+		if err != nil {
+			conn.Close()
+			return nil, errors.New("failed to listen QUIC(for WS3) on ", address, ":", port).Base(err)
+		}
+		l.h3conn = conn
+		l.h3listener = h3listener
+		errors.LogInfo(ctx, "listening QUIC(for WS3) on ", address, ":", port)
+
+		l.h3server = &http3.Server{
+			Handler: handler,
+		}
+		go func() {
+			if err := l.h3server.ServeListener(l.h3listener); err != nil {
+				errors.LogWarningInner(ctx, err, "failed to serve http3 for WebSocket")
+			}
+		}()
+		return l, nil
+	}
+
 	var listener net.Listener
-	var err error
 	if port == net.Port(0) { // unix
 		listener, err = internet.ListenSystem(ctx, &net.UnixAddr{
 			Name: address.Domain(),
@@ -122,23 +232,25 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 		errors.LogWarning(ctx, "accepting PROXY protocol")
 	}
 
-	if config := v2tls.ConfigFromStreamSettings(streamSettings); config != nil {
-		if tlsConfig := config.GetTLSConfig(); tlsConfig != nil {
-			listener = tls.NewListener(listener, tlsConfig)
-		}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
 	}
 
 	l.listener = listener
 
 	l.server = http.Server{
-		Handler: &requestHandler{
-			host: wsSettings.Host,
-			path: wsSettings.GetNormalizedPath(),
-			ln:   l,
-		},
+		Handler:           handler,
 		ReadHeaderTimeout: time.Second * 4,
 		MaxHeaderBytes:    8192,
 	}
+	if tlsConfig != nil && slices.Contains(tlsConfig.NextProtos, "h2") {
+		// the HTTP/2 server of net/http doesn't support extended CONNECT
+		l.server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){
+			"h2": func(_ *http.Server, conn *tls.Conn, handler http.Handler) {
+				serveH2(conn, handler, &l.h2conns)
+			},
+		}
+	}
 
 	go func() {
 		if err := l.server.Serve(l.listener); err != nil {
@@ -151,12 +263,19 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 
 // Addr implements net.Listener.Addr().
 func (ln *Listener) Addr() net.Addr {
+	if ln.h3listener != nil {
+		return ln.h3listener.Addr()
+	}
 	return ln.listener.Addr()
 }
 
-// Close implements net.Listener.Close().
+// Close implements net.Listener.Close(). The HTTP/2 and HTTP/3 connections are
+// closed too.
 func (ln *Listener) Close() error {
-	return ln.listener.Close()
+	if ln.h3server != nil {
+		return errors.Combine(ln.h3server.Close(), ln.h3listener.Close(), ln.h3conn.Close())
+	}
+	return errors.Combine(ln.server.Close(), ln.h2conns.Close())
 }
 
 func init() {
@@ -2,7 +2,11 @@ package websocket_test
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"runtime"
+	"strconv"
 	"testing"
 	"time"
 
@@ -10,7 +14,9 @@ import (
 	"github.com/luckyluke-a/xray-core/common/net"
 	"github.com/luckyluke-a/xray-core/common/protocol/tls/cert"
 	"github.com/luckyluke-a/xray-core/testing/servers/tcp"
+	"github.com/luckyluke-a/xray-core/testing/servers/udp"
 	"github.com/luckyluke-a/xray-core/transport/internet"
+	"github.com/luckyluke-a/xray-core/transport/internet/httpfallback"
 	"github.com/luckyluke-a/xray-core/transport/internet/stat"
 	"github.com/luckyluke-a/xray-core/transport/internet/tls"
 	. "github.com/luckyluke-a/xray-core/transport/internet/websocket"
@@ -151,3 +157,103 @@ func Test_listenWSAndDial_TLS(t *testing.T) {
 		t.Error("end: ", end, " start: ", start)
 	}
 }
+
+func Test_listenWSWithFallback(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("web " + request.URL.Path))
+	}))
+	defer web.Close()
+
+	listenPort := tcp.PickPort()
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path: "ws",
+			Fallback: &httpfallback.Config{
+				Type: "tcp",
+				Dest: web.Listener.Addr().String(),
+			},
+		},
+	}, func(conn stat.Connection) {
+		conn.Close()
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	for _, path := range []string{"/", "/ws"} {
+		resp, err := http.Get("http://" + net.LocalHostIP.String() + ":" + listenPort.String() + path)
+		common.Must(err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		common.Must(err)
+		if string(body) != "web "+path {
+			t.Error("unexpected response for ", path, ": ", string(body))
+		}
+	}
+}
+
+func testListenWSAndDialExtendedConnect(t *testing.T, alpn string, listenPort net.Port) {
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path: "ws",
+			Ed:   16,
+		},
+		SecurityType: "tls",
+		SecuritySettings: &tls.Config{
+			AllowInsecure:           true,
+			EnableSessionResumption: true,
+			NextProtocol:            []string{alpn},
+			Certificate:             []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil, cert.CommonName("localhost")))},
+		},
+	}
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, listenPort, streamSettings, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			for {
+				c.SetReadDeadline(time.Now().Add(2 * time.Second))
+				n, err := c.Read(b[:])
+				if err != nil {
+					return
+				}
+				if _, err := c.Write(b[:n]); err != nil {
+					return
+				}
+			}
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	var conns []stat.Connection
+	for i := 0; i < 3; i++ {
+		conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), listenPort), streamSettings)
+		common.Must(err)
+		conns = append(conns, conn)
+	}
+	for i, conn := range conns {
+		for _, payload := range []string{"early data", "payload larger than the early data"} {
+			payload += " " + strconv.Itoa(i)
+			common.Must2(conn.Write([]byte(payload)))
+			b := make([]byte, len(payload))
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			common.Must2(io.ReadFull(conn, b))
+			if string(b) != payload {
+				t.Error("response: ", string(b))
+			}
+		}
+	}
+	for _, conn := range conns {
+		common.Must(conn.Close())
+	}
+}
+
+func Test_listenWSAndDial_H2(t *testing.T) {
+	testListenWSAndDialExtendedConnect(t, "h2", tcp.PickPort())
+}
+
+func Test_listenWSAndDial_H3(t *testing.T) {
+	testListenWSAndDialExtendedConnect(t, "h3", udp.PickPort())
+}